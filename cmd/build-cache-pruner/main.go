@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/logrusutil"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/buildcache"
+)
+
+type options struct {
+	kubernetesOptions flagutil.KubernetesOptions
+	namespace         string
+	ttl               time.Duration
+	dry               bool
+}
+
+func opts() (*options, error) {
+	o := &options{kubernetesOptions: flagutil.KubernetesOptions{NOInClusterConfigDefault: true}}
+	fs := flag.CommandLine
+	o.kubernetesOptions.AddFlags(fs)
+	fs.StringVar(&o.namespace, "namespace", "", "Namespace holding the build cache")
+	fs.DurationVar(&o.ttl, "ttl", 7*24*time.Hour, "How long cached images are kept after they were published")
+	fs.BoolVar(&o.dry, "dry-run", true, "Enable dry-run")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+	return o, nil
+}
+
+func (o *options) validate() error {
+	if o.namespace == "" {
+		return fmt.Errorf("--namespace is required")
+	}
+	if o.ttl <= 0 {
+		return fmt.Errorf("--ttl must be positive")
+	}
+	return o.kubernetesOptions.Validate(o.dry)
+}
+
+func main() {
+	logrusutil.ComponentInit()
+
+	o, err := opts()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to get options")
+	}
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+	if err := imagev1.AddToScheme(scheme.Scheme); err != nil {
+		logrus.WithError(err).Fatal("Failed to add imagev1 to scheme")
+	}
+
+	kubeconfigs, err := o.kubernetesOptions.LoadClusterConfigs()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load kubeconfigs")
+	}
+	if len(kubeconfigs) == 0 {
+		logrus.Fatal("No kubeconfigs available")
+	}
+
+	ctx := signals.SetupSignalHandler()
+	var failed bool
+	for cluster, config := range kubeconfigs {
+		config := config
+		logger := logrus.WithField("cluster", cluster)
+		client, err := ctrlruntimeclient.New(&config, ctrlruntimeclient.Options{})
+		if err != nil {
+			logger.WithError(err).Error("Failed to construct client for cluster")
+			failed = true
+			continue
+		}
+		if o.dry {
+			client = ctrlruntimeclient.NewDryRunClient(client)
+		}
+		if err := prune(ctx, logger, buildcache.New(client, o.namespace), o.ttl); err != nil {
+			logger.WithError(err).Error("Failed to prune the build cache")
+			failed = true
+		}
+	}
+	if failed {
+		logrus.Fatal("Failed to prune the build cache on some clusters")
+	}
+}
+
+func prune(ctx context.Context, logger *logrus.Entry, cache *buildcache.Cache, ttl time.Duration) error {
+	pruned, err := cache.Prune(ctx, ttl)
+	logger.WithField("pruned", len(pruned)).Info("Pruned the build cache")
+	return err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kataras/tablewriter"
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/registry/server"
+)
+
+type options struct {
+	resolverAddress string
+	configDir       string
+	registryPath    string
+	output          string
+
+	query server.Query
+}
+
+func gatherOptions() options {
+	o := options{}
+	flag.StringVar(&o.resolverAddress, "resolver-address", api.URLForService(api.ServiceConfig), "Address of the configresolver to query, used when --config-dir is not set.")
+	flag.StringVar(&o.configDir, "config-dir", "", "Path to a directory with ci-operator configurations to query locally instead of asking the configresolver.")
+	flag.StringVar(&o.registryPath, "registry", "", "Path to the step registry, required with --config-dir.")
+	flag.StringVar(&o.output, "output", "table", "Output format, one of table or json.")
+	flag.StringVar(&o.query.Step, "step", "", "Select tests that run the step.")
+	flag.StringVar(&o.query.Chain, "chain", "", "Select tests that run the chain.")
+	flag.StringVar(&o.query.Workflow, "workflow", "", "Select tests that use the workflow.")
+	flag.StringVar(&o.query.ClusterProfile, "cluster-profile", "", "Select tests that run with the cluster profile.")
+	flag.StringVar(&o.query.Env, "env", "", "Select tests that set the variable, given as NAME or NAME=VALUE.")
+	flag.StringVar(&o.query.Image, "image", "", "Select tests with steps that run in or depend on the pipeline image tag.")
+	flag.StringVar(&o.query.Lease, "lease", "", "Select tests that acquire a lease of the resource type.")
+	flag.Parse()
+	return o
+}
+
+func (o *options) validate() error {
+	if o.query.Empty() {
+		return errors.New("at least one of --step, --chain, --workflow, --cluster-profile, --env, --image or --lease must be set")
+	}
+	if (o.configDir == "") != (o.registryPath == "") {
+		return errors.New("--config-dir and --registry must be set together")
+	}
+	if o.output != "table" && o.output != "json" {
+		return fmt.Errorf("--output must be one of table or json, got %q", o.output)
+	}
+	return nil
+}
+
+// localRegistry serves queries from a registry loaded from disk
+type localRegistry struct {
+	registry.Resolver
+	references registry.ReferenceByName
+	chains     registry.ChainByName
+	workflows  registry.WorkflowByName
+}
+
+func (r *localRegistry) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
+	return r.references, r.chains, r.workflows, nil, nil
+}
+
+func (o *options) run() ([]server.QueryResult, error) {
+	if o.configDir == "" {
+		return server.NewResolverClient(o.resolverAddress).Query(o.query)
+	}
+	configs, err := config.LoadByOrgRepo(o.configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configurations: %w", err)
+	}
+	references, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the registry: %w", err)
+	}
+	reg := &localRegistry{
+		Resolver:   registry.NewResolver(references, chains, workflows, observers),
+		references: references,
+		chains:     chains,
+		workflows:  workflows,
+	}
+	return server.RunQuery(server.ResolveTests(configs, reg), reg, o.query), nil
+}
+
+func printResults(out io.Writer, results []server.QueryResult) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"org", "repo", "branch", "variant", "test", "workflow", "cluster profile"})
+	for _, result := range results {
+		m := result.Metadata
+		table.Append([]string{m.Org, m.Repo, m.Branch, m.Variant, result.Test, result.Workflow, string(result.ClusterProfile)})
+	}
+	table.Render()
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+	results, err := o.run()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to query configurations")
+	}
+	if o.output == "json" {
+		if results == nil {
+			results = []server.QueryResult{}
+		}
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to marshal results")
+		}
+		fmt.Println(string(raw))
+		return
+	}
+	printResults(os.Stdout, results)
+}
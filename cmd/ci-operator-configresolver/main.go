@@ -32,6 +32,7 @@ import (
 type options struct {
 	configPath             string
 	registryPath           string
+	secretBootstrapConfig  string
 	logLevel               string
 	address                string
 	releaseRepoGitSyncPath string
@@ -41,9 +42,14 @@ type options struct {
 	gracePeriod            time.Duration
 	validateOnly           bool
 	flatRegistry           bool
+	registryHistory        int
+	registryHistoryDir     string
 	instrumentationOptions flagutil.InstrumentationOptions
 }
 
+// secretBootstrapConfigInRepoPath is where the ci-secret-bootstrap config lives in the release repository
+const secretBootstrapConfigInRepoPath = "core-services/ci-secret-bootstrap/_config.yaml"
+
 var (
 	configresolverMetrics = metrics.NewMetrics("configresolver")
 )
@@ -53,6 +59,7 @@ func gatherOptions() (options, error) {
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	fs.StringVar(&o.configPath, "config", "", "Path to config dirs")
 	fs.StringVar(&o.registryPath, "registry", "", "Path to registry dirs")
+	fs.StringVar(&o.secretBootstrapConfig, "secret-bootstrap-config", "", "Path to the ci-secret-bootstrap config, used to serve the secret usage endpoint")
 	fs.StringVar(&o.releaseRepoGitSyncPath, "release-repo-git-sync-path", "", "Path to release repository dir")
 	fs.StringVar(&o.logLevel, "log-level", "info", "Level at which to log output.")
 	fs.StringVar(&o.address, "address", ":8080", "DEPRECATED: Address to run server on")
@@ -63,6 +70,8 @@ func gatherOptions() (options, error) {
 	_ = fs.Duration("cycle", time.Minute*2, "Legacy flag kept for compatibility. Does nothing")
 	fs.BoolVar(&o.validateOnly, "validate-only", false, "Load the config and registry, validate them and exit.")
 	fs.BoolVar(&o.flatRegistry, "flat-registry", false, "Disable directory structure based registry validation")
+	fs.IntVar(&o.registryHistory, "registry-history", 20, "How many generations of the registry to retain for the history pages of the registry UI")
+	fs.StringVar(&o.registryHistoryDir, "registry-history-dir", "", "Directory to persist the retained generations of the registry in, so that the history survives restarts. If unset, the history is only kept in memory and starts over on every restart.")
 	o.instrumentationOptions.AddFlags(fs)
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return o, fmt.Errorf("failed to parse flags: %w", err)
@@ -76,8 +85,8 @@ func validateOptions(o *options) error {
 		return fmt.Errorf("invalid --log-level: %w", err)
 	}
 
-	if o.releaseRepoGitSyncPath != "" && (o.configPath != "" || o.registryPath != "") {
-		return fmt.Errorf("--release-repo-path is mutually exclusive with --config and --registry")
+	if o.releaseRepoGitSyncPath != "" && (o.configPath != "" || o.registryPath != "" || o.secretBootstrapConfig != "") {
+		return fmt.Errorf("--release-repo-path is mutually exclusive with --config, --registry and --secret-bootstrap-config")
 	}
 
 	if o.releaseRepoGitSyncPath == "" {
@@ -112,6 +121,7 @@ func validateOptions(o *options) error {
 
 		o.configPath = filepath.Join(o.releaseRepoGitSyncPath, config.CiopConfigInRepoPath)
 		o.registryPath = filepath.Join(o.releaseRepoGitSyncPath, config.RegistryPath)
+		o.secretBootstrapConfig = filepath.Join(o.releaseRepoGitSyncPath, secretBootstrapConfigInRepoPath)
 	}
 
 	if o.validateOnly && o.flatRegistry {
@@ -185,7 +195,7 @@ func main() {
 	go func() { logrus.Fatal(<-configErrCh) }()
 
 	registryErrCh := make(chan error)
-	registryAgent, err := agents.NewRegistryAgent(o.registryPath, registryErrCh, agents.WithRegistryMetrics(configresolverMetrics.ErrorRate), agents.WithRegistryFlat(o.flatRegistry), registryAgentOption)
+	registryAgent, err := agents.NewRegistryAgent(o.registryPath, registryErrCh, agents.WithRegistryMetrics(configresolverMetrics.ErrorRate), agents.WithRegistryFlat(o.flatRegistry), agents.WithRegistryHistory(o.registryHistory), agents.WithRegistryHistoryDir(o.registryHistoryDir), registryAgentOption)
 	if err != nil {
 		logrus.Fatalf("Failed to get registry agent: %v", err)
 	}
@@ -203,6 +213,8 @@ func main() {
 	simplifier := simplifypath.NewSimplifier(l("", // shadow element mimicing the root
 		l("config"),
 		l("resolve"),
+		l("query"),
+		l("secretUsage"),
 		l("configGeneration"),
 		l("registryGeneration"),
 	))
@@ -214,6 +226,11 @@ func main() {
 		l("reference"),
 		l("chain"),
 		l("workflow"),
+		l("history",
+			l("reference"),
+			l("chain"),
+			l("workflow"),
+		),
 	))
 	handler := metrics.TraceHandler(simplifier, configresolverMetrics.HTTPRequestDuration, configresolverMetrics.HTTPResponseSize)
 	uihandler := metrics.TraceHandler(uisimplifier, configresolverMetrics.HTTPRequestDuration, configresolverMetrics.HTTPResponseSize)
@@ -223,6 +240,12 @@ func main() {
 	http.HandleFunc("/configWithInjectedTest", handler(registryserver.ResolveConfigWithInjectedTest(configAgent, registryAgent, configresolverMetrics)).ServeHTTP)
 	http.HandleFunc("/mergeConfigsWithInjectedTest", handler(registryserver.ResolveAndMergeConfigsAndInjectTest(configAgent, registryAgent, configresolverMetrics)).ServeHTTP)
 	http.HandleFunc("/resolve", handler(registryserver.ResolveLiteralConfig(registryAgent, configresolverMetrics)).ServeHTTP)
+	// resolving every test is expensive, so the queries share the resolved tests
+	resolvedTests := registryserver.NewResolvedTestCache(configAgent, registryAgent)
+	http.HandleFunc("/query", handler(registryserver.QueryConfigs(resolvedTests, registryAgent, configresolverMetrics)).ServeHTTP)
+	if o.secretBootstrapConfig != "" {
+		http.HandleFunc("/secretUsage", handler(registryserver.SecretUsageHandler(resolvedTests, o.secretBootstrapConfig, configresolverMetrics)).ServeHTTP)
+	}
 	http.HandleFunc("/configGeneration", handler(getConfigGeneration(configAgent)).ServeHTTP)
 	http.HandleFunc("/registryGeneration", handler(getRegistryGeneration(registryAgent)).ServeHTTP)
 	http.HandleFunc("/readyz", func(_ http.ResponseWriter, _ *http.Request) {})
@@ -80,6 +80,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/registry/server"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/secrets"
+	stepgraphanalyzer "github.com/openshift/ci-tools/pkg/step-graph-analyzer"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/util"
 	"github.com/openshift/ci-tools/pkg/util/gzip"
@@ -352,9 +353,13 @@ type options struct {
 	targets stringSlice
 	promote bool
 
-	verbose    bool
-	help       bool
-	printGraph bool
+	verbose           bool
+	help              bool
+	printGraph        bool
+	plan              bool
+	planOutput        string
+	planArchitectures stringSlice
+	resume            bool
 
 	writeParams string
 	artifactDir string
@@ -424,6 +429,14 @@ type options struct {
 	targetAdditionalSuffix string
 	manifestToolDockerCfg  string
 	localRegistryDNS       string
+	buildCacheNamespace    string
+
+	imageBuilder      string
+	buildahImage      string
+	buildahAuthSecret string
+	buildahTLSVerify  bool
+	buildahPrivileged bool
+	buildah           *steps.BuildahConfig
 }
 
 func bindOptions(flag *flag.FlagSet) *options {
@@ -448,6 +461,10 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.StringVar(&opt.unresolvedConfigPath, "unresolved-config", "", "The configuration file, before resolution. If not specified the UNRESOLVED_CONFIG environment variable will be used, if set.")
 	flag.Var(&opt.targets, "target", "One or more targets in the configuration to build. Only steps that are required for this target will be run.")
 	flag.BoolVar(&opt.printGraph, "print-graph", opt.printGraph, "Print a directed graph of the build steps and exit. Intended for use with the golang digraph utility.")
+	flag.BoolVar(&opt.plan, "plan", false, "Render the objects the steps for the targets would create in the test namespace as YAML and exit, without contacting the cluster. Steps that do not support planning are listed at the start of the plan.")
+	flag.StringVar(&opt.planOutput, "plan-output", "", "With --plan, a directory to write the plan to, with a directory per step and a file per object. If not specified, the plan is printed as a multi-document YAML stream.")
+	flag.Var(&opt.planArchitectures, "plan-node-architecture", "With --plan, the architecture of the nodes to plan builds for. Can be passed multiple times. Defaults to amd64.")
+	flag.BoolVar(&opt.resume, "resume", false, "Record completed steps with reusable outputs, like image builds, in a checkpoint in the test namespace and skip steps recorded by a previous, interrupted attempt of the same job execution (same job name, build ID and ProwJob ID) when the images they created still exist. Tests are always executed.")
 
 	// add to the graph of things we run or create
 	flag.Var(&opt.templatePaths, "template", "A set of paths to optional templates to add as stages to this job. Each template is expected to contain at least one restart=Never pod. Parameters are filled from environment or from the automatic parameters generated by the operator.")
@@ -496,12 +513,35 @@ func bindOptions(flag *flag.FlagSet) *options {
 
 	flag.StringVar(&opt.manifestToolDockerCfg, "manifest-tool-dockercfg", "/secrets/manifest-tool/.dockerconfigjson", "The dockercfg file path to be used to push the manifest listed image after build. This is being used by the manifest-tool binary.")
 	flag.StringVar(&opt.localRegistryDNS, "local-registry-dns", "image-registry.openshift-image-registry.svc:5000", "Defines the target image registry.")
+	flag.StringVar(&opt.buildCacheNamespace, "build-cache-namespace", "", "If set, the namespace holding images built by other ci-operator runs. Builds whose inputs match a cached image reuse it instead of running, and successful builds are published to it. The namespace must be able to pull images from the job namespaces, and the service accounts of every job namespace must be able to pull images from it, e.g. with a RoleBinding of the system:image-puller ClusterRole to the system:serviceaccounts group in it, which ci-operator does not create. Without that access, restoring cached images fails and the images are built instead.")
+
+	flag.StringVar(&opt.imageBuilder, "image-builder", imageBuilderOpenShift, fmt.Sprintf("How to run image builds: %q creates OpenShift Builds, %q runs buildah in pods instead and does not need the OpenShift build subsystem. Either way, ci-operator needs the OpenShift image stream API: builds read their inputs from and push their outputs to image streams in the registry set by --local-registry-dns, so plain Kubernetes clusters are not supported. The buildah pods run as root with the SETFCAP capability, which needs the baseline Pod Security Standard in the test namespace and, on OpenShift, the anyuid SCC, or privileged with --buildah-privileged.", imageBuilderOpenShift, imageBuilderBuildah))
+	flag.StringVar(&opt.buildahImage, "buildah-image", steps.DefaultBuildahImage, "The buildah image to run builds with when --image-builder=buildah.")
+	flag.StringVar(&opt.buildahAuthSecret, "buildah-auth-secret", "", "The name of a secret of type kubernetes.io/dockerconfigjson in the test namespace with credentials to pull from and push to the registry set by --local-registry-dns, when --image-builder=buildah.")
+	flag.BoolVar(&opt.buildahTLSVerify, "buildah-tls-verify", true, "Whether buildah verifies the certificates of registries when --image-builder=buildah.")
+	flag.BoolVar(&opt.buildahPrivileged, "buildah-privileged", false, "Whether the build pods run privileged when --image-builder=buildah. Otherwise they run buildah as root with the SETFCAP capability, which needs the baseline Pod Security Standard in the test namespace and, on OpenShift, the anyuid SCC; privileged pods need the privileged Pod Security Standard and SCC.")
 
 	opt.resultsOptions.Bind(flag)
 	return opt
 }
 
+const (
+	imageBuilderOpenShift = "openshift"
+	imageBuilderBuildah   = "buildah"
+)
+
 func (o *options) Complete() error {
+	switch o.imageBuilder {
+	case imageBuilderOpenShift:
+	case imageBuilderBuildah:
+		o.buildah = &steps.BuildahConfig{Image: o.buildahImage, Registry: o.localRegistryDNS, AuthSecret: o.buildahAuthSecret, TLSVerify: o.buildahTLSVerify, Privileged: o.buildahPrivileged}
+	default:
+		return fmt.Errorf("--image-builder must be one of %q or %q, not %q", imageBuilderOpenShift, imageBuilderBuildah, o.imageBuilder)
+	}
+	if err := o.resultsOptions.InstallClassifier(); err != nil {
+		return err
+	}
+
 	jobSpec, err := api.ResolveSpecFromEnv()
 	if err != nil {
 		if len(o.gitRef) == 0 {
@@ -660,21 +700,24 @@ func (o *options) Complete() error {
 		o.templates = append(o.templates, template)
 	}
 
-	clusterConfig, err := util.LoadClusterConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load cluster config: %w", err)
-	}
+	// plans are rendered without contacting the cluster
+	if !o.plan {
+		clusterConfig, err := util.LoadClusterConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load cluster config: %w", err)
+		}
 
-	if len(o.impersonateUser) > 0 {
-		clusterConfig.Impersonate = rest.ImpersonationConfig{UserName: o.impersonateUser}
-	}
+		if len(o.impersonateUser) > 0 {
+			clusterConfig.Impersonate = rest.ImpersonationConfig{UserName: o.impersonateUser}
+		}
 
-	if o.verbose {
-		clusterConfig.ContentType = "application/json"
-		clusterConfig.AcceptContentTypes = "application/json"
-	}
+		if o.verbose {
+			clusterConfig.ContentType = "application/json"
+			clusterConfig.AcceptContentTypes = "application/json"
+		}
 
-	o.clusterConfig = clusterConfig
+		o.clusterConfig = clusterConfig
+	}
 
 	if o.pullSecretPath != "" {
 		if o.pullSecret, err = getDockerConfigSecret(api.RegistryPullCredentialsSecret, o.pullSecretPath); err != nil {
@@ -864,6 +907,13 @@ func (o *options) Run() []error {
 		logrus.Infof("error: Process interrupted with signal %s, cancelling execution...", s)
 		cancel()
 	}
+	if o.plan {
+		if err := o.writePlan(ctx); err != nil {
+			return []error{fmt.Errorf("could not plan the execution: %w", err)}
+		}
+		return nil
+	}
+
 	var leaseClient *lease.Client
 	if o.leaseServer != "" && o.leaseServerCredentialsFile != "" {
 		leaseClient = &o.leaseClient
@@ -884,7 +934,7 @@ func (o *options) Run() []error {
 	// load the graph from the configuration
 	buildSteps, postSteps, err := defaults.FromConfig(ctx, o.configSpec, &o.graphConfig, o.jobSpec, o.templates, o.writeParams, o.promote, o.clusterConfig,
 		o.podPendingTimeout, leaseClient, o.targets.values, o.cloneAuthConfig, o.pullSecret, o.pushSecret, o.censor, o.hiveKubeconfig,
-		o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix, o.manifestToolDockerCfg, o.localRegistryDNS)
+		o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix, o.manifestToolDockerCfg, o.localRegistryDNS, o.buildCacheNamespace, o.buildah)
 	if err != nil {
 		return []error{results.ForReason("defaulting_config").WithError(err).Errorf("failed to generate steps from config: %v", err)}
 	}
@@ -927,6 +977,17 @@ func (o *options) Run() []error {
 		}
 
 		_ = api.SaveArtifact(o.censor, api.CIOperatorStepGraphJSONFilename, serializedGraph)
+
+		analysis := stepgraphanalyzer.Analyze(*graph)
+		if len(analysis.CriticalPath) > 0 {
+			logrus.Infof("Critical path of the execution (%s): %s", analysis.Duration().Truncate(time.Second), strings.Join(analysis.CriticalPath, " -> "))
+		}
+		trace, err := stepgraphanalyzer.Trace(*graph, analysis)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal graph trace")
+			return
+		}
+		_ = api.SaveArtifact(o.censor, stepgraphanalyzer.TraceJSONFilename, trace)
 	}()
 	// initialize the namespace if necessary and create any resources that must
 	// exist prior to execution
@@ -951,8 +1012,19 @@ func (o *options) Run() []error {
 		}
 		runtimeObject := &coreapi.ObjectReference{Namespace: o.namespace}
 		eventRecorder.Event(runtimeObject, coreapi.EventTypeNormal, "CiJobStarted", eventJobDescription(o.jobSpec, o.namespace))
+		var checkpoint steps.Checkpoint
+		if o.resume {
+			checkpointClient, err := ctrlruntimeclient.New(o.clusterConfig, ctrlruntimeclient.Options{})
+			if err != nil {
+				return []error{fmt.Errorf("could not get client for the checkpoint: %w", err)}
+			}
+			checkpoint = steps.NewConfigMapCheckpoint(checkpointClient, o.namespace, steps.CheckpointExecution(o.jobSpec))
+		}
 		// execute the graph
-		suites, graphDetails, errs := steps.Run(ctx, nodes)
+		suites, graphDetails, errs := steps.RunWithCheckpoint(ctx, nodes, checkpoint)
+		if report := o.censor.RedactionReport(); suites != nil && report != nil {
+			suites.Suites = append(suites.Suites, report)
+		}
 		if err := o.writeJUnit(suites, "operator"); err != nil {
 			logrus.WithError(err).Warn("Unable to write JUnit result.")
 		}
@@ -1309,16 +1381,7 @@ func (o *options) initializeNamespace() error {
 	logrus.Debugf("Setting up pipeline ImageStream for the test")
 
 	// create the image stream or read it to get its uid
-	is := &imageapi.ImageStream{
-		ObjectMeta: meta.ObjectMeta{
-			Namespace: o.jobSpec.Namespace(),
-			Name:      api.PipelineImageStream,
-		},
-		Spec: imageapi.ImageStreamSpec{
-			// pipeline:* will now be directly referenceable
-			LookupPolicy: imageapi.ImageLookupPolicy{Local: true},
-		},
-	}
+	is := pipelineImageStream(o.jobSpec.Namespace())
 	if err := client.Create(ctx, is); err != nil {
 		if !kerrors.IsAlreadyExists(err) {
 			return fmt.Errorf("could not set up pipeline imagestream for test: %w", err)
@@ -1360,6 +1423,19 @@ func (o *options) initializeNamespace() error {
 	return nil
 }
 
+func pipelineImageStream(namespace string) *imageapi.ImageStream {
+	return &imageapi.ImageStream{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: namespace,
+			Name:      api.PipelineImageStream,
+		},
+		Spec: imageapi.ImageStreamSpec{
+			// pipeline:* will now be directly referenceable
+			LookupPolicy: imageapi.ImageLookupPolicy{Local: true},
+		},
+	}
+}
+
 func generateAuthorAccessRoleBinding(namespace string, authors []string) *rbacapi.RoleBinding {
 	var subjects []rbacapi.Subject
 	authorSet := sets.New[string](authors...)
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/defaults"
+	"github.com/openshift/ci-tools/pkg/steps"
+)
+
+// planNamespaceID replaces the input hash in the name of the test namespace
+// when planning, as the hash depends on inputs resolved from the cluster.
+const planNamespaceID = "plan"
+
+// plannedStep holds the objects a step would create
+type plannedStep struct {
+	name    string
+	objects []ctrlruntimeclient.Object
+}
+
+// executionPlan holds the objects the steps would create. Steps that do not
+// support planning are listed as unplanned, as the plan is incomplete without
+// the objects they create.
+type executionPlan struct {
+	steps     []plannedStep
+	unplanned []string
+}
+
+// writePlan renders the objects that running the targets would create in the
+// test namespace, without contacting the cluster.
+func (o *options) writePlan(ctx context.Context) error {
+	if len(o.namespace) == 0 {
+		o.namespace = "ci-op-{id}"
+	}
+	o.namespace = strings.Replace(o.namespace, "{id}", planNamespaceID, -1)
+	o.jobSpec.SetNamespace(o.namespace)
+
+	architectures := o.planArchitectures.values
+	if len(architectures) == 0 {
+		architectures = []string{string(api.ReleaseArchitectureAMD64)}
+	}
+	buildSteps, postSteps, err := defaults.FromConfigForPlan(ctx, o.configSpec, &o.graphConfig, o.jobSpec, o.templates, o.promote, o.targets.values,
+		o.cloneAuthConfig, o.pullSecret, o.pushSecret, o.censor, o.nodeName, architectures, o.targetAdditionalSuffix, o.buildah)
+	if err != nil {
+		return fmt.Errorf("failed to generate steps from config: %w", err)
+	}
+	nodes, err := api.BuildPartialGraph(buildSteps, o.targets.values)
+	if err != nil {
+		return fmt.Errorf("could not build execution graph: %w", err)
+	}
+	stepList, errs := nodes.TopologicalSort()
+	if errs != nil {
+		return fmt.Errorf("could not sort nodes: %w", utilerrors.NewAggregate(errs))
+	}
+	var toPlan []api.Step
+	for _, node := range stepList {
+		toPlan = append(toPlan, node.Step)
+	}
+	if o.promote {
+		toPlan = append(toPlan, postSteps...)
+	}
+	plan, err := planSteps(o.planNamespace(), toPlan)
+	if err != nil {
+		return err
+	}
+	if len(plan.unplanned) > 0 {
+		logrus.Warnf("The plan is incomplete, steps that do not support planning are not part of it: %s", strings.Join(plan.unplanned, ", "))
+	}
+	if o.planOutput != "" {
+		return writePlanDir(o.planOutput, plan)
+	}
+	return writePlanYAML(os.Stdout, plan)
+}
+
+// planNamespace renders the objects initializeNamespace creates. Secrets are
+// rendered without their data.
+func (o *options) planNamespace() []ctrlruntimeclient.Object {
+	objects := []ctrlruntimeclient.Object{pipelineImageStream(o.namespace)}
+	if o.givePrAuthorAccessToNamespace && len(o.authors) > 0 {
+		objects = append(objects, generateAuthorAccessRoleBinding(o.namespace, o.authors))
+	}
+	secrets := []*coreapi.Secret{o.pullSecret, o.pushSecret, o.uploadSecret}
+	if o.cloneAuthConfig != nil {
+		secrets = append(secrets, o.cloneAuthConfig.Secret)
+	}
+	secrets = append(secrets, o.secrets...)
+	for _, secret := range secrets {
+		if secret == nil {
+			continue
+		}
+		objects = append(objects, &coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: o.namespace, Name: secret.Name},
+			Type:       secret.Type,
+		})
+	}
+	pdb, mutateFn := pdb(steps.CreatedByCILabel, o.namespace)
+	_ = mutateFn()
+	return append(objects, pdb)
+}
+
+// planSteps collects the objects every step would create. Steps that do not
+// support planning are recorded as unplanned.
+func planSteps(namespace []ctrlruntimeclient.Object, toPlan []api.Step) (executionPlan, error) {
+	plan := executionPlan{steps: []plannedStep{{name: "namespace", objects: namespace}}}
+	var errs []error
+	for _, step := range toPlan {
+		objects, ok, err := steps.Plan(step)
+		if !ok {
+			plan.unplanned = append(plan.unplanned, step.Name())
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not plan step %s: %w", step.Name(), err))
+			continue
+		}
+		if len(objects) > 0 {
+			plan.steps = append(plan.steps, plannedStep{name: step.Name(), objects: objects})
+		}
+	}
+	return plan, utilerrors.NewAggregate(errs)
+}
+
+// unplannedNotice explains which steps are missing from an incomplete plan
+func (p executionPlan) unplannedNotice(prefix string) string {
+	if len(p.unplanned) == 0 {
+		return ""
+	}
+	notice := prefix + "This plan is incomplete. The following steps do not support planning, the objects they create are not part of it:\n"
+	for _, name := range p.unplanned {
+		notice += prefix + "- " + name + "\n"
+	}
+	return notice
+}
+
+func encodePlanned(obj ctrlruntimeclient.Object) ([]byte, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the kind of %s: %w", obj.GetName(), err)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	return yaml.Marshal(obj)
+}
+
+// unplannedFile lists the steps missing from an incomplete plan written to a
+// directory
+const unplannedFile = "UNPLANNED"
+
+// writePlanYAML writes the plan as a multi-document YAML stream, starting with
+// a comment listing the unplanned steps if the plan is incomplete
+func writePlanYAML(w io.Writer, plan executionPlan) error {
+	if _, err := io.WriteString(w, plan.unplannedNotice("# ")); err != nil {
+		return err
+	}
+	for _, step := range plan.steps {
+		for _, obj := range step.objects {
+			raw, err := encodePlanned(obj)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "---\n# step: %s\n%s", step.name, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writePlanDir writes the plan with a directory per step and a file per object,
+// both numbered in the order they are created. Unplanned steps are listed in
+// a separate file if the plan is incomplete.
+func writePlanDir(dir string, plan executionPlan) error {
+	if notice := plan.unplannedNotice(""); notice != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("could not create directory for the plan: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, unplannedFile), []byte(notice), 0644); err != nil {
+			return fmt.Errorf("could not write %s: %w", unplannedFile, err)
+		}
+	}
+	for i, step := range plan.steps {
+		stepDir := filepath.Join(dir, fmt.Sprintf("%03d-%s", i, step.name))
+		if err := os.MkdirAll(stepDir, 0755); err != nil {
+			return fmt.Errorf("could not create directory for step %s: %w", step.name, err)
+		}
+		for j, obj := range step.objects {
+			raw, err := encodePlanned(obj)
+			if err != nil {
+				return err
+			}
+			kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+			name := fmt.Sprintf("%03d-%s-%s.yaml", j, kind, strings.ReplaceAll(obj.GetName(), ":", "-"))
+			if err := os.WriteFile(filepath.Join(stepDir, name), raw, 0644); err != nil {
+				return fmt.Errorf("could not write %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
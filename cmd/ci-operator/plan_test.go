@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestWritePlan(t *testing.T) {
+	jobSpec := &api.JobSpec{}
+	jobSpec.SetNamespace("ci-op-plan")
+	o := &options{namespace: "ci-op-plan", givePrAuthorAccessToNamespace: true, authors: []string{"author"}}
+	toPlan := []api.Step{
+		steps.ImagesReadyStep(nil),
+		steps.OutputImageTagStep(api.OutputImageTagStepConfiguration{
+			From: "app",
+			To:   api.ImageStreamTagReference{Name: api.StableImageStream, Tag: "app"},
+		}, nil, jobSpec),
+		&fakeValidationStep{name: "[release-inputs]"},
+	}
+	plan, err := planSteps(o.planNamespace(), toPlan)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := writePlanYAML(&out, plan); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+	testhelper.CompareWithFixture(t, out.String())
+
+	dir := t.TempDir()
+	if err := writePlanDir(dir, plan); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+	var files []string
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, rel)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to walk plan: %v", err)
+	}
+	expected := []string{
+		"000-namespace/000-imagestream-pipeline.yaml",
+		"000-namespace/001-rolebinding-ci-op-author-access.yaml",
+		"000-namespace/002-poddisruptionbudget-ci-operator-created-by-ci.yaml",
+		"001-[output:stable:app]/000-imagestreamtag-stable-app.yaml",
+		"UNPLANNED",
+	}
+	if diff := cmp.Diff(expected, files); diff != "" {
+		t.Errorf("unexpected files: %s", diff)
+	}
+}
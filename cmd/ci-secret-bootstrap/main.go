@@ -28,8 +28,11 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	prowconfig "k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/flagutil"
+	"k8s.io/test-infra/prow/interrupts"
 	"k8s.io/test-infra/prow/logrusutil"
+	"k8s.io/test-infra/prow/metrics"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
@@ -63,6 +66,11 @@ type options struct {
 	allowUnused flagutil.Strings
 
 	validateOnly bool
+
+	reconcile              bool
+	vaultPollInterval      time.Duration
+	fullResyncInterval     time.Duration
+	instrumentationOptions flagutil.InstrumentationOptions
 }
 
 const (
@@ -77,7 +85,7 @@ func parseOptions(censor *secrets.DynamicCensor) (options, error) {
 	o.allowUnused = flagutil.NewStrings()
 	fs.BoolVar(&o.validateOnly, "validate-only", false, "If set, the tool exists after validating its config file.")
 	fs.Var(&o.allowUnused, "bw-allow-unused", "One or more items that will be ignored when the --validate-items-usage is specified")
-	fs.BoolVar(&o.validateItemsUsage, "validate-bitwarden-items-usage", false, fmt.Sprintf("If set, the tool only validates if all fields that exist in Vault and were last modified before %d days ago are being used in the given config.", allowUnusedDays))
+	fs.BoolVar(&o.validateItemsUsage, "validate-bitwarden-items-usage", false, fmt.Sprintf("If set, the tool only validates if all fields that exist in Vault and were last modified before %d days ago are being used in the given config. The previous values kept by the rotation of the items in --generator-config are not required to be used.", allowUnusedDays))
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether to actually create the secrets with oc command")
 	fs.BoolVar(&o.confirm, "confirm", true, "Whether to mutate the actual secrets in the targeted clusters")
 	o.kubernetesOptions.AddFlags(fs)
@@ -88,6 +96,10 @@ func parseOptions(censor *secrets.DynamicCensor) (options, error) {
 	fs.BoolVar(&o.force, "force", false, "If true, update the secrets even if existing one differs from Bitwarden items instead of existing with error. Default false.")
 	fs.StringVar(&o.logLevel, "log-level", "info", fmt.Sprintf("Log level is one of %v.", logrus.AllLevels))
 	fs.StringVar(&o.impersonateUser, "as", "", "Username to impersonate")
+	fs.BoolVar(&o.reconcile, "reconcile", false, "If set, keep running and re-sync secrets whose Vault items change or that are deleted or modified on a cluster.")
+	fs.DurationVar(&o.vaultPollInterval, "vault-poll-interval", 2*time.Minute, "How often to poll Vault for new versions of the items in --reconcile mode.")
+	fs.DurationVar(&o.fullResyncInterval, "full-resync-interval", time.Hour, "How often to sync all secrets in --reconcile mode.")
+	o.instrumentationOptions.AddFlags(fs)
 	o.secrets.Bind(fs, os.Getenv, censor)
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return options{}, err
@@ -110,6 +122,17 @@ func (o *options) validateOptions() error {
 		errs = append(errs, errors.New("--bw-allow-unused must be specified with --validate-items-usage"))
 	}
 	errs = append(errs, o.kubernetesOptions.Validate(o.dryRun))
+	if o.reconcile {
+		if o.dryRun || o.validateOnly {
+			errs = append(errs, errors.New("--reconcile cannot be used with --dry-run or --validate-only"))
+		}
+		if !o.force {
+			errs = append(errs, errors.New("--reconcile requires --force to be able to heal modified secrets"))
+		}
+		if o.vaultPollInterval <= 0 || o.fullResyncInterval <= 0 {
+			errs = append(errs, errors.New("--vault-poll-interval and --full-resync-interval must be positive"))
+		}
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -754,12 +777,16 @@ func insertIfNotEmpty(s sets.Set[string], items ...string) sets.Set[string] {
 	return s
 }
 
-func getUnusedItems(config secretbootstrap.Config, client secrets.ReadOnlyClient, allowUnused sets.Set[string], allowUnusedAfter time.Time) error {
+// getUnusedItems returns an error listing the items and fields in the secret
+// store that are not used by the config. The previous fields that the rotation
+// of generated items keeps are not expected to be used.
+func getUnusedItems(config secretbootstrap.Config, generatorConfig secretgenerator.Config, client secrets.ReadOnlyClient, allowUnused sets.Set[string], allowUnusedAfter time.Time) error {
 	allSecretStoreItems, err := client.GetInUseInformationForAllItems(config.VaultDPTPPrefix)
 	if err != nil {
 		return fmt.Errorf("failed to get in-use information from secret store: %w", err)
 	}
 	cfgComparableItemsByName := constructConfigItemsByName(config)
+	previousFields := generatorConfig.PreviousFields()
 
 	unused := make(map[string]*comparable)
 	for itemName, item := range allSecretStoreItems {
@@ -783,7 +810,12 @@ func getUnusedItems(config secretbootstrap.Config, client secrets.ReadOnlyClient
 			continue
 		}
 
-		diffFields := item.UnusedFields(cfgComparableItemsByName[itemName].fields)
+		rotationFields := previousFields[stripDPTPPrefixFromItem(itemName, &config)]
+		if rotationFields == nil {
+			rotationFields = sets.New[string]()
+		}
+		// the previous fields are marked as used, but they only exist once a value was replaced
+		diffFields := item.UnusedFields(cfgComparableItemsByName[itemName].fields.Union(rotationFields)).Difference(rotationFields)
 		if diffFields.Len() > 0 {
 			if allowUnused.Has(itemName) {
 				l.WithField("fields", strings.Join(sets.List(diffFields), ",")).Info("Unused fields from item are allowed by arguments")
@@ -920,6 +952,16 @@ func main() {
 		logrus.WithError(err).Fatal("Failed to create client.")
 	}
 
+	if o.reconcile {
+		metrics.ExposeMetrics("ci-secret-bootstrap", prowconfig.PushGateway{}, o.instrumentationOptions.MetricsPort)
+		r := newReconciler(o, client, disabledClusters)
+		interrupts.Run(func(ctx context.Context) {
+			r.run(ctx, o.vaultPollInterval, o.fullResyncInterval)
+		})
+		interrupts.WaitForGracefulShutdown()
+		return
+	}
+
 	if errs := reconcileSecrets(o, client, disabledClusters); len(errs) > 0 {
 		logrus.WithError(utilerrors.NewAggregate(errs)).Fatalf("errors while updating secrets")
 	}
@@ -951,7 +993,7 @@ func reconcileSecrets(o options, client secrets.ReadOnlyClient, prowDisabledClus
 
 	if o.validateItemsUsage {
 		unusedGracePeriod := time.Now().AddDate(0, 0, -allowUnusedDays)
-		err := getUnusedItems(o.config, client, o.allowUnused.StringSet(), unusedGracePeriod)
+		err := getUnusedItems(o.config, o.generatorConfig, client, o.allowUnused.StringSet(), unusedGracePeriod)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -1904,11 +1904,12 @@ func TestGetUnusedItems(t *testing.T) {
 	dayBefore := threshold.AddDate(0, 0, -1)
 
 	testCases := []struct {
-		id            string
-		config        secretbootstrap.Config
-		items         map[string]vaultclient.KVData
-		allowItems    sets.Set[string]
-		expectedError string
+		id              string
+		config          secretbootstrap.Config
+		generatorConfig secretgenerator.Config
+		items           map[string]vaultclient.KVData
+		allowItems      sets.Set[string]
+		expectedError   string
 	}{
 		{
 			id:         "all used, no unused items expected",
@@ -2038,6 +2039,50 @@ func TestGetUnusedItems(t *testing.T) {
 				},
 			},
 		},
+		{
+			id:         "previous fields of rotated items are not reported",
+			allowItems: sets.New[string](),
+			items: map[string]vaultclient.KVData{
+				"item-name-1": {
+					Data: map[string]string{
+						"token":          "testdata",
+						"token.next":     "testdata",
+						"token.previous": "testdata",
+					},
+				},
+				"item-name-2": {
+					Data: map[string]string{
+						"token":          "testdata",
+						"token.previous": "testdata",
+					},
+				},
+				"item-name-3": {
+					Data: map[string]string{
+						"token":      "testdata",
+						"token.next": "testdata",
+					},
+				},
+			},
+			config: secretbootstrap.Config{
+				Secrets: []secretbootstrap.SecretConfig{
+					{
+						From: map[string]secretbootstrap.ItemContext{
+							"1": {Item: "item-name-1", Field: "token"},
+							"2": {Item: "item-name-1", Field: "token.next"},
+							"3": {Item: "item-name-2", Field: "token"},
+							"4": {Item: "item-name-3", Field: "token"},
+							"5": {Item: "item-name-3", Field: "token.next"},
+						},
+					},
+				},
+			},
+			generatorConfig: secretgenerator.Config{
+				{ItemName: "item-name-1", Fields: []secretgenerator.FieldGenerator{{Name: "token"}}, Rotation: &secretgenerator.RotationPolicy{Schedule: "@daily"}},
+				{ItemName: "item-name-2", Fields: []secretgenerator.FieldGenerator{{Name: "token"}}},
+				{ItemName: "item-name-3", Fields: []secretgenerator.FieldGenerator{{Name: "token"}}, Rotation: &secretgenerator.RotationPolicy{Schedule: "@daily"}},
+			},
+			expectedError: "Unused item: 'item-name-2' with  SuperfluousFields: [token.previous]",
+		},
 		{
 			id: "unused item last modified after threshold is not reported",
 			items: map[string]vaultclient.KVData{
@@ -2067,7 +2112,7 @@ func TestGetUnusedItems(t *testing.T) {
 		t.Run(tc.id, func(t *testing.T) {
 			client := vaultClientFromTestItems(tc.items)
 			var actualErrMsg string
-			actualErr := getUnusedItems(tc.config, client, tc.allowItems, threshold)
+			actualErr := getUnusedItems(tc.config, tc.generatorConfig, client, tc.allowItems, threshold)
 			if actualErr != nil {
 				actualErrMsg = actualErr.Error()
 			}
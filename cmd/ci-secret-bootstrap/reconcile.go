@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+var (
+	secretSyncStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ci_secret_bootstrap_secret_in_sync",
+		Help: "Whether the last sync of the secret succeeded.",
+	}, []string{"cluster", "namespace", "name"})
+	secretLastSynced = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ci_secret_bootstrap_secret_last_synced_timestamp_seconds",
+		Help: "When the secret was last successfully synced.",
+	}, []string{"cluster", "namespace", "name"})
+	itemSyncedVersion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ci_secret_bootstrap_item_synced_version",
+		Help: "The version of the Vault item the secrets using it were last synced from.",
+	}, []string{"item"})
+	syncErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ci_secret_bootstrap_sync_errors_total",
+		Help: "Number of errors encountered while syncing secrets.",
+	}, []string{"cluster", "reason"})
+)
+
+func init() {
+	prometheus.MustRegister(secretSyncStatus, secretLastSynced, itemSyncedVersion, syncErrors)
+}
+
+// target identifies a secret on a cluster
+type target struct {
+	cluster   string
+	namespace string
+	name      string
+}
+
+func targetOf(cluster string, secret *coreapi.Secret) target {
+	return target{cluster: cluster, namespace: secret.Namespace, name: secret.Name}
+}
+
+// dataHash identifies the content of a secret, so that the desired content
+// does not need to be kept in memory to detect changes made on the cluster
+func dataHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		// hash writes never return errors
+		_, _ = hash.Write([]byte(key))
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write(data[key])
+		_, _ = hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// reconciler keeps the secrets on the build clusters in sync with Vault. Instead
+// of writing every secret on every pass, it re-syncs the entries of the config
+// whose Vault items got a new version and the secrets that were deleted or
+// modified on a cluster. A full sync runs periodically to catch anything else.
+type reconciler struct {
+	config                   secretbootstrap.Config
+	client                   secrets.ReadOnlyClient
+	getters                  map[string]Getter
+	force                    bool
+	confirm                  bool
+	osdGlobalPullSecretGroup sets.Set[string]
+	prowDisabledClusters     sets.Set[string]
+
+	// targets maps every secret to the indexes of the config entries that populate it
+	targets map[target][]int
+
+	lock sync.Mutex
+	// syncedVersions are the Vault versions of the items the secrets were last synced from
+	syncedVersions map[string]int
+	// latestVersions are the Vault versions of the items as seen by the last poll
+	latestVersions map[string]int
+	// desired holds the hash of the data of every secret that was synced
+	desired map[target]string
+	// pending holds the indexes of the config entries that need to be synced
+	pending sets.Set[int]
+	trigger chan struct{}
+}
+
+func newReconciler(o options, client secrets.ReadOnlyClient, prowDisabledClusters sets.Set[string]) *reconciler {
+	r := &reconciler{
+		config:                   o.config,
+		client:                   client,
+		getters:                  o.secretsGetters,
+		force:                    o.force,
+		confirm:                  o.confirm,
+		osdGlobalPullSecretGroup: sets.New[string](o.config.OSDGlobalPullSecretGroup()...),
+		prowDisabledClusters:     prowDisabledClusters,
+		targets:                  map[target][]int{},
+		syncedVersions:           map[string]int{},
+		latestVersions:           map[string]int{},
+		desired:                  map[target]string{},
+		pending:                  sets.New[int](),
+		trigger:                  make(chan struct{}, 1),
+	}
+	for idx, cfg := range o.config.Secrets {
+		for _, to := range cfg.To {
+			t := target{cluster: to.Cluster, namespace: to.Namespace, name: to.Name}
+			r.targets[t] = append(r.targets[t], idx)
+		}
+	}
+	return r
+}
+
+// itemsOf returns the Vault items a config entry reads from
+func itemsOf(cfg secretbootstrap.SecretConfig) sets.Set[string] {
+	items := sets.New[string]()
+	for _, from := range cfg.From {
+		if from.Item != "" {
+			items.Insert(from.Item)
+		}
+		for _, data := range from.DockerConfigJSONData {
+			items.Insert(data.Item)
+		}
+	}
+	return items
+}
+
+// run syncs everything once and then keeps the secrets in sync until the context is cancelled
+func (r *reconciler) run(ctx context.Context, pollInterval, resyncInterval time.Duration) {
+	r.pollVault()
+	r.syncAll()
+	for cluster, getter := range r.getters {
+		go r.watch(ctx, cluster, getter)
+	}
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	resync := time.NewTicker(resyncInterval)
+	defer resync.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			r.pollVault()
+		case <-resync.C:
+			r.pollVault()
+			r.syncAll()
+			continue
+		case <-r.trigger:
+		}
+		r.syncPending()
+	}
+}
+
+// enqueue marks config entries as drifted and wakes up the sync loop
+func (r *reconciler) enqueue(indexes ...int) {
+	r.lock.Lock()
+	r.pending.Insert(indexes...)
+	r.lock.Unlock()
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// pollVault records the current versions of the Vault items and marks the
+// config entries reading from items that changed since they were synced
+func (r *reconciler) pollVault() {
+	items, err := r.client.GetInUseInformationForAllItems(r.config.VaultDPTPPrefix)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to poll the versions of the Vault items")
+		syncErrors.WithLabelValues("", "vault").Inc()
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.latestVersions = map[string]int{}
+	for name, item := range items {
+		r.latestVersions[name] = item.Version()
+	}
+	for idx, cfg := range r.config.Secrets {
+		for _, item := range sets.List(itemsOf(cfg)) {
+			if synced, ok := r.syncedVersions[item]; ok && synced != r.latestVersions[item] {
+				logrus.WithFields(logrus.Fields{"item": item, "synced": synced, "latest": r.latestVersions[item]}).Info("Vault item changed")
+				r.pending.Insert(idx)
+			}
+		}
+	}
+}
+
+// watch observes the secrets ci-secret-bootstrap manages on a cluster and
+// re-syncs the config entries of secrets that are deleted or modified
+func (r *reconciler) watch(ctx context.Context, cluster string, getter Getter) {
+	selector := api.DPTPRequesterLabel + "=ci-secret-bootstrap"
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector
+			return getter.Secrets(metav1.NamespaceAll).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector
+			return getter.Secrets(metav1.NamespaceAll).Watch(ctx, options)
+		},
+	}
+	_, controller := cache.NewInformer(lw, &coreapi.Secret{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) {
+			if secret, ok := obj.(*coreapi.Secret); ok {
+				r.observed(cluster, secret, false)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if secret, ok := obj.(*coreapi.Secret); ok {
+				r.observed(cluster, secret, true)
+			}
+		},
+	})
+	controller.Run(ctx.Done())
+}
+
+// observed handles a change of a secret on a cluster. Secrets that no longer
+// carry the label are seen as deleted, so removing it is healed as well.
+func (r *reconciler) observed(cluster string, secret *coreapi.Secret, deleted bool) {
+	t := targetOf(cluster, secret)
+	indexes, managed := r.targets[t]
+	if !managed {
+		return
+	}
+	r.lock.Lock()
+	desired, synced := r.desired[t]
+	r.lock.Unlock()
+	if !synced || (!deleted && desired == dataHash(secret.Data)) {
+		return
+	}
+	logrus.WithFields(logrus.Fields{"cluster": cluster, "namespace": secret.Namespace, "name": secret.Name, "deleted": deleted}).Info("Secret drifted from Vault")
+	r.enqueue(indexes...)
+}
+
+func (r *reconciler) syncAll() {
+	r.lock.Lock()
+	r.pending = sets.New[int]()
+	r.lock.Unlock()
+	all := make([]int, 0, len(r.config.Secrets))
+	for idx := range r.config.Secrets {
+		all = append(all, idx)
+	}
+	// user secrets are not described by the config entries and only synced here
+	r.sync(all, r.config.UserSecretsTargetClusters)
+}
+
+func (r *reconciler) syncPending() {
+	r.lock.Lock()
+	indexes := sets.List(r.pending)
+	r.pending = sets.New[int]()
+	r.lock.Unlock()
+	if len(indexes) > 0 {
+		r.sync(indexes, nil)
+	}
+}
+
+// sync writes the secrets of the given config entries to the clusters
+func (r *reconciler) sync(indexes []int, userSecretsTargetClusters []string) {
+	subset := secretbootstrap.Config{
+		VaultDPTPPrefix:           r.config.VaultDPTPPrefix,
+		ClusterGroups:             r.config.ClusterGroups,
+		UserSecretsTargetClusters: userSecretsTargetClusters,
+	}
+	items := sets.New[string]()
+	for _, idx := range indexes {
+		subset.Secrets = append(subset.Secrets, r.config.Secrets[idx])
+		items = items.Union(itemsOf(r.config.Secrets[idx]))
+	}
+	// the versions are captured before reading the items, so that a change
+	// made while syncing is picked up by the next poll
+	r.lock.Lock()
+	versions := map[string]int{}
+	for _, item := range sets.List(items) {
+		versions[item] = r.latestVersions[item]
+	}
+	r.lock.Unlock()
+
+	logger := logrus.WithField("entries", len(indexes))
+	logger.Info("Syncing secrets")
+	secretsMap, constructErr := constructSecrets(subset, r.client, r.prowDisabledClusters)
+	if constructErr != nil {
+		// constructSecrets returns the secrets it could construct along with the errors
+		logger.WithError(constructErr).Error("Failed to construct some of the secrets")
+		syncErrors.WithLabelValues("", "construct").Inc()
+	}
+
+	var failed int
+	for cluster, clusterSecrets := range secretsMap {
+		for _, secret := range clusterSecrets {
+			t := targetOf(cluster, secret)
+			err := updateSecrets(r.getters, map[string][]*coreapi.Secret{cluster: {secret}}, r.force, r.confirm, r.osdGlobalPullSecretGroup, r.prowDisabledClusters)
+			labels := []string{cluster, secret.Namespace, secret.Name}
+			if err != nil {
+				failed++
+				logger.WithError(err).WithFields(logrus.Fields{"cluster": cluster, "namespace": secret.Namespace, "name": secret.Name}).Error("Failed to sync secret")
+				syncErrors.WithLabelValues(cluster, "update").Inc()
+				secretSyncStatus.WithLabelValues(labels...).Set(0)
+				continue
+			}
+			secretSyncStatus.WithLabelValues(labels...).Set(1)
+			secretLastSynced.WithLabelValues(labels...).SetToCurrentTime()
+			if secret.Namespace == "openshift-config" && secret.Name == "pull-secret" && r.osdGlobalPullSecretGroup.Has(cluster) {
+				// the global pull secret is merged into the existing one and never matches what was constructed
+				continue
+			}
+			r.lock.Lock()
+			r.desired[t] = dataHash(secret.Data)
+			r.lock.Unlock()
+		}
+	}
+
+	if constructErr == nil {
+		r.lock.Lock()
+		for item, version := range versions {
+			r.syncedVersions[item] = version
+			itemSyncedVersion.WithLabelValues(item).Set(float64(version))
+		}
+		r.lock.Unlock()
+	}
+	if failed > 0 {
+		logger.WithField("failed", failed).Warn("Some secrets failed to sync, they will be retried with the next full sync")
+		return
+	}
+	logger.Info("Synced secrets")
+}
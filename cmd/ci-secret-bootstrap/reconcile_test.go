@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/vaultclient"
+)
+
+func TestReconciler(t *testing.T) {
+	item := func(value string, version int) *vaultclient.KVData {
+		return &vaultclient.KVData{Data: map[string]string{"field": value}, Metadata: vaultclient.KVMetadata{Version: version}}
+	}
+	vault := &fakeVaultClient{items: map[string]*vaultclient.KVData{
+		"prefix/dptp/item-a": item("a1", 1),
+		"prefix/dptp/item-b": item("b1", 1),
+	}}
+	censor := secrets.NewDynamicCensor()
+	client := secrets.NewVaultClient(vault, "prefix", &censor)
+	config := secretbootstrap.Config{
+		VaultDPTPPrefix: "dptp",
+		Secrets: []secretbootstrap.SecretConfig{
+			{
+				From: map[string]secretbootstrap.ItemContext{"key": {Item: "dptp/item-a", Field: "field"}},
+				To:   []secretbootstrap.SecretContext{{Cluster: "build01", Namespace: "ns", Name: "secret-a"}},
+			},
+			{
+				From: map[string]secretbootstrap.ItemContext{"key": {Item: "dptp/item-b", Field: "field"}},
+				To:   []secretbootstrap.SecretContext{{Cluster: "build01", Namespace: "ns", Name: "secret-b"}},
+			},
+		},
+	}
+	cluster := fake.NewSimpleClientset().CoreV1()
+	r := newReconciler(options{config: config, secretsGetters: map[string]Getter{"build01": cluster}, force: true, confirm: true}, client, sets.New[string]())
+
+	get := func(name string) *coreapi.Secret {
+		secret, err := cluster.Secrets("ns").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get secret %s: %v", name, err)
+		}
+		return secret
+	}
+	checkData := func(expected map[string]string) {
+		t.Helper()
+		actual := map[string]string{}
+		for _, name := range []string{"secret-a", "secret-b"} {
+			actual[name] = string(get(name).Data["key"])
+		}
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Errorf("unexpected secrets: %s", diff)
+		}
+	}
+
+	r.pollVault()
+	r.syncAll()
+	checkData(map[string]string{"secret-a": "a1", "secret-b": "b1"})
+
+	// a hand-edited secret is only healed once the edit is observed
+	edited := get("secret-b")
+	edited.Data["key"] = []byte("edited")
+	if _, err := cluster.Secrets("ns").Update(context.TODO(), edited, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to edit secret: %v", err)
+	}
+	vault.items["prefix/dptp/item-a"] = item("a2", 2)
+	r.pollVault()
+	if diff := cmp.Diff([]int{0}, sets.List(r.pending)); diff != "" {
+		t.Errorf("unexpected pending entries after a Vault change: %s", diff)
+	}
+	r.syncPending()
+	checkData(map[string]string{"secret-a": "a2", "secret-b": "edited"})
+
+	r.observed("build01", edited, false)
+	r.syncPending()
+	checkData(map[string]string{"secret-a": "a2", "secret-b": "b1"})
+
+	// secrets that match what was synced do not trigger anything
+	r.observed("build01", get("secret-a"), false)
+	if r.pending.Len() != 0 {
+		t.Errorf("expected no pending entries, got %v", sets.List(r.pending))
+	}
+
+	deleted := get("secret-a")
+	if err := cluster.Secrets("ns").Delete(context.TODO(), "secret-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	r.observed("build01", deleted, true)
+	r.syncPending()
+	checkData(map[string]string{"secret-a": "a2", "secret-b": "b1"})
+}
@@ -9,11 +9,14 @@ import (
 	"os/exec"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/test-infra/prow/flagutil"
 	"k8s.io/test-infra/prow/logrusutil"
 
 	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
@@ -36,7 +39,8 @@ var (
 )
 
 type options struct {
-	secrets secrets.CLIOptions
+	secrets           secrets.CLIOptions
+	kubernetesOptions flagutil.KubernetesOptions
 
 	logLevel            string
 	configPath          string
@@ -45,6 +49,8 @@ type options struct {
 	dryRun              bool
 	validate            bool
 	validateOnly        bool
+	rotate              bool
+	overdueReport       string
 	maxConcurrency      int
 	disabledClusters    sets.Set[string]
 
@@ -53,7 +59,7 @@ type options struct {
 }
 
 func parseOptions(censor *secrets.DynamicCensor) options {
-	var o options
+	o := options{kubernetesOptions: flagutil.KubernetesOptions{NOInClusterConfigDefault: true}}
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	fs.BoolVar(&o.dryRun, "dry-run", true, "Whether to actually create the secrets in vault.")
 	fs.StringVar(&o.configPath, "config", "", "Path to the config file to use for this tool.")
@@ -63,6 +69,9 @@ func parseOptions(censor *secrets.DynamicCensor) options {
 	fs.StringVar(&o.outputFile, "output-file", "", "output file for dry-run mode")
 	fs.StringVar(&o.logLevel, "log-level", "info", fmt.Sprintf("Log level is one of %v.", logrus.AllLevels))
 	fs.IntVar(&o.maxConcurrency, "concurrency", 1, "Maximum number of concurrent in-flight goroutines to BitWarden.")
+	fs.BoolVar(&o.rotate, "rotate", false, "Rotate the items that have a rotation policy instead of generating the items without one. Requires access to the clusters ci-secret-bootstrap distributes the items to.")
+	fs.StringVar(&o.overdueReport, "overdue-report", "", "Path to write the items that are overdue for rotation to as JSON. Requires --rotate.")
+	o.kubernetesOptions.AddFlags(fs)
 	o.secrets.Bind(fs, os.Getenv, censor)
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		logrus.WithError(err).Errorf("cannot parse args: %q", os.Args[1:])
@@ -87,6 +96,15 @@ func (o *options) validateOptions() error {
 	if o.validate && o.bootstrapConfigPath == "" {
 		return errors.New("--bootstrap-config is required with --validate")
 	}
+	if o.overdueReport != "" && !o.rotate {
+		return errors.New("--overdue-report requires --rotate")
+	}
+	if o.rotate {
+		if o.bootstrapConfigPath == "" {
+			return errors.New("--bootstrap-config is required with --rotate")
+		}
+		return o.kubernetesOptions.Validate(o.dryRun)
+	}
 	return nil
 }
 
@@ -143,6 +161,11 @@ func (o *options) validateConfig() error {
 		if !hasCluster {
 			return fmt.Errorf("failed to find params['cluster'] in the %d item with name %q", i, item.ItemName)
 		}
+		if item.Rotation != nil {
+			if err := item.Rotation.Validate(); err != nil {
+				return fmt.Errorf("config[%d].rotation: %w", i, err)
+			}
+		}
 	}
 	return nil
 }
@@ -197,6 +220,10 @@ func updateSecrets(config secretgenerator.Config, client secrets.Client, disable
 	var errs []error
 	for _, item := range config {
 		logger := logrus.WithField("item", item.ItemName)
+		if item.Rotation != nil {
+			logger.Info("ignored item with a rotation policy, it is generated with --rotate")
+			continue
+		}
 		for _, field := range item.Fields {
 			logger = logger.WithFields(logrus.Fields{
 				"field":   field.Name,
@@ -300,6 +327,33 @@ func generateSecrets(o options, censor *secrets.DynamicCensor) (errs []error) {
 		}
 	}
 
+	if o.rotate {
+		distribution, err := o.distributionChecker()
+		if err != nil {
+			return append(errs, err)
+		}
+		r := &rotator{
+			client:           client,
+			distribution:     distribution,
+			disabledClusters: o.disabledClusters,
+			now:              time.Now,
+			generate:         executeCommand,
+			revoke:           revokeWithCommand,
+		}
+		if err := r.rotate(o.config); err != nil {
+			errs = append(errs, fmt.Errorf("failed to rotate secrets: %w", err))
+		}
+		if len(r.overdue) > 0 {
+			logrus.WithField("items", len(r.overdue)).Error("Items are overdue for rotation")
+		}
+		if o.overdueReport != "" {
+			if err := writeOverdueReport(o.overdueReport, r.overdue); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+
 	if err := updateSecrets(o.config, client, o.disabledClusters); err != nil {
 		errs = append(errs, fmt.Errorf("failed to update secrets: %w", err))
 	}
@@ -307,6 +361,23 @@ func generateSecrets(o options, censor *secrets.DynamicCensor) (errs []error) {
 	return errs
 }
 
+func (o *options) distributionChecker() (distributionChecker, error) {
+	kubeConfigs, err := o.kubernetesOptions.LoadClusterConfigs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster configs: %w", err)
+	}
+	clients := map[string]coreclientset.SecretsGetter{}
+	for cluster, kubeConfig := range kubeConfigs {
+		kubeConfig := kubeConfig
+		client, err := coreclientset.NewForConfig(&kubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for cluster %s: %w", cluster, err)
+		}
+		clients[cluster] = client
+	}
+	return &clusterDistributionChecker{config: o.bootstrapConfig, clients: clients, disabledClusters: o.disabledClusters}, nil
+}
+
 func itemContextsFromConfig(items secretgenerator.Config) []secretbootstrap.ItemContext {
 	var itemContexts []secretbootstrap.ItemContext
 	for _, item := range items {
@@ -315,6 +386,13 @@ func itemContextsFromConfig(items secretgenerator.Config) []secretbootstrap.Item
 				Item:  item.ItemName,
 				Field: field.Name,
 			})
+			if item.Rotation != nil {
+				// new values are only promoted once the staging field was distributed
+				itemContexts = append(itemContexts, secretbootstrap.ItemContext{
+					Item:  item.ItemName,
+					Field: secretgenerator.StagingField(field.Name),
+				})
+			}
 		}
 	}
 	return itemContexts
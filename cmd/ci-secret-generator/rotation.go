@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/api/secretgenerator"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+// previousValueEnv holds the value replaced by a rotation when the revoke command runs
+const previousValueEnv = "PREVIOUS_VALUE"
+
+// distributionChecker determines whether a value of a field has reached every
+// secret that ci-secret-bootstrap populates from that field
+type distributionChecker interface {
+	distributed(item, field string, value []byte) (bool, error)
+}
+
+type clusterDistributionChecker struct {
+	config           secretbootstrap.Config
+	clients          map[string]coreclientset.SecretsGetter
+	disabledClusters sets.Set[string]
+}
+
+func (c *clusterDistributionChecker) distributed(item, field string, value []byte) (bool, error) {
+	var targets int
+	for _, secret := range c.config.Secrets {
+		for key, from := range secret.From {
+			if strings.TrimPrefix(from.Item, c.config.VaultDPTPPrefix+"/") != item || from.Field != field {
+				continue
+			}
+			expected := value
+			if from.Base64Decode {
+				decoded, err := base64.StdEncoding.DecodeString(string(value))
+				if err != nil {
+					return false, fmt.Errorf("failed to base64-decode field %s of item %s: %w", field, item, err)
+				}
+				expected = decoded
+			}
+			for _, to := range secret.To {
+				if c.disabledClusters.Has(to.Cluster) {
+					continue
+				}
+				client, ok := c.clients[to.Cluster]
+				if !ok {
+					return false, fmt.Errorf("failed to find cluster context %q in the kubeconfig", to.Cluster)
+				}
+				targets++
+				actual, err := client.Secrets(to.Namespace).Get(context.TODO(), to.Name, metav1.GetOptions{})
+				if kerrors.IsNotFound(err) {
+					return false, nil
+				}
+				if err != nil {
+					return false, fmt.Errorf("failed to get secret %s: %w", to, err)
+				}
+				if !bytes.Equal(actual.Data[key], expected) {
+					return false, nil
+				}
+			}
+		}
+	}
+	if targets == 0 {
+		return false, fmt.Errorf("field %s of item %s is not distributed to any cluster by ci-secret-bootstrap", field, item)
+	}
+	return true, nil
+}
+
+// overdueItem is an item that was not rotated within its maximum age
+type overdueItem struct {
+	Item        string    `json:"item"`
+	LastChanged time.Time `json:"last_changed"`
+	MaxAge      string    `json:"max_age"`
+	OverdueBy   string    `json:"overdue_by"`
+}
+
+// writeOverdueReport writes the overdue items as JSON to path, so that they
+// are surfaced without reading through the logs of the rotation
+func writeOverdueReport(path string, overdue []overdueItem) error {
+	if overdue == nil {
+		overdue = []overdueItem{}
+	}
+	raw, err := json.MarshalIndent(overdue, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the overdue items: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write the overdue items: %w", err)
+	}
+	return nil
+}
+
+// rotator rotates the fields of items with a rotation policy in four phases:
+// a new value is generated into the staging field, it is left there until
+// ci-secret-bootstrap distributed it to every target cluster, it is then
+// promoted to the field while the value it replaces is kept in the previous
+// field, and that value is revoked once the promoted one was distributed as
+// well. The phase a field is in is derived from the values stored for it, so
+// every run of the rotator picks up where the previous one left off.
+type rotator struct {
+	client           secrets.Client
+	distribution     distributionChecker
+	disabledClusters sets.Set[string]
+	now              func() time.Time
+	generate         func(command string) ([]byte, error)
+	revoke           func(command string, previous []byte) error
+	// overdue holds the items that were overdue for rotation in the last run
+	overdue []overdueItem
+}
+
+func (r *rotator) rotate(config secretgenerator.Config) error {
+	items, err := r.client.GetInUseInformationForAllItems("")
+	if err != nil {
+		return fmt.Errorf("failed to determine when items last changed: %w", err)
+	}
+	var errs []error
+	r.overdue = nil
+	for _, item := range config {
+		if item.Rotation == nil {
+			continue
+		}
+		logger := logrus.WithField("item", item.ItemName)
+		var lastChanged time.Time
+		if usage, ok := items[item.ItemName]; ok {
+			lastChanged = usage.LastChanged()
+		}
+		now := r.now()
+		if !lastChanged.IsZero() && item.Rotation.Overdue(lastChanged, now) {
+			logger.WithField("last_changed", lastChanged).Error("Item is overdue for rotation")
+			r.overdue = append(r.overdue, overdueItem{
+				Item:        item.ItemName,
+				LastChanged: lastChanged,
+				MaxAge:      item.Rotation.MaxAge.Duration.String(),
+				OverdueBy:   (now.Sub(lastChanged) - item.Rotation.MaxAge.Duration).String(),
+			})
+		}
+		due, err := item.Rotation.Due(lastChanged, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", item.ItemName, err))
+			continue
+		}
+		for _, field := range item.Fields {
+			fieldLogger := logger.WithFields(logrus.Fields{"field": field.Name, "cluster": field.Cluster})
+			if r.disabledClusters.Has(field.Cluster) {
+				fieldLogger.Info("ignored field for disabled cluster")
+				continue
+			}
+			if err := r.rotateField(item, field, due, fieldLogger); err != nil {
+				fieldLogger.WithError(err).Error("failed to rotate field")
+				errs = append(errs, fmt.Errorf("failed to rotate field %s of item %s: %w", field.Name, item.ItemName, err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *rotator) rotateField(item secretgenerator.SecretItem, field secretgenerator.FieldGenerator, due bool, logger *logrus.Entry) error {
+	staging, previousField := secretgenerator.StagingField(field.Name), secretgenerator.PreviousField(field.Name)
+	current, err := r.getField(item.ItemName, field.Name)
+	if err != nil {
+		return err
+	}
+	staged, err := r.getField(item.ItemName, staging)
+	if err != nil {
+		return err
+	}
+	previous, err := r.getField(item.ItemName, previousField)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current == nil:
+		logger.Info("Generating the initial value")
+		value, err := r.generate(field.Cmd)
+		if err != nil {
+			return err
+		}
+		if err := r.client.SetFieldOnItem(item.ItemName, field.Name, value); err != nil {
+			return err
+		}
+		return r.client.SetFieldOnItem(item.ItemName, staging, value)
+	case previous != nil && !bytes.Equal(previous, current):
+		// consumers of the field may still use the replaced value until the
+		// promoted one reached them, so it is only revoked afterwards
+		distributed, err := r.distribution.distributed(item.ItemName, field.Name, current)
+		if err != nil {
+			return fmt.Errorf("failed to determine whether the promoted value was distributed: %w", err)
+		}
+		if !distributed {
+			logger.Info("Waiting for the promoted value to be distributed to every cluster")
+			return nil
+		}
+		if item.Rotation.RevokeCmd != "" {
+			logger.Info("Revoking the previous value")
+			if err := r.revoke(item.Rotation.RevokeCmd, previous); err != nil {
+				return fmt.Errorf("failed to revoke the previous value: %w", err)
+			}
+		}
+		return r.client.SetFieldOnItem(item.ItemName, previousField, current)
+	case staged != nil && !bytes.Equal(staged, current):
+		distributed, err := r.distribution.distributed(item.ItemName, staging, staged)
+		if err != nil {
+			return fmt.Errorf("failed to determine whether the new value was distributed: %w", err)
+		}
+		if !distributed {
+			logger.Info("Waiting for the new value to be distributed to every cluster")
+			return nil
+		}
+		logger.Info("Promoting the new value")
+		// the replaced value is stored first so it is never lost
+		if err := r.client.SetFieldOnItem(item.ItemName, previousField, current); err != nil {
+			return err
+		}
+		return r.client.SetFieldOnItem(item.ItemName, field.Name, staged)
+	case due:
+		logger.Info("Generating a new value")
+		value, err := r.generate(field.Cmd)
+		if err != nil {
+			return err
+		}
+		return r.client.SetFieldOnItem(item.ItemName, staging, value)
+	case staged == nil:
+		// the field existed before it got a rotation policy
+		logger.Info("Initializing the staging field")
+		return r.client.SetFieldOnItem(item.ItemName, staging, current)
+	}
+	return nil
+}
+
+func (r *rotator) getField(item, field string) ([]byte, error) {
+	value, err := r.client.GetFieldOnItem(item, field)
+	if secrets.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field %s: %w", field, err)
+	}
+	return value, nil
+}
+
+func revokeWithCommand(command string, previous []byte) error {
+	cmd := exec.Command("bash", "-o", "errexit", "-o", "nounset", "-o", "pipefail", "-c", command)
+	cmd.Env = append(os.Environ(), previousValueEnv+"="+string(previous))
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		_, partialStreams := err.(*exec.ExitError)
+		return fmtExecCmdErr(execCmdRunErrAction, command, err, outBuf.Bytes(), errBuf.Bytes(), !partialStreams)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	vaultapi "github.com/hashicorp/vault/api"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/api/secretgenerator"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+type fakeUsage struct {
+	lastChanged time.Time
+}
+
+func (f fakeUsage) LastChanged() time.Time                         { return f.lastChanged }
+func (f fakeUsage) Version() int                                   { return 1 }
+func (f fakeUsage) UnusedFields(sets.Set[string]) sets.Set[string] { return nil }
+func (f fakeUsage) SuperfluousFields() sets.Set[string]            { return nil }
+
+type fakeSecretsClient struct {
+	items       map[string]map[string]string
+	lastChanged map[string]time.Time
+}
+
+func (f *fakeSecretsClient) GetFieldOnItem(item, field string) ([]byte, error) {
+	value, ok := f.items[item][field]
+	if !ok {
+		return nil, fmt.Errorf("failed to get %s.%s: %w", item, field, &vaultapi.ResponseError{StatusCode: http.StatusNotFound})
+	}
+	return []byte(value), nil
+}
+
+func (f *fakeSecretsClient) GetInUseInformationForAllItems(string) (map[string]secrets.SecretUsageComparer, error) {
+	usage := map[string]secrets.SecretUsageComparer{}
+	for item, lastChanged := range f.lastChanged {
+		usage[item] = fakeUsage{lastChanged: lastChanged}
+	}
+	return usage, nil
+}
+
+func (f *fakeSecretsClient) GetUserSecrets() (map[types.NamespacedName]map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeSecretsClient) HasItem(item string) (bool, error) {
+	_, ok := f.items[item]
+	return ok, nil
+}
+
+func (f *fakeSecretsClient) SetFieldOnItem(item, field string, value []byte) error {
+	if f.items[item] == nil {
+		f.items[item] = map[string]string{}
+	}
+	f.items[item][field] = string(value)
+	return nil
+}
+
+func (f *fakeSecretsClient) UpdateNotesOnItem(string, string) error {
+	return nil
+}
+
+type fakeDistribution map[string]string
+
+func (f fakeDistribution) distributed(item, field string, value []byte) (bool, error) {
+	return f[item+"."+field] == string(value), nil
+}
+
+func TestRotate(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := &secretgenerator.RotationPolicy{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}, RevokeCmd: "revoke"}
+	config := secretgenerator.Config{
+		{ItemName: "item", Fields: []secretgenerator.FieldGenerator{{Name: "token", Cmd: "generate"}}, Rotation: policy},
+		{ItemName: "unrotated", Fields: []secretgenerator.FieldGenerator{{Name: "token", Cmd: "generate"}}},
+	}
+	for _, tc := range []struct {
+		name            string
+		items           map[string]map[string]string
+		lastChanged     time.Time
+		distribution    fakeDistribution
+		expected        map[string]map[string]string
+		expectedRevoked []string
+	}{
+		{
+			name:     "missing field is generated directly",
+			items:    map[string]map[string]string{},
+			expected: map[string]map[string]string{"item": {"token": "new", "token.next": "new"}},
+		},
+		{
+			name:        "field that is not due is left alone",
+			items:       map[string]map[string]string{"item": {"token": "old", "token.next": "old"}},
+			lastChanged: now.Add(-24 * time.Hour),
+			expected:    map[string]map[string]string{"item": {"token": "old", "token.next": "old"}},
+		},
+		{
+			name:        "staging field is initialized for an existing field",
+			items:       map[string]map[string]string{"item": {"token": "old"}},
+			lastChanged: now.Add(-24 * time.Hour),
+			expected:    map[string]map[string]string{"item": {"token": "old", "token.next": "old"}},
+		},
+		{
+			name:        "due field gets a new staged value",
+			items:       map[string]map[string]string{"item": {"token": "old", "token.next": "old"}},
+			lastChanged: now.Add(-60 * 24 * time.Hour),
+			expected:    map[string]map[string]string{"item": {"token": "old", "token.next": "new"}},
+		},
+		{
+			name:         "staged value is not promoted before it is distributed",
+			items:        map[string]map[string]string{"item": {"token": "old", "token.next": "staged"}},
+			lastChanged:  now,
+			distribution: fakeDistribution{"item.token.next": "old"},
+			expected:     map[string]map[string]string{"item": {"token": "old", "token.next": "staged"}},
+		},
+		{
+			name:         "distributed value is promoted and the previous one kept",
+			items:        map[string]map[string]string{"item": {"token": "old", "token.next": "staged"}},
+			lastChanged:  now,
+			distribution: fakeDistribution{"item.token.next": "staged", "item.token": "old"},
+			expected:     map[string]map[string]string{"item": {"token": "staged", "token.next": "staged", "token.previous": "old"}},
+		},
+		{
+			name:         "previous value is not revoked before the promoted value is distributed",
+			items:        map[string]map[string]string{"item": {"token": "staged", "token.next": "staged", "token.previous": "old"}},
+			lastChanged:  now.Add(-60 * 24 * time.Hour),
+			distribution: fakeDistribution{"item.token.next": "staged", "item.token": "old"},
+			expected:     map[string]map[string]string{"item": {"token": "staged", "token.next": "staged", "token.previous": "old"}},
+		},
+		{
+			name:            "previous value is revoked once the promoted value is distributed",
+			items:           map[string]map[string]string{"item": {"token": "staged", "token.next": "staged", "token.previous": "old"}},
+			lastChanged:     now,
+			distribution:    fakeDistribution{"item.token.next": "staged", "item.token": "staged"},
+			expected:        map[string]map[string]string{"item": {"token": "staged", "token.next": "staged", "token.previous": "staged"}},
+			expectedRevoked: []string{"old"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeSecretsClient{items: tc.items, lastChanged: map[string]time.Time{}}
+			if !tc.lastChanged.IsZero() {
+				client.lastChanged["item"] = tc.lastChanged
+			}
+			var revoked []string
+			r := &rotator{
+				client:           client,
+				distribution:     tc.distribution,
+				disabledClusters: sets.New[string](),
+				now:              func() time.Time { return now },
+				generate:         func(string) ([]byte, error) { return []byte("new"), nil },
+				revoke: func(command string, previous []byte) error {
+					revoked = append(revoked, string(previous))
+					return nil
+				},
+			}
+			if err := r.rotate(config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, client.items); diff != "" {
+				t.Errorf("unexpected items: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedRevoked, revoked); diff != "" {
+				t.Errorf("unexpected revocations: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRotationOrdering(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	config := secretgenerator.Config{{
+		ItemName: "item",
+		Fields:   []secretgenerator.FieldGenerator{{Name: "token", Cmd: "generate"}},
+		Rotation: &secretgenerator.RotationPolicy{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}, RevokeCmd: "revoke"},
+	}}
+	client := &fakeSecretsClient{
+		items:       map[string]map[string]string{"item": {"token": "old", "token.next": "old"}},
+		lastChanged: map[string]time.Time{"item": now.Add(-60 * 24 * time.Hour)},
+	}
+	distribution := fakeDistribution{"item.token": "old", "item.token.next": "old"}
+	var events []string
+	r := &rotator{
+		client:           client,
+		distribution:     distribution,
+		disabledClusters: sets.New[string](),
+		now:              func() time.Time { return now },
+		generate:         func(string) ([]byte, error) { return []byte("new"), nil },
+		revoke: func(command string, previous []byte) error {
+			events = append(events, fmt.Sprintf("revoke %s while consumers have %s", previous, distribution["item.token"]))
+			return nil
+		},
+	}
+	for i := 0; i < 5; i++ {
+		if err := r.rotate(config); err != nil {
+			t.Fatalf("unexpected error in run %d: %v", i, err)
+		}
+		events = append(events, fmt.Sprintf("token=%s next=%s previous=%s", client.items["item"]["token"], client.items["item"]["token.next"], client.items["item"]["token.previous"]))
+		// ci-secret-bootstrap runs between the rotations and distributes every field
+		for field, value := range client.items["item"] {
+			distribution["item."+field] = value
+		}
+		client.lastChanged["item"] = now
+	}
+	expected := []string{
+		"token=old next=new previous=",
+		"token=new next=new previous=old",
+		"revoke old while consumers have new",
+		"token=new next=new previous=new",
+		"token=new next=new previous=new",
+		"token=new next=new previous=new",
+	}
+	if diff := cmp.Diff(expected, events); diff != "" {
+		t.Errorf("unexpected rotation events: %s", diff)
+	}
+}
+
+func TestClusterDistributionChecker(t *testing.T) {
+	secret := func(value string) *coreapi.Secret {
+		return &coreapi.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: "token"}, Data: map[string][]byte{"token": []byte(value)}}
+	}
+	config := secretbootstrap.Config{
+		VaultDPTPPrefix: "dptp",
+		Secrets: []secretbootstrap.SecretConfig{{
+			From: map[string]secretbootstrap.ItemContext{"token": {Item: "dptp/item", Field: "token.next"}},
+			To: []secretbootstrap.SecretContext{
+				{Cluster: "build01", Namespace: "ci", Name: "token"},
+				{Cluster: "build02", Namespace: "ci", Name: "token"},
+				{Cluster: "disabled", Namespace: "ci", Name: "token"},
+			},
+		}},
+	}
+	for _, tc := range []struct {
+		name        string
+		clients     map[string]coreclientset.SecretsGetter
+		field       string
+		expected    bool
+		expectedErr string
+	}{
+		{
+			name: "value reached every cluster",
+			clients: map[string]coreclientset.SecretsGetter{
+				"build01": fake.NewSimpleClientset(secret("new")).CoreV1(),
+				"build02": fake.NewSimpleClientset(secret("new")).CoreV1(),
+			},
+			field:    "token.next",
+			expected: true,
+		},
+		{
+			name: "value is missing in a cluster",
+			clients: map[string]coreclientset.SecretsGetter{
+				"build01": fake.NewSimpleClientset(secret("new")).CoreV1(),
+				"build02": fake.NewSimpleClientset(secret("old")).CoreV1(),
+			},
+			field: "token.next",
+		},
+		{
+			name: "secret is missing in a cluster",
+			clients: map[string]coreclientset.SecretsGetter{
+				"build01": fake.NewSimpleClientset(secret("new")).CoreV1(),
+				"build02": fake.NewSimpleClientset().CoreV1(),
+			},
+			field: "token.next",
+		},
+		{
+			name:        "field is not distributed",
+			field:       "other",
+			expectedErr: "field other of item item is not distributed to any cluster by ci-secret-bootstrap",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := &clusterDistributionChecker{config: config, clients: tc.clients, disabledClusters: sets.New[string]("disabled")}
+			actual, err := checker.distributed("item", tc.field, []byte("new"))
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRotateReportsOverdueItems(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := &secretgenerator.RotationPolicy{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}}
+	config := secretgenerator.Config{
+		{ItemName: "overdue", Fields: []secretgenerator.FieldGenerator{{Name: "token", Cmd: "generate"}}, Rotation: policy},
+		{ItemName: "recent", Fields: []secretgenerator.FieldGenerator{{Name: "token", Cmd: "generate"}}, Rotation: policy},
+	}
+	client := &fakeSecretsClient{
+		items: map[string]map[string]string{
+			"overdue": {"token": "old", "token.next": "old"},
+			"recent":  {"token": "old", "token.next": "old"},
+		},
+		lastChanged: map[string]time.Time{"overdue": now.Add(-32 * 24 * time.Hour), "recent": now.Add(-24 * time.Hour)},
+	}
+	r := &rotator{
+		client:           client,
+		distribution:     fakeDistribution{},
+		disabledClusters: sets.New[string](),
+		now:              func() time.Time { return now },
+		generate:         func(string) ([]byte, error) { return []byte("new"), nil },
+		revoke:           func(string, []byte) error { return nil },
+	}
+	if err := r.rotate(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []overdueItem{{Item: "overdue", LastChanged: now.Add(-32 * 24 * time.Hour), MaxAge: "720h0m0s", OverdueBy: "48h0m0s"}}
+	if diff := cmp.Diff(expected, r.overdue); diff != "" {
+		t.Errorf("unexpected overdue items: %s", diff)
+	}
+
+	report := filepath.Join(t.TempDir(), "overdue.json")
+	if err := writeOverdueReport(report, r.overdue); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	raw, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var actual []overdueItem
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected report: %s", diff)
+	}
+}
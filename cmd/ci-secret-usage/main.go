@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kataras/tablewriter"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/logrusutil"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/registry/server"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+type options struct {
+	resolverAddress string
+	configDir       string
+	registryPath    string
+	bootstrapConfig string
+	output          string
+	unused          bool
+
+	query   server.SecretUsageQuery
+	secrets secrets.CLIOptions
+}
+
+func gatherOptions(censor *secrets.DynamicCensor) options {
+	o := options{}
+	fs := flag.CommandLine
+	fs.StringVar(&o.resolverAddress, "resolver-address", api.URLForService(api.ServiceConfig), "Address of the configresolver to query, used when --config-dir is not set.")
+	fs.StringVar(&o.configDir, "config-dir", "", "Path to a directory with ci-operator configurations to build the graph from locally instead of asking the configresolver.")
+	fs.StringVar(&o.registryPath, "registry", "", "Path to the step registry, required with --config-dir.")
+	fs.StringVar(&o.bootstrapConfig, "bootstrap-config", "", "Path to the ci-secret-bootstrap config, required with --config-dir.")
+	fs.StringVar(&o.output, "output", "table", "Output format, one of table or json.")
+	fs.BoolVar(&o.unused, "unused", false, "List the fields of the Vault items under the prefix of the bootstrap config that nothing consumes. Requires --config-dir and the Vault flags.")
+	fs.StringVar(&o.query.Item, "item", "", "Show the secrets populated from the Vault item and the tests that get them.")
+	fs.StringVar(&o.query.Field, "field", "", "Narrow --item down to a single field.")
+	fs.StringVar(&o.query.Namespace, "namespace", "", "Show where the secret in the namespace is populated from and the tests that get it, requires --name.")
+	fs.StringVar(&o.query.Name, "name", "", "Name of the secret to show, requires --namespace.")
+	fs.StringVar(&o.query.Metadata.Org, "org", "", "Organization of the test to show the secrets of.")
+	fs.StringVar(&o.query.Metadata.Repo, "repo", "", "Repository of the test to show the secrets of.")
+	fs.StringVar(&o.query.Metadata.Branch, "branch", "", "Branch of the test to show the secrets of.")
+	fs.StringVar(&o.query.Metadata.Variant, "variant", "", "Variant of the test to show the secrets of.")
+	fs.StringVar(&o.query.Test, "test", "", "Show the secrets the test gets, requires --org, --repo and --branch.")
+	o.secrets.Bind(fs, os.Getenv, censor)
+	flag.Parse()
+	return o
+}
+
+func (o *options) validate() error {
+	if (o.configDir == "") != (o.registryPath == "") || (o.configDir == "") != (o.bootstrapConfig == "") {
+		return errors.New("--config-dir, --registry and --bootstrap-config must be set together")
+	}
+	if o.output != "table" && o.output != "json" {
+		return fmt.Errorf("--output must be one of table or json, got %q", o.output)
+	}
+	if o.unused {
+		if o.query != (server.SecretUsageQuery{}) {
+			return errors.New("--unused cannot be combined with a query")
+		}
+		if o.configDir == "" {
+			return errors.New("--unused requires --config-dir")
+		}
+		return o.secrets.Validate()
+	}
+	if err := o.query.Validate(); err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	return nil
+}
+
+// localRegistry resolves tests against a registry loaded from disk
+type localRegistry struct {
+	registry.Resolver
+	references registry.ReferenceByName
+	chains     registry.ChainByName
+	workflows  registry.WorkflowByName
+}
+
+func (r *localRegistry) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
+	return r.references, r.chains, r.workflows, nil, nil
+}
+
+func (o *options) loadGraph() (*server.SecretUsageGraph, secretbootstrap.Config, error) {
+	var bootstrap secretbootstrap.Config
+	if err := secretbootstrap.LoadConfigFromFile(o.bootstrapConfig, &bootstrap); err != nil {
+		return nil, bootstrap, fmt.Errorf("failed to load the ci-secret-bootstrap config: %w", err)
+	}
+	configs, err := config.LoadByOrgRepo(o.configDir)
+	if err != nil {
+		return nil, bootstrap, fmt.Errorf("failed to load configurations: %w", err)
+	}
+	references, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+	if err != nil {
+		return nil, bootstrap, fmt.Errorf("failed to load the registry: %w", err)
+	}
+	reg := &localRegistry{
+		Resolver:   registry.NewResolver(references, chains, workflows, observers),
+		references: references,
+		chains:     chains,
+		workflows:  workflows,
+	}
+	return server.BuildSecretUsageGraph(server.ResolveTests(configs, reg), bootstrap), bootstrap, nil
+}
+
+func (o *options) runQuery() ([]server.SecretUsage, error) {
+	if o.configDir == "" {
+		return server.NewResolverClient(o.resolverAddress).SecretUsage(o.query)
+	}
+	graph, _, err := o.loadGraph()
+	if err != nil {
+		return nil, err
+	}
+	return graph.Run(o.query), nil
+}
+
+func (o *options) runUnused(censor *secrets.DynamicCensor) ([]server.UnusedField, error) {
+	graph, bootstrap, err := o.loadGraph()
+	if err != nil {
+		return nil, err
+	}
+	if err := o.secrets.Complete(censor); err != nil {
+		return nil, fmt.Errorf("failed to complete the Vault options: %w", err)
+	}
+	client, err := o.secrets.NewReadOnlyClient(censor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the Vault client: %w", err)
+	}
+	items, err := client.GetInUseInformationForAllItems(bootstrap.VaultDPTPPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-use information from Vault: %w", err)
+	}
+	return graph.UnusedFields(items), nil
+}
+
+func printUsage(out io.Writer, usage []server.SecretUsage) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"secret", "key", "item", "field", "consumer"})
+	for _, u := range usage {
+		var keys, items, fields, consumers []string
+		for _, source := range u.Sources {
+			keys = append(keys, source.Key)
+			items = append(items, source.Item)
+			fields = append(fields, source.Field)
+		}
+		for _, consumer := range u.Consumers {
+			consumers = append(consumers, describeConsumer(consumer))
+		}
+		for i := 0; i < len(keys) || i < len(consumers) || i == 0; i++ {
+			row := []string{"", at(keys, i), at(items, i), at(fields, i), at(consumers, i)}
+			if i == 0 {
+				row[0] = u.Secret.String()
+			}
+			table.Append(row)
+		}
+	}
+	table.Render()
+}
+
+func at(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
+
+func describeConsumer(consumer server.SecretConsumer) string {
+	name := fmt.Sprintf("%s %s", consumer.Metadata.AsString(), consumer.Test)
+	switch {
+	case consumer.Step != "":
+		return fmt.Sprintf("%s (step %s)", name, consumer.Step)
+	case consumer.ClusterProfile != "":
+		return fmt.Sprintf("%s (cluster profile %s)", name, consumer.ClusterProfile)
+	}
+	return name
+}
+
+func printUnused(out io.Writer, unused []server.UnusedField) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"item", "field", "distributed to"})
+	for _, field := range unused {
+		var distributedTo []string
+		for _, ref := range field.DistributedTo {
+			distributedTo = append(distributedTo, ref.String())
+		}
+		table.Append([]string{field.Item, field.Field, strings.Join(distributedTo, ", ")})
+	}
+	table.Render()
+}
+
+func printJSON(results interface{}) {
+	raw, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal results")
+	}
+	fmt.Println(string(raw))
+}
+
+func main() {
+	logrusutil.ComponentInit()
+	censor := secrets.NewDynamicCensor()
+	logrus.SetFormatter(logrusutil.NewFormatterWithCensor(logrus.StandardLogger().Formatter, &censor))
+	o := gatherOptions(&censor)
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+	if o.unused {
+		unused, err := o.runUnused(&censor)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to find unused secrets")
+		}
+		if o.output == "json" {
+			if unused == nil {
+				unused = []server.UnusedField{}
+			}
+			printJSON(unused)
+			return
+		}
+		printUnused(os.Stdout, unused)
+		return
+	}
+	usage, err := o.runQuery()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to query the secret usage")
+	}
+	if o.output == "json" {
+		if usage == nil {
+			usage = []server.SecretUsage{}
+		}
+		printJSON(usage)
+		return
+	}
+	printUsage(os.Stdout, usage)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/interrupts"
+	"k8s.io/test-infra/prow/logrusutil"
+
+	"github.com/openshift/ci-tools/pkg/lease"
+)
+
+type options struct {
+	logLevel     string
+	address      string
+	gracePeriod  time.Duration
+	configPath   string
+	ttl          time.Duration
+	reapInterval time.Duration
+}
+
+func gatherOptions() (options, error) {
+	o := options{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(&o.logLevel, "log-level", "info", "Level at which to log output.")
+	fs.StringVar(&o.address, "address", ":8080", "Address to run server on")
+	fs.DurationVar(&o.gracePeriod, "gracePeriod", time.Second*10, "Grace period for server shutdown")
+	fs.StringVar(&o.configPath, "config", "", "Path to a Boskos configuration file listing the resources to serve.")
+	fs.DurationVar(&o.ttl, "ttl", 5*time.Minute, "Time after which leases not updated by their owner are released.")
+	fs.DurationVar(&o.reapInterval, "reap-interval", 30*time.Second, "How often to look for leases to release.")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return o, fmt.Errorf("failed to parse flags: %w", err)
+	}
+	return o, nil
+}
+
+func (o *options) validate() error {
+	if _, err := logrus.ParseLevel(o.logLevel); err != nil {
+		return fmt.Errorf("invalid --log-level: %w", err)
+	}
+	if o.configPath == "" {
+		return errors.New("--config must be specified")
+	}
+	if o.ttl <= 0 {
+		return errors.New("--ttl must be positive")
+	}
+	if o.reapInterval <= 0 {
+		return errors.New("--reap-interval must be positive")
+	}
+	return nil
+}
+
+func main() {
+	logrusutil.ComponentInit()
+	o, err := gatherOptions()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to gather options")
+	}
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("invalid options")
+	}
+	level, _ := logrus.ParseLevel(o.logLevel)
+	logrus.SetLevel(level)
+
+	config, err := lease.LoadServerConfig(o.configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to load the configuration")
+	}
+	server, err := lease.NewServer(config, o.ttl)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed to create the lease server")
+	}
+	interrupts.TickLiteral(server.Reap, o.reapInterval)
+	interrupts.ListenAndServe(&http.Server{Addr: o.address, Handler: server.Handler()}, o.gracePeriod)
+	logrus.WithField("address", o.address).Info("Serving leases.")
+	interrupts.WaitForGracefulShutdown()
+}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -53,6 +54,8 @@ type options struct {
 
 	stickyLabelAuthors flagutil.Strings
 
+	jobStatsFile string
+
 	webhookSecretFile        string
 	githubEventServerOptions githubeventserver.Options
 	github                   prowflagutil.GitHubOptions
@@ -79,6 +82,8 @@ func gatherOptions() (options, error) {
 	fs.IntVar(&o.moreLimit, "more-limit", 20, "Upper limit of jobs attempted to rehearse with more command (if more jobs are being touched, only this many will be rehearsed)")
 	fs.IntVar(&o.maxLimit, "max-limit", 35, "Upper limit of jobs attempted to rehearse with max command (if more jobs are being touched, only this many will be rehearsed)")
 
+	fs.StringVar(&o.jobStatsFile, "job-stats-file", "", "Path to a YAML file with historical durations and pass rates of jobs, keyed by job name. When set, the cheapest rehearsals are preferred when too many jobs are affected.")
+
 	fs.Var(&o.stickyLabelAuthors, "sticky-label-author", "PR Author for which the 'rehearsals-ack' label will not be removed upon a new push. Can be passed multiple times.")
 	fs.StringVar(&o.webhookSecretFile, "hmac-secret-file", "/etc/webhook/hmac", "Path to the file containing the GitHub HMAC secret.")
 
@@ -138,7 +143,7 @@ func (o *dryRunOptions) validate() error {
 }
 
 func rehearsalConfigFromOptions(o options) rehearse.RehearsalConfig {
-	return rehearse.RehearsalConfig{
+	rc := rehearse.RehearsalConfig{
 		ProwjobKubeconfig:  o.prowjobKubeconfig,
 		KubernetesOptions:  o.kubernetesOptions,
 		NoTemplates:        o.noTemplates,
@@ -153,6 +158,10 @@ func rehearsalConfigFromOptions(o options) rehearse.RehearsalConfig {
 		GCSCredentialsFile: o.gcsCredentialsFile,
 		GCSBrowserPrefix:   o.gcsBrowserPrefix,
 	}
+	if o.jobStatsFile != "" {
+		rc.JobStats = rehearse.NewFileJobStatsSource(o.jobStatsFile)
+	}
+	return rc
 }
 
 func dryRun(o options, logger *logrus.Entry) error {
@@ -178,17 +187,20 @@ func dryRun(o options, logger *logrus.Entry) error {
 		return fmt.Errorf("error determining affected jobs: %w: %s", err, "ERROR: pj-rehearse: misconfiguration")
 	}
 
-	prConfig, prRefs, imageStreamTags, presubmitsToRehearse, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, dro.limit, logger)
+	prConfig, prRefs, imageStreamTags, presubmitsToRehearse, plan, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, dro.limit, logger)
 	if err != nil {
 		return fmt.Errorf("error setting up jobs: %w: %s", err, "ERROR: pj-rehearse: setup failure")
 	}
+	if plan != nil {
+		logger.Info(strings.Join(getRehearsalPlanLines(plan), "\n"))
+	}
 
 	if len(presubmitsToRehearse) > 0 {
 		if err := prConfig.Prow.ValidateJobConfig(); err != nil {
 			return fmt.Errorf("%s: %w", "ERROR: pj-rehearse: failed to validate rehearsal jobs", err)
 		}
 
-		_, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+		_, _, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
 		return err
 	}
 
@@ -7,9 +7,11 @@ import (
 	"math"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowconfig "k8s.io/test-infra/prow/config"
 	"k8s.io/test-infra/prow/git/v2"
 	"k8s.io/test-infra/prow/github"
@@ -364,12 +366,18 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 						limit = rc.MaxLimit
 					}
 
-					prConfig, prRefs, imageStreamTags, presubmitsToRehearse, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, limit, logger)
+					prConfig, prRefs, imageStreamTags, presubmitsToRehearse, plan, err := rc.SetupJobs(candidate, candidatePath, presubmits, periodics, changedTemplates, changedClusterProfiles, limit, logger)
 					if err != nil {
 						logger.WithError(err).Error("couldn't set up jobs")
 						s.reportFailure("unable to set up jobs", err, org, repo, user, number, true, false, logger)
 						continue
 					}
+					if plan != nil {
+						message := fmt.Sprintf("@%s: %s", user, strings.Join(getRehearsalPlanLines(plan), "\n"))
+						if err := s.ghc.CreateComment(org, repo, number, message); err != nil {
+							logger.WithError(err).Error("failed to create comment")
+						}
+					}
 
 					if err := prConfig.Prow.ValidateJobConfig(); err != nil {
 						logger.WithError(err).Error("validation of job config failed")
@@ -377,7 +385,13 @@ func (s *server) handlePotentialCommands(pullRequest *github.PullRequest, commen
 						continue
 					}
 
-					success, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+					success, comparisons, err := rc.RehearseJobs(candidate, candidatePath, prRefs, imageStreamTags, presubmitsToRehearse, changedTemplates, changedClusterProfiles, logger)
+					// the rehearsals that finished are worth reporting even when the others did not
+					if len(comparisons) > 0 {
+						if err := s.ghc.CreateComment(org, repo, number, strings.Join(getBaselineComparisonLines(comparisons, user), "\n")); err != nil {
+							logger.WithError(err).Error("failed to create comment")
+						}
+					}
 					if err != nil {
 						logger.WithError(err).Error("couldn't rehearse jobs")
 						s.reportFailure("failed to create rehearsal jobs", err, org, repo, user, number, true, false, logger)
@@ -521,6 +535,68 @@ func getAffectedJobFormattedList(presubmits config.Presubmits, periodics config.
 	return jobs
 }
 
+// getRehearsalPlanLines returns a Markdown formatted description of which
+// rehearsals were selected out of the affected jobs and why
+func getRehearsalPlanLines(plan *rehearse.RehearsalPlan) []string {
+	lines := []string{
+		fmt.Sprintf("%d jobs are affected, which is more than the limit of %d. The following %d rehearsals were selected as the cheapest set that exercises every change at least once, with an estimated cost of %s of cluster time:", plan.Affected, plan.Limit, len(plan.Selected), formatCost(plan.EstimatedCost())),
+		"",
+		"Test name | Duration | Pass rate | Covers",
+		"--- | --- | --- | ---",
+	}
+	var estimated bool
+	for _, rehearsal := range plan.Selected {
+		duration, passRate := formatCost(rehearsal.Stats.Duration.Duration), fmt.Sprintf("%.0f%%", rehearsal.Stats.PassRate*100)
+		if !rehearsal.FromHistory {
+			estimated = true
+			duration, passRate = duration+"\\*", passRate+"\\*"
+		}
+		lines = append(lines, fmt.Sprintf("%s | %s | %s | %s", rehearsal.Job, duration, passRate, strings.Join(rehearsal.Covers, ", ")))
+	}
+	lines = append(lines, "")
+	if estimated {
+		lines = append(lines, "\\* No history is available for the job, so defaults were assumed.", "")
+	}
+	if len(plan.Uncovered) > 0 {
+		lines = append(lines, fmt.Sprintf("The following changes are not exercised by any rehearsal within the limit: %s", strings.Join(plan.Uncovered, ", ")), "")
+	}
+	return append(lines, fmt.Sprintf("Specific jobs can still be rehearsed with `%s {test-name}`.", rehearseNormal))
+}
+
+// getBaselineComparisonLines returns a Markdown formatted table classifying the
+// finished rehearsals against the recent runs of the rehearsed jobs
+func getBaselineComparisonLines(comparisons []rehearse.BaselineComparison, user string) []string {
+	lines := []string{
+		fmt.Sprintf("@%s: the following rehearsals have finished. Each rehearsal is compared with the most recent runs of the rehearsed test on its base branch:", user),
+		"",
+		"Test name | Result | Rehearsal | Base branch runs",
+		"--- | --- | --- | ---",
+	}
+	link := func(pj prowapi.ProwJob) string {
+		if pj.Status.URL == "" {
+			return string(pj.Status.State)
+		}
+		return fmt.Sprintf("[%s](%s)", pj.Status.State, pj.Status.URL)
+	}
+	for _, comparison := range comparisons {
+		baseline := "no recent runs"
+		if len(comparison.Baseline) > 0 {
+			var runs []string
+			for _, run := range comparison.Baseline {
+				runs = append(runs, link(run))
+			}
+			baseline = fmt.Sprintf("%d/%d passed: %s", comparison.BaselinePassed(), len(comparison.Baseline), strings.Join(runs, ", "))
+		}
+		lines = append(lines, fmt.Sprintf("%s | %s | %s | %s", comparison.Job, comparison.Outcome, link(comparison.Rehearsal), baseline))
+	}
+	return append(lines, "", fmt.Sprintf("A job is considered failing on the base branch when most of its recent runs failed. Rehearsals that are **%s** are likely caused by this change. Rehearsals that were **%s** did not finish and say nothing about it.", rehearse.OutcomeNewlyFailing, rehearse.OutcomeAborted))
+}
+
+func formatCost(cost time.Duration) string {
+	cost = cost.Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(cost.Hours()), int(cost.Minutes())%60)
+}
+
 func (s *server) getUsageDetailsLines() []string {
 	rc := s.rehearsalConfig
 	return []string{
@@ -291,7 +291,7 @@ func mutatePodResources(pod *corev1.Pod, server *resourceServer, mutateResourceL
 			meta := pod_scaler.MetadataFor(pod.ObjectMeta.Labels, pod.ObjectMeta.Name, containers[i].Name)
 			resources, recommendationExists := server.recommendedRequestFor(meta)
 			if recommendationExists {
-				logger.Debugf("recommendation exists for: %s", containers[i].Name)
+				logger.WithFields(server.evidenceFor(meta)).Debugf("recommendation exists for: %s", containers[i].Name)
 				workloadType := determineWorkloadType(pod.Annotations, pod.Labels)
 				workloadName := determineWorkloadName(pod.Name, containers[i].Name, workloadType, pod.Labels)
 				useOursIfLarger(&resources, &containers[i].Resources, workloadName, workloadType, reporter, logger)
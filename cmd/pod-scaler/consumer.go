@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/prow/interrupts"
 	"k8s.io/test-infra/prow/pjutil"
 
@@ -48,6 +50,10 @@ func (c *cacheReloader) reload() {
 	// technically this can race as we read the attribute and data from the handle at
 	// different times, but there doesn't seem to be an atomic call to GCS for that anyway
 	lastUpdated, err := lastUpdated(c.cache, c.name)
+	if errors.Is(err, notExist{}) {
+		c.logger.Debug("No data has been cached yet, won't reload this tick.")
+		return
+	}
 	if err != nil {
 		c.logger.WithError(err).Warn("Failed to query for last cache update time, won't reload this tick.")
 		return
@@ -84,6 +90,11 @@ func (c *cacheReloader) reload() {
 	logger.Debug("Newer update loaded.")
 }
 
+// optionalMetrics are not required to serve recommendations, so we do not wait
+// for their data to be loaded before we report readiness. Their data may not
+// have been produced yet at all, which is the same as having no data.
+var optionalMetrics = sets.New[string](MetricNameLastTerminatedReason)
+
 func digestAll(data map[string][]*cacheReloader, digesters map[string]digester, health *pjutil.Health, logger *logrus.Entry) {
 	var infos []digestInfo
 	for id, d := range digesters {
@@ -95,6 +106,7 @@ func digestAll(data map[string][]*cacheReloader, digesters map[string]digester,
 				data:         item,
 				digest:       d,
 				subscription: s,
+				optional:     optionalMetrics.Has(id),
 			})
 		}
 	}
@@ -123,20 +135,30 @@ type digestInfo struct {
 	data         *cacheReloader
 	digest       digester
 	subscription chan *pod_scaler.CachedQuery
+	// optional data does not need to be loaded for us to be ready
+	optional bool
 }
 
 func digest(logger *logrus.Entry, infos ...digestInfo) <-chan interface{} {
-	var loaded int
-	loadDone := make(chan interface{})
+	var loaded, required int
+	for _, info := range infos {
+		if !info.optional {
+			required++
+		}
+	}
+	loadDone := make(chan interface{}, 1)
+	if required == 0 {
+		loadDone <- struct{}{}
+	}
 	loadLock := &sync.Mutex{}
 	update := func() {
 		loadLock.Lock()
 		defer loadLock.Unlock()
-		if loaded != len(infos)-1 {
+		if loaded != required-1 {
 			loaded += 1
-			logger.Debugf("Now loaded %d info(s) out of %d", loaded, len(infos))
+			logger.Debugf("Now loaded %d info(s) out of %d", loaded, required)
 		} else {
-			logger.Debugf("Now loaded all %d info(s)", len(infos))
+			logger.Debugf("Now loaded all %d info(s)", required)
 			loadDone <- struct{}{}
 		}
 	}
@@ -154,7 +176,9 @@ func digest(logger *logrus.Entry, infos ...digestInfo) <-chan interface{} {
 				case data := <-info.subscription:
 					subLogger.Debug("Digesting new data from subscription.")
 					info.digest(data)
-					thisOnce.Do(update)
+					if !info.optional {
+						thisOnce.Do(update)
+					}
 				}
 			}
 		})
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func TestDigestWaitsOnlyForRequiredData(t *testing.T) {
+	required, optional := make(chan *pod_scaler.CachedQuery, 1), make(chan *pod_scaler.CachedQuery, 1)
+	digested := make(chan string, 2)
+	loadDone := digest(logrus.WithField("test", t.Name()),
+		digestInfo{name: "required", digest: func(*pod_scaler.CachedQuery) { digested <- "required" }, subscription: required},
+		digestInfo{name: "optional", digest: func(*pod_scaler.CachedQuery) { digested <- "optional" }, subscription: optional, optional: true},
+	)
+
+	required <- &pod_scaler.CachedQuery{}
+	select {
+	case <-loadDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected to be ready without the optional data")
+	}
+
+	optional <- &pod_scaler.CachedQuery{}
+	for _, expected := range []string{"required", "optional"} {
+		select {
+		case name := <-digested:
+			if name != expected {
+				t.Errorf("expected %s data to be digested, got %s", expected, name)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected %s data to be digested", expected)
+		}
+	}
+}
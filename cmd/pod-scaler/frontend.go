@@ -22,6 +22,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/prow/interrupts"
 	"k8s.io/test-infra/prow/metrics"
 	"k8s.io/test-infra/prow/pjutil"
@@ -204,16 +205,19 @@ var (
 func serveUI(port, healthPort int, dataDir string, loaders map[string][]*cacheReloader) {
 	logger := logrus.WithField("component", "pod-scaler frontend")
 	server := &frontendServer{
-		logger:   logger,
-		lock:     sync.RWMutex{},
-		mappings: endpoints(),
-		indices:  map[string][]*IndexNode{},
-		dataDir:  dataDir,
+		logger:          logger,
+		lock:            sync.RWMutex{},
+		mappings:        endpoints(),
+		indices:         map[string][]*IndexNode{},
+		dataDir:         dataDir,
+		oomKills:        map[pod_scaler.FullMetadata]int{},
+		oomKillsByQuery: map[string]sets.Set[pod_scaler.FullMetadata]{},
 	}
 	health := pjutil.NewHealthOnPort(healthPort)
 	digestAll(loaders, map[string]digester{
-		MetricNameCPUUsage:         server.digestCPU,
-		MetricNameMemoryWorkingSet: server.digestMemory,
+		MetricNameCPUUsage:             server.digestCPU,
+		MetricNameMemoryWorkingSet:     server.digestMemory,
+		MetricNameLastTerminatedReason: server.digestOOMKills,
 	}, health, logger)
 
 	var nodes []simplifypath.Node
@@ -323,6 +327,12 @@ type frontendServer struct {
 
 	// dataDir is where we hold sharded data by metadata identifier
 	dataDir string
+
+	// oomKills counts OOMKilled executions by metadata identifier
+	oomKills map[pod_scaler.FullMetadata]int
+	// oomKillsByQuery records which entries of oomKills were digested from
+	// which query, so that stale entries can be removed.
+	oomKillsByQuery map[string]sets.Set[pod_scaler.FullMetadata]
 }
 
 // dataForDisplay caches precomputed values for displaying data
@@ -331,6 +341,10 @@ type dataForDisplay struct {
 	LowerBound float64                     `json:"lower_bound"`
 	Merged     *circonusllhist.Histogram   `json:"merged"`
 	Histograms []*circonusllhist.Histogram `json:"histograms"`
+	usage      `json:",inline"`
+	// OOMKills is only set for memory and is not stored with the rest of the
+	// data, as it is digested from a separate query.
+	OOMKills int `json:"oom_kills,omitempty"`
 }
 
 func (s *frontendServer) getIndex(index string) http.HandlerFunc {
@@ -442,6 +456,10 @@ func (s *frontendServer) getDatum(meta pod_scaler.FullMetadata) (map[corev1.Reso
 	}); err != nil {
 		return nil, true, fmt.Errorf("failed to read data: %w", err)
 	}
+	if memory, ok := datum[corev1.ResourceMemory]; ok {
+		memory.OOMKills = s.oomKills[meta]
+		datum[corev1.ResourceMemory] = memory
+	}
 	return datum, true, nil
 }
 
@@ -471,6 +489,14 @@ func (s *frontendServer) digestMemory(data *pod_scaler.CachedQuery) {
 	s.digestData(data, corev1.ResourceMemory, memRequestQuantile)
 }
 
+func (s *frontendServer) digestOOMKills(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new container termination metrics.")
+	kills := oomKillsFrom(data)
+	s.lock.Lock()
+	updateOOMKills(s.oomKills, s.oomKillsByQuery, data.Query, kills)
+	s.lock.Unlock()
+}
+
 func (s *frontendServer) digestData(data *pod_scaler.CachedQuery, metric corev1.ResourceName, quantile float64) {
 	s.logger.Debugf("Digesting %d identifiers.", len(data.DataByMetaData))
 	for meta, fingerprints := range data.DataByMetaData {
@@ -500,6 +526,7 @@ func (s *frontendServer) digestData(data *pod_scaler.CachedQuery, metric corev1.
 			LowerBound: overall.ValueAtQuantile(.001),
 			Merged:     overall,
 			Histograms: members,
+			usage:      usageFor(overall, quantile),
 		}); err != nil {
 			s.logger.WithError(err).Error("Could not record data.")
 		}
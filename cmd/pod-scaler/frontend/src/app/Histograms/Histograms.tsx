@@ -1,6 +1,6 @@
 import {Buffer} from 'buffer';
 import * as React from 'react';
-import {Alert, Flex, Spinner} from '@patternfly/react-core';
+import {Alert, Flex, FlexItem, Spinner, Text, TextContent} from '@patternfly/react-core';
 import {DeserializeHistogram, Histogram} from "@app/CircLLHist/CircLLHist";
 import {LogarithmicComparativePlot} from "@app/CircLLHist/LogarithmicComparativePlot";
 
@@ -16,6 +16,10 @@ export interface rawData {
     lower_bound: string;
     merged: string;
     histograms: string[];
+    samples?: number;
+    confidence_lower?: string;
+    confidence_upper?: string;
+    oom_kills?: number;
 }
 
 export interface Data {
@@ -23,6 +27,10 @@ export interface Data {
     lower_bound: number;
     merged: Histogram;
     histograms: Histogram[];
+    samples: number;
+    confidence_lower: number;
+    confidence_upper: number;
+    oom_kills: number;
 }
 
 export type HistogramData = Record<string, Data>;
@@ -35,6 +43,10 @@ const process = (raw: Record<string, rawData>): HistogramData => {
                 lower_bound: parseFloat(raw[resource].lower_bound),
                 merged: DeserializeHistogram(Buffer.from(raw[resource].merged, 'base64')),
                 histograms: [],
+                samples: raw[resource].samples || 0,
+                confidence_lower: parseFloat(raw[resource].confidence_lower || "0"),
+                confidence_upper: parseFloat(raw[resource].confidence_upper || "0"),
+                oom_kills: raw[resource].oom_kills || 0,
             };
             for (const histogram of raw[resource].histograms) {
                 datum.histograms.push(DeserializeHistogram(Buffer.from(histogram, 'base64')))
@@ -44,6 +56,22 @@ const process = (raw: Record<string, rawData>): HistogramData => {
     return data;
 }
 
+const Summary: React.FunctionComponent<{ datum: Data, format: (value: number) => string, unit: string }> = (
+    {
+        datum,
+        format,
+        unit,
+    }) => {
+    return <TextContent>
+        <Text component="p">
+            Recommendation {format(datum.cutoff)} {unit} from {datum.samples} samples,
+            95% confidence interval [{format(datum.confidence_lower)}, {format(datum.confidence_upper)}] {unit}.
+        </Text>
+        {datum.oom_kills > 0 && <Alert variant="warning" isInline
+                                       title={datum.oom_kills + " recent execution(s) were OOMKilled, the memory request is bumped above the largest recorded usage."}/>}
+    </TextContent>;
+}
+
 export const Histograms: React.FunctionComponent<HistogramsProps> = (
     {
         dataUrl,
@@ -87,7 +115,7 @@ export const Histograms: React.FunctionComponent<HistogramsProps> = (
                  justifyContent={{default: 'justifyContentSpaceAround'}}
                  alignItems={{default: 'alignItemsCenter'}}
                  alignContent={{default: 'alignContentStretch'}}>
-        {data["cpu"] && <LogarithmicComparativePlot
+        {data["cpu"] && <FlexItem><LogarithmicComparativePlot
             {...data["cpu"]}
             canvasProps={{
                 title: "CPU Usage",
@@ -101,8 +129,10 @@ export const Histograms: React.FunctionComponent<HistogramsProps> = (
                 yAxisMin: 1e-5,
                 yAxisTitle: "CPU Used",
                 yAxisUnit: "mCPU",
-            }}/>}
-        {data["memory"] && <LogarithmicComparativePlot
+            }}/>
+            <Summary datum={data["cpu"]} format={(value: number) => (value * 1000).toFixed(0)} unit="mCPU"/>
+        </FlexItem>}
+        {data["memory"] && <FlexItem><LogarithmicComparativePlot
             {...data["memory"]}
             canvasProps={{
                 title: "Memory Usage",
@@ -116,7 +146,9 @@ export const Histograms: React.FunctionComponent<HistogramsProps> = (
                 yAxisMin: 10 * Math.pow(2, 20),
                 yAxisTitle: "Memory Used",
                 yAxisUnit: "MiB",
-            }}/>}
+            }}/>
+            <Summary datum={data["memory"]} format={(value: number) => (value / Math.pow(2, 20)).toFixed(0)} unit="MiB"/>
+        </FlexItem>}
     </Flex>;
 };
 
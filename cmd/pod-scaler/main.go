@@ -5,9 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bombsimon/logrusr/v3"
 	prometheusclient "github.com/prometheus/client_golang/api"
 	prometheusapi "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -43,11 +48,25 @@ type options struct {
 	loglevel string
 	logStyle string
 
+	cacheOptions cacheOptions
+	// migrateTo is the cache data is copied to in migrate mode
+	migrateTo cacheOptions
+
+	resultsOptions results.Options
+}
+
+// cacheOptions configure one of the storage backends for cached data
+type cacheOptions struct {
+	// flagPrefix is prepended to all flags, so more than one backend can be configured
+	flagPrefix string
+
 	cacheDir           string
+	volumeDir          string
 	cacheBucket        string
 	gcsCredentialsFile string
-
-	resultsOptions results.Options
+	s3Bucket           string
+	s3Region           string
+	s3Endpoint         string
 }
 
 type producerOptions struct {
@@ -81,10 +100,10 @@ func bindOptions(fs *flag.FlagSet) *options {
 	fs.BoolVar(&o.mutateResourceLimits, "mutate-resource-limits", false, "Enable resource limit mutation in the admission webhook.")
 	fs.StringVar(&o.loglevel, "loglevel", "debug", "Logging level.")
 	fs.StringVar(&o.logStyle, "log-style", "json", "Logging style: json or text.")
-	fs.StringVar(&o.cacheDir, "cache-dir", "", "Local directory holding cache data (for development mode).")
+	o.cacheOptions.bind(fs)
+	o.migrateTo.flagPrefix = "migrate-to-"
+	o.migrateTo.bind(fs)
 	fs.StringVar(&o.dataDir, "data-dir", "", "Local directory to cache UI data into.")
-	fs.StringVar(&o.cacheBucket, "cache-bucket", "", "GCS bucket name holding cached Prometheus data.")
-	fs.StringVar(&o.gcsCredentialsFile, "gcs-credentials-file", "", "File where GCS credentials are stored.")
 	fs.Int64Var(&o.cpuCap, "cpu-cap", 10, "The maximum CPU request value, ex: 10")
 	fs.StringVar(&o.memoryCap, "memory-cap", "20Gi", "The maximum memory request value, ex: '20Gi'")
 	fs.Int64Var(&o.cpuPriorityScheduling, "cpu-priority-scheduling", 8, "Pods with CPU requests at, or above, this value will be admitted with priority scheduling")
@@ -92,6 +111,69 @@ func bindOptions(fs *flag.FlagSet) *options {
 	return &o
 }
 
+func (o *cacheOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.cacheDir, o.flagPrefix+"cache-dir", "", "Local directory holding cache data (for development mode).")
+	fs.StringVar(&o.volumeDir, o.flagPrefix+"cache-volume-dir", "", "Directory on a persistent volume holding cached Prometheus data, written atomically and verified by content hash.")
+	fs.StringVar(&o.cacheBucket, o.flagPrefix+"cache-bucket", "", "GCS bucket name holding cached Prometheus data.")
+	fs.StringVar(&o.gcsCredentialsFile, o.flagPrefix+"gcs-credentials-file", "", "File where GCS credentials are stored.")
+	fs.StringVar(&o.s3Bucket, o.flagPrefix+"cache-s3-bucket", "", "S3 bucket name holding cached Prometheus data. Credentials are read from the standard AWS environment variables or shared configuration.")
+	fs.StringVar(&o.s3Region, o.flagPrefix+"cache-s3-region", "us-east-1", "Region of the S3 bucket.")
+	fs.StringVar(&o.s3Endpoint, o.flagPrefix+"cache-s3-endpoint", "", "Endpoint of an S3-compatible storage service, if not using AWS.")
+}
+
+func (o *cacheOptions) validate() error {
+	var configured []string
+	for flag, value := range map[string]string{
+		"cache-dir":        o.cacheDir,
+		"cache-volume-dir": o.volumeDir,
+		"cache-bucket":     o.cacheBucket,
+		"cache-s3-bucket":  o.s3Bucket,
+	} {
+		if value != "" {
+			configured = append(configured, "--"+o.flagPrefix+flag)
+		}
+	}
+	switch len(configured) {
+	case 0:
+		return fmt.Errorf("one of --%[1]scache-dir, --%[1]scache-volume-dir, --%[1]scache-bucket or --%[1]scache-s3-bucket is required", o.flagPrefix)
+	case 1:
+	default:
+		sort.Strings(configured)
+		return fmt.Errorf("only one cache may be configured, got %s", strings.Join(configured, ", "))
+	}
+	if o.cacheBucket != "" && o.gcsCredentialsFile == "" {
+		return fmt.Errorf("--%sgcs-credentials-file is required", o.flagPrefix)
+	}
+	return nil
+}
+
+func (o *cacheOptions) cache() (cache, error) {
+	switch {
+	case o.cacheDir != "":
+		return &localCache{dir: o.cacheDir}, nil
+	case o.volumeDir != "":
+		return &volumeCache{dir: o.volumeDir}, nil
+	case o.s3Bucket != "":
+		config := &aws.Config{Region: aws.String(o.s3Region)}
+		if o.s3Endpoint != "" {
+			// S3-compatible services commonly do not support virtual-hosted buckets
+			config.Endpoint = aws.String(o.s3Endpoint)
+			config.S3ForcePathStyle = aws.Bool(true)
+		}
+		awsSession, err := session.NewSession(config)
+		if err != nil {
+			return nil, fmt.Errorf("could not create AWS session: %w", err)
+		}
+		return &s3Cache{client: s3.New(awsSession), bucket: o.s3Bucket}, nil
+	default:
+		gcsClient, err := storage.NewClient(interrupts.Context(), option.WithCredentialsFile(o.gcsCredentialsFile))
+		if err != nil {
+			return nil, fmt.Errorf("could not initialize GCS client: %w", err)
+		}
+		return &bucketCache{bucket: gcsClient.Bucket(o.cacheBucket)}, nil
+	}
+}
+
 const (
 	logStyleJson = "json"
 	logStyleText = "text"
@@ -124,17 +206,16 @@ func (o *options) validate() error {
 		if err := o.resultsOptions.Validate(); err != nil {
 			return err
 		}
+	case "migrate":
+		if err := o.migrateTo.validate(); err != nil {
+			return err
+		}
 
 	default:
-		return errors.New("--mode must be either \"producer\", \"consumer.ui\", or \"consumer.admission\"")
+		return errors.New("--mode must be either \"producer\", \"consumer.ui\", \"consumer.admission\", or \"migrate\"")
 	}
-	if o.cacheDir == "" {
-		if o.cacheBucket == "" {
-			return errors.New("--cache-bucket is required")
-		}
-		if o.gcsCredentialsFile == "" {
-			return errors.New("--gcs-credentials-file is required")
-		}
+	if err := o.cacheOptions.validate(); err != nil {
+		return err
 	}
 	if level, err := logrus.ParseLevel(o.loglevel); err != nil {
 		return fmt.Errorf("--loglevel invalid: %w", err)
@@ -173,19 +254,21 @@ func main() {
 	pprofutil.Instrument(opts.instrumentationOptions)
 	metrics.ExposeMetrics("pod-scaler", prowConfig.PushGateway{}, opts.instrumentationOptions.MetricsPort)
 
-	var cache cache
-	if opts.cacheDir != "" {
-		cache = &localCache{dir: opts.cacheDir}
-	} else {
-		gcsClient, err := storage.NewClient(interrupts.Context(), option.WithCredentialsFile(opts.gcsCredentialsFile))
-		if err != nil {
-			logrus.WithError(err).Fatal("Could not initialize GCS client.")
-		}
-		bucket := gcsClient.Bucket(opts.cacheBucket)
-		cache = &bucketCache{bucket: bucket}
+	cache, err := opts.cacheOptions.cache()
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not initialize cache.")
 	}
 
 	switch opts.mode {
+	case "migrate":
+		destination, err := opts.migrateTo.cache()
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not initialize cache to migrate to.")
+		}
+		if err := migrate(cache, destination, logrus.WithField("component", "pod-scaler migrate")); err != nil {
+			logrus.WithError(err).Fatal("Failed to migrate cached data.")
+		}
+		return
 	case "producer":
 		mainProduce(opts, cache)
 	case "consumer.ui":
@@ -267,6 +350,7 @@ func loaders(cache cache) map[string][]*cacheReloader {
 	for _, prefix := range []string{prowjobsCachePrefix, podsCachePrefix, stepsCachePrefix} {
 		l[MetricNameCPUUsage] = append(l[MetricNameCPUUsage], newReloader(prefix+"/"+MetricNameCPUUsage, cache))
 		l[MetricNameMemoryWorkingSet] = append(l[MetricNameMemoryWorkingSet], newReloader(prefix+"/"+MetricNameMemoryWorkingSet, cache))
+		l[MetricNameLastTerminatedReason] = append(l[MetricNameLastTerminatedReason], newReloader(prefix+"/"+MetricNameLastTerminatedReason, cache))
 	}
 	return l
 }
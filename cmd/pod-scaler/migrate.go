@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// migrate copies all cached query data from one storage backend to another.
+// Data is copied as-is, without pruning, and is read back from the destination
+// to ensure it arrived intact.
+func migrate(from loader, to cache, logger *logrus.Entry) error {
+	var names []string
+	for name := range queriesByMetric() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		nameLogger := logger.WithField("metric", name)
+		data, err := loadCache(from, name, nameLogger)
+		if errors.Is(err, notExist{}) {
+			nameLogger.Info("No cached data to migrate.")
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: could not marshal cached data: %w", name, err))
+			continue
+		}
+		if err := storeTo(to, name, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		migrated, err := loadCache(to, name, nameLogger)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: could not read back migrated data: %w", name, err))
+			continue
+		}
+		if len(migrated.Data) != len(data.Data) || len(migrated.DataByMetaData) != len(data.DataByMetaData) {
+			errs = append(errs, fmt.Errorf("%s: migrated data holds %d distributions for %d identifiers, expected %d for %d", name, len(migrated.Data), len(migrated.DataByMetaData), len(data.Data), len(data.DataByMetaData)))
+			continue
+		}
+		nameLogger.Infof("Migrated %d distributions.", len(data.Data))
+	}
+	return kerrors.NewAggregate(errs)
+}
@@ -23,8 +23,13 @@ import (
 const (
 	MetricNameCPUUsage         = `container_cpu_usage_seconds_total`
 	MetricNameMemoryWorkingSet = `container_memory_working_set_bytes`
+	// MetricNameLastTerminatedReason is exposed by kube-state-metrics and is set
+	// for containers that have terminated at least once, which lets us find
+	// containers that were OOMKilled.
+	MetricNameLastTerminatedReason = `kube_pod_container_status_last_terminated_reason`
 
 	containerFilter = `{container!="POD",container!=""}`
+	oomKilledFilter = `{reason="OOMKilled",container!=""}`
 
 	// MaxSamplesPerRequest is the maximum number of samples that Prometheus will allow a client to ask for in
 	// one request. We also use this to approximate the maximum number of samples we should be asking any one
@@ -61,8 +66,9 @@ func queriesByMetric() map[string]string {
 		},
 	} {
 		for name, metric := range map[string]string{
-			MetricNameCPUUsage:         `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
-			MetricNameMemoryWorkingSet: MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameCPUUsage:             `rate(` + MetricNameCPUUsage + containerFilter + `[3m])`,
+			MetricNameMemoryWorkingSet:     MetricNameMemoryWorkingSet + containerFilter,
+			MetricNameLastTerminatedReason: MetricNameLastTerminatedReason + oomKilledFilter,
 		} {
 			queries[fmt.Sprintf("%s/%s", info.prefix, name)] = queryFor(metric, info.selector, info.labels)
 		}
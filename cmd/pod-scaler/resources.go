@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"math"
 	"sync"
 
 	"github.com/openhistogram/circonusllhist"
@@ -8,6 +10,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/prow/pjutil"
 
 	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
@@ -16,13 +19,17 @@ import (
 func newResourceServer(loaders map[string][]*cacheReloader, health *pjutil.Health) *resourceServer {
 	logger := logrus.WithField("component", "pod-scaler request server")
 	server := &resourceServer{
-		logger:     logger,
-		lock:       sync.RWMutex{},
-		byMetaData: map[pod_scaler.FullMetadata]corev1.ResourceRequirements{},
+		logger:             logger,
+		lock:               sync.RWMutex{},
+		byMetaData:         map[pod_scaler.FullMetadata]corev1.ResourceRequirements{},
+		usageByMetaData:    map[pod_scaler.FullMetadata]map[corev1.ResourceName]usage{},
+		oomKillsByMetaData: map[pod_scaler.FullMetadata]int{},
+		oomKillsByQuery:    map[string]sets.Set[pod_scaler.FullMetadata]{},
 	}
 	digestAll(loaders, map[string]digester{
-		MetricNameCPUUsage:         server.digestCPU,
-		MetricNameMemoryWorkingSet: server.digestMemory,
+		MetricNameCPUUsage:             server.digestCPU,
+		MetricNameMemoryWorkingSet:     server.digestMemory,
+		MetricNameLastTerminatedReason: server.digestOOMKills,
 	}, health, logger)
 
 	return server
@@ -34,6 +41,47 @@ type resourceServer struct {
 	// byMetaData caches resource requirements calculated for the full assortment of
 	// metadata labels.
 	byMetaData map[pod_scaler.FullMetadata]corev1.ResourceRequirements
+	// usageByMetaData records the data backing the recommendations in byMetaData.
+	usageByMetaData map[pod_scaler.FullMetadata]map[corev1.ResourceName]usage
+	// oomKillsByMetaData counts the executions for a set of metadata labels whose
+	// container was last terminated for running out of memory.
+	oomKillsByMetaData map[pod_scaler.FullMetadata]int
+	// oomKillsByQuery records which entries of oomKillsByMetaData were
+	// digested from which query, so that stale entries can be removed.
+	oomKillsByQuery map[string]sets.Set[pod_scaler.FullMetadata]
+}
+
+// confidenceZ is the z-score for a two-sided 95% confidence interval
+const confidenceZ = 1.96
+
+// usage summarizes the distribution a recommendation was determined from.
+type usage struct {
+	// Samples is the number of data points in the distribution.
+	Samples uint64 `json:"samples"`
+	// Lower and Upper bound the 95% confidence interval for the value at the
+	// quantile we use for the recommendation.
+	Lower float64 `json:"confidence_lower"`
+	Upper float64 `json:"confidence_upper"`
+	// Max is the largest value recorded.
+	Max float64 `json:"max"`
+}
+
+// usageFor summarizes the histogram. The confidence interval is distribution-free:
+// the number of samples below the true quantile is binomially distributed, so we
+// can bound the rank of the quantile with a normal approximation and read the
+// values at those ranks from the histogram.
+func usageFor(hist *circonusllhist.Histogram, quantile float64) usage {
+	samples := hist.Count()
+	if samples == 0 {
+		return usage{}
+	}
+	spread := confidenceZ * math.Sqrt(quantile*(1-quantile)/float64(samples))
+	return usage{
+		Samples: samples,
+		Lower:   hist.ValueAtQuantile(math.Max(0, quantile-spread)),
+		Upper:   hist.ValueAtQuantile(math.Min(1, quantile+spread)),
+		Max:     hist.Max(),
+	}
 }
 
 const (
@@ -92,15 +140,93 @@ func (s *resourceServer) digestData(data *pod_scaler.CachedQuery, quantile float
 		}
 		q := quantity(valueAtQuantile)
 		s.byMetaData[meta].Requests[request] = *q
+		if _, exists := s.usageByMetaData[meta]; !exists {
+			s.usageByMetaData[meta] = map[corev1.ResourceName]usage{}
+		}
+		s.usageByMetaData[meta][request] = usageFor(overall, quantile)
 		metaLogger.Trace("unlocking for meta")
 		s.lock.Unlock()
 	}
 	logger.Debug("Finished digesting new data.")
 }
 
+const (
+	// oomMemoryBump is the factor by which we increase the memory request for
+	// workloads that have been killed for running out of memory. As the working
+	// set of such a container was cut short at its limit, we bump from the largest
+	// value we have seen instead of the quantile.
+	oomMemoryBump = 1.2
+)
+
+// digestOOMKills records how many executions of a container were OOMKilled. The
+// query only returns series for containers whose last termination reason was
+// an OOMKill, so every fingerprint we have data for is one such execution.
+func (s *resourceServer) digestOOMKills(data *pod_scaler.CachedQuery) {
+	s.logger.Debugf("Digesting new container termination metrics.")
+	kills := oomKillsFrom(data)
+	s.lock.Lock()
+	updateOOMKills(s.oomKillsByMetaData, s.oomKillsByQuery, data.Query, kills)
+	s.lock.Unlock()
+	s.logger.Debugf("Finished digesting OOMKills for %d identifiers.", len(kills))
+}
+
+// updateOOMKills replaces the counts previously digested from the query with
+// the new ones, removing those for workloads that are no longer in its data so
+// that the counts do not grow without bound. The data of each query is about
+// its own set of workloads, so the counts digested from others are kept.
+func updateOOMKills(counts map[pod_scaler.FullMetadata]int, byQuery map[string]sets.Set[pod_scaler.FullMetadata], query string, kills map[pod_scaler.FullMetadata]int) {
+	current := sets.KeySet(kills)
+	for meta := range byQuery[query].Difference(current) {
+		delete(counts, meta)
+	}
+	for meta, count := range kills {
+		counts[meta] = count
+	}
+	byQuery[query] = current
+}
+
+func oomKillsFrom(data *pod_scaler.CachedQuery) map[pod_scaler.FullMetadata]int {
+	kills := map[pod_scaler.FullMetadata]int{}
+	for meta, fingerprints := range data.DataByMetaData {
+		for _, fingerprint := range fingerprints {
+			if hist, ok := data.Data[fingerprint]; ok && hist.Histogram().Count() > 0 {
+				kills[meta]++
+			}
+		}
+	}
+	return kills
+}
+
 func (s *resourceServer) recommendedRequestFor(meta pod_scaler.FullMetadata) (corev1.ResourceRequirements, bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	data, ok := s.byMetaData[meta]
-	return data, ok
+	if !ok || s.oomKillsByMetaData[meta] == 0 {
+		return data, ok
+	}
+	memory, recorded := s.usageByMetaData[meta][corev1.ResourceMemory]
+	if !recorded {
+		return data, ok
+	}
+	bumped := data.DeepCopy()
+	current := bumped.Requests[corev1.ResourceMemory]
+	value := math.Max(float64(current.Value()), memory.Max) * oomMemoryBump
+	bumped.Requests[corev1.ResourceMemory] = *formatMemory()(value)
+	return *bumped, ok
+}
+
+// evidenceFor describes the data backing the recommendation for the metadata,
+// for logging alongside the decisions we make with it.
+func (s *resourceServer) evidenceFor(meta pod_scaler.FullMetadata) logrus.Fields {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	fields := logrus.Fields{}
+	for resource, data := range s.usageByMetaData[meta] {
+		fields[fmt.Sprintf("%s_samples", resource)] = data.Samples
+		fields[fmt.Sprintf("%s_confidence", resource)] = fmt.Sprintf("[%g, %g]", data.Lower, data.Upper)
+	}
+	if kills := s.oomKillsByMetaData[meta]; kills > 0 {
+		fields["oom_kills"] = kills
+	}
+	return fields
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func TestUsageFor(t *testing.T) {
+	hist := circonusllhist.New()
+	for i := 1; i <= 1000; i++ {
+		if err := hist.RecordValue(float64(i)); err != nil {
+			t.Fatalf("failed to record value: %v", err)
+		}
+	}
+	actual := usageFor(hist, 0.8)
+	if actual.Samples != 1000 {
+		t.Errorf("expected 1000 samples, got %d", actual.Samples)
+	}
+	value := hist.ValueAtQuantile(0.8)
+	if !(actual.Lower < value && value < actual.Upper) {
+		t.Errorf("expected %f to be within the confidence interval [%f, %f]", value, actual.Lower, actual.Upper)
+	}
+	if actual.Max != hist.Max() {
+		t.Errorf("expected max %f, got %f", hist.Max(), actual.Max)
+	}
+
+	fewer := circonusllhist.New()
+	for i := 1; i <= 1000; i += 100 {
+		if err := fewer.RecordValue(float64(i)); err != nil {
+			t.Fatalf("failed to record value: %v", err)
+		}
+	}
+	sparse := usageFor(fewer, 0.8)
+	if sparse.Upper-sparse.Lower <= actual.Upper-actual.Lower {
+		t.Errorf("expected fewer samples to widen the confidence interval, got [%f, %f] for %d and [%f, %f] for %d samples", sparse.Lower, sparse.Upper, sparse.Samples, actual.Lower, actual.Upper, actual.Samples)
+	}
+
+	if diff := cmp.Diff(usage{}, usageFor(circonusllhist.New(), 0.8)); diff != "" {
+		t.Errorf("expected no usage for an empty histogram: %v", diff)
+	}
+}
+
+func TestOOMKillsFrom(t *testing.T) {
+	killed := circonusllhist.New(circonusllhist.NoLookup())
+	if err := killed.RecordValue(1); err != nil {
+		t.Fatalf("failed to record value: %v", err)
+	}
+	meta := pod_scaler.FullMetadata{Target: "unit", Container: "test"}
+	other := pod_scaler.FullMetadata{Target: "e2e", Container: "test"}
+	data := &pod_scaler.CachedQuery{
+		Data: map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{
+			1: circonusllhist.NewHistogramWithoutLookups(killed),
+			2: circonusllhist.NewHistogramWithoutLookups(killed),
+			3: circonusllhist.NewHistogramWithoutLookups(circonusllhist.New(circonusllhist.NoLookup())),
+		},
+		DataByMetaData: map[pod_scaler.FullMetadata][]model.Fingerprint{
+			meta:  {1, 2},
+			other: {3},
+		},
+	}
+	if diff := cmp.Diff(map[pod_scaler.FullMetadata]int{meta: 2}, oomKillsFrom(data)); diff != "" {
+		t.Errorf("incorrect OOMKills: %v", diff)
+	}
+}
+
+func TestUpdateOOMKills(t *testing.T) {
+	unit := pod_scaler.FullMetadata{Target: "unit", Container: "test"}
+	e2e := pod_scaler.FullMetadata{Target: "e2e", Container: "test"}
+	step := pod_scaler.FullMetadata{Target: "e2e", Step: "install", Container: "test"}
+	counts := map[pod_scaler.FullMetadata]int{}
+	byQuery := map[string]sets.Set[pod_scaler.FullMetadata]{}
+	updateOOMKills(counts, byQuery, "pods", map[pod_scaler.FullMetadata]int{unit: 1, e2e: 2})
+	updateOOMKills(counts, byQuery, "steps", map[pod_scaler.FullMetadata]int{step: 1})
+	// the unit workload is gone from the data of its query
+	updateOOMKills(counts, byQuery, "pods", map[pod_scaler.FullMetadata]int{e2e: 3})
+	if diff := cmp.Diff(map[pod_scaler.FullMetadata]int{e2e: 3, step: 1}, counts); diff != "" {
+		t.Errorf("incorrect OOMKills: %v", diff)
+	}
+}
+
+func TestRecommendedRequestFor(t *testing.T) {
+	meta := pod_scaler.FullMetadata{Target: "unit", Container: "test"}
+	requirements := corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(1e9, resource.BinarySI),
+	}}
+	var testCases = []struct {
+		name     string
+		usage    usage
+		oomKills int
+		expected int64
+	}{
+		{
+			name:     "not OOMKilled uses the quantile",
+			usage:    usage{Max: 2e9},
+			expected: 1e9,
+		},
+		{
+			name:     "OOMKilled bumps from the largest value recorded",
+			usage:    usage{Max: 2e9},
+			oomKills: 1,
+			expected: 2.4e9,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := &resourceServer{
+				logger:             logrus.WithField("test", t.Name()),
+				lock:               sync.RWMutex{},
+				byMetaData:         map[pod_scaler.FullMetadata]corev1.ResourceRequirements{meta: requirements},
+				usageByMetaData:    map[pod_scaler.FullMetadata]map[corev1.ResourceName]usage{meta: {corev1.ResourceMemory: testCase.usage}},
+				oomKillsByMetaData: map[pod_scaler.FullMetadata]int{meta: testCase.oomKills},
+			}
+			actual, ok := server.recommendedRequestFor(meta)
+			if !ok {
+				t.Fatal("expected a recommendation")
+			}
+			if memory := actual.Requests[corev1.ResourceMemory]; memory.Value() != testCase.expected {
+				t.Errorf("expected a memory request of %d, got %d", testCase.expected, memory.Value())
+			}
+			if original := requirements.Requests[corev1.ResourceMemory]; original.Value() != 1e9 {
+				t.Errorf("recommendation was mutated in place: %s", original.String())
+			}
+		})
+	}
+}
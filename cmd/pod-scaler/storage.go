@@ -1,17 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/sirupsen/logrus"
 
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -65,6 +76,9 @@ func (b *bucketCache) store(ctx context.Context, name string) (io.WriteCloser, e
 func (b *bucketCache) lastUpdated(ctx context.Context, name string) (time.Time, error) {
 	handle := b.bucket.Object(name)
 	attrs, err := handle.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		err = notExist{wrapped: err}
+	}
 	if err != nil {
 		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
 	}
@@ -95,12 +109,263 @@ func (l *localCache) store(_ context.Context, name string) (io.WriteCloser, erro
 
 func (l *localCache) lastUpdated(_ context.Context, name string) (time.Time, error) {
 	info, err := os.Stat(path.Join(l.dir, name))
+	if os.IsNotExist(err) {
+		err = notExist{wrapped: err}
+	}
 	if err != nil {
 		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
 	}
 	return info.ModTime(), nil
 }
 
+type s3Client interface {
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+	HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error)
+}
+
+// s3Cache stores data in an S3-compatible bucket
+type s3Cache struct {
+	client s3Client
+	bucket string
+}
+
+var _ cache = &s3Cache{}
+
+func (c *s3Cache) load(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := c.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			err = notExist{wrapped: err}
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// store buffers the data and uploads it when the writer is closed, as S3 has no
+// streaming writes. The upload carries the MD5 digest of the content, so the
+// server rejects the object if it was corrupted in transit.
+func (c *s3Cache) store(ctx context.Context, name string) (io.WriteCloser, error) {
+	return &s3Writer{ctx: ctx, cache: c, name: name}, nil
+}
+
+func (c *s3Cache) lastUpdated(ctx context.Context, name string) (time.Time, error) {
+	out, err := c.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(name),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NotFound" {
+		// HEAD responses have no body, so S3 does not tell us it's NoSuchKey
+		err = notExist{wrapped: err}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
+	}
+	return aws.TimeValue(out.LastModified), nil
+}
+
+type s3Writer struct {
+	ctx   context.Context
+	cache *s3Cache
+	name  string
+	buf   bytes.Buffer
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	digest := md5.Sum(w.buf.Bytes())
+	if _, err := w.cache.client.PutObjectWithContext(w.ctx, &s3.PutObjectInput{
+		Bucket:     aws.String(w.cache.bucket),
+		Key:        aws.String(w.name),
+		Body:       bytes.NewReader(w.buf.Bytes()),
+		ContentMD5: aws.String(base64.StdEncoding.EncodeToString(digest[:])),
+	}); err != nil {
+		return fmt.Errorf("could not upload %s to bucket %s: %w", w.name, w.cache.bucket, err)
+	}
+	return nil
+}
+
+// objectsDir holds the content-addressed data in a volumeCache
+const objectsDir = ".objects"
+
+// volumeCache stores data in a directory, usually on a persistent volume that
+// is shared between the producer and consumers. Data is stored under the SHA256
+// hash of its content and every name is a symbolic link to the data it holds.
+// Writes replace the link atomically, so readers never see partial data, and
+// reads verify the content against its hash.
+type volumeCache struct {
+	dir string
+}
+
+var _ cache = &volumeCache{}
+
+func (v *volumeCache) load(_ context.Context, name string) (io.ReadCloser, error) {
+	link := filepath.Join(v.dir, name)
+	target, err := os.Readlink(link)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = notExist{wrapped: err}
+		}
+		return nil, err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		return nil, fmt.Errorf("could not open data for %s: %w", name, err)
+	}
+	return &verifyingReader{file: file, name: name, hash: sha256.New(), expected: filepath.Base(target)}, nil
+}
+
+func (v *volumeCache) store(_ context.Context, name string) (io.WriteCloser, error) {
+	objects := filepath.Join(v.dir, objectsDir)
+	if err := os.MkdirAll(objects, 0777); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(objects, ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &volumeWriter{cache: v, name: name, tmp: tmp, hash: sha256.New()}, nil
+}
+
+func (v *volumeCache) lastUpdated(_ context.Context, name string) (time.Time, error) {
+	info, err := os.Lstat(filepath.Join(v.dir, name))
+	if os.IsNotExist(err) {
+		err = notExist{wrapped: err}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not query cache for attributes: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+// referenced determines if any name in the cache links to the object
+func (v *volumeCache) referenced(object string) (bool, error) {
+	var found bool
+	err := filepath.WalkDir(v.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == objectsDir {
+			return filepath.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if filepath.Base(target) == object {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
+
+type volumeWriter struct {
+	cache *volumeCache
+	name  string
+	tmp   *os.File
+	hash  hash.Hash
+}
+
+func (w *volumeWriter) Write(p []byte) (int, error) {
+	n, err := w.tmp.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+// Close moves the data into place under its hash and then points the name at it.
+// Data that is no longer referenced by any name is removed.
+func (w *volumeWriter) Close() error {
+	if err := w.tmp.Sync(); err != nil {
+		_ = w.tmp.Close()
+		return fmt.Errorf("could not sync data: %w", err)
+	}
+	if err := w.tmp.Close(); err != nil {
+		return fmt.Errorf("could not close data: %w", err)
+	}
+	sum := hex.EncodeToString(w.hash.Sum(nil))
+	objects := filepath.Join(w.cache.dir, objectsDir)
+	object := filepath.Join(objects, sum)
+	if _, err := os.Stat(object); err == nil {
+		// we already hold this content
+		if err := os.Remove(w.tmp.Name()); err != nil {
+			return fmt.Errorf("could not remove temporary data: %w", err)
+		}
+	} else if err := os.Rename(w.tmp.Name(), object); err != nil {
+		return fmt.Errorf("could not move data into place: %w", err)
+	}
+
+	link := filepath.Join(w.cache.dir, w.name)
+	if err := os.MkdirAll(filepath.Dir(link), 0777); err != nil {
+		return err
+	}
+	target, err := filepath.Rel(filepath.Dir(link), object)
+	if err != nil {
+		return fmt.Errorf("could not determine link target: %w", err)
+	}
+	previous, err := os.Readlink(link)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read previous link: %w", err)
+	}
+	tmpLink := fmt.Sprintf("%s.tmp-%d", link, time.Now().UnixNano())
+	if err := os.Symlink(target, tmpLink); err != nil {
+		return fmt.Errorf("could not link data: %w", err)
+	}
+	if err := os.Rename(tmpLink, link); err != nil {
+		return fmt.Errorf("could not move link into place: %w", err)
+	}
+
+	if previous == "" || filepath.Base(previous) == sum {
+		return nil
+	}
+	stale := filepath.Base(previous)
+	if referenced, err := w.cache.referenced(stale); err != nil || referenced {
+		return err
+	}
+	if err := os.Remove(filepath.Join(objects, stale)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove stale data: %w", err)
+	}
+	return nil
+}
+
+// verifyingReader checks that the data read matches the expected hash once it
+// has been read in full.
+type verifyingReader struct {
+	file     *os.File
+	name     string
+	hash     hash.Hash
+	expected string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		if actual := hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+			return n, fmt.Errorf("data for %s is corrupt: expected hash %s, got %s", r.name, r.expected, actual)
+		}
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.file.Close()
+}
+
 // notExist closes over the different ways in which storage libraries may expose a nonexistent file
 type notExist struct {
 	wrapped error
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/openhistogram/circonusllhist"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+
+	pod_scaler "github.com/openshift/ci-tools/pkg/pod-scaler"
+)
+
+func write(t *testing.T, c cache, name, content string) {
+	t.Helper()
+	writer, err := c.store(context.Background(), name)
+	if err != nil {
+		t.Fatalf("could not open writer: %v", err)
+	}
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("could not close writer: %v", err)
+	}
+}
+
+func read(c cache, name string) (string, error) {
+	reader, err := c.load(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	raw, err := io.ReadAll(reader)
+	return string(raw), err
+}
+
+func objects(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(dir, objectsDir))
+	if err != nil {
+		t.Fatalf("could not list objects: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestVolumeCache(t *testing.T) {
+	dir := t.TempDir()
+	c := &volumeCache{dir: dir}
+
+	if _, err := read(c, "pods/missing.json"); !errors.Is(err, notExist{}) {
+		t.Fatalf("expected a missing file to not exist, got %v", err)
+	}
+	if _, err := c.lastUpdated(context.Background(), "pods/missing.json"); !errors.Is(err, notExist{}) {
+		t.Fatalf("expected a missing file to not exist, got %v", err)
+	}
+
+	write(t, c, "pods/first.json", "data")
+	write(t, c, "steps/second.json", "data")
+	if content, err := read(c, "pods/first.json"); err != nil || content != "data" {
+		t.Fatalf("expected to read data, got %q, %v", content, err)
+	}
+	if diff := cmp.Diff([]string{"3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"}, objects(t, dir)); diff != "" {
+		t.Errorf("expected identical content to be stored once: %v", diff)
+	}
+	if _, err := c.lastUpdated(context.Background(), "pods/first.json"); err != nil {
+		t.Errorf("could not determine last update: %v", err)
+	}
+
+	write(t, c, "pods/first.json", "other")
+	if content, err := read(c, "steps/second.json"); err != nil || content != "data" {
+		t.Fatalf("expected data still referenced by another name to be kept, got %q, %v", content, err)
+	}
+	write(t, c, "steps/second.json", "other")
+	if diff := cmp.Diff([]string{"d9298a10d1b0735837dc4bd85dac641b0f3cef27a47e5d53a54f2f3f5b2fcffa"}, objects(t, dir)); diff != "" {
+		t.Errorf("expected unreferenced content to be removed: %v", diff)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, objectsDir, "d9298a10d1b0735837dc4bd85dac641b0f3cef27a47e5d53a54f2f3f5b2fcffa"), []byte("corrupt"), 0644); err != nil {
+		t.Fatalf("could not corrupt data: %v", err)
+	}
+	if _, err := read(c, "pods/first.json"); err == nil {
+		t.Error("expected corrupt data to fail verification")
+	}
+}
+
+type fakeS3 struct {
+	objects  map[string][]byte
+	modified map[string]time.Time
+}
+
+func (f *fakeS3) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	data, ok := f.objects[*input.Bucket+"/"+*input.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeS3) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	if input.ContentMD5 == nil {
+		return nil, errors.New("no content MD5")
+	}
+	f.objects[*input.Bucket+"/"+*input.Key] = data
+	f.modified[*input.Bucket+"/"+*input.Key] = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) HeadObjectWithContext(_ aws.Context, input *s3.HeadObjectInput, _ ...request.Option) (*s3.HeadObjectOutput, error) {
+	modified, ok := f.modified[*input.Bucket+"/"+*input.Key]
+	if !ok {
+		return nil, awserr.New("NotFound", "not found", nil)
+	}
+	return &s3.HeadObjectOutput{LastModified: &modified}, nil
+}
+
+func TestS3Cache(t *testing.T) {
+	c := &s3Cache{client: &fakeS3{objects: map[string][]byte{}, modified: map[string]time.Time{}}, bucket: "bucket"}
+	if _, err := read(c, "pods/missing.json"); !errors.Is(err, notExist{}) {
+		t.Fatalf("expected a missing file to not exist, got %v", err)
+	}
+	if _, err := c.lastUpdated(context.Background(), "pods/missing.json"); !errors.Is(err, notExist{}) {
+		t.Fatalf("expected a missing file to not exist, got %v", err)
+	}
+	write(t, c, "pods/first.json", "data")
+	if content, err := read(c, "pods/first.json"); err != nil || content != "data" {
+		t.Fatalf("expected to read data, got %q, %v", content, err)
+	}
+	updated, err := c.lastUpdated(context.Background(), "pods/first.json")
+	if err != nil {
+		t.Fatalf("could not determine last update: %v", err)
+	}
+	if expected := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC); !updated.Equal(expected) {
+		t.Errorf("expected last update at %s, got %s", expected, updated)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	logger := logrus.WithField("test", t.Name())
+	from, to := &localCache{dir: t.TempDir()}, &volumeCache{dir: t.TempDir()}
+	hist := circonusllhist.New(circonusllhist.NoLookup())
+	if err := hist.RecordValue(1); err != nil {
+		t.Fatalf("could not record value: %v", err)
+	}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := &pod_scaler.CachedQuery{
+		Query:           "query",
+		RangesByCluster: map[string][]pod_scaler.TimeRange{"cluster": {{Start: now.Add(-time.Hour), End: now}}},
+		Data:            map[model.Fingerprint]*circonusllhist.HistogramWithoutLookups{1: circonusllhist.NewHistogramWithoutLookups(hist)},
+		DataByMetaData:  map[pod_scaler.FullMetadata][]model.Fingerprint{{Container: "test"}: {1}},
+	}
+	if err := storeCache(from, "pods/"+MetricNameCPUUsage, data, logger); err != nil {
+		t.Fatalf("could not seed cache: %v", err)
+	}
+	if err := migrate(from, to, logger); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	migrated, err := loadCache(to, "pods/"+MetricNameCPUUsage, logger)
+	if err != nil {
+		t.Fatalf("could not load migrated data: %v", err)
+	}
+	if diff := cmp.Diff(data.RangesByCluster, migrated.RangesByCluster); diff != "" {
+		t.Errorf("ranges were not migrated as-is: %v", diff)
+	}
+	if diff := cmp.Diff(data.DataByMetaData, migrated.DataByMetaData); diff != "" {
+		t.Errorf("identifiers were not migrated as-is: %v", diff)
+	}
+	if !migrated.Data[1].Histogram().Equals(hist) {
+		t.Error("histogram was not migrated as-is")
+	}
+	if _, err := loadCache(to, "pods/"+MetricNameMemoryWorkingSet, logger); !errors.Is(err, notExist{}) {
+		t.Errorf("expected no data to be created for missing metrics, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/openshift/ci-tools/pkg/cmd/promote"
+)
+
+func main() {
+	cmd := promote.NewCommand()
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
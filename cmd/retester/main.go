@@ -38,6 +38,10 @@ type options struct {
 	cacheRecordAge time.Duration
 
 	configFile string
+
+	passRateFile         string
+	passRateSippyURL     string
+	passRateSippyRelease string
 }
 
 func (o *options) Validate() error {
@@ -52,6 +56,9 @@ func (o *options) Validate() error {
 	if o.cacheFileOnS3 && o.cacheFile == "" {
 		return fmt.Errorf("--cache-file is required if --cache-file-on-s3 is set to true")
 	}
+	if o.passRateFile != "" && o.passRateSippyURL != "" {
+		return fmt.Errorf("--pass-rate-file and --pass-rate-sippy-url are mutually exclusive")
+	}
 	return nil
 }
 
@@ -79,6 +86,9 @@ func gatherOptions() options {
 	fs.StringVar(&o.cacheFile, "cache-file", "", "File to persist cache. No persistence of cache if not set")
 	fs.StringVar(&o.cacheRecordAgeRaw, "cache-record-age", "168h", "Parseable duration string that specifies how long a cache record lives in cache after the last time it was considered")
 	fs.StringVar(&o.configFile, "config-file", "", "Path to the configure file of the retest.")
+	fs.StringVar(&o.passRateFile, "pass-rate-file", "", "Path to a file mapping job names to their recent pass rate, used to avoid retesting broken jobs.")
+	fs.StringVar(&o.passRateSippyURL, "pass-rate-sippy-url", "", "URL of a Sippy instance to get recent pass rates of jobs from, used to avoid retesting broken jobs.")
+	fs.StringVar(&o.passRateSippyRelease, "pass-rate-sippy-release", "Presubmits", "Release in Sippy to get recent pass rates of jobs from.")
 
 	for _, group := range []flagutil.OptionGroup{&o.github, &o.config} {
 		group.AddFlags(fs)
@@ -132,7 +142,17 @@ func main() {
 		}
 	}
 
-	c := retester.NewController(gc, configAgent.Config, git.ClientFactoryFrom(gitClient), o.github.AppPrivateKeyPath != "", o.cacheFile, o.cacheRecordAge, config, awsSession)
+	var passRates retester.PassRateSource
+	switch {
+	case o.passRateFile != "":
+		if passRates, err = retester.NewFilePassRateSource(o.passRateFile); err != nil {
+			logrus.WithError(err).Fatal("Failed to load pass rates")
+		}
+	case o.passRateSippyURL != "":
+		passRates = retester.NewSippyPassRateSource(o.passRateSippyURL, o.passRateSippyRelease)
+	}
+
+	c := retester.NewController(gc, configAgent.Config, git.ClientFactoryFrom(gitClient), o.github.AppPrivateKeyPath != "", o.cacheFile, o.cacheRecordAge, config, awsSession, passRates)
 
 	metrics.ExposeMetrics("retester", prowConfig.PushGateway{}, prowflagutil.DefaultMetricsPort)
 
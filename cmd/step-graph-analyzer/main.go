@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	stepgraphanalyzer "github.com/openshift/ci-tools/pkg/step-graph-analyzer"
+)
+
+type options struct {
+	stepGraphPath string
+	jobURL        string
+	traceOutput   string
+}
+
+func gatherOptions() options {
+	o := options{}
+	flag.StringVar(&o.stepGraphPath, "step-graph", "", "Path to a ci-operator-step-graph.json file.")
+	flag.StringVar(&o.jobURL, "job-url", "", "URL to a job whose step graph should be analyzed, used when --step-graph is not set.")
+	flag.StringVar(&o.traceOutput, "trace-output", "", "If set, write the step graph as Chrome trace events to this path.")
+	flag.Parse()
+	return o
+}
+
+func (o *options) validate() error {
+	if (o.stepGraphPath == "") == (o.jobURL == "") {
+		return errors.New("exactly one of --step-graph or --job-url must be set")
+	}
+	return nil
+}
+
+func (o *options) loadStepGraph() (api.CIOperatorStepGraph, error) {
+	var raw []byte
+	if o.stepGraphPath != "" {
+		var err error
+		if raw, err = os.ReadFile(o.stepGraphPath); err != nil {
+			return nil, fmt.Errorf("failed to read step graph: %w", err)
+		}
+	} else {
+		url := api.StepGraphJSONURL(o.jobURL)
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GET %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("got unexpected http status code %d for url %s", resp.StatusCode, url)
+		}
+		if raw, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to read response body for request to %s: %w", url, err)
+		}
+	}
+	graph := api.CIOperatorStepGraph{}
+	if err := json.Unmarshal(raw, &graph); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal step graph: %w", err)
+	}
+	return graph, nil
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+	graph, err := o.loadStepGraph()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load the step graph")
+	}
+	analysis := stepgraphanalyzer.Analyze(graph)
+	analysis.Print(os.Stdout)
+	if o.traceOutput == "" {
+		return
+	}
+	trace, err := stepgraphanalyzer.Trace(graph, analysis)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to serialize the trace")
+	}
+	if err := os.WriteFile(o.traceOutput, trace, 0644); err != nil {
+		logrus.WithError(err).Fatal("Failed to write the trace")
+	}
+}
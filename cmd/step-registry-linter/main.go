@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry/lint"
+)
+
+type options struct {
+	registryPath string
+	reportPath   string
+}
+
+func gatherOptions() options {
+	o := options{}
+	flag.StringVar(&o.registryPath, "registry", "", "Path to the step registry directory.")
+	flag.StringVar(&o.reportPath, "report", "", "If set, write the findings as a JSON report to this path.")
+	flag.Parse()
+	return o
+}
+
+func (o *options) validate() error {
+	if o.registryPath == "" {
+		return errors.New("--registry is required")
+	}
+	return nil
+}
+
+func main() {
+	o := gatherOptions()
+	if err := o.validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid options")
+	}
+	references, _, _, _, _, _, err := load.Registry(o.registryPath, load.RegistryFlag(0))
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load the registry")
+	}
+	report := lint.Registry(references)
+	if o.reportPath != "" {
+		raw, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to marshal the report")
+		}
+		if err := os.WriteFile(o.reportPath, raw, 0644); err != nil {
+			logrus.WithError(err).Fatal("Failed to write the report")
+		}
+	}
+	for _, finding := range report.Findings {
+		fmt.Println(finding)
+	}
+	logrus.Infof("Linted %d steps: %d findings, %d suppressed", len(references), len(report.Findings), len(report.Suppressed))
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
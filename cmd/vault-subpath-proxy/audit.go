@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	auditOperationWrite  = "write"
+	auditOperationPatch  = "patch"
+	auditOperationDelete = "delete"
+)
+
+// vaultIdentity is who a Vault token belongs to
+type vaultIdentity struct {
+	DisplayName string `json:"display_name,omitempty"`
+	EntityID    string `json:"entity_id,omitempty"`
+}
+
+// syncOutcome is the result of syncing a secret into a cluster
+type syncOutcome struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Operation is what happened to the secret, if syncing succeeded
+	Operation string `json:"operation,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// auditEntry records a write or delete in the kv store. It never holds any
+// values, only the names of the fields that changed.
+type auditEntry struct {
+	Time       time.Time     `json:"time"`
+	Operation  string        `json:"operation"`
+	Path       string        `json:"path"`
+	Identity   vaultIdentity `json:"identity"`
+	Fields     []string      `json:"fields,omitempty"`
+	StatusCode int           `json:"status_code"`
+	// Errors are the reasons the proxy rejected the request for
+	Errors []string      `json:"errors,omitempty"`
+	Sync   []syncOutcome `json:"sync,omitempty"`
+}
+
+// auditLogger writes audit entries as JSON lines
+type auditLogger struct {
+	lock sync.Mutex
+	out  io.Writer
+	now  func() time.Time
+}
+
+func newAuditLogger(out io.Writer) *auditLogger {
+	return &auditLogger{out: out, now: time.Now}
+}
+
+func (a *auditLogger) log(entry auditEntry) {
+	if a == nil {
+		return
+	}
+	entry.Time = a.now()
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		logrus.WithError(err).WithField("path", entry.Path).Error("Failed to marshal audit entry")
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if _, err := a.out.Write(append(raw, '\n')); err != nil {
+		logrus.WithError(err).WithField("path", entry.Path).Error("Failed to write audit entry")
+	}
+}
+
+// tokenIdentityResolver looks up who a token belongs to with the token itself
+func tokenIdentityResolver(client *api.Client) func(token string) (vaultIdentity, error) {
+	return func(token string) (vaultIdentity, error) {
+		clone, err := client.Clone()
+		if err != nil {
+			return vaultIdentity{}, fmt.Errorf("failed to clone vault client: %w", err)
+		}
+		clone.SetToken(token)
+		secret, err := clone.Auth().Token().LookupSelf()
+		if err != nil {
+			return vaultIdentity{}, fmt.Errorf("failed to look up token: %w", err)
+		}
+		var identity vaultIdentity
+		if secret != nil {
+			identity.DisplayName, _ = secret.Data["display_name"].(string)
+			identity.EntityID, _ = secret.Data["entity_id"].(string)
+		}
+		return identity, nil
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeUpstream struct {
+	statusCode int
+	requests   int
+}
+
+func (f *fakeUpstream) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.requests++
+	return newResponse(f.statusCode, r), nil
+}
+
+func TestKVUpdateTransportAudit(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	identity := vaultIdentity{DisplayName: "oidc-user", EntityID: "entity"}
+	schemas := &schemaConfig{Schemas: []pathSchema{{
+		Path:               "team/pull-secret",
+		Fields:             []fieldSchema{{Name: ".dockerconfigjson", Required: true, Format: formatDockerConfigJSON}},
+		AllowUnknownFields: true,
+	}}}
+	for _, tc := range []struct {
+		name               string
+		method             string
+		path               string
+		data               map[string]string
+		rawBody            string
+		upstreamStatusCode int
+		expectedStatusCode int
+		expectedRequests   int
+		expected           auditEntry
+		expectedSecrets    int
+	}{{
+		name:               "write is synced and audited",
+		method:             http.MethodPost,
+		path:               "/v1/secret/data/team/item",
+		data:               map[string]string{"secretsync/target-namespace": "ns", "secretsync/target-name": "secret", "token": "value"},
+		upstreamStatusCode: http.StatusOK,
+		expectedStatusCode: http.StatusOK,
+		expectedRequests:   1,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationWrite, Path: "team/item", Identity: identity, StatusCode: http.StatusOK,
+			Fields: []string{"secretsync/target-name", "secretsync/target-namespace", "token"},
+			Sync:   []syncOutcome{{Cluster: "build01", Namespace: "ns", Name: "secret", Operation: "created"}},
+		},
+		expectedSecrets: 1,
+	}, {
+		name:               "write violating the schema is rejected",
+		method:             http.MethodPut,
+		path:               "/v1/secret/data/team/pull-secret",
+		data:               map[string]string{"secretsync/target-namespace": "ns", "secretsync/target-name": "secret", ".dockerconfigjson": `{"auths": {}}`},
+		expectedStatusCode: http.StatusBadRequest,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationWrite, Path: "team/pull-secret", Identity: identity, StatusCode: http.StatusBadRequest,
+			Fields: []string{".dockerconfigjson", "secretsync/target-name", "secretsync/target-namespace"},
+			Errors: []string{"field .dockerconfigjson is not valid dockerconfigjson: no registries in auths"},
+		},
+	}, {
+		name:               "patch is validated as the whole item",
+		method:             http.MethodPatch,
+		path:               "/v1/secret/data/team/pull-secret",
+		rawBody:            `{"data": {".dockerconfigjson": null, "note": "value"}}`,
+		expectedStatusCode: http.StatusBadRequest,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationPatch, Path: "team/pull-secret", Identity: identity, StatusCode: http.StatusBadRequest,
+			Fields: []string{"note"},
+			Errors: []string{"field .dockerconfigjson is required by the schema for team/pull-secret"},
+		},
+	}, {
+		name:               "request with a body that cannot be unmarshalled is audited",
+		method:             http.MethodPost,
+		path:               "/v1/secret/data/team/item",
+		rawBody:            `{"data": `,
+		expectedStatusCode: http.StatusInternalServerError,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationWrite, Path: "team/item", Identity: identity, StatusCode: http.StatusInternalServerError,
+			Errors: []string{"failed to deserialize request body"},
+		},
+	}, {
+		name:               "write rejected by vault is not synced",
+		method:             http.MethodPatch,
+		path:               "/v1/secret/data/team/item",
+		data:               map[string]string{"secretsync/target-namespace": "ns", "secretsync/target-name": "secret", "token": "value"},
+		upstreamStatusCode: http.StatusForbidden,
+		expectedStatusCode: http.StatusForbidden,
+		expectedRequests:   1,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationPatch, Path: "team/item", Identity: identity, StatusCode: http.StatusForbidden,
+			Fields: []string{"secretsync/target-name", "secretsync/target-namespace", "token"},
+		},
+	}, {
+		name:               "delete is audited",
+		method:             http.MethodDelete,
+		path:               "/v1/secret/metadata/team/item",
+		upstreamStatusCode: http.StatusNoContent,
+		expectedStatusCode: http.StatusNoContent,
+		expectedRequests:   1,
+		expected: auditEntry{
+			Time: now, Operation: auditOperationDelete, Path: "team/item", Identity: identity, StatusCode: http.StatusNoContent,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream := &fakeUpstream{statusCode: tc.upstreamStatusCode}
+			client := fakectrlruntimeclient.NewClientBuilder().Build()
+			out := &bytes.Buffer{}
+			transport := &kvUpdateTransport{
+				kvMountPath: "secret",
+				upstream:    upstream,
+				kubeClients: func() map[string]ctrlruntimeclient.Client {
+					return map[string]ctrlruntimeclient.Client{"build01": client}
+				},
+				synchronousSecretSync: true,
+				schemas:               schemas,
+				audit:                 &auditLogger{out: out, now: func() time.Time { return now }},
+				identity: func(token string) (vaultIdentity, error) {
+					if token != "token" {
+						t.Errorf("expected the token of the request, got %q", token)
+					}
+					return identity, nil
+				},
+			}
+			var body io.Reader
+			if tc.rawBody != "" {
+				body = strings.NewReader(tc.rawBody)
+			}
+			if tc.data != nil {
+				raw, err := json.Marshal(simpleKVUpdateRequestBody{Data: tc.data})
+				if err != nil {
+					t.Fatalf("failed to marshal body: %v", err)
+				}
+				body = bytes.NewBuffer(raw)
+			}
+			req, err := http.NewRequest(tc.method, "http://vault"+tc.path, body)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("X-Vault-Token", "token")
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("round trip failed: %v", err)
+			}
+			if resp.StatusCode != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, resp.StatusCode)
+			}
+			if upstream.requests != tc.expectedRequests {
+				t.Errorf("expected %d requests to vault, got %d", tc.expectedRequests, upstream.requests)
+			}
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("expected exactly one audit entry, got %d: %s", len(lines), out.String())
+			}
+			if strings.Contains(lines[0], "value") {
+				t.Errorf("audit entry contains a value: %s", lines[0])
+			}
+			var actual auditEntry
+			if err := json.Unmarshal([]byte(lines[0]), &actual); err != nil {
+				t.Fatalf("failed to unmarshal audit entry: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected audit entry: %s", diff)
+			}
+
+			secrets := &corev1.SecretList{}
+			if err := client.List(req.Context(), secrets); err != nil {
+				t.Fatalf("failed to list secrets: %v", err)
+			}
+			if len(secrets.Items) != tc.expectedSecrets {
+				t.Errorf("expected %d synced secrets, got %d", tc.expectedSecrets, len(secrets.Items))
+			}
+		})
+	}
+}
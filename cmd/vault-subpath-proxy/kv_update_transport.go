@@ -9,10 +9,12 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/semaphore"
 
@@ -52,6 +54,13 @@ type kvUpdateTransport struct {
 	// the key cache (existingSecretKeysByNamespaceName) when Vault entries
 	// get updated/deleted.
 	existingSecretKeysByVaultSecretName map[string][]namespacedNameKey
+
+	// schemas constrain the content of writes, if set
+	schemas *schemaConfig
+	// audit records every write and delete
+	audit *auditLogger
+	// identity resolves who a token belongs to for the audit log
+	identity func(token string) (vaultIdentity, error)
 }
 
 func (k *kvUpdateTransport) initialize() {
@@ -84,8 +93,17 @@ func (k *kvUpdateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	if (r.Method != http.MethodPut && r.Method != http.MethodPost && r.Method != http.MethodPatch && r.Method != http.MethodDelete) || !strings.HasPrefix(r.URL.Path, "/v1/"+k.kvMountPath) {
 		return k.upstream.RoundTrip(r)
 	}
+	entry := auditEntry{
+		Operation: k.auditOperation(r),
+		Path:      k.kvItemFromURLPath(r.URL.Path),
+		Identity:  k.resolveIdentity(r),
+	}
+	if entry.Operation == auditOperationDelete {
+		entry.Fields = k.existingFields(entry.Path)
+	}
 	if r.Method == http.MethodDelete {
 		resp, err := k.upstream.RoundTrip(r)
+		k.auditResponse(entry, resp, err)
 		if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
 			return resp, err
 		}
@@ -97,17 +115,40 @@ func (k *kvUpdateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	requestBodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
 		logrus.WithError(err).Error("failed to read request body")
-		return newResponse(http.StatusInternalServerError, r, "failed to read request body"), nil
+		return k.reject(entry, r, http.StatusInternalServerError, "failed to read request body"), nil
 	}
 
 	var body simpleKVUpdateRequestBody
 	if err := json.Unmarshal(requestBodyBytes, &body); err != nil {
 		logrus.WithError(err).WithField("raw-body", string(requestBodyBytes)).Error("failed to unmarshal request body")
-		return newResponse(http.StatusInternalServerError, r, "failed to deserialize request body"), nil
+		return k.reject(entry, r, http.StatusInternalServerError, "failed to deserialize request body"), nil
+	}
+
+	// data is the content of the item after the write, written are the
+	// fields the write sets. They only differ for a patch, which leaves
+	// the fields it does not mention alone and removes the ones set to null.
+	isDataWrite := strings.HasPrefix(r.URL.Path, "/v1/"+k.kvMountPath+"/data/")
+	data, written := body.Data, body.Data
+	var current map[string]string
+	if isDataWrite {
+		current, err = k.getItem(entry.Path)
+		if err != nil {
+			logrus.WithError(err).WithField("path", entry.Path).Warn("Failed to get the current item")
+			if r.Method == http.MethodPatch {
+				return k.reject(entry, r, http.StatusInternalServerError, "failed to get the current item to validate the patch"), nil
+			}
+		}
+		if r.Method == http.MethodPatch {
+			data, written, err = patchItem(current, requestBodyBytes)
+			if err != nil {
+				logrus.WithError(err).WithField("raw-body", string(requestBodyBytes)).Error("failed to unmarshal request body")
+				return k.reject(entry, r, http.StatusInternalServerError, "failed to deserialize request body"), nil
+			}
+		}
 	}
 
 	var errs []string
-	for key, value := range body.Data {
+	for key, value := range written {
 		if key == vault.SecretSyncTargetNamepaceKey {
 			for _, namespace := range strings.Split(value, ",") {
 				if valueErrs := validation.IsDNS1123Label(namespace); len(valueErrs) > 0 {
@@ -130,37 +171,166 @@ func (k *kvUpdateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 		}
 	}
 
-	if err := ci_validation.ValidateSecretInStep(body.Data[vault.SecretSyncTargetNamepaceKey], body.Data[vault.SecretSyncTargetNameKey]); err != nil {
-		errs = append(errs, fmt.Sprintf("secret %s in namespace %s cannot be used in a step: %s", body.Data[vault.SecretSyncTargetNameKey], body.Data[vault.SecretSyncTargetNamepaceKey], err.Error()))
+	if err := ci_validation.ValidateSecretInStep(data[vault.SecretSyncTargetNamepaceKey], data[vault.SecretSyncTargetNameKey]); err != nil {
+		errs = append(errs, fmt.Sprintf("secret %s in namespace %s cannot be used in a step: %s", data[vault.SecretSyncTargetNameKey], data[vault.SecretSyncTargetNamepaceKey], err.Error()))
+	}
+
+	if schema := k.schemas.schemaFor(entry.Path); schema != nil && isDataWrite {
+		errs = append(errs, schema.validate(data)...)
 	}
 
-	keyConflictValidationErrs, err := k.validateKeysDontConflict(r.Context(), r.URL.Path, body.Data)
+	keyConflictValidationErrs, err := k.validateKeysDontConflict(r.Context(), r.URL.Path, data)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to validate keys don't conflict")
 		errs = append(errs, "secret key validation check failed, please contact @dptp-helpdesk in #forum-ocp-testplatform")
 	}
 	errs = append(errs, keyConflictValidationErrs...)
 
+	if isDataWrite {
+		entry.Fields = changedFields(current, data)
+	}
 	if len(errs) > 0 {
-		return newResponse(400, r, errs...), nil
+		return k.reject(entry, r, http.StatusBadRequest, errs...), nil
 	}
 
 	r.Body = io.NopCloser(bytes.NewBuffer(requestBodyBytes))
 	response, err := k.upstream.RoundTrip(r)
-	if err != nil {
+	if err != nil || response.StatusCode < 200 || response.StatusCode > 299 {
+		k.auditResponse(entry, response, err)
 		return response, err
 	}
 
+	entry.StatusCode = response.StatusCode
 	if k.synchronousSecretSync {
-		k.syncSecret(body.Data)
+		entry.Sync = k.syncSecret(data)
+		k.audit.log(entry)
 	} else {
-		go k.syncSecret(body.Data)
+		go func() {
+			entry.Sync = k.syncSecret(data)
+			k.audit.log(entry)
+		}()
 	}
-	if response.StatusCode > 199 && response.StatusCode < 300 {
-		k.updateKeyCacheForSecret(r.URL.Path, body.Data)
+	k.updateKeyCacheForSecret(r.URL.Path, data)
+	return response, nil
+}
 
+func (k *kvUpdateTransport) auditOperation(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodDelete,
+		strings.HasPrefix(r.URL.Path, "/v1/"+k.kvMountPath+"/delete/"),
+		strings.HasPrefix(r.URL.Path, "/v1/"+k.kvMountPath+"/destroy/"):
+		return auditOperationDelete
+	case r.Method == http.MethodPatch:
+		return auditOperationPatch
 	}
-	return response, nil
+	return auditOperationWrite
+}
+
+// kvItemFromURLPath returns the path of the item relative to the kv mount
+func (k *kvUpdateTransport) kvItemFromURLPath(urlPath string) string {
+	item := strings.TrimPrefix(urlPath, "/v1/"+k.kvMountPath+"/")
+	for _, prefix := range []string{"data/", "metadata/", "delete/", "undelete/", "destroy/"} {
+		if strings.HasPrefix(item, prefix) {
+			return strings.TrimPrefix(item, prefix)
+		}
+	}
+	return item
+}
+
+func (k *kvUpdateTransport) resolveIdentity(r *http.Request) vaultIdentity {
+	if k.identity == nil {
+		return vaultIdentity{}
+	}
+	identity, err := k.identity(r.Header.Get(consts.AuthHeaderName))
+	if err != nil {
+		logrus.WithError(err).WithField("path", r.URL.Path).Warn("Failed to resolve the identity of the request for the audit log")
+	}
+	return identity
+}
+
+// reject audits a request that is not passed on to Vault and responds to it
+func (k *kvUpdateTransport) reject(entry auditEntry, r *http.Request, statusCode int, errs ...string) *http.Response {
+	entry.StatusCode = statusCode
+	entry.Errors = errs
+	k.audit.log(entry)
+	return newResponse(statusCode, r, errs...)
+}
+
+func (k *kvUpdateTransport) auditResponse(entry auditEntry, response *http.Response, err error) {
+	if err != nil {
+		entry.Errors = []string{err.Error()}
+	}
+	if response != nil {
+		entry.StatusCode = response.StatusCode
+	}
+	k.audit.log(entry)
+}
+
+// getItem returns the current content of the item, which is empty if the
+// item does not exist or cannot be read without a privileged client
+func (k *kvUpdateTransport) getItem(item string) (map[string]string, error) {
+	if k.privilegedVaultClient == nil {
+		return nil, nil
+	}
+	current, err := k.privilegedVaultClient.GetKV(k.kvMountPath + "/" + item)
+	if err != nil {
+		if vaultclient.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return current.Data, nil
+}
+
+// existingFields returns the names of the fields of the item before it is deleted
+func (k *kvUpdateTransport) existingFields(item string) []string {
+	current, err := k.getItem(item)
+	if err != nil {
+		logrus.WithError(err).WithField("path", item).Warn("Failed to get the current item for the audit log")
+	}
+	return sets.List(sets.KeySet(current))
+}
+
+// patchItem applies a patch, a JSON merge patch of the data of the item, to
+// the current content of the item. It returns the content after the patch and
+// the fields the patch sets, without the ones it removes.
+func patchItem(current map[string]string, rawPatch []byte) (data map[string]string, written map[string]string, err error) {
+	var patch struct {
+		Data map[string]*string `json:"data"`
+	}
+	if err := json.Unmarshal(rawPatch, &patch); err != nil {
+		return nil, nil, err
+	}
+	data, written = map[string]string{}, map[string]string{}
+	for key, value := range current {
+		data[key] = value
+	}
+	for key, value := range patch.Data {
+		if value == nil {
+			delete(data, key)
+			continue
+		}
+		data[key] = *value
+		written[key] = *value
+	}
+	return data, written, nil
+}
+
+// changedFields returns the names of the fields a write adds, changes or
+// removes, given the content of the item before and after it
+func changedFields(current, data map[string]string) []string {
+	changed := sets.New[string]()
+	for key, value := range data {
+		if existing, ok := current[key]; !ok || existing != value {
+			changed.Insert(key)
+		}
+	}
+	for key := range current {
+		if _, ok := data[key]; !ok {
+			changed.Insert(key)
+		}
+	}
+	return sets.List(changed)
 }
 
 func (k *kvUpdateTransport) kvCacheKeyFromURLPath(urlPath string) string {
@@ -330,15 +500,16 @@ func (k *kvUpdateTransport) populateKeyCache(ctx context.Context) (err error) {
 	return nil
 }
 
-func (k *kvUpdateTransport) syncSecret(data map[string]string) {
+func (k *kvUpdateTransport) syncSecret(data map[string]string) []syncOutcome {
 	if k.kubeClients == nil || data[vault.SecretSyncTargetNamepaceKey] == "" || data[vault.SecretSyncTargetNameKey] == "" {
-		return
+		return nil
 	}
 	// This is part of a long-running server, so give a gracious timeout
 	// to prevent stuck goroutines
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
+	var outcomes []syncOutcome
 	for cluster, client := range k.kubeClients() {
 		if !vault.TargetsCluster(cluster, data) {
 			continue
@@ -366,6 +537,7 @@ func (k *kvUpdateTransport) syncSecret(data map[string]string) {
 				return nil
 			}
 
+			outcome := syncOutcome{Cluster: cluster, Namespace: secret.Namespace, Name: secret.Name}
 			var result crcontrollerutil.OperationResult
 			var err error
 			if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
@@ -377,8 +549,12 @@ func (k *kvUpdateTransport) syncSecret(data map[string]string) {
 					"namespace": secret.Namespace,
 					"name":      secret.Name,
 				}).Error("failed to upsert secret")
+				outcome.Error = err.Error()
+				outcomes = append(outcomes, outcome)
 				continue
 			}
+			outcome.Operation = string(result)
+			outcomes = append(outcomes, outcome)
 			if result != crcontrollerutil.OperationResultNone {
 				logrus.WithFields(logrus.Fields{
 					"cluster":   cluster,
@@ -389,6 +565,13 @@ func (k *kvUpdateTransport) syncSecret(data map[string]string) {
 			}
 		}
 	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		if outcomes[i].Cluster != outcomes[j].Cluster {
+			return outcomes[i].Cluster < outcomes[j].Cluster
+		}
+		return outcomes[i].Namespace < outcomes[j].Namespace
+	})
+	return outcomes
 }
 
 func newResponse(statusCode int, req *http.Request, errs ...string) *http.Response {
@@ -39,6 +39,8 @@ type options struct {
 	kubernetesOptions flagutil.KubernetesOptions
 	vaultToken        string
 	vaultRole         string
+	schemaConfig      string
+	auditLogFile      string
 }
 
 func gatherOptions() (*options, error) {
@@ -52,6 +54,8 @@ func gatherOptions() (*options, error) {
 	o.kubernetesOptions.AddFlags(fs)
 	fs.StringVar(&o.vaultToken, "vault-token", "", "Vault token that will be used to detect conflicting secrets. Must have read access to the whole kv store. Mutually exclusive with --vault-token.")
 	fs.StringVar(&o.vaultRole, "vault-role", "", "Vault role to use for detecting conflicting secrets. Must have access to the whole kv store. Mutually exclusive with --vault-token.")
+	fs.StringVar(&o.schemaConfig, "schema-config", "", "Path to a config with the schemas that writes to the kv store must satisfy. If unset, writes are not checked against any schema.")
+	fs.StringVar(&o.auditLogFile, "audit-log-file", "", "Path to a file to append the audit log of writes and deletes to. If unset, the audit log is written to stdout.")
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return nil, fmt.Errorf("failed to parse flags: %w", err)
 	}
@@ -92,7 +96,24 @@ func main() {
 		logrus.WithError(err).Fatal("failed to load kubeconfigs")
 	}
 
-	server, err := createProxyServer(opts.vaultAddr, opts.listenAddr, opts.kvMountPath, clientGetter, privilegedVaultClient)
+	var schemas *schemaConfig
+	if opts.schemaConfig != "" {
+		if schemas, err = loadSchemaConfig(opts.schemaConfig); err != nil {
+			logrus.WithError(err).Fatal("failed to load schema config")
+		}
+	}
+
+	auditOut := io.Writer(os.Stdout)
+	if opts.auditLogFile != "" {
+		auditFile, err := os.OpenFile(opts.auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to open audit log file")
+		}
+		defer auditFile.Close()
+		auditOut = auditFile
+	}
+
+	server, err := createProxyServer(opts.vaultAddr, opts.listenAddr, opts.kvMountPath, clientGetter, privilegedVaultClient, schemas, auditOut)
 	if err != nil {
 		logrus.WithError(err).Fatal("failed to create server")
 	}
@@ -111,7 +132,7 @@ func main() {
 	}
 }
 
-func createProxyServer(vaultAddr string, listenAddr string, kvMountPath string, clients func() map[string]ctrlruntimeclient.Client, privilegedVaultClient *vaultclient.VaultClient) (*http.Server, error) {
+func createProxyServer(vaultAddr string, listenAddr string, kvMountPath string, clients func() map[string]ctrlruntimeclient.Client, privilegedVaultClient *vaultclient.VaultClient, schemas *schemaConfig, auditOut io.Writer) (*http.Server, error) {
 	vaultClient, err := api.NewClient(&api.Config{Address: vaultAddr})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vault client: %w", err)
@@ -122,7 +143,15 @@ func createProxyServer(vaultAddr string, listenAddr string, kvMountPath string,
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(vaultURL)
-	transport := &kvUpdateTransport{kvMountPath: kvMountPath, upstream: http.DefaultTransport, kubeClients: clients, privilegedVaultClient: privilegedVaultClient}
+	transport := &kvUpdateTransport{
+		kvMountPath:           kvMountPath,
+		upstream:              http.DefaultTransport,
+		kubeClients:           clients,
+		privilegedVaultClient: privilegedVaultClient,
+		schemas:               schemas,
+		audit:                 newAuditLogger(auditOut),
+		identity:              tokenIdentityResolver(vaultClient),
+	}
 	transport.initialize()
 	proxy.Transport = transport
 	injector := &kvSubPathInjector{
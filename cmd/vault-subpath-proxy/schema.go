@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/api/vault"
+)
+
+// valueFormat is a format that the values of a field must be in
+type valueFormat string
+
+const (
+	formatJSON             valueFormat = "json"
+	formatPEM              valueFormat = "pem"
+	formatDockerConfigJSON valueFormat = "dockerconfigjson"
+	formatKubeconfig       valueFormat = "kubeconfig"
+)
+
+var validFormats = sets.New[valueFormat](formatJSON, formatPEM, formatDockerConfigJSON, formatKubeconfig)
+
+// schemaConfig constrains what can be written to items in the kv store
+type schemaConfig struct {
+	// Schemas are matched in order against the path of an item relative to
+	// the kv mount and the first one that matches applies. Items no schema
+	// matches are not constrained.
+	Schemas []pathSchema `json:"schemas"`
+}
+
+type pathSchema struct {
+	// Path is a glob as understood by path.Match, e.g. selfservice/*/pull-secret
+	Path string `json:"path"`
+	// Fields constrain the fields of the item with a name they match.
+	// The first field schema that matches a field applies.
+	Fields []fieldSchema `json:"fields,omitempty"`
+	// AllowUnknownFields allows fields that no field schema matches
+	AllowUnknownFields bool `json:"allow_unknown_fields,omitempty"`
+	// MaxSize limits the size of all values of the item together, in bytes
+	MaxSize int `json:"max_size,omitempty"`
+}
+
+type fieldSchema struct {
+	// Name is a glob as understood by path.Match
+	Name string `json:"name"`
+	// Required fields must be present, a glob requires at least one field
+	// that it matches
+	Required bool `json:"required,omitempty"`
+	// Format is the format the value must be in, if any
+	Format valueFormat `json:"format,omitempty"`
+	// MaxSize limits the size of the value, in bytes
+	MaxSize int `json:"max_size,omitempty"`
+}
+
+func loadSchemaConfig(file string) (*schemaConfig, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema config: %w", err)
+	}
+	var config schemaConfig
+	if err := yaml.UnmarshalStrict(raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schema config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema config: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *schemaConfig) validate() error {
+	var errs []error
+	for i, schema := range c.Schemas {
+		if _, err := path.Match(schema.Path, ""); err != nil || schema.Path == "" {
+			errs = append(errs, fmt.Errorf("schemas[%d]: invalid path %q", i, schema.Path))
+		}
+		if schema.MaxSize < 0 {
+			errs = append(errs, fmt.Errorf("schemas[%d]: max_size must not be negative", i))
+		}
+		for j, field := range schema.Fields {
+			if _, err := path.Match(field.Name, ""); err != nil || field.Name == "" {
+				errs = append(errs, fmt.Errorf("schemas[%d].fields[%d]: invalid name %q", i, j, field.Name))
+			}
+			if field.Format != "" && !validFormats.Has(field.Format) {
+				errs = append(errs, fmt.Errorf("schemas[%d].fields[%d]: unknown format %q, must be one of %v", i, j, field.Format, sets.List(validFormats)))
+			}
+			if field.MaxSize < 0 {
+				errs = append(errs, fmt.Errorf("schemas[%d].fields[%d]: max_size must not be negative", i, j))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// schemaFor returns the schema that applies to the item, if any
+func (c *schemaConfig) schemaFor(item string) *pathSchema {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Schemas {
+		if matched, _ := path.Match(c.Schemas[i].Path, item); matched {
+			return &c.Schemas[i]
+		}
+	}
+	return nil
+}
+
+func isSecretSyncKey(key string) bool {
+	return key == vault.SecretSyncTargetNamepaceKey || key == vault.SecretSyncTargetNameKey || key == vault.SecretSyncTargetClusterKey
+}
+
+// validate checks the content of the item after a write against the schema.
+// For a patch, that is the current content with the patch applied.
+func (s *pathSchema) validate(data map[string]string) []string {
+	var errs []string
+	present := make([]bool, len(s.Fields))
+	var size int
+	for _, key := range sets.List(sets.KeySet(data)) {
+		if isSecretSyncKey(key) {
+			continue
+		}
+		value := data[key]
+		size += len(value)
+		field := -1
+		for i := range s.Fields {
+			if matched, _ := path.Match(s.Fields[i].Name, key); matched {
+				field = i
+				break
+			}
+		}
+		if field == -1 {
+			if !s.AllowUnknownFields {
+				errs = append(errs, fmt.Sprintf("field %s is not allowed by the schema for %s", key, s.Path))
+			}
+			continue
+		}
+		present[field] = true
+		schema := s.Fields[field]
+		if schema.MaxSize > 0 && len(value) > schema.MaxSize {
+			errs = append(errs, fmt.Sprintf("field %s is %d bytes, more than the maximum of %d", key, len(value), schema.MaxSize))
+		}
+		if err := validateFormat(schema.Format, value); err != nil {
+			errs = append(errs, fmt.Sprintf("field %s is not valid %s: %v", key, schema.Format, err))
+		}
+	}
+	for i, field := range s.Fields {
+		if field.Required && !present[i] {
+			errs = append(errs, fmt.Sprintf("field %s is required by the schema for %s", field.Name, s.Path))
+		}
+	}
+	if s.MaxSize > 0 && size > s.MaxSize {
+		errs = append(errs, fmt.Sprintf("fields are %d bytes in total, more than the maximum of %d", size, s.MaxSize))
+	}
+	return errs
+}
+
+func validateFormat(format valueFormat, value string) error {
+	switch format {
+	case formatJSON:
+		if !json.Valid([]byte(value)) {
+			return errors.New("value is not a JSON document")
+		}
+	case formatPEM:
+		return validatePEM(value)
+	case formatDockerConfigJSON:
+		return validateDockerConfigJSON(value)
+	case formatKubeconfig:
+		config, err := clientcmd.Load([]byte(value))
+		if err != nil {
+			return err
+		}
+		return clientcmd.Validate(*config)
+	}
+	return nil
+}
+
+func validatePEM(value string) error {
+	rest := []byte(value)
+	var blocks int
+	for {
+		block, remainder := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks++
+		rest = remainder
+	}
+	if blocks == 0 {
+		return errors.New("no PEM block found")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return errors.New("unexpected content after the last PEM block")
+	}
+	return nil
+}
+
+func validateDockerConfigJSON(value string) error {
+	var config secretbootstrap.DockerConfigJSON
+	if err := json.Unmarshal([]byte(value), &config); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	if len(config.Auths) == 0 {
+		return errors.New("no registries in auths")
+	}
+	var errs []error
+	for _, registry := range sets.List(sets.KeySet(config.Auths)) {
+		auth := config.Auths[registry].Auth
+		if auth == "" {
+			errs = append(errs, fmt.Errorf("auth for registry %s is empty", registry))
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("auth for registry %s is not base64-encoded: %w", registry, err))
+			continue
+		}
+		if user, password, found := strings.Cut(string(decoded), ":"); !found || user == "" || password == "" {
+			errs = append(errs, fmt.Errorf("auth for registry %s is not in the user:password format", registry))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
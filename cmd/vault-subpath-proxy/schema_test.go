@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testCertificate = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIQIRi6zePL6mKjOipn+dNuaTAKBggqhkjOPQQDAjASMRAw
+DgYDVQQKEwdBY21lIENvMB4XDTE3MTAyMDE5NDMwNloXDTE4MTAyMDE5NDMwNlow
+-----END CERTIFICATE-----
+`
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: build01
+  cluster:
+    server: https://api.build01.ci.devcluster.openshift.com:6443
+users:
+- name: ci
+  user:
+    token: token
+contexts:
+- name: build01
+  context:
+    cluster: build01
+    user: ci
+current-context: build01
+`
+
+func TestValidateFormat(t *testing.T) {
+	auth := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	for _, tc := range []struct {
+		name        string
+		format      valueFormat
+		value       string
+		expectedErr string
+	}{{
+		name:   "valid JSON",
+		format: formatJSON,
+		value:  `{"key": ["value"]}`,
+	}, {
+		name:        "invalid JSON",
+		format:      formatJSON,
+		value:       `{"key":`,
+		expectedErr: "value is not a JSON document",
+	}, {
+		name:   "valid PEM",
+		format: formatPEM,
+		value:  testCertificate + testCertificate,
+	}, {
+		name:        "no PEM block",
+		format:      formatPEM,
+		value:       "certificate",
+		expectedErr: "no PEM block found",
+	}, {
+		name:        "content after a PEM block",
+		format:      formatPEM,
+		value:       testCertificate + "garbage",
+		expectedErr: "unexpected content after the last PEM block",
+	}, {
+		name:   "valid dockerconfigjson",
+		format: formatDockerConfigJSON,
+		value:  `{"auths": {"quay.io": {"auth": "` + auth("user:password") + `"}}}`,
+	}, {
+		name:        "dockerconfigjson without registries",
+		format:      formatDockerConfigJSON,
+		value:       `{"quay.io": {"auth": "` + auth("user:password") + `"}}`,
+		expectedErr: "no registries in auths",
+	}, {
+		name:        "dockerconfigjson with broken auths",
+		format:      formatDockerConfigJSON,
+		value:       `{"auths": {"quay.io": {"auth": "` + auth("token") + `"}, "registry.ci.openshift.org": {"auth": "%%%"}, "docker.io": {}}}`,
+		expectedErr: "[auth for registry docker.io is empty, auth for registry quay.io is not in the user:password format, auth for registry registry.ci.openshift.org is not base64-encoded: illegal base64 data at input byte 0]",
+	}, {
+		name:   "valid kubeconfig",
+		format: formatKubeconfig,
+		value:  testKubeconfig,
+	}, {
+		name:        "empty kubeconfig",
+		format:      formatKubeconfig,
+		value:       "apiVersion: v1\nkind: Config\n",
+		expectedErr: "invalid configuration: no configuration has been provided, try setting KUBERNETES_MASTER environment variable",
+	}, {
+		name:  "no format",
+		value: "anything",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var actualErr string
+			if err := validateFormat(tc.format, tc.value); err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestPathSchemaValidate(t *testing.T) {
+	config := &schemaConfig{Schemas: []pathSchema{{
+		Path:    "selfservice/*/pull-secret",
+		MaxSize: 20,
+		Fields: []fieldSchema{
+			{Name: ".dockerconfigjson", Required: true, Format: formatJSON, MaxSize: 15},
+			{Name: "note-*"},
+		},
+	}, {
+		Path:               "selfservice/*",
+		AllowUnknownFields: true,
+		Fields:             []fieldSchema{{Name: "*.json", Format: formatJSON}},
+	}}}
+	for _, tc := range []struct {
+		name     string
+		item     string
+		data     map[string]string
+		expected []string
+	}{{
+		name: "valid item",
+		item: "selfservice/team/pull-secret",
+		data: map[string]string{".dockerconfigjson": "{}", "note-owner": "team", "secretsync/target-name": "pull-secret"},
+	}, {
+		name: "violations of the first matching schema",
+		item: "selfservice/team/pull-secret",
+		data: map[string]string{"unknown": "{}", "note-owner": "a note that is too long"},
+		expected: []string{
+			"field unknown is not allowed by the schema for selfservice/*/pull-secret",
+			"field .dockerconfigjson is required by the schema for selfservice/*/pull-secret",
+			"fields are 25 bytes in total, more than the maximum of 20",
+		},
+	}, {
+		name:     "invalid and too large value",
+		item:     "selfservice/team/pull-secret",
+		data:     map[string]string{".dockerconfigjson": `{"auths": {}, "x"`},
+		expected: []string{"field .dockerconfigjson is 17 bytes, more than the maximum of 15", "field .dockerconfigjson is not valid json: value is not a JSON document"},
+	}, {
+		name:     "second schema allows unknown fields",
+		item:     "selfservice/team",
+		data:     map[string]string{"token": "value", "config.json": "{"},
+		expected: []string{"field config.json is not valid json: value is not a JSON document"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := config.schemaFor(tc.item)
+			if schema == nil {
+				t.Fatalf("no schema for %s", tc.item)
+			}
+			if diff := cmp.Diff(tc.expected, schema.validate(tc.data)); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+	if schema := config.schemaFor("team/item"); schema != nil {
+		t.Errorf("expected no schema for an unmatched item, got %s", schema.Path)
+	}
+}
+
+func TestSchemaConfigValidate(t *testing.T) {
+	config := schemaConfig{Schemas: []pathSchema{{
+		Path:    "[",
+		MaxSize: -1,
+		Fields:  []fieldSchema{{Name: "", Format: "yaml"}},
+	}}}
+	expected := `[schemas[0]: invalid path "[", schemas[0]: max_size must not be negative, schemas[0].fields[0]: invalid name "", schemas[0].fields[0]: unknown format "yaml", must be one of [dockerconfigjson json kubeconfig pem]]`
+	var actual string
+	if err := config.validate(); err != nil {
+		actual = err.Error()
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected error: %s", diff)
+	}
+}
+
+func TestPatchItem(t *testing.T) {
+	current := map[string]string{".dockerconfigjson": "{}", "note-owner": "team", "note-contact": "someone"}
+	for _, tc := range []struct {
+		name            string
+		patch           string
+		expectedData    map[string]string
+		expectedWritten map[string]string
+	}{{
+		name:            "patch changes and adds fields",
+		patch:           `{"data": {"note-owner": "other-team", "note-channel": "#channel"}}`,
+		expectedData:    map[string]string{".dockerconfigjson": "{}", "note-owner": "other-team", "note-contact": "someone", "note-channel": "#channel"},
+		expectedWritten: map[string]string{"note-owner": "other-team", "note-channel": "#channel"},
+	}, {
+		name:            "null removes a field",
+		patch:           `{"data": {".dockerconfigjson": null, "note-owner": ""}}`,
+		expectedData:    map[string]string{"note-owner": "", "note-contact": "someone"},
+		expectedWritten: map[string]string{"note-owner": ""},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, written, err := patchItem(current, []byte(tc.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expectedData, data); diff != "" {
+				t.Errorf("unexpected data: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedWritten, written); diff != "" {
+				t.Errorf("unexpected written fields: %s", diff)
+			}
+		})
+	}
+	if _, ok := current["note-channel"]; ok {
+		t.Errorf("patching modified the current item")
+	}
+}
@@ -233,6 +233,20 @@ func ReleaseImageTagLink(name, tag string) StepLink {
 	}
 }
 
+// ImageStreamFor returns the ImageStream in the test namespace that a link
+// describes and, if the link describes a single tag, the tag. Links that do not
+// describe an ImageStream in the test namespace are not found.
+func ImageStreamFor(link StepLink) (stream, tag string, found bool) {
+	switch link := link.(type) {
+	case *internalImageStreamLink:
+		return link.name, "", true
+	case *internalImageStreamTagLink:
+		return link.name, link.tag, true
+	default:
+		return "", "", false
+	}
+}
+
 func Comparer() cmp.Option {
 	return cmp.AllowUnexported(
 		internalImageStreamLink{},
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/api/utils"
+)
+
+const (
+	// LedgerNamespace is the namespace on the cluster hosting the integration
+	// image streams that holds all promotion records
+	LedgerNamespace = "ci"
+
+	LabelKeyOrg    = "ci.openshift.io/promotion-org"
+	LabelKeyRepo   = "ci.openshift.io/promotion-repo"
+	LabelKeyBranch = "ci.openshift.io/promotion-branch"
+
+	// RollbackDigestAnnotation is set on the spec tag of an ImageStreamTag that was
+	// rolled back, to the digest of the image it was rolled back to. As long as the
+	// tag still points to that image, it must not be promoted again automatically.
+	RollbackDigestAnnotation = "ci.openshift.io/rollback-digest"
+	// RollbackRecordAnnotation is set on the spec tag of an ImageStreamTag that was
+	// rolled back, to the name of the record of the rollback
+	RollbackRecordAnnotation = "ci.openshift.io/rollback-record"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PromotionRecord records the image stream tags that were pointed to new
+// images by one promotion or rollback. Records are not changed once the tags
+// were pointed to the images, together they are the history of the
+// integration image streams.
+type PromotionRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec PromotionRecordSpec `json:"spec"`
+}
+
+type PromotionRecordSpec struct {
+	// Time is when the tags were pointed to the images
+	Time metav1.Time `json:"time"`
+	// Source is the revision the images were built from, it is not set for rollbacks
+	Source *PromotionSource `json:"source,omitempty"`
+	// Job is the job that promoted the images, it is not set for rollbacks
+	Job *PromotionJob `json:"job,omitempty"`
+	// Rollback is set if the record is a rollback to earlier records
+	Rollback *PromotionRollback `json:"rollback,omitempty"`
+	// Tags are the image stream tags that were pointed to new images
+	Tags []PromotedTag `json:"tags"`
+}
+
+type PromotionSource struct {
+	Org    string `json:"org"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	// Commit is the commit the images were built from
+	Commit string `json:"commit,omitempty"`
+}
+
+type PromotionJob struct {
+	// Name is the name of the Prow job
+	Name string `json:"name"`
+	// BuildID is the build id of the Prow job
+	BuildID string `json:"buildID,omitempty"`
+	// ProwJobID is the name of the ProwJob object
+	ProwJobID string `json:"prowJobID,omitempty"`
+}
+
+type PromotionRollback struct {
+	// User is who rolled the tags back
+	User string `json:"user"`
+	// Reason is why the tags were rolled back
+	Reason string `json:"reason"`
+}
+
+type PromotedTag struct {
+	// Namespace is the namespace of the image stream
+	Namespace string `json:"namespace"`
+	// Name is the name of the image stream
+	Name string `json:"name"`
+	// Tag is the tag in the image stream
+	Tag string `json:"tag"`
+	// Digest is the digest of the image the tag was pointed to
+	Digest string `json:"digest"`
+	// Previous is the digest of the image the tag pointed to before, if known
+	Previous string `json:"previous,omitempty"`
+	// RolledBackTo is the name of the record the digest was taken from, for rollbacks
+	RolledBackTo string `json:"rolledBackTo,omitempty"`
+	// QuayImage is the tag in quay.io that was pointed to the image as well, if any
+	QuayImage string `json:"quayImage,omitempty"`
+}
+
+// ISTagName returns the name of the tag in the namespace/name:tag format
+func (t PromotedTag) ISTagName() string {
+	return t.Namespace + "/" + t.Name + ":" + t.Tag
+}
+
+// Tag returns the entry of the record for the image stream tag, if any
+func (r *PromotionRecord) Tag(namespace, name, tag string) *PromotedTag {
+	for i := range r.Spec.Tags {
+		if t := &r.Spec.Tags[i]; t.Namespace == namespace && t.Name == name && t.Tag == tag {
+			return t
+		}
+	}
+	return nil
+}
+
+// LabelsForSource returns the labels by which the records of promotions
+// from a given org, repo and branch can be selected. Empty values are omitted.
+func LabelsForSource(org, repo, branch string) map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{LabelKeyOrg: org, LabelKeyRepo: repo, LabelKeyBranch: branch} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return utils.SanitizeLabels(labels)
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PromotionRecordList is a list of PromotionRecord resources
+type PromotionRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []PromotionRecord `json:"items"`
+}
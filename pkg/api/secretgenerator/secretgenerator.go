@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/getlantern/deepcopy"
+	"gopkg.in/robfig/cron.v2"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/util/gzip"
@@ -90,6 +94,90 @@ type SecretItem struct {
 	Fields   []FieldGenerator    `json:"fields,omitempty"`
 	Notes    string              `json:"notes,omitempty"`
 	Params   map[string][]string `json:"params,omitempty"`
+	// Rotation configures the scheduled rotation of the fields of the item.
+	// Items with a rotation policy are only generated in rotation mode.
+	Rotation *RotationPolicy `json:"rotation,omitempty"`
+}
+
+// RotationPolicy determines when the fields of an item are rotated and how
+// the values they replace are revoked.
+type RotationPolicy struct {
+	// MaxAge is the age after which the fields are rotated regardless of the schedule.
+	// Items older than this are reported as overdue.
+	MaxAge *metav1.Duration `json:"max_age,omitempty"`
+	// Schedule is a cron expression. The fields are rotated on the first run after
+	// a scheduled time that follows their last change.
+	Schedule string `json:"schedule,omitempty"`
+	// RevokeCmd is run for every field once its promoted value was distributed. It
+	// receives the replaced value in the $PREVIOUS_VALUE environment variable.
+	RevokeCmd string `json:"revoke_cmd,omitempty"`
+}
+
+// Validate checks that the policy can ever trigger a rotation
+func (p RotationPolicy) Validate() error {
+	if p.MaxAge == nil && p.Schedule == "" {
+		return fmt.Errorf("at least one of max_age and schedule is required")
+	}
+	if p.MaxAge != nil && p.MaxAge.Duration <= 0 {
+		return fmt.Errorf("max_age must be positive")
+	}
+	if p.Schedule != "" {
+		if _, err := cron.Parse(p.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", p.Schedule, err)
+		}
+	}
+	return nil
+}
+
+// Overdue determines whether fields last changed at the given time are older than the maximum age
+func (p RotationPolicy) Overdue(lastChanged, now time.Time) bool {
+	return p.MaxAge != nil && now.Sub(lastChanged) > p.MaxAge.Duration
+}
+
+// Due determines whether fields last changed at the given time need to be rotated
+func (p RotationPolicy) Due(lastChanged, now time.Time) (bool, error) {
+	if p.Overdue(lastChanged, now) {
+		return true, nil
+	}
+	if p.Schedule == "" {
+		return false, nil
+	}
+	schedule, err := cron.Parse(p.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("invalid schedule %q: %w", p.Schedule, err)
+	}
+	return !schedule.Next(lastChanged).After(now), nil
+}
+
+// StagingField returns the name of the field a new value of the given
+// field is stored in until it is distributed and can be promoted
+func StagingField(field string) string {
+	return field + ".next"
+}
+
+// PreviousField returns the name of the field the value replaced by a
+// promotion is kept in until it is revoked
+func PreviousField(field string) string {
+	return field + ".previous"
+}
+
+// PreviousFields returns the previous fields of the items with a rotation
+// policy by item name. These fields are only used by the rotation, so nothing
+// else consumes them.
+func (c Config) PreviousFields() map[string]sets.Set[string] {
+	fields := map[string]sets.Set[string]{}
+	for _, item := range c {
+		if item.Rotation == nil {
+			continue
+		}
+		if fields[item.ItemName] == nil {
+			fields[item.ItemName] = sets.New[string]()
+		}
+		for _, field := range item.Fields {
+			fields[item.ItemName].Insert(PreviousField(field.Name))
+		}
+	}
+	return fields
 }
 
 func (si SecretItem) generateItemsFromParams() ([]SecretItem, error) {
@@ -119,6 +207,9 @@ func (si SecretItem) generateItemsFromParams() ([]SecretItem, error) {
 					}
 				}
 				argItem.Notes = replaceParameter(paramName, param, argItem.Notes)
+				if argItem.Rotation != nil {
+					argItem.Rotation.RevokeCmd = replaceParameter(paramName, param, argItem.Rotation.RevokeCmd)
+				}
 				itemsProcessed = append(itemsProcessed, argItem)
 			}
 		}
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"sort"
 	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
@@ -51,3 +54,49 @@ func TestLoadConfigFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRotationPolicyDue(t *testing.T) {
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name        string
+		policy      RotationPolicy
+		lastChanged time.Time
+		expected    bool
+	}{
+		{
+			name:        "younger than the maximum age",
+			policy:      RotationPolicy{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}},
+			lastChanged: now.Add(-24 * time.Hour),
+		},
+		{
+			name:        "older than the maximum age",
+			policy:      RotationPolicy{MaxAge: &metav1.Duration{Duration: 30 * 24 * time.Hour}},
+			lastChanged: now.Add(-31 * 24 * time.Hour),
+			expected:    true,
+		},
+		{
+			name:        "scheduled time passed since the last change",
+			policy:      RotationPolicy{Schedule: "0 0 1 * *"},
+			lastChanged: time.Date(2023, 5, 20, 0, 0, 0, 0, time.UTC),
+			expected:    true,
+		},
+		{
+			name:        "no scheduled time passed since the last change",
+			policy:      RotationPolicy{Schedule: "0 0 1 * *"},
+			lastChanged: time.Date(2023, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.Validate(); err != nil {
+				t.Fatalf("invalid policy: %v", err)
+			}
+			actual, err := tc.policy.Due(tc.lastChanged, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -833,6 +833,29 @@ func (config TestStepConfiguration) IsPeriodic() bool {
 	return config.Interval != nil || config.MinimumInterval != nil || config.Cron != nil || config.ReleaseController
 }
 
+// GetClusterProfile returns the cluster profile the test is configured to run
+// against, if any. Multi-stage tests may also get one from their workflow,
+// which is only known once they are resolved.
+func (config TestStepConfiguration) GetClusterProfile() ClusterProfile {
+	switch {
+	case config.MultiStageTestConfigurationLiteral != nil:
+		return config.MultiStageTestConfigurationLiteral.ClusterProfile
+	case config.MultiStageTestConfiguration != nil:
+		return config.MultiStageTestConfiguration.ClusterProfile
+	case config.OpenshiftAnsibleClusterTestConfiguration != nil:
+		return config.OpenshiftAnsibleClusterTestConfiguration.ClusterProfile
+	case config.OpenshiftAnsibleCustomClusterTestConfiguration != nil:
+		return config.OpenshiftAnsibleCustomClusterTestConfiguration.ClusterProfile
+	case config.OpenshiftInstallerClusterTestConfiguration != nil:
+		return config.OpenshiftInstallerClusterTestConfiguration.ClusterProfile
+	case config.OpenshiftInstallerUPIClusterTestConfiguration != nil:
+		return config.OpenshiftInstallerUPIClusterTestConfiguration.ClusterProfile
+	case config.OpenshiftInstallerCustomTestImageClusterTestConfiguration != nil:
+		return config.OpenshiftInstallerCustomTestImageClusterTestConfiguration.ClusterProfile
+	}
+	return ""
+}
+
 // Cloud is the name of a cloud provider, e.g., aws cluster topology, etc.
 type Cloud string
 
@@ -1045,6 +1068,18 @@ type LiteralTestStep struct {
 	// RunAsScript defines if this step should be executed as a script mounted
 	// in the test container instead of being executed directly via bash
 	RunAsScript *bool `json:"run_as_script,omitempty"`
+	// Retries defines how many times the step should be retried when it
+	// fails. Overrides the policy set for the whole test, if any.
+	Retries *RetryPolicy `json:"retries,omitempty"`
+}
+
+// RetryPolicy defines how a failed step is retried.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times a failed step is retried.
+	Attempts uint `json:"attempts"`
+	// Backoff is how long to wait before the first retry. The delay is
+	// doubled for every subsequent retry.
+	Backoff *prowv1.Duration `json:"backoff,omitempty"`
 }
 
 // StepParameter is a variable set by the test, with an optional default.
@@ -1152,6 +1187,9 @@ type MultiStageTestConfiguration struct {
 	// they fail. The given step must explicitly ask for being ignored by setting
 	// the OptionalOnSuccess flag to true.
 	AllowBestEffortPostSteps *bool `json:"allow_best_effort_post_steps,omitempty"`
+	// Retries defines how many times failed steps should be retried, unless
+	// the step defines its own policy.
+	Retries *RetryPolicy `json:"retries,omitempty"`
 	// Observers are the observers that should be running
 	Observers *Observers `json:"observers,omitempty"`
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
@@ -1189,6 +1227,9 @@ type MultiStageTestConfigurationLiteral struct {
 	// they fail. The given step must explicitly ask for being ignored by setting
 	// the OptionalOnSuccess flag to true.
 	AllowBestEffortPostSteps *bool `json:"allow_best_effort_post_steps,omitempty"`
+	// Retries defines how many times failed steps should be retried, unless
+	// the step defines its own policy.
+	Retries *RetryPolicy `json:"retries,omitempty"`
 	// Observers are the observers that need to be run
 	Observers []Observer `json:"observers,omitempty"`
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
@@ -604,6 +604,11 @@ func (in *LiteralTestStep) DeepCopyInto(out *LiteralTestStep) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LiteralTestStep.
@@ -725,6 +730,11 @@ func (in *MultiStageTestConfiguration) DeepCopyInto(out *MultiStageTestConfigura
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Observers != nil {
 		in, out := &in.Observers, &out.Observers
 		*out = new(Observers)
@@ -807,6 +817,11 @@ func (in *MultiStageTestConfigurationLiteral) DeepCopyInto(out *MultiStageTestCo
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Observers != nil {
 		in, out := &in.Observers, &out.Observers
 		*out = make([]Observer, len(*in))
@@ -1727,6 +1742,26 @@ func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Secret) DeepCopyInto(out *Secret) {
 	*out = *in
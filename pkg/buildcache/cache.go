@@ -0,0 +1,278 @@
+package buildcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	buildapi "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const (
+	// ImageStream is the name of the image stream in the cache namespace that
+	// holds the cached build results, tagged by the hash of the build inputs.
+	ImageStream = "build-cache"
+	// PublishedByAnnotation records the namespace whose build populated a cache entry.
+	PublishedByAnnotation = "ci.openshift.io/build-cache-published-by"
+)
+
+// Cache shares the results of image builds between ci-operator namespaces. A
+// build that has the same inputs as one that already ran in another namespace
+// is not run again; instead, the cached image is tagged into the namespace.
+//
+// Builds are identified by a content hash over their inputs: the Dockerfile,
+// the digests of the base image and of every image the build copies from, the
+// build arguments, the environment and the labels. Builds that pull source
+// from a mutable ref or consume secrets are never cached.
+type Cache struct {
+	client    ctrlruntimeclient.Client
+	namespace string
+	now       func() time.Time
+	// restoreTimeout bounds how long we wait for a cached image to be imported
+	restoreTimeout time.Duration
+}
+
+// New returns a cache backed by the build-cache image stream in the namespace.
+func New(client ctrlruntimeclient.Client, namespace string) *Cache {
+	return &Cache{client: client, namespace: namespace, now: time.Now, restoreTimeout: 10 * time.Minute}
+}
+
+// Namespace is where the cached images are stored.
+func (c *Cache) Namespace() string {
+	return c.namespace
+}
+
+// inputs are the parts of a build that determine its result
+type inputs struct {
+	Dockerfile     string            `json:"dockerfile,omitempty"`
+	DockerfilePath string            `json:"dockerfile_path,omitempty"`
+	ContextDir     string            `json:"context_dir,omitempty"`
+	From           string            `json:"from,omitempty"`
+	Images         []imageInput      `json:"images,omitempty"`
+	BuildArgs      []corev1.EnvVar   `json:"build_args,omitempty"`
+	Env            []corev1.EnvVar   `json:"env,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Architectures  []string          `json:"architectures,omitempty"`
+}
+
+type imageInput struct {
+	Digest string                     `json:"digest"`
+	As     []string                   `json:"as,omitempty"`
+	Paths  []buildapi.ImageSourcePath `json:"paths,omitempty"`
+}
+
+// Key determines the cache key for a build that will run for the architectures.
+// If the build must not be cached, ok is false.
+func (c *Cache) Key(ctx context.Context, build *buildapi.Build, architectures []string) (key string, ok bool, err error) {
+	source, strategy := build.Spec.Source, build.Spec.Strategy.DockerStrategy
+	if strategy == nil || source.Git != nil || source.Binary != nil || len(source.Secrets) > 0 || len(source.ConfigMaps) > 0 || source.SourceSecret != nil {
+		return "", false, nil
+	}
+	in := inputs{
+		DockerfilePath: strategy.DockerfilePath,
+		ContextDir:     source.ContextDir,
+		BuildArgs:      strategy.BuildArgs,
+		Env:            strategy.Env,
+		Architectures:  architectures,
+	}
+	if source.Dockerfile != nil {
+		in.Dockerfile = *source.Dockerfile
+	}
+	if strategy.From != nil {
+		if in.From, ok, err = c.digestFor(ctx, build.Namespace, *strategy.From); err != nil || !ok {
+			return "", false, err
+		}
+	}
+	for _, image := range source.Images {
+		digest, ok, err := c.digestFor(ctx, build.Namespace, image.From)
+		if err != nil || !ok {
+			return "", false, err
+		}
+		in.Images = append(in.Images, imageInput{Digest: digest, As: image.As, Paths: image.Paths})
+	}
+	if len(build.Spec.Output.ImageLabels) > 0 {
+		in.Labels = map[string]string{}
+		for _, label := range build.Spec.Output.ImageLabels {
+			in.Labels[label.Name] = label.Value
+		}
+	}
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to marshal build inputs: %w", err)
+	}
+	hash := sha256.Sum256(raw)
+	return hex.EncodeToString(hash[:]), true, nil
+}
+
+// digestFor resolves an image reference to an immutable digest. References to
+// mutable images that we cannot resolve are not cacheable.
+func (c *Cache) digestFor(ctx context.Context, namespace string, ref corev1.ObjectReference) (string, bool, error) {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	switch ref.Kind {
+	case "ImageStreamTag":
+		ist := &imagev1.ImageStreamTag{}
+		if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: ref.Name}, ist); err != nil {
+			return "", false, fmt.Errorf("could not resolve image stream tag %s/%s: %w", namespace, ref.Name, err)
+		}
+		return ist.Image.Name, true, nil
+	case "ImageStreamImage":
+		_, digest, _ := strings.Cut(ref.Name, "@")
+		return digest, digest != "", nil
+	case "DockerImage":
+		_, digest, _ := strings.Cut(ref.Name, "@")
+		return digest, digest != "", nil
+	}
+	return "", false, nil
+}
+
+// Restore tags the image cached under the key into the output of a build. If
+// nothing is cached under the key, it returns false. The image is tagged from
+// the cache namespace, so the namespace of the output needs to be able to pull
+// images from it; this is not granted by the cache.
+func (c *Cache) Restore(ctx context.Context, key string, to *corev1.ObjectReference) (bool, error) {
+	cached := &imagev1.ImageStreamTag{}
+	if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: c.namespace, Name: tagFor(key)}, cached); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up cached image %s: %w", key, err)
+	}
+	stream, tag, ok := strings.Cut(to.Name, ":")
+	if !ok {
+		return false, fmt.Errorf("build output %s is not an image stream tag", to.Name)
+	}
+	ist := &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{Name: to.Name, Namespace: to.Namespace},
+		Tag: &imagev1.TagReference{
+			ReferencePolicy: imagev1.TagReferencePolicy{Type: imagev1.LocalTagReferencePolicy},
+			ImportPolicy:    imagev1.TagImportPolicy{ImportMode: imagev1.ImportModePreserveOriginal},
+			From: &corev1.ObjectReference{
+				Kind:      "ImageStreamImage",
+				Name:      fmt.Sprintf("%s@%s", ImageStream, cached.Image.Name),
+				Namespace: c.namespace,
+			},
+		},
+	}
+	if err := c.client.Create(ctx, ist); err != nil && !kerrors.IsAlreadyExists(err) {
+		if kerrors.IsForbidden(err) {
+			return false, fmt.Errorf("failed to tag cached image %s into %s/%s, %s: %w", key, to.Namespace, to.Name, c.pullAccessHint(to.Namespace), err)
+		}
+		return false, fmt.Errorf("failed to tag cached image %s into %s/%s: %w", key, to.Namespace, to.Name, err)
+	}
+	importCtx, cancel := context.WithTimeout(ctx, c.restoreTimeout)
+	defer cancel()
+	if err := wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		is := &imagev1.ImageStream{}
+		if err := c.client.Get(importCtx, ctrlruntimeclient.ObjectKey{Namespace: to.Namespace, Name: stream}, is); err != nil {
+			return false, err
+		}
+		if _, exists := util.ResolvePullSpec(is, tag, true); exists {
+			return true, nil
+		}
+		// without pull access, the import fails instead of never finishing
+		if message, failed := importFailure(is, tag); failed {
+			return false, fmt.Errorf("import failed, %s: %s", c.pullAccessHint(to.Namespace), message)
+		}
+		return false, nil
+	}, importCtx.Done()); err != nil {
+		return false, fmt.Errorf("cached image %s was not imported into %s/%s: %w", key, to.Namespace, to.Name, err)
+	}
+	return true, nil
+}
+
+// pullAccessHint describes the access a namespace needs to restore images.
+func (c *Cache) pullAccessHint(namespace string) string {
+	return fmt.Sprintf("make sure service accounts in %s can pull images from the build cache namespace %s (system:image-puller)", namespace, c.namespace)
+}
+
+// importFailure returns the message of a failed import of the tag.
+func importFailure(is *imagev1.ImageStream, tag string) (string, bool) {
+	for _, status := range is.Status.Tags {
+		if status.Tag != tag {
+			continue
+		}
+		for _, condition := range status.Conditions {
+			if condition.Type == imagev1.ImportSuccess && condition.Status == corev1.ConditionFalse {
+				return condition.Message, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Publish stores the output of a successful build in the cache under the key.
+func (c *Cache) Publish(ctx context.Context, key string, from *corev1.ObjectReference) error {
+	built := &imagev1.ImageStreamTag{}
+	if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: from.Namespace, Name: from.Name}, built); err != nil {
+		return fmt.Errorf("could not resolve build output %s/%s: %w", from.Namespace, from.Name, err)
+	}
+	stream, _, _ := strings.Cut(from.Name, ":")
+	ist := &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        tagFor(key),
+			Namespace:   c.namespace,
+			Annotations: map[string]string{PublishedByAnnotation: from.Namespace},
+		},
+		Tag: &imagev1.TagReference{
+			ReferencePolicy: imagev1.TagReferencePolicy{Type: imagev1.LocalTagReferencePolicy},
+			ImportPolicy:    imagev1.TagImportPolicy{ImportMode: imagev1.ImportModePreserveOriginal},
+			From: &corev1.ObjectReference{
+				Kind:      "ImageStreamImage",
+				Name:      fmt.Sprintf("%s@%s", stream, built.Image.Name),
+				Namespace: from.Namespace,
+			},
+		},
+	}
+	// another namespace may have published the same build in the meantime, either result is fine
+	if err := c.client.Create(ctx, ist); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to publish %s/%s to the build cache: %w", from.Namespace, from.Name, err)
+	}
+	return nil
+}
+
+// Prune removes cached images that were published longer than the TTL ago and
+// returns the keys it removed.
+func (c *Cache) Prune(ctx context.Context, ttl time.Duration) ([]string, error) {
+	is := &imagev1.ImageStream{}
+	if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: c.namespace, Name: ImageStream}, is); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get the build cache image stream: %w", err)
+	}
+	cutoff := c.now().Add(-ttl)
+	var pruned []string
+	for _, tag := range is.Status.Tags {
+		if len(tag.Items) == 0 || tag.Items[0].Created.Time.After(cutoff) {
+			continue
+		}
+		ist := &imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: c.namespace, Name: tagFor(tag.Tag)}}
+		if err := c.client.Delete(ctx, ist); err != nil && !kerrors.IsNotFound(err) {
+			return pruned, fmt.Errorf("failed to prune cached image %s: %w", tag.Tag, err)
+		}
+		logrus.WithField("key", tag.Tag).WithField("created", tag.Items[0].Created.Time).Debug("Pruned cached image.")
+		pruned = append(pruned, tag.Tag)
+	}
+	return pruned, nil
+}
+
+func tagFor(key string) string {
+	return fmt.Sprintf("%s:%s", ImageStream, key)
+}
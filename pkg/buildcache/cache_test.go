@@ -0,0 +1,304 @@
+package buildcache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	buildapi "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func fakeClient(t *testing.T, objects ...ctrlruntimeclient.Object) ctrlruntimeclient.Client {
+	scheme := runtime.NewScheme()
+	if err := imagev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add imagev1 to scheme: %v", err)
+	}
+	return fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func ist(namespace, name, digest string) *imagev1.ImageStreamTag {
+	return &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Image:      imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: digest}},
+	}
+}
+
+func TestKey(t *testing.T) {
+	dockerfile := "FROM root\nRUN make"
+	build := func(mutate ...func(*buildapi.Build)) *buildapi.Build {
+		b := &buildapi.Build{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1", Name: "bin"},
+			Spec: buildapi.BuildSpec{CommonSpec: buildapi.CommonSpec{
+				Source: buildapi.BuildSource{
+					Dockerfile: &dockerfile,
+					Images: []buildapi.ImageSource{{
+						From:  corev1.ObjectReference{Kind: "ImageStreamTag", Name: "pipeline:src"},
+						Paths: []buildapi.ImageSourcePath{{SourcePath: "/go/src", DestinationDir: "."}},
+					}},
+				},
+				Strategy: buildapi.BuildStrategy{DockerStrategy: &buildapi.DockerBuildStrategy{
+					From:      &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ci-op-1", Name: "pipeline:root"},
+					BuildArgs: []corev1.EnvVar{{Name: "ARG", Value: "value"}},
+				}},
+				Output: buildapi.BuildOutput{ImageLabels: []buildapi.ImageLabel{{Name: "vcs-ref", Value: "abcdef"}}},
+			}},
+		}
+		for _, m := range mutate {
+			m(b)
+		}
+		return b
+	}
+	objects := func(rootDigest string) []ctrlruntimeclient.Object {
+		return []ctrlruntimeclient.Object{
+			ist("ci-op-1", "pipeline:root", rootDigest),
+			ist("ci-op-1", "pipeline:src", "sha256:src"),
+			ist("ci-op-2", "pipeline:root", "sha256:root"),
+			ist("ci-op-2", "pipeline:src", "sha256:src"),
+		}
+	}
+	reference, ok, err := New(fakeClient(t, objects("sha256:root")...), "cache").Key(context.Background(), build(), []string{"amd64"})
+	if err != nil || !ok {
+		t.Fatalf("failed to determine the reference key: ok=%v, err=%v", ok, err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		build         *buildapi.Build
+		architectures []string
+		rootDigest    string
+		same          bool
+		uncacheable   bool
+		notFound      bool
+	}{{
+		name: "identical build in another namespace has the same key",
+		build: build(func(b *buildapi.Build) {
+			b.Namespace = "ci-op-2"
+			b.Spec.Strategy.DockerStrategy.From.Namespace = "ci-op-2"
+		}),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+		same:          true,
+	}, {
+		name:          "different base image digest changes the key",
+		build:         build(),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:other",
+	}, {
+		name:          "different build args change the key",
+		build:         build(func(b *buildapi.Build) { b.Spec.Strategy.DockerStrategy.BuildArgs[0].Value = "other" }),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+	}, {
+		name:          "different input paths change the key",
+		build:         build(func(b *buildapi.Build) { b.Spec.Source.Images[0].Paths[0].SourcePath = "/other" }),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+	}, {
+		name:          "different architectures change the key",
+		build:         build(),
+		architectures: []string{"amd64", "arm64"},
+		rootDigest:    "sha256:root",
+	}, {
+		name:          "builds consuming secrets are not cached",
+		build:         build(func(b *buildapi.Build) { b.Spec.Source.Secrets = []buildapi.SecretBuildSource{{}} }),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+		uncacheable:   true,
+	}, {
+		name: "builds from git are not cached",
+		build: build(func(b *buildapi.Build) {
+			b.Spec.Source.Git = &buildapi.GitBuildSource{URI: "https://github.com/org/repo", Ref: "main"}
+		}),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+		uncacheable:   true,
+	}, {
+		name: "builds from mutable external images are not cached",
+		build: build(func(b *buildapi.Build) {
+			b.Spec.Strategy.DockerStrategy.From = &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/org/image:latest"}
+		}),
+		architectures: []string{"amd64"},
+		uncacheable:   true,
+	}, {
+		name:          "unresolvable inputs are an error",
+		build:         build(func(b *buildapi.Build) { b.Spec.Source.Images[0].From.Name = "pipeline:missing" }),
+		architectures: []string{"amd64"},
+		rootDigest:    "sha256:root",
+		uncacheable:   true,
+		notFound:      true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := New(fakeClient(t, objects(tc.rootDigest)...), "cache")
+			key, ok, err := cache.Key(context.Background(), tc.build, tc.architectures)
+			if tc.notFound {
+				if !kerrors.IsNotFound(err) {
+					t.Fatalf("expected a not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ok == tc.uncacheable {
+				t.Fatalf("expected cacheable=%v, got %v", !tc.uncacheable, ok)
+			}
+			if tc.uncacheable {
+				return
+			}
+			if same := key == reference; same != tc.same {
+				t.Errorf("expected key equal to the reference: %v, got %s (reference %s)", tc.same, key, reference)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	to := &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ci-op-1", Name: "pipeline:bin"}
+	pipeline := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1", Name: "pipeline"},
+		Status: imagev1.ImageStreamStatus{
+			PublicDockerImageRepository: "registry/ci-op-1/pipeline",
+			Tags:                        []imagev1.NamedTagEventList{{Tag: "bin", Items: []imagev1.TagEvent{{Image: "sha256:cached"}}}},
+		},
+	}
+	for _, tc := range []struct {
+		name     string
+		objects  []ctrlruntimeclient.Object
+		expected bool
+	}{{
+		name:    "miss",
+		objects: []ctrlruntimeclient.Object{pipeline.DeepCopy()},
+	}, {
+		name:     "hit tags the cached image into the namespace",
+		objects:  []ctrlruntimeclient.Object{pipeline.DeepCopy(), ist("cache", "build-cache:key", "sha256:cached")},
+		expected: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakeClient(t, tc.objects...)
+			restored, err := New(client, "cache").Restore(context.Background(), "key", to)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if restored != tc.expected {
+				t.Fatalf("expected restored=%v, got %v", tc.expected, restored)
+			}
+			tagged := &imagev1.ImageStreamTag{}
+			err = client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ci-op-1", Name: "pipeline:bin"}, tagged)
+			if !tc.expected {
+				if !kerrors.IsNotFound(err) {
+					t.Errorf("expected nothing to be tagged on a miss, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get tagged image: %v", err)
+			}
+			expected := &corev1.ObjectReference{Kind: "ImageStreamImage", Namespace: "cache", Name: "build-cache@sha256:cached"}
+			if diff := cmp.Diff(expected, tagged.Tag.From); diff != "" {
+				t.Errorf("unexpected tag source: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRestoreFailsWithoutPullAccess(t *testing.T) {
+	to := &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ci-op-1", Name: "pipeline:bin"}
+	pipeline := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1", Name: "pipeline"},
+		Status: imagev1.ImageStreamStatus{
+			Tags: []imagev1.NamedTagEventList{{Tag: "bin", Conditions: []imagev1.TagEventCondition{{
+				Type:    imagev1.ImportSuccess,
+				Status:  corev1.ConditionFalse,
+				Message: "you may not have access to the container image",
+			}}}},
+		},
+	}
+	client := fakeClient(t, pipeline, ist("cache", "build-cache:key", "sha256:cached"))
+	restored, err := New(client, "cache").Restore(context.Background(), "key", to)
+	if err == nil || restored {
+		t.Fatalf("expected the restore to fail, got restored=%v", restored)
+	}
+	if !strings.Contains(err.Error(), "system:image-puller") {
+		t.Errorf("expected the error to point at the missing pull access, got: %v", err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	client := fakeClient(t, ist("ci-op-1", "pipeline:bin", "sha256:built"))
+	from := &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ci-op-1", Name: "pipeline:bin"}
+	cache := New(client, "cache")
+	if err := cache.Publish(context.Background(), "key", from); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// publishing an already cached result is not an error
+	if err := cache.Publish(context.Background(), "key", from); err != nil {
+		t.Fatalf("unexpected error publishing twice: %v", err)
+	}
+	published := &imagev1.ImageStreamTag{}
+	if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "cache", Name: "build-cache:key"}, published); err != nil {
+		t.Fatalf("failed to get published image: %v", err)
+	}
+	expected := &corev1.ObjectReference{Kind: "ImageStreamImage", Namespace: "ci-op-1", Name: "pipeline@sha256:built"}
+	if diff := cmp.Diff(expected, published.Tag.From); diff != "" {
+		t.Errorf("unexpected tag source: %s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{PublishedByAnnotation: "ci-op-1"}, published.Annotations); diff != "" {
+		t.Errorf("unexpected annotations: %s", diff)
+	}
+
+	missing := &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ci-op-1", Name: "pipeline:missing"}
+	if err := cache.Publish(context.Background(), "other", missing); !kerrors.IsNotFound(err) {
+		t.Errorf("expected a not found error publishing a missing output, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	event := func(created time.Time) []imagev1.TagEvent {
+		return []imagev1.TagEvent{{Created: metav1.NewTime(created), Image: "sha256:image"}}
+	}
+	client := fakeClient(t,
+		&imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cache", Name: ImageStream},
+			Status: imagev1.ImageStreamStatus{Tags: []imagev1.NamedTagEventList{
+				{Tag: "old", Items: event(now.Add(-8 * 24 * time.Hour))},
+				{Tag: "fresh", Items: event(now.Add(-time.Hour))},
+				{Tag: "pending"},
+			}},
+		},
+		ist("cache", "build-cache:old", "sha256:image"),
+		ist("cache", "build-cache:fresh", "sha256:image"),
+	)
+	cache := New(client, "cache")
+	cache.now = func() time.Time { return now }
+	pruned, err := cache.Prune(context.Background(), 7*24*time.Hour)
+	if diff := cmp.Diff(nil, err, testhelper.EquateErrorMessage); diff != "" {
+		t.Fatalf("unexpected error: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"old"}, pruned); diff != "" {
+		t.Errorf("unexpected pruned keys: %s", diff)
+	}
+	if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "cache", Name: "build-cache:old"}, &imagev1.ImageStreamTag{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the old entry to be pruned, got %v", err)
+	}
+	if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "cache", Name: "build-cache:fresh"}, &imagev1.ImageStreamTag{}); err != nil {
+		t.Errorf("expected the fresh entry to be kept, got %v", err)
+	}
+
+	if pruned, err := New(fakeClient(t), "cache").Prune(context.Background(), time.Hour); err != nil || len(pruned) != 0 {
+		t.Errorf("expected a missing cache to be a no-op, got %v, %v", pruned, err)
+	}
+}
@@ -0,0 +1,150 @@
+package promote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+	"github.com/openshift/ci-tools/pkg/promotion"
+)
+
+type historyOptions struct {
+	tag    string
+	org    string
+	repo   string
+	branch string
+	limit  int
+}
+
+func newHistoryCommand(o *options) *cobra.Command {
+	var h historyOptions
+	ret := cobra.Command{
+		Use:   "history",
+		Short: "show the promotion history of a tag or repository",
+		Long: `Lists the records in the promotion ledger from the newest to the oldest,
+either those that pointed an image stream tag to an image or the promotions of
+an org, repository and branch.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if (h.tag == "") == (h.org == "") {
+				return errors.New("exactly one of --tag and --org must be set")
+			}
+			if (h.repo != "" && h.org == "") || (h.branch != "" && h.repo == "") {
+				return errors.New("--repo requires --org and --branch requires --repo")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			return history(cmd.Context(), client, h, os.Stdout)
+		},
+	}
+	flags := ret.Flags()
+	flags.StringVar(&h.tag, "tag", "", "image stream tag to show the history of, in the namespace/name:tag format")
+	flags.StringVar(&h.org, "org", "", "org to show the promotions of")
+	flags.StringVar(&h.repo, "repo", "", "repository to show the promotions of, requires --org")
+	flags.StringVar(&h.branch, "branch", "", "branch to show the promotions of, requires --org and --repo")
+	flags.IntVar(&h.limit, "limit", 10, "maximum number of records to show, 0 for all")
+	return &ret
+}
+
+func history(ctx context.Context, client ctrlruntimeclient.Client, h historyOptions, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if h.tag != "" {
+		if err := tagHistory(ctx, client, h.tag, h.limit, w); err != nil {
+			return err
+		}
+	} else {
+		all, err := records(ctx, client, ledgerv1.LabelsForSource(h.org, h.repo, h.branch))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "RECORD\tTIME\tSOURCE\tJOB\tTAGS")
+		for i, record := range all {
+			if h.limit > 0 && i == h.limit {
+				break
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", record.Name, record.Spec.Time.UTC().Format(time.RFC3339), source(&record), job(&record), len(record.Spec.Tags))
+		}
+	}
+	return w.Flush()
+}
+
+func tagHistory(ctx context.Context, client ctrlruntimeclient.Client, s string, limit int, w io.Writer) error {
+	tag, err := parseTag(s)
+	if err != nil {
+		return err
+	}
+	all, err := records(ctx, client, nil)
+	if err != nil {
+		return err
+	}
+	current, err := currentDigest(ctx, client, tag.Namespace, tag.Name, tag.Tag)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\tRECORD\tTIME\tDIGEST\tSOURCE\tJOB")
+	for i, entry := range promotion.History(all, tag) {
+		if limit > 0 && i == limit {
+			break
+		}
+		var marker string
+		if entry.Tag.Digest == current {
+			marker = "*"
+			// only the newest record of the current image is the one in effect
+			current = ""
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", marker, entry.Record.Name, entry.Record.Spec.Time.UTC().Format(time.RFC3339), short(entry.Tag.Digest), source(entry.Record), job(entry.Record))
+	}
+	return nil
+}
+
+// currentDigest returns the digest of the image the tag points to, or an empty
+// string if the tag does not exist
+func currentDigest(ctx context.Context, client ctrlruntimeclient.Client, namespace, name, tag string) (string, error) {
+	ist := &imagev1.ImageStreamTag{}
+	if err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name + ":" + tag}, ist); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get imagestreamtag %s/%s:%s: %w", namespace, name, tag, err)
+	}
+	return ist.Image.Name, nil
+}
+
+func source(record *ledgerv1.PromotionRecord) string {
+	switch {
+	case record.Spec.Rollback != nil:
+		return fmt.Sprintf("rollback by %s: %s", record.Spec.Rollback.User, record.Spec.Rollback.Reason)
+	case record.Spec.Source != nil:
+		s := fmt.Sprintf("%s/%s@%s", record.Spec.Source.Org, record.Spec.Source.Repo, record.Spec.Source.Branch)
+		if record.Spec.Source.Commit != "" {
+			s += " " + short(record.Spec.Source.Commit)
+		}
+		return s
+	}
+	return ""
+}
+
+func job(record *ledgerv1.PromotionRecord) string {
+	if record.Spec.Job == nil {
+		return ""
+	}
+	if record.Spec.Job.BuildID == "" {
+		return record.Spec.Job.Name
+	}
+	return record.Spec.Job.Name + "/" + record.Spec.Job.BuildID
+}
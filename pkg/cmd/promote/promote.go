@@ -0,0 +1,107 @@
+package promote
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	userv1 "github.com/openshift/api/user/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+	"github.com/openshift/ci-tools/pkg/promotion"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+type options struct {
+	kubeconfig string
+}
+
+func NewCommand() *cobra.Command {
+	var o options
+	ret := cobra.Command{
+		Use: "promote",
+		Long: `promote is a command-line program to inspect the promotion ledger and to
+roll back promoted image stream tags.
+
+Every promotion into the integration image streams is recorded in the ledger
+with the revision and job it came from and the digests of the promoted images.
+Rollbacks re-point the tags to images of earlier records and are recorded in
+the ledger themselves.`,
+		SilenceUsage: true,
+	}
+	flags := ret.PersistentFlags()
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig for the cluster with the integration image streams (default: $KUBECONFIG)")
+	ret.AddCommand(newHistoryCommand(&o))
+	ret.AddCommand(newRollbackCommand(&o))
+	return &ret
+}
+
+func (o *options) client() (ctrlruntimeclient.Client, error) {
+	var config *rest.Config
+	var err error
+	if o.kubeconfig != "" {
+		config, err = util.LoadKubeConfig(o.kubeconfig)
+	} else {
+		config, err = util.LoadClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster config: %w", err)
+	}
+	client, err := ctrlruntimeclient.New(config, ctrlruntimeclient.Options{Scheme: newScheme()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct client: %w", err)
+	}
+	return client, nil
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(imagev1.AddToScheme(scheme))
+	utilruntime.Must(userv1.AddToScheme(scheme))
+	utilruntime.Must(ledgerv1.AddToScheme(scheme))
+	return scheme
+}
+
+// records lists the records in the ledger, from the newest to the oldest
+func records(ctx context.Context, client ctrlruntimeclient.Client, labels map[string]string) ([]ledgerv1.PromotionRecord, error) {
+	list := &ledgerv1.PromotionRecordList{}
+	if err := client.List(ctx, list, ctrlruntimeclient.InNamespace(ledgerv1.LedgerNamespace), ctrlruntimeclient.MatchingLabels(labels)); err != nil {
+		return nil, fmt.Errorf("failed to list promotion records: %w", err)
+	}
+	promotion.SortRecords(list.Items)
+	return list.Items, nil
+}
+
+// parseTag parses an image stream tag in the namespace/name:tag format
+func parseTag(s string) (api.ImageStreamTagReference, error) {
+	namespace, nameAndTag, found := strings.Cut(s, "/")
+	if !found || namespace == "" {
+		return api.ImageStreamTagReference{}, fmt.Errorf("invalid image stream tag %s, must be namespace/name:tag", s)
+	}
+	tag, err := util.ParseImageStreamTagReference(nameAndTag)
+	if err != nil || tag.Name == "" || tag.Tag == "" {
+		return api.ImageStreamTagReference{}, fmt.Errorf("invalid image stream tag %s, must be namespace/name:tag", s)
+	}
+	tag.Namespace = namespace
+	return tag, nil
+}
+
+// short abbreviates digests and commits to their first twelve characters
+func short(s string) string {
+	if _, hex, found := strings.Cut(s, ":"); found {
+		s = hex
+	}
+	if len(s) > 12 {
+		return s[:12]
+	}
+	return s
+}
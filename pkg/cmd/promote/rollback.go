@@ -0,0 +1,275 @@
+package promote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	userv1 "github.com/openshift/api/user/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+	"github.com/openshift/ci-tools/pkg/promotion"
+)
+
+type rollbackOptions struct {
+	tags           []string
+	to             string
+	reason         string
+	confirm        bool
+	registryConfig string
+	// mirror points a tag in quay.io to an image in quay.io
+	mirror func(ctx context.Context, registryConfig, source, target string) error
+}
+
+func newRollbackCommand(o *options) *cobra.Command {
+	var r rollbackOptions
+	ret := cobra.Command{
+		Use:   "rollback",
+		Short: "roll back promoted image stream tags",
+		Long: `Re-points image stream tags to the images of earlier records in the
+promotion ledger and records the rollback in the ledger.
+
+With --to, all tags of that record are rolled back to the images in it, unless
+--tag limits them. Without --to, each --tag is rolled back to the image it
+pointed to before its current one, skipping images that earlier rollbacks
+replaced. Rolled back tags are not promoted again by
+the promotion reconciler until a new promotion replaces their image.
+
+Tags that were promoted to quay.io as well are rolled back there, too, which
+requires --registry-config with push access to quay.io/openshift/ci. Records
+from before quay.io tags were recorded do not know them, so those tags are only
+rolled back in the image streams and a warning names them.
+
+Without --confirm, only the planned changes are printed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if len(r.tags) == 0 && r.to == "" {
+				return errors.New("at least one of --tag and --to must be set")
+			}
+			if r.reason == "" {
+				return errors.New("--reason must be set")
+			}
+			client, err := o.client()
+			if err != nil {
+				return err
+			}
+			user := &userv1.User{}
+			if err := client.Get(cmd.Context(), types.NamespacedName{Name: "~"}, user); err != nil {
+				return fmt.Errorf("failed to determine the current user: %w", err)
+			}
+			r.mirror = mirrorWithOC
+			return rollback(cmd.Context(), client, r, user.Name, time.Now(), os.Stdout)
+		},
+	}
+	flags := ret.Flags()
+	flags.StringSliceVar(&r.tags, "tag", nil, "image stream tag to roll back, in the namespace/name:tag format, can be repeated")
+	flags.StringVar(&r.to, "to", "", "name of the record in the promotion ledger to roll back to")
+	flags.StringVar(&r.reason, "reason", "", "why the tags are rolled back, recorded in the promotion ledger")
+	flags.BoolVar(&r.confirm, "confirm", false, "roll the tags back instead of only printing the planned changes")
+	flags.StringVar(&r.registryConfig, "registry-config", "", "path to a registry config with push access to "+api.QuayOpenShiftCIRepo+", required to roll back tags that were promoted there")
+	return &ret
+}
+
+func rollback(ctx context.Context, client ctrlruntimeclient.Client, r rollbackOptions, user string, now time.Time, out io.Writer) error {
+	options := promotion.RollbackOptions{To: r.to}
+	for _, s := range r.tags {
+		tag, err := parseTag(s)
+		if err != nil {
+			return err
+		}
+		options.Tags = append(options.Tags, tag)
+	}
+	all, err := records(ctx, client, nil)
+	if err != nil {
+		return err
+	}
+
+	// the tags of the record to roll back to are only known from the ledger
+	istags := sets.New[string]()
+	for _, tag := range options.Tags {
+		istags.Insert(tag.ISTagName())
+	}
+	if options.To != "" && len(options.Tags) == 0 {
+		for _, record := range all {
+			if record.Name == options.To {
+				for _, tag := range record.Spec.Tags {
+					istags.Insert(tag.ISTagName())
+				}
+			}
+		}
+	}
+	current := map[string]string{}
+	for _, istag := range sets.List(istags) {
+		tag, _ := parseTag(istag)
+		digest, err := currentDigest(ctx, client, tag.Namespace, tag.Name, tag.Tag)
+		if err != nil {
+			return err
+		}
+		if digest != "" {
+			current[istag] = digest
+		}
+	}
+
+	plan, err := promotion.PlanRollback(all, current, options)
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		fmt.Fprintln(out, "All tags already point to the images to roll back to.")
+		return nil
+	}
+	var notInQuay []string
+	for _, tag := range plan {
+		previous := "(none)"
+		if tag.Previous != "" {
+			previous = short(tag.Previous)
+		}
+		fmt.Fprintf(out, "%s: %s -> %s from %s\n", tag.ISTagName(), previous, short(tag.Digest), tag.RolledBackTo)
+		if tag.QuayImage != "" {
+			fmt.Fprintf(out, "  and %s\n", tag.QuayImage)
+		} else {
+			notInQuay = append(notInQuay, tag.ISTagName())
+		}
+	}
+	if len(notInQuay) > 0 {
+		logrus.Warnf("The records do not know the tags in %s for %s, they are only rolled back in the image streams. Roll the tags in %s back manually.", api.QuayOpenShiftCIRepo, strings.Join(notInQuay, ", "), api.QuayOpenShiftCIRepo)
+	}
+	if !r.confirm {
+		fmt.Fprintln(out, "Run with --confirm to roll back.")
+		return nil
+	}
+	if len(notInQuay) < len(plan) && r.registryConfig == "" {
+		return fmt.Errorf("nothing was rolled back: --registry-config is required to roll back the tags in %s", api.QuayOpenShiftCIRepo)
+	}
+
+	// images that were pruned from the image streams cannot be rolled back to
+	var errs []error
+	for _, tag := range plan {
+		image := &imagev1.ImageStreamImage{}
+		if err := client.Get(ctx, types.NamespacedName{Namespace: tag.Namespace, Name: tag.Name + "@" + tag.Digest}, image); err != nil {
+			errs = append(errs, fmt.Errorf("image %s is not available in %s/%s: %w", tag.Digest, tag.Namespace, tag.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("nothing was rolled back: %w", utilerrors.NewAggregate(errs))
+	}
+
+	// the record is created first, so that a rollback that is interrupted
+	// leaves a trace in the ledger and the retagged tags can refer to it
+	record := &ledgerv1.PromotionRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    ledgerv1.LedgerNamespace,
+			GenerateName: "rollback-",
+		},
+		Spec: ledgerv1.PromotionRecordSpec{
+			Time:     metav1.NewTime(now),
+			Rollback: &ledgerv1.PromotionRollback{User: user, Reason: r.reason},
+		},
+	}
+	if err := client.Create(ctx, record); err != nil {
+		return fmt.Errorf("nothing was rolled back, failed to create the rollback record: %w", err)
+	}
+	for _, tag := range plan {
+		// quay.io is rolled back first, so that a tag is either rolled back
+		// in both places or in neither
+		if tag.QuayImage != "" {
+			source := api.QuayOpenShiftCIRepo + "@" + tag.Digest
+			if err := r.mirror(ctx, r.registryConfig, source, tag.QuayImage); err != nil {
+				errs = append(errs, fmt.Errorf("failed to roll back %s to %s, %s was not rolled back either: %w", tag.QuayImage, source, tag.ISTagName(), err))
+				continue
+			}
+		}
+		if err := retag(ctx, client, tag, record.Name); err != nil {
+			if tag.QuayImage != "" {
+				err = fmt.Errorf("%w, but %s was rolled back already", err, tag.QuayImage)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		record.Spec.Tags = append(record.Spec.Tags, tag)
+	}
+	if len(record.Spec.Tags) == 0 {
+		if err := client.Delete(ctx, record); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete the rollback record %s/%s: %w", record.Namespace, record.Name, err))
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+	if err := client.Update(ctx, record); err != nil {
+		errs = append(errs, fmt.Errorf("failed to record the rollback of %d tags in %s/%s: %w", len(record.Spec.Tags), record.Namespace, record.Name, err))
+	} else {
+		logrus.Infof("Rolled back %d tags, recorded in %s/%s", len(record.Spec.Tags), record.Namespace, record.Name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// retag points the image stream tag to the image in the same image stream and
+// marks it as rolled back
+func retag(ctx context.Context, client ctrlruntimeclient.Client, tag ledgerv1.PromotedTag, record string) error {
+	ist := &imagev1.ImageStreamTag{}
+	key := types.NamespacedName{Namespace: tag.Namespace, Name: tag.Name + ":" + tag.Tag}
+	err := client.Get(ctx, key, ist)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get imagestreamtag %s: %w", tag.ISTagName(), err)
+	}
+	exists := err == nil
+
+	referencePolicy := imagev1.TagReferencePolicy{Type: imagev1.SourceTagReferencePolicy}
+	if exists && ist.Tag != nil && ist.Tag.ReferencePolicy.Type != "" {
+		referencePolicy = ist.Tag.ReferencePolicy
+	}
+	ist.Tag = &imagev1.TagReference{
+		Name: tag.Tag,
+		From: &corev1.ObjectReference{
+			Kind:      "ImageStreamImage",
+			Namespace: tag.Namespace,
+			Name:      tag.Name + "@" + tag.Digest,
+		},
+		Annotations: map[string]string{
+			ledgerv1.RollbackDigestAnnotation: tag.Digest,
+			ledgerv1.RollbackRecordAnnotation: record,
+		},
+		ReferencePolicy: referencePolicy,
+	}
+
+	if exists {
+		err = client.Update(ctx, ist)
+	} else {
+		ist.ObjectMeta = metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}
+		err = client.Create(ctx, ist)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll back imagestreamtag %s: %w", tag.ISTagName(), err)
+	}
+	return nil
+}
+
+// mirrorWithOC points the target to the source image with oc image mirror
+func mirrorWithOC(ctx context.Context, registryConfig, source, target string) error {
+	cmd := exec.CommandContext(ctx, "oc", "image", "mirror", "--keep-manifest-list", "--registry-config="+registryConfig, source, target)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("oc image mirror failed: %w: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
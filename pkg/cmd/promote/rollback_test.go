@@ -0,0 +1,202 @@
+package promote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+)
+
+func TestRollback(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	const quayImage = "quay.io/openshift/ci:ocp_4.14_installer"
+	record := func(name string, hours int, digest, quayImage string) *ledgerv1.PromotionRecord {
+		return &ledgerv1.PromotionRecord{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ci", Name: name},
+			Spec: ledgerv1.PromotionRecordSpec{
+				Time:   metav1.NewTime(now.Add(time.Duration(hours-24) * time.Hour)),
+				Source: &ledgerv1.PromotionSource{Org: "openshift", Repo: "installer", Branch: "master"},
+				Tags:   []ledgerv1.PromotedTag{{Namespace: "ocp", Name: "4.14", Tag: "installer", Digest: digest, QuayImage: quayImage}},
+			},
+		}
+	}
+	objectsWithQuay := func(quayImage string, images ...string) []ctrlruntimeclient.Object {
+		objects := []ctrlruntimeclient.Object{
+			record("good", 0, "sha256:good", quayImage),
+			record("bad", 1, "sha256:bad", quayImage),
+			&imagev1.ImageStreamTag{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.14:installer"},
+				Image:      imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: "sha256:bad"}},
+			},
+		}
+		for _, image := range images {
+			objects = append(objects, &imagev1.ImageStreamImage{ObjectMeta: metav1.ObjectMeta{Namespace: "ocp", Name: "4.14@" + image}})
+		}
+		return objects
+	}
+	objects := func(images ...string) []ctrlruntimeclient.Object {
+		return objectsWithQuay(quayImage, images...)
+	}
+	expectedTag := &imagev1.TagReference{
+		Name: "installer",
+		From: &corev1.ObjectReference{Kind: "ImageStreamImage", Namespace: "ocp", Name: "4.14@sha256:good"},
+		Annotations: map[string]string{
+			"ci.openshift.io/rollback-digest": "sha256:good",
+			"ci.openshift.io/rollback-record": "rollback-20230601-120000",
+		},
+		ReferencePolicy: imagev1.TagReferencePolicy{Type: imagev1.SourceTagReferencePolicy},
+	}
+
+	for _, tc := range []struct {
+		name           string
+		objects        []ctrlruntimeclient.Object
+		confirm        bool
+		registryConfig string
+		mirrorErr      error
+		expectedOutput string
+		expectedMirror []string
+		expectedErr    string
+		expectedTag    *imagev1.TagReference
+		expectedRecord *ledgerv1.PromotionRecordSpec
+	}{{
+		name:           "planned changes are printed without confirm",
+		objects:        objects("sha256:good"),
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n  and quay.io/openshift/ci:ocp_4.14_installer\nRun with --confirm to roll back.\n",
+	}, {
+		name:           "tag is rolled back in the image stream and quay.io and the rollback recorded",
+		objects:        objects("sha256:good"),
+		confirm:        true,
+		registryConfig: "config.json",
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n  and quay.io/openshift/ci:ocp_4.14_installer\n",
+		expectedMirror: []string{"config.json: quay.io/openshift/ci@sha256:good -> quay.io/openshift/ci:ocp_4.14_installer"},
+		expectedTag:    expectedTag,
+		expectedRecord: &ledgerv1.PromotionRecordSpec{
+			Time:     metav1.NewTime(now),
+			Rollback: &ledgerv1.PromotionRollback{User: "developer", Reason: "broke the installer"},
+			Tags: []ledgerv1.PromotedTag{
+				{Namespace: "ocp", Name: "4.14", Tag: "installer", Digest: "sha256:good", Previous: "sha256:bad", RolledBackTo: "good", QuayImage: quayImage},
+			},
+		},
+	}, {
+		name:           "tag without a recorded quay.io tag is only rolled back in the image stream",
+		objects:        objectsWithQuay("", "sha256:good"),
+		confirm:        true,
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n",
+		expectedTag:    expectedTag,
+		expectedRecord: &ledgerv1.PromotionRecordSpec{
+			Time:     metav1.NewTime(now),
+			Rollback: &ledgerv1.PromotionRollback{User: "developer", Reason: "broke the installer"},
+			Tags: []ledgerv1.PromotedTag{
+				{Namespace: "ocp", Name: "4.14", Tag: "installer", Digest: "sha256:good", Previous: "sha256:bad", RolledBackTo: "good"},
+			},
+		},
+	}, {
+		name:           "nothing is rolled back without a registry config for quay.io",
+		objects:        objects("sha256:good"),
+		confirm:        true,
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n  and quay.io/openshift/ci:ocp_4.14_installer\n",
+		expectedErr:    "nothing was rolled back: --registry-config is required to roll back the tags in quay.io/openshift/ci",
+	}, {
+		name:           "tag is not rolled back in the image stream if quay.io fails",
+		objects:        objects("sha256:good"),
+		confirm:        true,
+		registryConfig: "config.json",
+		mirrorErr:      errors.New("unauthorized"),
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n  and quay.io/openshift/ci:ocp_4.14_installer\n",
+		expectedMirror: []string{"config.json: quay.io/openshift/ci@sha256:good -> quay.io/openshift/ci:ocp_4.14_installer"},
+		expectedErr:    "failed to roll back quay.io/openshift/ci:ocp_4.14_installer to quay.io/openshift/ci@sha256:good, ocp/4.14:installer was not rolled back either: unauthorized",
+	}, {
+		name:           "nothing is rolled back if an image was pruned",
+		objects:        objects(),
+		confirm:        true,
+		registryConfig: "config.json",
+		expectedOutput: "ocp/4.14:installer: bad -> good from good\n  and quay.io/openshift/ci:ocp_4.14_installer\n",
+		expectedErr:    `nothing was rolled back: image sha256:good is not available in ocp/4.14: imagestreamimages.image.openshift.io "4.14@sha256:good" not found`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(newScheme()).WithObjects(tc.objects...).Build()
+			out := &bytes.Buffer{}
+			var mirrored []string
+			options := rollbackOptions{
+				tags: []string{"ocp/4.14:installer"}, reason: "broke the installer", confirm: tc.confirm, registryConfig: tc.registryConfig,
+				mirror: func(_ context.Context, registryConfig, source, target string) error {
+					mirrored = append(mirrored, fmt.Sprintf("%s: %s -> %s", registryConfig, source, target))
+					return tc.mirrorErr
+				},
+			}
+			err := rollback(context.Background(), client, options, "developer", now, out)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedOutput, out.String()); diff != "" {
+				t.Errorf("unexpected output: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedMirror, mirrored); diff != "" {
+				t.Errorf("unexpected mirroring to quay.io: %s", diff)
+			}
+
+			var actualRecord *ledgerv1.PromotionRecordSpec
+			var recordName string
+			list := &ledgerv1.PromotionRecordList{}
+			if err := client.List(context.Background(), list, ctrlruntimeclient.InNamespace("ci")); err != nil {
+				t.Fatalf("failed to list records: %v", err)
+			}
+			for i := range list.Items {
+				if list.Items[i].Spec.Rollback != nil {
+					if actualRecord != nil {
+						t.Fatalf("expected at most one rollback record, got %v", list.Items)
+					}
+					actualRecord, recordName = &list.Items[i].Spec, list.Items[i].Name
+				}
+			}
+			if diff := cmp.Diff(tc.expectedRecord, actualRecord); diff != "" {
+				t.Errorf("unexpected rollback record: %s", diff)
+			}
+
+			ist := &imagev1.ImageStreamTag{}
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: "ocp", Name: "4.14:installer"}, ist); err != nil {
+				t.Fatalf("failed to get imagestreamtag: %v", err)
+			}
+			if tc.expectedTag != nil {
+				tc.expectedTag = tc.expectedTag.DeepCopy()
+				tc.expectedTag.Annotations["ci.openshift.io/rollback-record"] = recordName
+			}
+			if diff := cmp.Diff(tc.expectedTag, ist.Tag); diff != "" {
+				t.Errorf("unexpected tag: %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	for _, s := range []string{"4.14:installer", "ocp/4.14", "/4.14:installer", "ocp/4.14:", "ocp/:installer"} {
+		if _, err := parseTag(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	tag, err := parseTag("ocp/4.14:installer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag.Namespace != "ocp" || tag.Name != "4.14" || tag.Tag != "installer" {
+		t.Errorf("unexpected tag: %v", tag)
+	}
+}
@@ -38,6 +38,8 @@ type Prowgen struct {
 	AdditionalArchitectures []cioperatorapi.ReleaseArchitecture `json:"additional_architectures"`
 	// If true build images targeting multiple architectures
 	MultiArch bool `json:"multi_arch"`
+	// PodSpecRules inject additional content into the generated jobs they match
+	PodSpecRules []PodSpecRule `json:"pod_spec_rules,omitempty"`
 }
 
 func (p *Prowgen) Validate() error {
@@ -53,6 +55,7 @@ func (p *Prowgen) Validate() error {
 			strings.Join(invalidArchs, ", "), strings.Join(cioperatorapi.GetAvailableArchitectures(), ", "))
 		errs = append(errs, e)
 	}
+	errs = append(errs, validatePodSpecRules(p.PodSpecRules)...)
 	return utilerrors.NewAggregate(errs)
 }
 
@@ -73,6 +76,7 @@ func (p *Prowgen) MergeDefaults(defaults *Prowgen) {
 		p.MultiArch = true
 	}
 	p.Rehearsals.DisabledRehearsals = append(p.Rehearsals.DisabledRehearsals, defaults.Rehearsals.DisabledRehearsals...)
+	p.PodSpecRules = append(append([]PodSpecRule{}, p.PodSpecRules...), defaults.PodSpecRules...)
 }
 
 type Rehearsals struct {
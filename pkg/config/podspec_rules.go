@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PodSpecRule injects additional content into the jobs generated by prowgen
+// that it matches, on top of what prowgen sets up by itself.
+type PodSpecRule struct {
+	// Name identifies the rule in errors.
+	Name string `json:"name"`
+	// Match selects the generated jobs the rule applies to.
+	// A rule without any matcher applies to every job.
+	Match PodSpecRuleMatch `json:"match,omitempty"`
+	// Volumes are added to the pod of the job.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are added to the ci-operator container.
+	// They can only mount volumes declared by the same rule.
+	VolumeMounts []corev1.VolumeMount `json:"volume_mounts,omitempty"`
+	// Env are added to the environment of the ci-operator container.
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// Args are added to the arguments of the ci-operator container, in the
+	// --flag=value form. A flag that is already set to a different value is a
+	// conflict, unless ci-operator accepts it multiple times.
+	Args []string `json:"args,omitempty"`
+	// Labels are added to the job.
+	Labels map[string]string `json:"labels,omitempty"`
+	// NodeSelector entries are added to the pod of the job.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+}
+
+// PodSpecRuleMatch selects jobs with glob patterns as understood by path.Match.
+// A job matches when, for every non-empty field, it matches any of the patterns.
+type PodSpecRuleMatch struct {
+	Orgs            []string `json:"orgs,omitempty"`
+	Repos           []string `json:"repos,omitempty"`
+	Tests           []string `json:"tests,omitempty"`
+	ClusterProfiles []string `json:"cluster_profiles,omitempty"`
+}
+
+// Matches determines whether a job generated for the test in the repository
+// is selected. Jobs that do not use a cluster profile pass an empty profile
+// and never match a rule that lists cluster profiles.
+func (m PodSpecRuleMatch) Matches(org, repo, test, clusterProfile string) bool {
+	return matchesAny(m.Orgs, org) && matchesAny(m.Repos, repo) && matchesAny(m.Tests, test) && matchesAny(m.ClusterProfiles, clusterProfile)
+}
+
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		// patterns are validated on load, so errors cannot occur here
+		if matched, _ := path.Match(pattern, value); matched && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m PodSpecRuleMatch) validate() []error {
+	var errs []error
+	for _, field := range []struct {
+		name     string
+		patterns []string
+	}{{"orgs", m.Orgs}, {"repos", m.Repos}, {"tests", m.Tests}, {"cluster_profiles", m.ClusterProfiles}} {
+		for _, pattern := range field.patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("match.%s: invalid pattern %q: %w", field.name, pattern, err))
+			}
+		}
+	}
+	return errs
+}
+
+func (r PodSpecRule) injectsNothing() bool {
+	return len(r.Volumes) == 0 && len(r.VolumeMounts) == 0 && len(r.Env) == 0 && len(r.Args) == 0 && len(r.Labels) == 0 && len(r.NodeSelector) == 0
+}
+
+// Validate checks that the rule is well-formed. Conflicts with the content
+// prowgen generates can only be detected when the rule is applied to a job.
+func (r PodSpecRule) Validate() error {
+	errs := r.Match.validate()
+	if r.injectsNothing() {
+		errs = append(errs, fmt.Errorf("the rule does not inject anything"))
+	}
+	volumes := sets.New[string]()
+	for i, volume := range r.Volumes {
+		if volume.Name == "" {
+			errs = append(errs, fmt.Errorf("volumes[%d]: name must be set", i))
+		} else if volumes.Has(volume.Name) {
+			errs = append(errs, fmt.Errorf("volumes[%d]: duplicate volume %q", i, volume.Name))
+		}
+		volumes.Insert(volume.Name)
+	}
+	mountPaths := sets.New[string]()
+	for i, mount := range r.VolumeMounts {
+		if !volumes.Has(mount.Name) {
+			errs = append(errs, fmt.Errorf("volume_mounts[%d]: volume %q is not declared by the rule", i, mount.Name))
+		}
+		if mount.MountPath == "" {
+			errs = append(errs, fmt.Errorf("volume_mounts[%d]: mount_path must be set", i))
+		} else if mountPaths.Has(mount.MountPath) {
+			errs = append(errs, fmt.Errorf("volume_mounts[%d]: duplicate mount path %q", i, mount.MountPath))
+		}
+		mountPaths.Insert(mount.MountPath)
+	}
+	envs := sets.New[string]()
+	for i, env := range r.Env {
+		for _, msg := range validation.IsEnvVarName(env.Name) {
+			errs = append(errs, fmt.Errorf("env[%d]: invalid name %q: %s", i, env.Name, msg))
+		}
+		if envs.Has(env.Name) {
+			errs = append(errs, fmt.Errorf("env[%d]: duplicate variable %q", i, env.Name))
+		}
+		envs.Insert(env.Name)
+	}
+	for i, arg := range r.Args {
+		if !strings.HasPrefix(arg, "--") {
+			errs = append(errs, fmt.Errorf("args[%d]: %q is not a flag", i, arg))
+		}
+	}
+	for _, field := range []struct {
+		name   string
+		values map[string]string
+	}{{"labels", r.Labels}, {"node_selector", r.NodeSelector}} {
+		for _, key := range sets.List(sets.KeySet(field.values)) {
+			for _, msg := range validation.IsQualifiedName(key) {
+				errs = append(errs, fmt.Errorf("%s: invalid key %q: %s", field.name, key, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(field.values[key]) {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q for %q: %s", field.name, field.values[key], key, msg))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func validatePodSpecRules(rules []PodSpecRule) []error {
+	var errs []error
+	names := sets.New[string]()
+	for i, rule := range rules {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("pod_spec_rules[%d]: name must be set", i))
+		} else if names.Has(rule.Name) {
+			errs = append(errs, fmt.Errorf("pod_spec_rules[%d]: duplicate rule name %q", i, rule.Name))
+		}
+		names.Insert(rule.Name)
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("pod_spec_rules[%d]: %w", i, err))
+		}
+	}
+	return errs
+}
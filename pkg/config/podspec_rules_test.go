@@ -0,0 +1,119 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodSpecRuleMatchMatches(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		match          PodSpecRuleMatch
+		clusterProfile string
+		expected       bool
+	}{
+		{
+			name:     "empty matcher matches everything",
+			expected: true,
+		},
+		{
+			name:     "all fields must match",
+			match:    PodSpecRuleMatch{Orgs: []string{"openshift"}, Tests: []string{"e2e-*"}},
+			expected: true,
+		},
+		{
+			name:  "any field not matching excludes the job",
+			match: PodSpecRuleMatch{Orgs: []string{"openshift"}, Repos: []string{"other"}},
+		},
+		{
+			name:     "any pattern of a field may match",
+			match:    PodSpecRuleMatch{Repos: []string{"other", "ci-*"}},
+			expected: true,
+		},
+		{
+			name:  "jobs without a cluster profile do not match cluster profile patterns",
+			match: PodSpecRuleMatch{ClusterProfiles: []string{"*"}},
+		},
+		{
+			name:           "cluster profile pattern",
+			match:          PodSpecRuleMatch{ClusterProfiles: []string{"aws*"}},
+			clusterProfile: "aws-2",
+			expected:       true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.match.Matches("openshift", "ci-tools", "e2e-aws", tc.clusterProfile); actual != tc.expected {
+				t.Errorf("expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidatePodSpecRules(t *testing.T) {
+	env := []corev1.EnvVar{{Name: "VAR", Value: "value"}}
+	for _, tc := range []struct {
+		name     string
+		rules    []PodSpecRule
+		expected []string
+	}{
+		{
+			name: "valid rules",
+			rules: []PodSpecRule{
+				{Name: "env", Match: PodSpecRuleMatch{Orgs: []string{"open*"}}, Env: env},
+				{
+					Name:         "volume",
+					Volumes:      []corev1.Volume{{Name: "mirror"}},
+					VolumeMounts: []corev1.VolumeMount{{Name: "mirror", MountPath: "/etc/mirror"}},
+					Args:         []string{"--flag=value"},
+					Labels:       map[string]string{"ci.openshift.io/label": "value"},
+					NodeSelector: map[string]string{"region": "east"},
+				},
+			},
+		},
+		{
+			name:  "rules must be named uniquely",
+			rules: []PodSpecRule{{Env: env}, {Name: "rule", Env: env}, {Name: "rule", Env: env}},
+			expected: []string{
+				"pod_spec_rules[0]: name must be set",
+				`pod_spec_rules[2]: duplicate rule name "rule"`,
+			},
+		},
+		{
+			name: "invalid rule",
+			rules: []PodSpecRule{{
+				Name:         "rule",
+				Match:        PodSpecRuleMatch{Tests: []string{"[e2e"}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "missing"}},
+				Env:          []corev1.EnvVar{{Name: "VAR"}, {Name: "VAR"}},
+				Args:         []string{"value"},
+				Labels:       map[string]string{"label": "not a value"},
+			}},
+			expected: []string{
+				`pod_spec_rules[0]: [match.tests: invalid pattern "[e2e": syntax error in pattern, ` +
+					`volume_mounts[0]: volume "missing" is not declared by the rule, ` +
+					`volume_mounts[0]: mount_path must be set, ` +
+					`env[1]: duplicate variable "VAR", ` +
+					`args[0]: "value" is not a flag, ` +
+					`labels: invalid value "not a value" for "label": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')]`,
+			},
+		},
+		{
+			name:     "rule without content",
+			rules:    []PodSpecRule{{Name: "empty"}},
+			expected: []string{"pod_spec_rules[0]: the rule does not inject anything"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, err := range validatePodSpecRules(tc.rules) {
+				actual = append(actual, err.Error())
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
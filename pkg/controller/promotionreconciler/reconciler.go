@@ -22,6 +22,7 @@ import (
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/api/helper"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
 	"github.com/openshift/ci-tools/pkg/controller/promotionreconciler/prowjobreconciler"
 	controllerutil "github.com/openshift/ci-tools/pkg/controller/util"
 	"github.com/openshift/ci-tools/pkg/load/agents"
@@ -160,6 +161,11 @@ func (r *reconciler) reconcile(ctx context.Context, req controllerruntime.Reques
 		return fmt.Errorf("failed to get object: %w", err)
 	}
 
+	if rolledBack(ist) {
+		log.WithField("record", ist.Tag.Annotations[ledgerv1.RollbackRecordAnnotation]).Debug("Ignored rolled back imageStreamTag")
+		return nil
+	}
+
 	if !ist.CreationTimestamp.After(time.Now().Add(-r.since)) {
 		log.WithField("creationTimestamp", ist.CreationTimestamp).Trace("Ignored old imageStreamTag")
 		return nil
@@ -205,6 +211,16 @@ func (r *reconciler) reconcile(ctx context.Context, req controllerruntime.Reques
 	return nil
 }
 
+// rolledBack determines if the imageStreamTag still points to the image it was rolled back to.
+// Promoting the current HEAD again would undo the rollback.
+func rolledBack(ist *imagev1.ImageStreamTag) bool {
+	if ist.Tag == nil {
+		return false
+	}
+	digest := ist.Tag.Annotations[ledgerv1.RollbackDigestAnnotation]
+	return digest != "" && digest == ist.Image.Name
+}
+
 func (r *reconciler) promotionConfig(ist *imagev1.ImageStreamTag) (*cioperatorapi.ReleaseBuildConfiguration, error) {
 	results, err := r.releaseBuildConfigs(configIndexKeyForIST(ist))
 	if err != nil {
@@ -24,6 +24,7 @@ import (
 	imagev1 "github.com/openshift/api/image/v1"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
 	"github.com/openshift/ci-tools/pkg/controller/promotionreconciler/prowjobreconciler"
 	controllerutil "github.com/openshift/ci-tools/pkg/controller/util"
 )
@@ -96,6 +97,7 @@ func TestReconcile(t *testing.T) {
 		name              string
 		githubClient      func(owner, repo, ref string) (string, error)
 		promotionDisabled bool
+		rolledBack        bool
 		verify            func(error, *prowjobreconciler.OrgRepoBranchCommit) error
 	}{
 		{
@@ -157,6 +159,20 @@ func TestReconcile(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:         "Ist outdated but rolled back, no prowjob created",
+			githubClient: func(_, _, _ string) (string, error) { return "newer", nil },
+			rolledBack:   true,
+			verify: func(e error, req *prowjobreconciler.OrgRepoBranchCommit) error {
+				if e != nil {
+					return fmt.Errorf("expected error to be nil, was %w", e)
+				}
+				if req != nil {
+					return fmt.Errorf("expected no request, got %v", req)
+				}
+				return nil
+			},
+		},
 		{
 			name:         "Ist outdated, prowjob created",
 			githubClient: func(_, _, _ string) (string, error) { return "newer", nil },
@@ -300,6 +316,14 @@ func TestReconcile(t *testing.T) {
 				},
 			}
 
+			imageStreamTag.Image.Name = "sha256:current"
+			if tc.rolledBack {
+				imageStreamTag.Tag = &imagev1.TagReference{
+					Name:        "tag",
+					Annotations: map[string]string{ledgerv1.RollbackDigestAnnotation: "sha256:current"},
+				}
+			}
+
 			var req *prowjobreconciler.OrgRepoBranchCommit
 
 			client := fakectrlruntimeclient.NewClientBuilder().WithRuntimeObjects(imageStreamTag).Build()
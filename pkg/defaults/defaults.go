@@ -24,6 +24,7 @@ import (
 	"k8s.io/test-infra/prow/pod-utils/decorate"
 	utilpointer "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/api/image/docker10"
@@ -34,6 +35,7 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	testimagestreamtagimportv1 "github.com/openshift/ci-tools/pkg/api/testimagestreamtagimport/v1"
+	"github.com/openshift/ci-tools/pkg/buildcache"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/lease"
 	"github.com/openshift/ci-tools/pkg/release"
@@ -78,6 +80,8 @@ func FromConfig(
 	targetAdditionalSuffix string,
 	manifestToolDockerCfg string,
 	localRegistryDNS string,
+	buildCacheNamespace string,
+	buildah *steps.BuildahConfig,
 ) ([]api.Step, []api.Step, error) {
 	crclient, err := ctrlruntimeclient.NewWithWatch(clusterConfig, ctrlruntimeclient.Options{})
 	crclient = secretrecordingclient.Wrap(crclient, censor)
@@ -89,7 +93,11 @@ func FromConfig(
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not get build client for cluster config: %w", err)
 	}
-	buildClient := steps.NewBuildClient(client, buildGetter.RESTClient(), nodeArchitectures, manifestToolDockerCfg, localRegistryDNS)
+	var buildCache *buildcache.Cache
+	if buildCacheNamespace != "" {
+		buildCache = buildcache.New(client, buildCacheNamespace)
+	}
+	buildClient := steps.NewBuildClient(client, buildGetter.RESTClient(), nodeArchitectures, manifestToolDockerCfg, localRegistryDNS, buildCache, buildah)
 
 	templateGetter, err := templateclientset.NewForConfig(clusterConfig)
 	if err != nil {
@@ -117,6 +125,32 @@ func FromConfig(
 	return fromConfig(ctx, config, graphConf, jobSpec, templates, paramFile, promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient.StandardClient(), requiredTargets, cloneAuthConfig, pullSecret, pushSecret, api.NewDeferredParameters(nil), censor, consoleHost, nodeName, targetAdditionalSuffix, nodeArchitectures)
 }
 
+// FromConfigForPlan generates the execution graph like FromConfig does, but
+// with clients that never contact a cluster. The steps can only be planned,
+// not run: anything they would read from the cluster is not found.
+func FromConfigForPlan(
+	ctx context.Context,
+	config *api.ReleaseBuildConfiguration,
+	graphConf *api.GraphConfiguration,
+	jobSpec *api.JobSpec,
+	templates []*templateapi.Template,
+	promote bool,
+	requiredTargets []string,
+	cloneAuthConfig *steps.CloneAuthConfig,
+	pullSecret, pushSecret *coreapi.Secret,
+	censor *secrets.DynamicCensor,
+	nodeName string,
+	nodeArchitectures []string,
+	targetAdditionalSuffix string,
+	buildah *steps.BuildahConfig,
+) ([]api.Step, []api.Step, error) {
+	client := loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build())
+	buildClient := steps.NewBuildClient(client, nil, nodeArchitectures, "", "", nil, buildah)
+	templateClient := steps.NewTemplateClient(client, nil)
+	podClient := kubernetes.NewPodClient(client, nil, nil, 0)
+	return fromConfig(ctx, config, graphConf, jobSpec, templates, "", promote, client, buildClient, templateClient, podClient, nil, nil, nil, requiredTargets, cloneAuthConfig, pullSecret, pushSecret, api.NewDeferredParameters(nil), censor, "", nodeName, targetAdditionalSuffix, nodeArchitectures)
+}
+
 func fromConfig(
 	ctx context.Context,
 	config *api.ReleaseBuildConfiguration,
@@ -307,7 +341,7 @@ func fromConfig(
 	}
 
 	for _, template := range templates {
-		step := steps.TemplateExecutionStep(template, params, podClient, templateClient, jobSpec, config.Resources)
+		step := steps.TemplateExecutionStep(template, params, podClient, templateClient, jobSpec, config.Resources, censor)
 		var hasClusterType, hasUseLease bool
 		for _, p := range template.Parameters {
 			hasClusterType = hasClusterType || p.Name == "CLUSTER_TYPE"
@@ -426,7 +460,7 @@ func stepForTest(
 			return nil, nil
 		}
 		params = api.NewDeferredParameters(params)
-		step, err := clusterinstall.E2ETestStep(*c.OpenshiftInstallerClusterTestConfiguration, *c, params, podClient, templateClient, jobSpec, config.Resources)
+		step, err := clusterinstall.E2ETestStep(*c.OpenshiftInstallerClusterTestConfiguration, *c, params, podClient, templateClient, jobSpec, config.Resources, censor)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create end to end test step: %w", err)
 		}
@@ -96,6 +96,9 @@ type FailureOutput struct {
 	// Message holds the failure message from the test
 	Message string `xml:"message,attr"`
 
+	// Type holds the category of the failure, like "infra" or "user", when known
+	Type string `xml:"type,attr,omitempty"`
+
 	// Output holds verbose failure output from the test
 	Output string `xml:",chardata"`
 }
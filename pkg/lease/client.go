@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	boskos "sigs.k8s.io/boskos/client"
 	"sigs.k8s.io/boskos/common"
 )
@@ -21,6 +24,7 @@ const (
 )
 
 type boskosClient interface {
+	AcquireWithPriority(rtype, state, dest, requestID string) (*common.Resource, error)
 	AcquireWaitWithPriority(ctx context.Context, rtype, state, dest, requestID string) (*common.Resource, error)
 	UpdateOne(name, dest string, _ *common.UserData) error
 	ReleaseOne(name, dest string) error
@@ -30,10 +34,31 @@ type boskosClient interface {
 
 var ErrNotFound = boskos.ErrNotFound
 
+// ErrAlreadyInUse is returned by the server when another client holds a
+// queued request with the same ID, which we treat like unavailable resources.
+var ErrAlreadyInUse = boskos.ErrAlreadyInUse
+
+// unavailable determines if an acquisition failed only because resources are
+// not available right now, so it makes sense to retry it
+func unavailable(err error) bool {
+	return err == ErrNotFound || err == ErrAlreadyInUse
+}
+
 type Metrics struct {
 	Free, Leased int
 }
 
+// Request describes a number of resources of one type to be leased.
+type Request struct {
+	ResourceType string
+	Count        uint
+}
+
+// acquirePollInterval is how often all resources are requested again when
+// acquiring them atomically. It must be shorter than the time the server
+// keeps a request in its queue without being polled.
+const acquirePollInterval = 10 * time.Second
+
 // Client manages resource leases, acquiring, releasing, and keeping them
 // updated.
 type Client interface {
@@ -42,6 +67,16 @@ type Client interface {
 	// `ctx` can be used to abort the operation, `cancel` is called if any
 	// subsequent updates to the lease fail.
 	Acquire(rtype string, n uint, ctx context.Context, cancel context.CancelFunc) ([]string, error)
+	// AcquireAll leases resources for all requests at once: either all of
+	// them are leased or, if an error is returned, none are held. The
+	// resources of a type are only kept once all of them were acquired, while
+	// the requests keep their place in the server's queue through stable
+	// request IDs. Types acquired while waiting for the others are held and
+	// released on timeout. The lease names are returned in the same order as
+	// the requests. Will block until all resources are available or the
+	// acquisition timeout passes.
+	// `ctx` and `cancel` are used as in Acquire.
+	AcquireAll(requests []Request, ctx context.Context, cancel context.CancelFunc) ([][]string, error)
 	// Heartbeat updates all leases. It calls the cancellation function of each
 	// lease it fails to update.
 	Heartbeat() error
@@ -75,6 +110,7 @@ func newClient(boskos boskosClient, retries int, acquireTimeout time.Duration) C
 		boskos:         boskos,
 		retries:        retries,
 		acquireTimeout: acquireTimeout,
+		pollInterval:   acquirePollInterval,
 		leases:         make(map[string]*lease),
 	}
 }
@@ -84,6 +120,7 @@ type client struct {
 	boskos         boskosClient
 	retries        int
 	acquireTimeout time.Duration
+	pollInterval   time.Duration
 	leases         map[string]*lease
 }
 
@@ -101,7 +138,8 @@ func (c *client) Acquire(rtype string, n uint, ctx context.Context, cancel conte
 	ctx, cancelAcquire = context.WithTimeout(ctx, c.acquireTimeout)
 	defer cancelAcquire()
 	var ret []string
-	// TODO `m` processes may fight for the last `m * n` remaining leases
+	// TODO `m` processes may fight for the last `m * n` remaining leases and
+	// wait for each other forever, AcquireAll never holds part of a type
 	for i := uint(0); i < n; i++ {
 		r, err := c.boskos.AcquireWaitWithPriority(ctx, rtype, freeState, leasedState, randId())
 		if err != nil {
@@ -115,6 +153,178 @@ func (c *client) Acquire(rtype string, n uint, ctx context.Context, cancel conte
 	return ret, nil
 }
 
+func (c *client) AcquireAll(requests []Request, ctx context.Context, cancel context.CancelFunc) ([][]string, error) {
+	var cancelAcquire context.CancelFunc
+	ctx, cancelAcquire = context.WithTimeout(ctx, c.acquireTimeout)
+	defer cancelAcquire()
+	// A stable request ID for each resource keeps our position in the
+	// server's FIFO queue for that type while we are polling.
+	ids := make([][]string, len(requests))
+	inQueue := make([][]bool, len(requests))
+	for i, r := range requests {
+		for j := uint(0); j < r.Count; j++ {
+			ids[i] = append(ids[i], randId())
+		}
+		inQueue[i] = make([]bool, r.Count)
+	}
+	held := make([][]string, len(requests))
+	queued := make([]time.Time, len(requests))
+	for attempt := 1; ; attempt++ {
+		err := c.tryAcquireAll(requests, ids, inQueue, held, queued, cancel)
+		if err == nil {
+			return held, nil
+		}
+		if !unavailable(err) {
+			return nil, c.releaseHeld(held, err)
+		}
+		c.logWaiting(requests, inQueue, held, queued, attempt)
+		select {
+		case <-ctx.Done():
+			return nil, c.releaseHeld(held, fmt.Errorf("timed out waiting for all leases: %w", ErrNotFound))
+		// clients that requested the same type at the same time would do so
+		// again on every attempt without the jitter
+		case <-time.After(wait.Jitter(c.pollInterval, 1)):
+		}
+	}
+}
+
+// tryAcquireAll attempts to acquire the requested types that are not held yet
+// without waiting. Types are always requested in the same order and a type is
+// only requested once the previous ones are held, so that concurrent clients
+// never wait for each other's holdings. Acquired resources are added to held
+// and updated by Heartbeat like any other lease. queued records when we
+// started waiting for a type.
+func (c *client) tryAcquireAll(requests []Request, ids [][]string, inQueue [][]bool, held [][]string, queued []time.Time, cancel context.CancelFunc) error {
+	order := make([]int, len(requests))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return requests[order[i]].ResourceType < requests[order[j]].ResourceType
+	})
+	for _, i := range order {
+		if uint(len(held[i])) == requests[i].Count {
+			continue
+		}
+		if queued[i].IsZero() {
+			queued[i] = time.Now()
+		}
+		names, err := c.tryAcquireType(requests[i].ResourceType, ids[i], inQueue[i], cancel)
+		if err != nil {
+			return err
+		}
+		held[i] = names
+	}
+	return nil
+}
+
+// tryAcquireType acquires the resources for all of ids or none of them, as
+// clients holding part of the last resources of a type would wait for each
+// other forever. inQueue marks the requests the server refused and keeps
+// queued.
+//
+// The server serves queued requests in order, so once all of our requests are
+// queued, the one queued last is only granted when there is room for all of
+// them and they are requested from the last one. Requests granted while others
+// are refused are released right away. The server forgets the ID of a granted
+// request, so requesting it again would queue it behind other clients waiting
+// for the type, which can leave two clients taking turns forever. Instead, the
+// requests that are still queued are drained first and the type is requested
+// again once none is left.
+func (c *client) tryAcquireType(rtype string, ids []string, inQueue []bool, cancel context.CancelFunc) ([]string, error) {
+	waiting := countQueued(inQueue)
+	draining := waiting != 0 && waiting != len(ids)
+	var polled []int
+	for j := range ids {
+		switch {
+		case draining && !inQueue[j]:
+		case waiting == len(ids):
+			polled = append([]int{j}, polled...)
+		default:
+			polled = append(polled, j)
+		}
+	}
+	names := make([]string, len(ids))
+	var acquired []string
+	refused := draining
+	for _, j := range polled {
+		r, err := c.boskos.AcquireWithPriority(rtype, freeState, leasedState, ids[j])
+		if unavailable(err) {
+			inQueue[j] = true
+			refused = true
+			continue
+		}
+		if err != nil {
+			return nil, c.releaseHeld([][]string{acquired}, err)
+		}
+		inQueue[j] = false
+		c.Lock()
+		c.leases[r.Name] = &lease{cancel: cancel}
+		c.Unlock()
+		acquired = append(acquired, r.Name)
+		names[j] = r.Name
+	}
+	if refused {
+		return nil, c.releaseHeld([][]string{acquired}, ErrNotFound)
+	}
+	return names, nil
+}
+
+func countQueued(inQueue []bool) int {
+	var n int
+	for _, queued := range inQueue {
+		if queued {
+			n++
+		}
+	}
+	return n
+}
+
+// releaseHeld releases all resources acquired while waiting for the others
+// and returns the error that ended the acquisition along with any that
+// occurred releasing them.
+func (c *client) releaseHeld(held [][]string, err error) error {
+	errs := []error{err}
+	for _, names := range held {
+		for _, name := range names {
+			if releaseErr := c.Release(name); releaseErr != nil {
+				errs = append(errs, fmt.Errorf("failed to release partially acquired lease %q: %w", name, releaseErr))
+			}
+		}
+	}
+	if len(errs) == 1 {
+		return err
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// logWaiting reports our position for each requested type: whether the
+// resources are held, how many of the requests are queued and for how long we
+// have been waiting, along with the current capacity. The server does not
+// expose the rank of a request in its queue, so this is the best indication of
+// progress.
+func (c *client) logWaiting(requests []Request, inQueue [][]bool, held [][]string, queued []time.Time, attempt int) {
+	var status []string
+	for i, r := range requests {
+		var position string
+		switch {
+		case uint(len(held[i])) == r.Count:
+			position = fmt.Sprintf("holding %d", r.Count)
+		case queued[i].IsZero():
+			position = "not queued until the previous types are held"
+		default:
+			position = fmt.Sprintf("%d of %d queued for %s", countQueued(inQueue[i]), r.Count, time.Since(queued[i]).Truncate(time.Second))
+		}
+		m, err := c.Metrics(r.ResourceType)
+		if err != nil {
+			status = append(status, fmt.Sprintf("%s (%s)", r.ResourceType, position))
+			continue
+		}
+		status = append(status, fmt.Sprintf("%s (%s, %d free, %d leased)", r.ResourceType, position, m.Free, m.Leased))
+	}
+	logrus.Infof("Waiting for all leases to be available (attempt %d): %s", attempt, strings.Join(status, ", "))
+}
+
 func (c *client) Heartbeat() error {
 	c.Lock()
 	defer c.Unlock()
@@ -2,11 +2,19 @@ package lease
 
 import (
 	"context"
+	"math/rand"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/boskos/common"
 )
 
 func TestAcquire(t *testing.T) {
@@ -131,3 +139,208 @@ func TestHeartbeatRetries(t *testing.T) {
 		})
 	}
 }
+
+// capacityClient grants resources while there are free ones of the type.
+type capacityClient struct {
+	fakeClient
+	free map[string]int
+}
+
+func (c *capacityClient) AcquireWithPriority(rtype, state, dest, requestID string) (*common.Resource, error) {
+	if c.free[rtype] == 0 {
+		_ = c.addCall("acquire-not-found", rtype)
+		return nil, ErrNotFound
+	}
+	c.free[rtype]--
+	return c.fakeClient.AcquireWithPriority(rtype, state, dest, requestID)
+}
+
+func (c *capacityClient) ReleaseOne(name, dest string) error {
+	c.free[strings.Split(name, "_")[0]]++
+	return c.fakeClient.ReleaseOne(name, dest)
+}
+
+func TestAcquireAll(t *testing.T) {
+	requests := []Request{
+		{ResourceType: "rtype1", Count: 1},
+		{ResourceType: "rtype0", Count: 2},
+	}
+	for _, tc := range []struct {
+		name          string
+		free          map[string]int
+		expectedNames [][]string
+		expectedErr   bool
+		expectedCalls []string
+		expectedFree  map[string]int
+	}{{
+		name:          "all resources available",
+		free:          map[string]int{"rtype0": 2, "rtype1": 1},
+		expectedNames: [][]string{{"rtype1_2"}, {"rtype0_0", "rtype0_1"}},
+		expectedCalls: []string{
+			"acquire owner rtype0 free leased random",
+			"acquire owner rtype0 free leased random",
+			"acquire owner rtype1 free leased random",
+		},
+		expectedFree: map[string]int{"rtype0": 0, "rtype1": 0},
+	}, {
+		name:        "partial holdings are released",
+		free:        map[string]int{"rtype0": 2, "rtype1": 0},
+		expectedErr: true,
+		expectedCalls: []string{
+			"acquire owner rtype0 free leased random",
+			"acquire owner rtype0 free leased random",
+			"acquire-not-found owner rtype1",
+			"releaseone owner rtype0_0 free",
+			"releaseone owner rtype0_1 free",
+		},
+		expectedFree: map[string]int{"rtype0": 2, "rtype1": 0},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			randId = func() string { return "random" }
+			boskos := &capacityClient{fakeClient: fakeClient{owner: "owner", calls: &calls}, free: tc.free}
+			client := newClient(boskos, 0, 0).(*client)
+			names, err := client.AcquireAll(requests, context.Background(), nil)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if diff := cmp.Diff(tc.expectedNames, names); diff != "" {
+				t.Errorf("unexpected lease names: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedCalls, calls[:len(tc.expectedCalls)]); diff != "" {
+				t.Errorf("unexpected calls to the boskos client: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedFree, boskos.free); diff != "" {
+				t.Errorf("unexpected capacity after acquisition: %s", diff)
+			}
+			if held := len(client.leases); tc.expectedErr && held != 0 {
+				t.Errorf("expected no leases to be held, got %d", held)
+			}
+		})
+	}
+}
+
+func TestAcquireAllKeepsHoldingsWhileWaiting(t *testing.T) {
+	var calls []string
+	randId = func() string { return "random" }
+	// a resource of the second type is freed after we started waiting
+	boskos := &restockingClient{
+		capacityClient: &capacityClient{fakeClient: fakeClient{owner: "owner", calls: &calls}, free: map[string]int{"rtype0": 1, "rtype1": 0}},
+		rtype:          "rtype1",
+	}
+	client := newClient(boskos, 0, time.Minute).(*client)
+	client.pollInterval = 0
+	names, err := client.AcquireAll([]Request{{ResourceType: "rtype0", Count: 1}, {ResourceType: "rtype1", Count: 1}}, context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([][]string{{"rtype0_0"}, {"rtype1_2"}}, names); diff != "" {
+		t.Errorf("unexpected lease names: %s", diff)
+	}
+	// the first type is not released and requested again while waiting
+	expected := []string{
+		"acquire owner rtype0 free leased random",
+		"acquire-not-found owner rtype1",
+		"acquire owner rtype1 free leased random",
+	}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("unexpected calls to the boskos client: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"rtype0_0", "rtype1_2"}, sets.List(sets.KeySet(client.leases))); diff != "" {
+		t.Errorf("unexpected leases held: %s", diff)
+	}
+}
+
+// restockingClient frees a resource of the type after it was not found once.
+type restockingClient struct {
+	*capacityClient
+	rtype string
+}
+
+func (c *restockingClient) AcquireWithPriority(rtype, state, dest, requestID string) (*common.Resource, error) {
+	r, err := c.capacityClient.AcquireWithPriority(rtype, state, dest, requestID)
+	if err == ErrNotFound && rtype == c.rtype {
+		c.free[rtype]++
+	}
+	return r, err
+}
+
+// busyClient fails acquisitions as if another client used the request ID
+// until the given number of attempts was made.
+type busyClient struct {
+	fakeClient
+	busy int
+}
+
+func (c *busyClient) AcquireWithPriority(rtype, state, dest, requestID string) (*common.Resource, error) {
+	if c.busy > 0 {
+		c.busy--
+		_ = c.addCall("acquire-in-use", rtype)
+		return nil, ErrAlreadyInUse
+	}
+	return c.fakeClient.AcquireWithPriority(rtype, state, dest, requestID)
+}
+
+func TestAcquireAllRetriesResourcesInUse(t *testing.T) {
+	var calls []string
+	randId = func() string { return "random" }
+	boskos := &busyClient{fakeClient: fakeClient{owner: "owner", calls: &calls}, busy: 1}
+	client := newClient(boskos, 0, time.Minute).(*client)
+	client.pollInterval = 0
+	names, err := client.AcquireAll([]Request{{ResourceType: "rtype", Count: 2}}, context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([][]string{{"rtype_5", "rtype_6"}}, names); diff != "" {
+		t.Errorf("unexpected lease names: %s", diff)
+	}
+	// the resource granted while the other request was refused is released
+	// and the request left in the queue is drained before both are requested
+	// again
+	expected := []string{
+		"acquire-in-use owner rtype",
+		"acquire owner rtype free leased random",
+		"releaseone owner rtype_1 free",
+		"acquire owner rtype free leased random",
+		"releaseone owner rtype_3 free",
+		"acquire owner rtype free leased random",
+		"acquire owner rtype free leased random",
+	}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("unexpected calls to the boskos client: %s", diff)
+	}
+}
+
+func TestAcquireAllCompetingForTheLastResources(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, url := newTestServer(t, &now)
+	randId = func() string { return strconv.Itoa(rand.Int()) }
+	owners := []string{"first", "second"}
+	errs := make([]error, len(owners))
+	var wg sync.WaitGroup
+	for i, owner := range owners {
+		client := newClient(newBoskosClient(t, owner, url), 0, 10*time.Second).(*client)
+		client.pollInterval = 10 * time.Millisecond
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// both clients need the two resources of the type, which neither
+			// gets if each holds one while waiting for the other
+			names, err := client.AcquireAll([]Request{{ResourceType: "aws-quota-slice", Count: 2}}, context.Background(), func() {})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if diff := cmp.Diff([]string{"aws-0", "aws-1"}, sets.List(sets.New[string](names[0]...))); diff != "" {
+				t.Errorf("%s: unexpected lease names: %s", owners[i], diff)
+			}
+			_, errs[i] = client.ReleaseAll()
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("%s: failed to acquire and release the leases: %v", owners[i], err)
+		}
+	}
+}
@@ -42,6 +42,11 @@ func (c *fakeClient) addCall(call string, args ...string) error {
 	return nil
 }
 
+func (c *fakeClient) AcquireWithPriority(rtype, state, dest, requestID string) (*common.Resource, error) {
+	err := c.addCall("acquire", rtype, state, dest, requestID)
+	return &common.Resource{Name: fmt.Sprintf("%s_%d", rtype, len(*c.calls)-1)}, err
+}
+
 func (c *fakeClient) AcquireWaitWithPriority(ctx context.Context, rtype, state, dest, requestID string) (*common.Resource, error) {
 	err := c.addCall("acquire", rtype, state, dest, requestID)
 	return &common.Resource{Name: fmt.Sprintf("%s_%d", rtype, len(*c.calls)-1)}, err
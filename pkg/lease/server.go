@@ -0,0 +1,402 @@
+package lease
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/boskos/common"
+)
+
+// requestTTL is how long a queued acquire request is kept without being
+// polled again, matching the Boskos default.
+const requestTTL = 30 * time.Second
+
+var (
+	resourcesDesc = prometheus.NewDesc("lease_server_resources", "Number of resources by type and state.", []string{"type", "state"}, nil)
+	reapedDesc    = prometheus.NewDesc("lease_server_reaped_total", "Number of leases returned to their initial state because their owner stopped updating them.", []string{"type"}, nil)
+)
+
+// Server is a small, in-memory implementation of the Boskos API. It serves
+// the endpoints used by the Boskos client so that ci-operator can acquire
+// leases without access to a shared Boskos instance. Only static resources
+// are supported and the server does not authenticate its clients.
+type Server struct {
+	lock sync.Mutex
+	// resources holds all resources, by name
+	resources map[string]*common.Resource
+	// initial holds the state each resource is reaped back to, by name
+	initial map[string]string
+	// types holds the names of all resources of a type, sorted
+	types map[string][]string
+	// queues holds the pending acquire requests per type, in arrival order
+	queues map[string][]queuedRequest
+	reaped map[string]int
+	ttl    time.Duration
+	now    func() time.Time
+	logger *logrus.Entry
+}
+
+type queuedRequest struct {
+	id       string
+	lastSeen time.Time
+}
+
+// LoadServerConfig reads a Boskos resource configuration file.
+func LoadServerConfig(path string) (*common.BoskosConfig, error) {
+	config, err := common.ParseConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := common.ValidateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// NewServer creates a server holding the resources in the configuration.
+// Leased resources which are not updated by their owner within `ttl` are
+// returned to the state they were configured with.
+func NewServer(config *common.BoskosConfig, ttl time.Duration) (*Server, error) {
+	s := &Server{
+		resources: map[string]*common.Resource{},
+		initial:   map[string]string{},
+		types:     map[string][]string{},
+		queues:    map[string][]queuedRequest{},
+		reaped:    map[string]int{},
+		ttl:       ttl,
+		now:       time.Now,
+		logger:    logrus.WithField("component", "lease-server"),
+	}
+	for _, entry := range config.Resources {
+		if entry.IsDRLC() {
+			return nil, fmt.Errorf("resource type %s: dynamic resources are not supported", entry.Type)
+		}
+		for _, resource := range common.NewResourcesFromConfig(entry) {
+			resource := resource
+			if _, exists := s.resources[resource.Name]; exists {
+				return nil, fmt.Errorf("resource %s is defined more than once", resource.Name)
+			}
+			resource.LastUpdate = s.now()
+			s.resources[resource.Name] = &resource
+			s.initial[resource.Name] = resource.State
+			s.types[resource.Type] = append(s.types[resource.Type], resource.Name)
+		}
+	}
+	for _, names := range s.types {
+		sort.Strings(names)
+	}
+	return s, nil
+}
+
+// Handler serves the Boskos API, along with Prometheus metrics on /metrics.
+func (s *Server) Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(s)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/acquire", s.post(s.handleAcquire))
+	mux.HandleFunc("/acquirebystate", s.post(s.handleAcquireByState))
+	mux.HandleFunc("/release", s.post(s.handleRelease))
+	mux.HandleFunc("/update", s.post(s.handleUpdate))
+	mux.HandleFunc("/reset", s.post(s.handleReset))
+	mux.HandleFunc("/metric", s.handleMetric)
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Reap returns leases whose owner has not updated them within the TTL to
+// their initial state.
+func (s *Server) Reap() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	deadline := s.now().Add(-s.ttl)
+	for name, resource := range s.resources {
+		if resource.Owner == "" || resource.LastUpdate.After(deadline) {
+			continue
+		}
+		s.logger.WithFields(logrus.Fields{"resource": name, "owner": resource.Owner}).Info("Reaping lease whose owner stopped updating it.")
+		resource.Owner = ""
+		resource.State = s.initial[name]
+		resource.LastUpdate = s.now()
+		s.reaped[resource.Type]++
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (s *Server) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourcesDesc
+	ch <- reapedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *Server) Collect(ch chan<- prometheus.Metric) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for rtype := range s.types {
+		for state, count := range s.metric(rtype).Current {
+			ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(count), rtype, state)
+		}
+		ch <- prometheus.MustNewConstMetric(reapedDesc, prometheus.CounterValue, float64(s.reaped[rtype]), rtype)
+	}
+}
+
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// post wraps a handler for a POST endpoint, serializing its response or
+// error.
+func (s *Server) post(handler func(*http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.respond(w, r, handler)
+	}
+}
+
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, handler func(*http.Request) (interface{}, error)) {
+	s.lock.Lock()
+	response, err := handler(r)
+	var raw []byte
+	if err == nil && response != nil {
+		// marshal while holding the lock, the response may point to a resource
+		raw, err = json.Marshal(response)
+	}
+	s.lock.Unlock()
+	if err != nil {
+		code := http.StatusInternalServerError
+		if statusErr, ok := err.(*statusError); ok {
+			code = statusErr.code
+		}
+		s.logger.WithError(err).WithField("path", r.URL.Path).Debug("Request failed.")
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if raw != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(raw); err != nil {
+			s.logger.WithError(err).Warn("Failed to write response.")
+		}
+	}
+}
+
+func requireParams(r *http.Request, names ...string) ([]string, error) {
+	var values []string
+	for _, name := range names {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return nil, errorf(http.StatusBadRequest, "parameter %q is required", name)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (s *Server) handleAcquire(r *http.Request) (interface{}, error) {
+	params, err := requireParams(r, "type", "state", "dest", "owner")
+	if err != nil {
+		return nil, err
+	}
+	rtype, state, dest, owner := params[0], params[1], params[2], params[3]
+	names, ok := s.types[rtype]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "%s", common.ResourceTypeNotFoundMessage(rtype))
+	}
+	var free []*common.Resource
+	for _, name := range names {
+		if resource := s.resources[name]; resource.Owner == "" && resource.State == state {
+			free = append(free, resource)
+		}
+	}
+	if !s.admit(rtype, r.URL.Query().Get("request_id"), len(free)) {
+		return nil, errorf(http.StatusNotFound, "no resource of type %s in state %s is available", rtype, state)
+	}
+	resource := free[0]
+	resource.Owner = owner
+	resource.State = dest
+	resource.LastUpdate = s.now()
+	return resource, nil
+}
+
+// admit decides whether a request may be granted one of `free` resources.
+// Requests carrying an identifier are served in the order they first arrived
+// in, as long as they keep polling; anonymous requests only get resources no
+// queued request is waiting for.
+func (s *Server) admit(rtype, requestID string, free int) bool {
+	now := s.now()
+	var queue []queuedRequest
+	position := -1
+	for _, request := range s.queues[rtype] {
+		if request.id == requestID {
+			request.lastSeen = now
+		}
+		if now.Sub(request.lastSeen) > requestTTL {
+			continue
+		}
+		if request.id == requestID {
+			position = len(queue)
+		}
+		queue = append(queue, request)
+	}
+	if requestID != "" && position == -1 {
+		position = len(queue)
+		queue = append(queue, queuedRequest{id: requestID, lastSeen: now})
+	}
+	if requestID == "" {
+		position = len(queue)
+	}
+	admitted := position < free
+	if admitted && requestID != "" {
+		queue = append(queue[:position], queue[position+1:]...)
+	}
+	s.queues[rtype] = queue
+	return admitted
+}
+
+func (s *Server) handleAcquireByState(r *http.Request) (interface{}, error) {
+	params, err := requireParams(r, "state", "dest", "names", "owner")
+	if err != nil {
+		return nil, err
+	}
+	state, dest, names, owner := params[0], params[1], strings.Split(params[2], ","), params[3]
+	var resources []*common.Resource
+	for _, name := range names {
+		resource, ok := s.resources[name]
+		if !ok || resource.State != state {
+			return nil, errorf(http.StatusNotFound, "resource %s is not in state %s", name, state)
+		}
+		if resource.Owner != "" && resource.Owner != owner {
+			return nil, errorf(http.StatusUnauthorized, "resource %s is owned by %s", name, resource.Owner)
+		}
+		resources = append(resources, resource)
+	}
+	var acquired []common.Resource
+	for _, resource := range resources {
+		resource.Owner = owner
+		resource.State = dest
+		resource.LastUpdate = s.now()
+		acquired = append(acquired, *resource)
+	}
+	return acquired, nil
+}
+
+// owned returns the named resource if it is owned by the owner in the request.
+func (s *Server) owned(name, owner string) (*common.Resource, error) {
+	resource, ok := s.resources[name]
+	if !ok {
+		return nil, errorf(http.StatusNotFound, "resource %s does not exist", name)
+	}
+	if resource.Owner != owner {
+		return nil, errorf(http.StatusUnauthorized, "resource %s is owned by %q, not %q", name, resource.Owner, owner)
+	}
+	return resource, nil
+}
+
+func (s *Server) handleRelease(r *http.Request) (interface{}, error) {
+	params, err := requireParams(r, "name", "dest", "owner")
+	if err != nil {
+		return nil, err
+	}
+	resource, err := s.owned(params[0], params[2])
+	if err != nil {
+		return nil, err
+	}
+	resource.Owner = ""
+	resource.State = params[1]
+	resource.LastUpdate = s.now()
+	return nil, nil
+}
+
+func (s *Server) handleUpdate(r *http.Request) (interface{}, error) {
+	params, err := requireParams(r, "name", "owner", "state")
+	if err != nil {
+		return nil, err
+	}
+	resource, err := s.owned(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+	if resource.State != params[2] {
+		return nil, errorf(http.StatusConflict, "resource %s is in state %s, not %s", params[0], resource.State, params[2])
+	}
+	if r.ContentLength != 0 {
+		data := &common.UserData{}
+		if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+			return nil, errorf(http.StatusBadRequest, "invalid user data: %v", err)
+		}
+		if resource.UserData == nil {
+			resource.UserData = &common.UserData{}
+		}
+		resource.UserData.Update(data)
+	}
+	resource.LastUpdate = s.now()
+	return nil, nil
+}
+
+func (s *Server) handleReset(r *http.Request) (interface{}, error) {
+	params, err := requireParams(r, "type", "state", "expire", "dest")
+	if err != nil {
+		return nil, err
+	}
+	expire, err := time.ParseDuration(params[2])
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid expiration: %v", err)
+	}
+	deadline := s.now().Add(-expire)
+	reset := map[string]string{}
+	for _, name := range s.types[params[0]] {
+		resource := s.resources[name]
+		if resource.State != params[1] || resource.Owner == "" || resource.LastUpdate.After(deadline) {
+			continue
+		}
+		reset[name] = resource.Owner
+		resource.Owner = ""
+		resource.State = params[3]
+		resource.LastUpdate = s.now()
+	}
+	return reset, nil
+}
+
+func (s *Server) handleMetric(w http.ResponseWriter, r *http.Request) {
+	s.respond(w, r, func(r *http.Request) (interface{}, error) {
+		params, err := requireParams(r, "type")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s.types[params[0]]; !ok {
+			return nil, errorf(http.StatusNotFound, "%s", common.ResourceTypeNotFoundMessage(params[0]))
+		}
+		return s.metric(params[0]), nil
+	})
+}
+
+func (s *Server) metric(rtype string) common.Metric {
+	metric := common.NewMetric(rtype)
+	for _, name := range s.types[rtype] {
+		resource := s.resources[name]
+		metric.Current[resource.State]++
+		if resource.Owner != "" {
+			metric.Owners[resource.Owner]++
+		}
+	}
+	return metric
+}
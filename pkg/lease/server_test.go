@@ -0,0 +1,164 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	boskos "sigs.k8s.io/boskos/client"
+	"sigs.k8s.io/boskos/common"
+)
+
+func newTestServer(t *testing.T, now *time.Time) (*Server, string) {
+	server, err := NewServer(&common.BoskosConfig{Resources: []common.ResourceEntry{
+		{Type: "aws-quota-slice", State: freeState, Names: []string{"aws-1", "aws-0"}},
+		{Type: "gcp-quota-slice", State: freeState, Names: []string{"gcp-0"}},
+	}}, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	server.now = func() time.Time { return *now }
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return server, httpServer.URL
+}
+
+func newBoskosClient(t *testing.T, owner, url string) *boskos.Client {
+	client, err := boskos.NewClientWithPasswordGetter(owner, url, "", nil)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.DistinguishNotFoundVsTypeNotFound = true
+	return client
+}
+
+func TestServerWithClient(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, url := newTestServer(t, &now)
+	client, err := NewClient("owner", url, "user", func() []byte { return []byte("pass") }, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	names, err := client.AcquireAll([]Request{{ResourceType: "aws-quota-slice", Count: 2}, {ResourceType: "gcp-quota-slice", Count: 1}}, ctx, cancel)
+	if err != nil {
+		t.Fatalf("failed to acquire leases: %v", err)
+	}
+	if diff := cmp.Diff([][]string{{"aws-0", "aws-1"}, {"gcp-0"}}, names); diff != "" {
+		t.Errorf("unexpected leases: %s", diff)
+	}
+	if err := client.Heartbeat(); err != nil {
+		t.Errorf("failed to update leases: %v", err)
+	}
+	metrics, err := client.Metrics("aws-quota-slice")
+	if err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	if diff := cmp.Diff(Metrics{Leased: 2}, metrics); diff != "" {
+		t.Errorf("unexpected metrics: %s", diff)
+	}
+	if _, err := client.ReleaseAll(); err != nil {
+		t.Fatalf("failed to release leases: %v", err)
+	}
+	if metrics, err = client.Metrics("aws-quota-slice"); err != nil {
+		t.Fatalf("failed to get metrics: %v", err)
+	}
+	if diff := cmp.Diff(Metrics{Free: 2}, metrics); diff != "" {
+		t.Errorf("unexpected metrics after release: %s", diff)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, url := newTestServer(t, &now)
+	client, other := newBoskosClient(t, "owner", url), newBoskosClient(t, "other", url)
+	if _, err := client.Acquire("unknown", freeState, leasedState); !errors.Is(err, boskos.ErrTypeNotFound) {
+		t.Errorf("expected type not found for an unknown type, got %v", err)
+	}
+	if _, err := client.Acquire("gcp-quota-slice", freeState, leasedState); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+	if _, err := other.Acquire("gcp-quota-slice", freeState, leasedState); !errors.Is(err, boskos.ErrNotFound) {
+		t.Errorf("expected not found when no resource is free, got %v", err)
+	}
+	// the client retries failed releases, so talk to the server directly
+	resp, err := http.Post(url+"/release?name=gcp-0&dest=free&owner=other", "", nil)
+	if err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected releasing a resource of another owner to be unauthorized, got %s", resp.Status)
+	}
+}
+
+func TestServerQueue(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, url := newTestServer(t, &now)
+	client, first, second := newBoskosClient(t, "owner", url), newBoskosClient(t, "first", url), newBoskosClient(t, "second", url)
+	if _, err := client.Acquire("gcp-quota-slice", freeState, leasedState); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+	for _, request := range []struct {
+		id     string
+		client *boskos.Client
+	}{{id: "first", client: first}, {id: "second", client: second}} {
+		if _, err := request.client.AcquireWithPriority("gcp-quota-slice", freeState, leasedState, request.id); !errors.Is(err, boskos.ErrNotFound) {
+			t.Fatalf("expected the %s request to be queued, got %v", request.id, err)
+		}
+	}
+	if err := client.ReleaseOne("gcp-0", freeState); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if _, err := second.AcquireWithPriority("gcp-quota-slice", freeState, leasedState, "second"); !errors.Is(err, boskos.ErrNotFound) {
+		t.Errorf("expected the second request to wait for the first, got %v", err)
+	}
+	if _, err := client.Acquire("gcp-quota-slice", freeState, leasedState); !errors.Is(err, boskos.ErrNotFound) {
+		t.Errorf("expected an anonymous request to wait for queued ones, got %v", err)
+	}
+	if _, err := first.AcquireWithPriority("gcp-quota-slice", freeState, leasedState, "first"); err != nil {
+		t.Errorf("expected the first request to be served, got %v", err)
+	}
+	if err := first.ReleaseOne("gcp-0", freeState); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := client.Acquire("gcp-quota-slice", freeState, leasedState); err != nil {
+		t.Errorf("expected requests that stopped polling to be dropped, got %v", err)
+	}
+}
+
+func TestServerReap(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	server, url := newTestServer(t, &now)
+	client := newBoskosClient(t, "owner", url)
+	for i := 0; i < 2; i++ {
+		if _, err := client.Acquire("aws-quota-slice", freeState, leasedState); err != nil {
+			t.Fatalf("failed to acquire: %v", err)
+		}
+	}
+	now = now.Add(45 * time.Second)
+	if err := client.UpdateOne("aws-0", leasedState, nil); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	now = now.Add(30 * time.Second)
+	server.Reap()
+	metric, err := client.Metric("aws-quota-slice")
+	if err != nil {
+		t.Fatalf("failed to get metric: %v", err)
+	}
+	expected := common.Metric{
+		Type:    "aws-quota-slice",
+		Current: map[string]int{freeState: 1, leasedState: 1},
+		Owners:  map[string]int{"owner": 1},
+	}
+	if diff := cmp.Diff(expected, metric); diff != "" {
+		t.Errorf("unexpected metric after reaping: %s", diff)
+	}
+}
@@ -21,9 +21,22 @@ type RegistryAgent interface {
 	ResolveConfig(config api.ReleaseBuildConfiguration) (api.ReleaseBuildConfiguration, error)
 	GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata)
 	GetGeneration() int
+	// GetHistory returns the retained snapshots of the registry, oldest
+	// first. The last snapshot is the registry currently in use.
+	GetHistory() []RegistrySnapshot
 	registry.Resolver
 }
 
+// RegistrySnapshot is the content of the registry at one generation
+type RegistrySnapshot struct {
+	Generation int
+	Loaded     time.Time
+	References registry.ReferenceByName
+	Chains     registry.ChainByName
+	Workflows  registry.WorkflowByName
+	Resolver   registry.Resolver
+}
+
 type registryAgent struct {
 	lock          *sync.RWMutex
 	resolver      registry.Resolver
@@ -36,6 +49,12 @@ type registryAgent struct {
 	workflows     registry.WorkflowByName
 	documentation map[string]string
 	metadata      api.RegistryMetadata
+	historySize   int
+	historyDir    string
+	history       []RegistrySnapshot
+	// restored is set when the history was read from historyDir and
+	// the registry has not been loaded since
+	restored bool
 }
 
 var registryReloadTimeMetric = prometheus.NewHistogram(
@@ -58,6 +77,13 @@ type RegistryAgentOptions struct {
 	// from the filepath. Defaults to true.
 	FlatRegistry            *bool
 	UniversalSymlinkWatcher *UniversalSymlinkWatcher
+	// HistorySize is how many snapshots of the registry are retained,
+	// including the current one. Defaults to only the current one.
+	HistorySize int
+	// HistoryDir is where the retained snapshots are persisted, so
+	// that the history survives restarts. If unset, the history is
+	// only kept in memory and starts over with every process.
+	HistoryDir string
 }
 
 type RegistryAgentOption func(*RegistryAgentOptions)
@@ -74,6 +100,19 @@ func WithRegistryFlat(v bool) RegistryAgentOption {
 	}
 }
 
+func WithRegistryHistory(size int) RegistryAgentOption {
+	return func(o *RegistryAgentOptions) {
+		o.HistorySize = size
+	}
+}
+
+// WithRegistryHistoryDir persists the retained snapshots in dir
+func WithRegistryHistoryDir(dir string) RegistryAgentOption {
+	return func(o *RegistryAgentOptions) {
+		o.HistoryDir = dir
+	}
+}
+
 // NewRegistryAgent returns a RegistryAgent interface that automatically reloads when
 // the registry is changed on disk.
 func NewRegistryAgent(registryPath string, errCh chan error, opts ...RegistryAgentOption) (RegistryAgent, error) {
@@ -87,6 +126,9 @@ func NewRegistryAgent(registryPath string, errCh chan error, opts ...RegistryAge
 	if opt.FlatRegistry == nil {
 		opt.FlatRegistry = utilpointer.Bool(true)
 	}
+	if opt.HistorySize < 1 {
+		opt.HistorySize = 1
+	}
 	flags := load.RegistryMetadata | load.RegistryDocumentation
 	if *opt.FlatRegistry {
 		flags |= load.RegistryFlat
@@ -96,6 +138,13 @@ func NewRegistryAgent(registryPath string, errCh chan error, opts ...RegistryAge
 		lock:         &sync.RWMutex{},
 		errorMetrics: opt.ErrorMetric,
 		flags:        flags,
+		historySize:  opt.HistorySize,
+		historyDir:   opt.HistoryDir,
+	}
+	if a.historyDir != "" {
+		if err := a.restoreHistory(); err != nil {
+			return nil, fmt.Errorf("failed to restore registry history: %w", err)
+		}
 	}
 	// Load config once so we fail early if that doesn't work and are ready as soon as we return
 	if err := a.loadRegistry(); err != nil {
@@ -134,6 +183,12 @@ func (a *registryAgent) GetGeneration() int {
 	return a.generation
 }
 
+func (a *registryAgent) GetHistory() []RegistrySnapshot {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return append([]RegistrySnapshot(nil), a.history...)
+}
+
 func (a *registryAgent) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
 	return a.references, a.chains, a.workflows, a.documentation, a.metadata
 }
@@ -155,7 +210,35 @@ func (a *registryAgent) loadRegistry() error {
 		a.documentation = documentation
 		a.metadata = metadata
 		a.resolver = registry.NewResolver(references, chains, workflows, observers)
+		restored := a.restored
+		a.restored = false
+		if restored && len(a.history) > 0 && a.history[len(a.history)-1].sameContent(references, chains, workflows) {
+			// a restart does not make a new generation of the registry
+			a.history[len(a.history)-1].Resolver = a.resolver
+			return time.Since(startTime), nil
+		}
 		a.generation++
+		snapshot := RegistrySnapshot{
+			Generation: a.generation,
+			Loaded:     startTime,
+			References: references,
+			Chains:     chains,
+			Workflows:  workflows,
+			Resolver:   a.resolver,
+		}
+		a.history = append(a.history, snapshot)
+		var dropped []RegistrySnapshot
+		if overflow := len(a.history) - a.historySize; overflow > 0 {
+			dropped = a.history[:overflow]
+			a.history = append([]RegistrySnapshot(nil), a.history[overflow:]...)
+		}
+		if a.historyDir != "" {
+			if err := persistSnapshot(a.historyDir, snapshot, observers, dropped); err != nil {
+				// the history is a convenience, the registry must be served regardless
+				recordErrorForMetric(a.errorMetrics, "failed to persist registry history")
+				logrus.WithError(err).Warn("Failed to persist registry history, it will not survive a restart.")
+			}
+		}
 		return time.Since(startTime), nil
 	}()
 	if err != nil {
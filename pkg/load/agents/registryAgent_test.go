@@ -0,0 +1,135 @@
+package agents
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openshift/ci-tools/pkg/load"
+)
+
+func writeRegistryStep(t *testing.T, dir, commands string) {
+	t.Helper()
+	stepDir := filepath.Join(dir, "step")
+	if err := os.MkdirAll(stepDir, 0755); err != nil {
+		t.Fatalf("failed to create step directory: %v", err)
+	}
+	ref := "ref:\n  as: step\n  from: src\n  commands: step-commands.sh\n  resources:\n    requests:\n      cpu: 100m\n"
+	if err := os.WriteFile(filepath.Join(stepDir, "step-ref.yaml"), []byte(ref), 0644); err != nil {
+		t.Fatalf("failed to write step: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(stepDir, "step-commands.sh"), []byte(commands), 0644); err != nil {
+		t.Fatalf("failed to write commands: %v", err)
+	}
+}
+
+func TestRegistryAgentHistory(t *testing.T) {
+	dir := t.TempDir()
+	writeStep := func(commands string) { writeRegistryStep(t, dir, commands) }
+	agent := &registryAgent{
+		lock:         &sync.RWMutex{},
+		registryPath: dir,
+		errorMetrics: prometheus.NewCounterVec(prometheus.CounterOpts{}, []string{"error"}),
+		flags:        load.RegistryMetadata | load.RegistryDocumentation,
+		historySize:  2,
+	}
+	for i := 1; i <= 3; i++ {
+		writeStep(fmt.Sprintf("echo %d", i))
+		if err := agent.loadRegistry(); err != nil {
+			t.Fatalf("failed to load registry: %v", err)
+		}
+	}
+
+	history := agent.GetHistory()
+	var generations []int
+	var commands []string
+	for _, snapshot := range history {
+		generations = append(generations, snapshot.Generation)
+		commands = append(commands, snapshot.References["step"].Commands)
+	}
+	if diff := cmp.Diff([]int{2, 3}, generations); diff != "" {
+		t.Errorf("unexpected generations retained: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"echo 2", "echo 3"}, commands); diff != "" {
+		t.Errorf("unexpected snapshots retained: %s", diff)
+	}
+}
+
+func TestRegistryAgentHistoryIsRestored(t *testing.T) {
+	dir := t.TempDir()
+	historyDir := filepath.Join(t.TempDir(), "history")
+	writeStep := func(commands string) { writeRegistryStep(t, dir, commands) }
+	start := func() *registryAgent {
+		agent := &registryAgent{
+			lock:         &sync.RWMutex{},
+			registryPath: dir,
+			errorMetrics: prometheus.NewCounterVec(prometheus.CounterOpts{}, []string{"error"}),
+			flags:        load.RegistryMetadata | load.RegistryDocumentation,
+			historySize:  2,
+			historyDir:   historyDir,
+		}
+		if err := agent.restoreHistory(); err != nil {
+			t.Fatalf("failed to restore history: %v", err)
+		}
+		if err := agent.loadRegistry(); err != nil {
+			t.Fatalf("failed to load registry: %v", err)
+		}
+		return agent
+	}
+	summarize := func(agent *registryAgent) ([]int, []string) {
+		var generations []int
+		var commands []string
+		for _, snapshot := range agent.GetHistory() {
+			generations = append(generations, snapshot.Generation)
+			commands = append(commands, snapshot.References["step"].Commands)
+		}
+		return generations, commands
+	}
+
+	writeStep("echo 1")
+	agent := start()
+	for i := 2; i <= 3; i++ {
+		writeStep(fmt.Sprintf("echo %d", i))
+		if err := agent.loadRegistry(); err != nil {
+			t.Fatalf("failed to load registry: %v", err)
+		}
+	}
+
+	restarted := start()
+	generations, commands := summarize(restarted)
+	if diff := cmp.Diff([]int{2, 3}, generations); diff != "" {
+		t.Errorf("unexpected generations after a restart without changes: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"echo 2", "echo 3"}, commands); diff != "" {
+		t.Errorf("unexpected snapshots after a restart without changes: %s", diff)
+	}
+	if _, err := restarted.GetHistory()[0].Resolver.ResolveChain("missing"); err == nil {
+		t.Errorf("expected the restored resolver to fail to resolve a missing chain")
+	}
+
+	writeStep("echo 4")
+	restarted = start()
+	generations, commands = summarize(restarted)
+	if diff := cmp.Diff([]int{3, 4}, generations); diff != "" {
+		t.Errorf("unexpected generations after a restart with changes: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"echo 3", "echo 4"}, commands); diff != "" {
+		t.Errorf("unexpected snapshots after a restart with changes: %s", diff)
+	}
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		t.Fatalf("failed to list history directory: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	if diff := cmp.Diff([]string{"3.json", "4.json"}, files); diff != "" {
+		t.Errorf("unexpected snapshots persisted: %s", diff)
+	}
+}
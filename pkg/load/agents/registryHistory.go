@@ -0,0 +1,149 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// persistedSnapshot is how a RegistrySnapshot is stored in the history
+// directory. The observers are stored so that the resolver can be rebuilt.
+type persistedSnapshot struct {
+	Generation int                      `json:"generation"`
+	Loaded     time.Time                `json:"loaded"`
+	References registry.ReferenceByName `json:"references"`
+	Chains     registry.ChainByName     `json:"chains"`
+	Workflows  registry.WorkflowByName  `json:"workflows"`
+	Observers  registry.ObserverByName  `json:"observers"`
+}
+
+const snapshotSuffix = ".json"
+
+func snapshotPath(dir string, generation int) string {
+	return filepath.Join(dir, strconv.Itoa(generation)+snapshotSuffix)
+}
+
+// sameContent compares the serialized content, as a restored snapshot does not
+// keep the distinction between empty and unset fields
+func (s RegistrySnapshot) sameContent(references registry.ReferenceByName, chains registry.ChainByName, workflows registry.WorkflowByName) bool {
+	for _, pair := range [][2]interface{}{{s.References, references}, {s.Chains, chains}, {s.Workflows, workflows}} {
+		current, err := json.Marshal(pair[0])
+		if err != nil {
+			return false
+		}
+		loaded, err := json.Marshal(pair[1])
+		if err != nil {
+			return false
+		}
+		if !bytes.Equal(current, loaded) {
+			return false
+		}
+	}
+	return true
+}
+
+// persistSnapshot writes the snapshot to dir and removes the snapshots that
+// are no longer retained
+func persistSnapshot(dir string, snapshot RegistrySnapshot, observers registry.ObserverByName, dropped []RegistrySnapshot) error {
+	raw, err := json.Marshal(persistedSnapshot{
+		Generation: snapshot.Generation,
+		Loaded:     snapshot.Loaded,
+		References: snapshot.References,
+		Chains:     snapshot.Chains,
+		Workflows:  snapshot.Workflows,
+		Observers:  observers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	// write to a temporary file first so a crash never leaves a partial snapshot behind
+	tmp, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), snapshotPath(dir, snapshot.Generation)); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	for _, old := range dropped {
+		if err := os.Remove(snapshotPath(dir, old.Generation)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove snapshot of generation %d: %w", old.Generation, err)
+		}
+	}
+	return nil
+}
+
+// restoreHistory reads the snapshots persisted in the history directory and
+// continues the generations from the newest one. Snapshots that cannot be
+// read are skipped, as are the ones beyond the retention.
+func (a *registryAgent) restoreHistory() error {
+	if err := os.MkdirAll(a.historyDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	entries, err := os.ReadDir(a.historyDir)
+	if err != nil {
+		return fmt.Errorf("failed to list history directory: %w", err)
+	}
+	var generations []int
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotSuffix) {
+			continue
+		}
+		generation, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), snapshotSuffix))
+		if err != nil {
+			continue
+		}
+		generations = append(generations, generation)
+	}
+	sort.Ints(generations)
+	if overflow := len(generations) - a.historySize; overflow > 0 {
+		for _, generation := range generations[:overflow] {
+			if err := os.Remove(snapshotPath(a.historyDir, generation)); err != nil && !os.IsNotExist(err) {
+				logrus.WithError(err).WithField("generation", generation).Warn("Failed to remove registry snapshot beyond the retention.")
+			}
+		}
+		generations = generations[overflow:]
+	}
+	for _, generation := range generations {
+		logger := logrus.WithField("generation", generation)
+		raw, err := os.ReadFile(snapshotPath(a.historyDir, generation))
+		if err != nil {
+			logger.WithError(err).Warn("Failed to read registry snapshot, skipping it.")
+			continue
+		}
+		var persisted persistedSnapshot
+		if err := json.Unmarshal(raw, &persisted); err != nil {
+			logger.WithError(err).Warn("Failed to unmarshal registry snapshot, skipping it.")
+			continue
+		}
+		a.history = append(a.history, RegistrySnapshot{
+			Generation: persisted.Generation,
+			Loaded:     persisted.Loaded,
+			References: persisted.References,
+			Chains:     persisted.Chains,
+			Workflows:  persisted.Workflows,
+			Resolver:   registry.NewResolver(persisted.References, persisted.Chains, persisted.Workflows, persisted.Observers),
+		})
+		a.generation = persisted.Generation
+	}
+	a.restored = true
+	logrus.WithField("snapshots", len(a.history)).Info("Restored registry history.")
+	return nil
+}
@@ -0,0 +1,143 @@
+package promotion
+
+import (
+	"fmt"
+	"sort"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+)
+
+// SortRecords sorts promotion records from the newest to the oldest
+func SortRecords(records []ledgerv1.PromotionRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].Spec.Time.Equal(&records[j].Spec.Time) {
+			return records[j].Spec.Time.Before(&records[i].Spec.Time)
+		}
+		return records[i].Name > records[j].Name
+	})
+}
+
+// HistoryEntry is a record that pointed an image stream tag to an image
+type HistoryEntry struct {
+	Record *ledgerv1.PromotionRecord
+	Tag    ledgerv1.PromotedTag
+}
+
+// History returns the records that pointed the image stream tag to an image,
+// from the newest to the oldest. The records must be sorted with SortRecords.
+func History(records []ledgerv1.PromotionRecord, tag cioperatorapi.ImageStreamTagReference) []HistoryEntry {
+	var history []HistoryEntry
+	for i := range records {
+		if promoted := records[i].Tag(tag.Namespace, tag.Name, tag.Tag); promoted != nil {
+			history = append(history, HistoryEntry{Record: &records[i], Tag: *promoted})
+		}
+	}
+	return history
+}
+
+// RollbackOptions select the tags to roll back and what to roll them back to
+type RollbackOptions struct {
+	// Tags are the image stream tags to roll back. If To is not set, each of
+	// them is rolled back to the image it pointed to before the current one,
+	// skipping images that were replaced by earlier rollbacks.
+	Tags []cioperatorapi.ImageStreamTagReference
+	// To is the name of the record to roll back to. All tags of the record are
+	// rolled back to the images in it, unless Tags limits them.
+	To string
+}
+
+// PlanRollback determines the images the tags need to be pointed to for the
+// rollback. The current digests of the tags are keyed by their namespace/name:tag.
+// Tags that already point to the image they would be rolled back to are omitted.
+// The records must be sorted with SortRecords.
+func PlanRollback(records []ledgerv1.PromotionRecord, current map[string]string, options RollbackOptions) ([]ledgerv1.PromotedTag, error) {
+	var plan []ledgerv1.PromotedTag
+	add := func(tag ledgerv1.PromotedTag, record string) {
+		previous := current[tag.ISTagName()]
+		if previous == tag.Digest {
+			return
+		}
+		plan = append(plan, ledgerv1.PromotedTag{
+			Namespace:    tag.Namespace,
+			Name:         tag.Name,
+			Tag:          tag.Tag,
+			Digest:       tag.Digest,
+			Previous:     previous,
+			RolledBackTo: record,
+			QuayImage:    tag.QuayImage,
+		})
+	}
+
+	if options.To != "" {
+		var to *ledgerv1.PromotionRecord
+		for i := range records {
+			if records[i].Name == options.To {
+				to = &records[i]
+				break
+			}
+		}
+		if to == nil {
+			return nil, fmt.Errorf("record %s not found", options.To)
+		}
+		if len(options.Tags) == 0 {
+			for _, tag := range to.Spec.Tags {
+				add(tag, to.Name)
+			}
+			return plan, nil
+		}
+		for _, tag := range options.Tags {
+			promoted := to.Tag(tag.Namespace, tag.Name, tag.Tag)
+			if promoted == nil {
+				return nil, fmt.Errorf("record %s did not promote %s", to.Name, tag.ISTagName())
+			}
+			add(*promoted, to.Name)
+		}
+		return plan, nil
+	}
+
+	if len(options.Tags) == 0 {
+		return nil, fmt.Errorf("either the tags or the record to roll back to must be set")
+	}
+	for _, tag := range options.Tags {
+		history := History(records, tag)
+		digest, found := current[tag.ISTagName()]
+		if !found {
+			return nil, fmt.Errorf("%s does not exist, the record to roll it back to must be set", tag.ISTagName())
+		}
+		// rollbacks only re-point tags to images of earlier promotions and the
+		// images they replaced are not rolled back to again, so the image in
+		// effect is that of the newest promotion that pointed the tag to it
+		rolledBack := map[string]bool{}
+		inEffect := -1
+		for i, entry := range history {
+			if entry.Record.Spec.Rollback != nil {
+				rolledBack[entry.Tag.Previous] = true
+				continue
+			}
+			if entry.Tag.Digest == digest {
+				inEffect = i
+				break
+			}
+		}
+		if inEffect == -1 {
+			return nil, fmt.Errorf("%s points to %s which is not in the promotion ledger, the record to roll it back to must be set", tag.ISTagName(), digest)
+		}
+		var target *HistoryEntry
+		for i := inEffect + 1; i < len(history); i++ {
+			if history[i].Record.Spec.Rollback != nil {
+				rolledBack[history[i].Tag.Previous] = true
+				continue
+			}
+			if history[i].Tag.Digest != digest && !rolledBack[history[i].Tag.Digest] {
+				target = &history[i]
+				break
+			}
+		}
+		if target == nil {
+			return nil, fmt.Errorf("%s was never pointed to an image before %s by a recorded promotion", tag.ISTagName(), digest)
+		}
+		add(target.Tag, target.Record.Name)
+	}
+	return plan, nil
+}
@@ -0,0 +1,134 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
+)
+
+func TestPlanRollback(t *testing.T) {
+	start := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	record := func(name string, hours int, tags ...ledgerv1.PromotedTag) ledgerv1.PromotionRecord {
+		return ledgerv1.PromotionRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       ledgerv1.PromotionRecordSpec{Time: metav1.NewTime(start.Add(time.Duration(hours) * time.Hour)), Tags: tags},
+		}
+	}
+	tag := func(name, digest string) ledgerv1.PromotedTag {
+		return ledgerv1.PromotedTag{Namespace: "ocp", Name: "4.14", Tag: name, Digest: digest}
+	}
+	ref := func(name string) cioperatorapi.ImageStreamTagReference {
+		return cioperatorapi.ImageStreamTagReference{Namespace: "ocp", Name: "4.14", Tag: name}
+	}
+	records := []ledgerv1.PromotionRecord{
+		record("first", 0, tag("cli", "sha256:cli-1"), tag("tests", "sha256:tests-1")),
+		record("third", 2, tag("cli", "sha256:cli-2"), tag("tests", "sha256:tests-3")),
+		record("second", 1, tag("cli", "sha256:cli-2"), tag("tests", "sha256:tests-2")),
+	}
+	SortRecords(records)
+	rollback := func(name string, hours int, tags ...ledgerv1.PromotedTag) ledgerv1.PromotionRecord {
+		r := record(name, hours, tags...)
+		r.Spec.Rollback = &ledgerv1.PromotionRollback{User: "developer", Reason: "broken"}
+		return r
+	}
+	rolledBack := func(name, digest, previous, to string) ledgerv1.PromotedTag {
+		t := tag(name, digest)
+		t.Previous, t.RolledBackTo = previous, to
+		return t
+	}
+	withRollbacks := []ledgerv1.PromotionRecord{
+		record("zeroth", -1, tag("cli", "sha256:cli-0")),
+		records[2], records[1], records[0],
+		record("fourth", 3, tag("cli", "sha256:cli-3")),
+		rollback("rollback-1", 4, rolledBack("cli", "sha256:cli-2", "sha256:cli-3", "third")),
+	}
+	SortRecords(withRollbacks)
+
+	for _, tc := range []struct {
+		name        string
+		records     []ledgerv1.PromotionRecord
+		current     map[string]string
+		options     RollbackOptions
+		expected    []ledgerv1.PromotedTag
+		expectedErr string
+	}{{
+		name:    "tag is rolled back to the previous different image",
+		current: map[string]string{"ocp/4.14:cli": "sha256:cli-2", "ocp/4.14:tests": "sha256:tests-3"},
+		options: RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli"), ref("tests")}},
+		expected: []ledgerv1.PromotedTag{
+			{Namespace: "ocp", Name: "4.14", Tag: "cli", Digest: "sha256:cli-1", Previous: "sha256:cli-2", RolledBackTo: "first"},
+			{Namespace: "ocp", Name: "4.14", Tag: "tests", Digest: "sha256:tests-2", Previous: "sha256:tests-3", RolledBackTo: "second"},
+		},
+	}, {
+		name:    "all tags of a record",
+		current: map[string]string{"ocp/4.14:cli": "sha256:cli-2", "ocp/4.14:tests": "sha256:tests-3"},
+		options: RollbackOptions{To: "second"},
+		expected: []ledgerv1.PromotedTag{
+			{Namespace: "ocp", Name: "4.14", Tag: "tests", Digest: "sha256:tests-2", Previous: "sha256:tests-3", RolledBackTo: "second"},
+		},
+	}, {
+		name:    "some tags of a record, including a deleted one",
+		current: map[string]string{"ocp/4.14:tests": "sha256:tests-3"},
+		options: RollbackOptions{To: "first", Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expected: []ledgerv1.PromotedTag{
+			{Namespace: "ocp", Name: "4.14", Tag: "cli", Digest: "sha256:cli-1", RolledBackTo: "first"},
+		},
+	}, {
+		name:        "tag not promoted by the record",
+		options:     RollbackOptions{To: "first", Tags: []cioperatorapi.ImageStreamTagReference{ref("installer")}},
+		expectedErr: "record first did not promote ocp/4.14:installer",
+	}, {
+		name:        "unknown record",
+		options:     RollbackOptions{To: "fourth"},
+		expectedErr: "record fourth not found",
+	}, {
+		name:        "current image not in the ledger",
+		current:     map[string]string{"ocp/4.14:cli": "sha256:manual"},
+		options:     RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expectedErr: "ocp/4.14:cli points to sha256:manual which is not in the promotion ledger, the record to roll it back to must be set",
+	}, {
+		name:        "no earlier image",
+		current:     map[string]string{"ocp/4.14:cli": "sha256:cli-1"},
+		options:     RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expectedErr: "ocp/4.14:cli was never pointed to an image before sha256:cli-1 by a recorded promotion",
+	}, {
+		name:        "deleted tag without a record",
+		options:     RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expectedErr: "ocp/4.14:cli does not exist, the record to roll it back to must be set",
+	}, {
+		name:     "rollback after a rollback goes further back",
+		records:  withRollbacks,
+		current:  map[string]string{"ocp/4.14:cli": "sha256:cli-2"},
+		options:  RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expected: []ledgerv1.PromotedTag{rolledBack("cli", "sha256:cli-1", "sha256:cli-2", "first")},
+	}, {
+		name:     "images replaced by a rollback are skipped",
+		records:  append([]ledgerv1.PromotionRecord{record("fifth", 5, tag("cli", "sha256:cli-4"))}, withRollbacks...),
+		current:  map[string]string{"ocp/4.14:cli": "sha256:cli-4"},
+		options:  RollbackOptions{Tags: []cioperatorapi.ImageStreamTagReference{ref("cli")}},
+		expected: []ledgerv1.PromotedTag{rolledBack("cli", "sha256:cli-2", "sha256:cli-4", "third")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.records == nil {
+				tc.records = records
+			}
+			actual, err := PlanRollback(tc.records, tc.current, tc.options)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected plan: %s", diff)
+			}
+		})
+	}
+}
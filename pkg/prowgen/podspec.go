@@ -611,10 +611,16 @@ func InjectTestFrom(source *cioperatorapi.MetadataWithTest) PodSpecMutator {
 // Build produces and returns a new `PodSpec` containing all configured elements
 func (c *ciOperatorPodSpecGenerator) Build() (*corev1.PodSpec, error) {
 	spec := defaultPodSpec.DeepCopy()
-	container := &spec.Containers[0]
-
 	c.buildErrors = append(c.buildErrors, aggregateMutator(c.mutators...)(spec))
+	sortPodSpec(spec)
+
+	return spec, kerrors.NewAggregate(c.buildErrors)
+}
 
+// sortPodSpec sorts the list fields of the ci-operator container and its pod
+// by their keys, so that the order in which mutators ran does not matter
+func sortPodSpec(spec *corev1.PodSpec) {
+	container := &spec.Containers[0]
 	sort.Slice(spec.Volumes, func(i, j int) bool {
 		return spec.Volumes[i].Name < spec.Volumes[j].Name
 	})
@@ -635,8 +641,6 @@ func (c *ciOperatorPodSpecGenerator) Build() (*corev1.PodSpec, error) {
 	if canSortArgs {
 		sort.Strings(container.Args)
 	}
-
-	return spec, kerrors.NewAggregate(c.buildErrors)
 }
 
 // MustBuild produces and returns a new `PodSpec` containing all configured elements
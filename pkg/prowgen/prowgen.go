@@ -50,6 +50,7 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 	for _, element := range configSpec.Tests {
 		g := NewProwJobBaseBuilderForTest(configSpec, info, NewCiOperatorPodSpecGenerator(), element)
 		disableRehearsal := rehearsals.DisableAll || disabledRehearsals.Has(element.As)
+		clusterProfile := element.GetClusterProfile()
 
 		if element.IsPeriodic() {
 			cron := ""
@@ -71,6 +72,9 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 				options.ReleaseController = element.ReleaseController
 				options.DisableRehearsal = disableRehearsal
 			})
+			if err := applyPodSpecRules(info, element.As, clusterProfile, &periodic.JobBase); err != nil {
+				return nil, err
+			}
 			periodics = append(periodics, *periodic)
 		} else if element.Postsubmit {
 			postsubmit := generatePostsubmitForTest(g, info, func(options *generatePostsubmitOptions) {
@@ -78,6 +82,9 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 				options.skipIfOnlyChanged = element.SkipIfOnlyChanged
 			})
 			postsubmit.MaxConcurrency = 1
+			if err := applyPodSpecRules(info, element.As, clusterProfile, &postsubmit.JobBase); err != nil {
+				return nil, err
+			}
 			postsubmits[orgrepo] = append(postsubmits[orgrepo], *postsubmit)
 		} else {
 			presubmit := generatePresubmitForTest(g, element.As, info, func(options *generatePresubmitOptions) {
@@ -92,6 +99,9 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 			if requestingKVM {
 				presubmit.Labels[cioperatorapi.KVMDeviceLabel] = v
 			}
+			if err := applyPodSpecRules(info, element.As, clusterProfile, &presubmit.JobBase); err != nil {
+				return nil, err
+			}
 			presubmits[orgrepo] = append(presubmits[orgrepo], *presubmit)
 		}
 	}
@@ -110,7 +120,11 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 		}
 		jobBaseGen := newJobBaseBuilder().TestName("images")
 		jobBaseGen.PodSpec.Add(Targets(presubmitTargets...))
-		presubmits[orgrepo] = append(presubmits[orgrepo], *generatePresubmitForTest(jobBaseGen, "images", info))
+		presubmit := generatePresubmitForTest(jobBaseGen, "images", info)
+		if err := applyPodSpecRules(info, "images", "", &presubmit.JobBase); err != nil {
+			return nil, err
+		}
+		presubmits[orgrepo] = append(presubmits[orgrepo], *presubmit)
 
 		if configSpec.PromotionConfiguration != nil {
 			postsubmitsForPromotion, err := generatePostsubmitsForPromotion(newJobBaseBuilderForPromotion, info, func(options *generatePostsubmitOptions) {
@@ -140,15 +154,23 @@ func GenerateJobs(configSpec *cioperatorapi.ReleaseBuildConfiguration, info *Pro
 			} else {
 				jobBaseGen.PodSpec.Add(Targets(testName))
 			}
-			presubmits[orgrepo] = append(presubmits[orgrepo], *generatePresubmitForTest(jobBaseGen, testName, info, func(options *generatePresubmitOptions) {
+			presubmit := generatePresubmitForTest(jobBaseGen, testName, info, func(options *generatePresubmitOptions) {
 				options.optional = bundle.Optional
-			}))
+			})
+			if err := applyPodSpecRules(info, testName, "", &presubmit.JobBase); err != nil {
+				return nil, err
+			}
+			presubmits[orgrepo] = append(presubmits[orgrepo], *presubmit)
 		}
 		if containsUnnamedBundle {
 			name := string(api.PipelineImageStreamTagReferenceIndexImage)
 			jobBaseGen := newJobBaseBuilder().TestName(name)
 			jobBaseGen.PodSpec.Add(Targets(name))
-			presubmits[orgrepo] = append(presubmits[orgrepo], *generatePresubmitForTest(jobBaseGen, name, info))
+			presubmit := generatePresubmitForTest(jobBaseGen, name, info)
+			if err := applyPodSpecRules(info, name, "", &presubmit.JobBase); err != nil {
+				return nil, err
+			}
+			presubmits[orgrepo] = append(presubmits[orgrepo], *presubmit)
 		}
 	}
 
@@ -272,15 +294,16 @@ func generatePostsubmitsForPromotion(jobBaseBuilderFactory func() *prowJobBaseBu
 	for _, arch := range architectures {
 		jobBaseBuilder := jobBaseBuilderFactory()
 		var jobBaseGen *prowJobBaseBuilder
+		testName := "images"
 		if arch != api.ReleaseArchitectureAMD64 {
-			testName := fmt.Sprintf("images-%s", string(arch))
+			testName = fmt.Sprintf("images-%s", string(arch))
 			cluster := arch.GetMappedCluster()
 			if cluster == "" {
 				return nil, fmt.Errorf("no cluster found for arch %s", string(arch))
 			}
 			jobBaseGen = jobBaseBuilder.Cluster(cluster).TestName(testName).WithLabel(api.ClusterLabel, string(cluster))
 		} else {
-			jobBaseGen = jobBaseBuilder.TestName("images")
+			jobBaseGen = jobBaseBuilder.TestName(testName)
 		}
 
 		jobBaseGen.PodSpec.Add(Promotion(), Targets(sets.List(opts.imageTargets)...))
@@ -291,6 +314,9 @@ func generatePostsubmitsForPromotion(jobBaseBuilderFactory func() *prowJobBaseBu
 			postsubmit.Labels = map[string]string{}
 		}
 		postsubmit.Labels[cioperatorapi.PromotionJobLabelKey] = "true"
+		if err := applyPodSpecRules(info, testName, "", &postsubmit.JobBase); err != nil {
+			return nil, err
+		}
 
 		postsubmits = append(postsubmits, *postsubmit)
 	}
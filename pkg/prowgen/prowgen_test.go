@@ -627,6 +627,42 @@ func TestGenerateJobs(t *testing.T) {
 				},
 			},
 		},
+		{
+			id:   "pod spec rules inject content into matching jobs",
+			keep: true,
+			config: &ciop.ReleaseBuildConfiguration{
+				Tests: []ciop.TestStepConfiguration{
+					{As: "unit", ContainerTestConfiguration: &ciop.ContainerTestConfiguration{From: "src"}},
+					{As: "e2e-aws", MultiStageTestConfiguration: &ciop.MultiStageTestConfiguration{ClusterProfile: ciop.ClusterProfileAWS}},
+				},
+				Images: []ciop.ProjectDirectoryImageBuildStepConfiguration{{From: "os", To: "ci-tools"}},
+			},
+			repoInfo: &ProwgenInfo{
+				Config: config.Prowgen{
+					PodSpecRules: []config.PodSpecRule{
+						{
+							Name:         "aws-mirror",
+							Match:        config.PodSpecRuleMatch{Orgs: []string{"organization"}, ClusterProfiles: []string{"aws*"}},
+							Volumes:      []corev1.Volume{{Name: "mirror", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "aws-mirror"}}}},
+							VolumeMounts: []corev1.VolumeMount{{Name: "mirror", MountPath: "/etc/mirror", ReadOnly: true}},
+							Env:          []corev1.EnvVar{{Name: "MIRROR", Value: "/etc/mirror"}},
+							NodeSelector: map[string]string{"region": "east"},
+						},
+						{
+							Name:   "unit-tests",
+							Match:  config.PodSpecRuleMatch{Tests: []string{"unit"}},
+							Args:   []string{"--retries=3"},
+							Labels: map[string]string{"ci.openshift.io/fast": "true"},
+						},
+					},
+				},
+				Metadata: ciop.Metadata{
+					Org:    "organization",
+					Repo:   "repository",
+					Branch: "branch",
+				},
+			},
+		},
 	}
 
 	for _, tc := range tests {
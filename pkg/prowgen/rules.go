@@ -0,0 +1,102 @@
+package prowgen
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+)
+
+// applyPodSpecRules injects the content of every configured rule that matches
+// the job generated for the test. Rules are applied on top of the built-in
+// mutators, so anything a rule would change in what prowgen generated, or in
+// what another rule injected, is reported as a conflict instead of overriding it.
+func applyPodSpecRules(info *ProwgenInfo, test string, clusterProfile cioperatorapi.ClusterProfile, job *prowconfig.JobBase) error {
+	var errs []error
+	var applied bool
+	for _, rule := range info.Config.PodSpecRules {
+		if !rule.Match.Matches(info.Org, info.Repo, test, string(clusterProfile)) {
+			continue
+		}
+		applied = true
+		if err := applyPodSpecRule(rule, job); err != nil {
+			errs = append(errs, fmt.Errorf("pod spec rule %q conflicts with the generated job %s: %w", rule.Name, job.Name, err))
+		}
+	}
+	if applied {
+		sortPodSpec(job.Spec)
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+func applyPodSpecRule(rule config.PodSpecRule, job *prowconfig.JobBase) error {
+	spec := job.Spec
+	container := &spec.Containers[0]
+	var errs []error
+	for _, volume := range rule.Volumes {
+		errs = append(errs, addVolume(spec, volume))
+	}
+	for _, mount := range rule.VolumeMounts {
+		errs = append(errs, addVolumeMount(container, mount))
+	}
+	for _, env := range rule.Env {
+		errs = append(errs, addEnvVar(container, env))
+	}
+	for _, arg := range rule.Args {
+		errs = append(errs, addArg(container, arg))
+	}
+	if len(rule.Labels) > 0 && job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	errs = append(errs, addMapEntries("label", job.Labels, rule.Labels)...)
+	if len(rule.NodeSelector) > 0 && spec.NodeSelector == nil {
+		spec.NodeSelector = map[string]string{}
+	}
+	errs = append(errs, addMapEntries("node selector", spec.NodeSelector, rule.NodeSelector)...)
+	return kerrors.NewAggregate(errs)
+}
+
+// repeatableArgs are the ci-operator flags that can be passed multiple times
+var repeatableArgs = sets.New[string](
+	"--dependency-override-param",
+	"--input-hash",
+	"--multi-stage-param",
+	"--secret-dir",
+	"--target",
+	"--template",
+)
+
+// addArg adds the argument unless it is already set. Flags are compared by
+// their name, so a flag that is set to a different value is a conflict.
+func addArg(container *corev1.Container, wantArg string) error {
+	name, _, _ := strings.Cut(wantArg, "=")
+	if !strings.HasPrefix(name, "-") || repeatableArgs.Has(name) {
+		addUniqueParameter(container, wantArg)
+		return nil
+	}
+	for _, arg := range container.Args {
+		if existing, _, _ := strings.Cut(arg, "="); existing == name && arg != wantArg {
+			return fmt.Errorf("argument '%s' added with different value", name)
+		}
+	}
+	addUniqueParameter(container, wantArg)
+	return nil
+}
+
+func addMapEntries(kind string, into, want map[string]string) []error {
+	var errs []error
+	for _, key := range sets.List(sets.KeySet(want)) {
+		if existing, set := into[key]; set && existing != want[key] {
+			errs = append(errs, fmt.Errorf("%s '%s' added with different value", kind, key))
+			continue
+		}
+		into[key] = want[key]
+	}
+	return errs
+}
@@ -0,0 +1,118 @@
+package prowgen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+)
+
+func TestApplyPodSpecRules(t *testing.T) {
+	info := func(rules ...config.PodSpecRule) *ProwgenInfo {
+		return &ProwgenInfo{
+			Metadata: cioperatorapi.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+			Config:   config.Prowgen{PodSpecRules: rules},
+		}
+	}
+	for _, tc := range []struct {
+		name           string
+		info           *ProwgenInfo
+		test           string
+		clusterProfile cioperatorapi.ClusterProfile
+		expectedEnv    []corev1.EnvVar
+		expectedArgs   []string
+		expectedLabels map[string]string
+		expectedErr    string
+	}{
+		{
+			name:           "rules that do not match are not applied",
+			info:           info(config.PodSpecRule{Name: "gcp", Match: config.PodSpecRuleMatch{ClusterProfiles: []string{"gcp"}}, Env: []corev1.EnvVar{{Name: "A", Value: "a"}}}),
+			test:           "unit",
+			expectedLabels: map[string]string{"existing": "label"},
+		},
+		{
+			name: "matching rules are applied",
+			info: info(
+				config.PodSpecRule{Name: "aws", Match: config.PodSpecRuleMatch{ClusterProfiles: []string{"aws"}}, Env: []corev1.EnvVar{{Name: "B", Value: "b"}}},
+				config.PodSpecRule{Name: "e2e", Match: config.PodSpecRuleMatch{Repos: []string{"re*"}, Tests: []string{"e2e-*"}}, Env: []corev1.EnvVar{{Name: "A", Value: "a"}}, Labels: map[string]string{"new": "label"}},
+			),
+			test:           "e2e-aws",
+			clusterProfile: cioperatorapi.ClusterProfileAWS,
+			expectedEnv:    []corev1.EnvVar{{Name: "A", Value: "a"}, {Name: "B", Value: "b"}},
+			expectedLabels: map[string]string{"existing": "label", "new": "label"},
+		},
+		{
+			name:           "conflicts with generated content are reported",
+			info:           info(config.PodSpecRule{Name: "relabel", Labels: map[string]string{"existing": "different"}}),
+			test:           "unit",
+			expectedLabels: map[string]string{"existing": "label"},
+			expectedErr:    `pod spec rule "relabel" conflicts with the generated job pull-ci-org-repo-master-unit: label 'existing' added with different value`,
+		},
+		{
+			name: "conflicts between rules are reported",
+			info: info(
+				config.PodSpecRule{Name: "first", Env: []corev1.EnvVar{{Name: "A", Value: "a"}}},
+				config.PodSpecRule{Name: "second", Env: []corev1.EnvVar{{Name: "A", Value: "b"}}},
+			),
+			test:           "unit",
+			expectedEnv:    []corev1.EnvVar{{Name: "A", Value: "a"}},
+			expectedLabels: map[string]string{"existing": "label"},
+			expectedErr:    `pod spec rule "second" conflicts with the generated job pull-ci-org-repo-master-unit: environment variable 'A' added with different value`,
+		},
+		{
+			name: "arguments are added unless they are already set",
+			info: info(config.PodSpecRule{Name: "args", Args: []string{"--report-credentials-file=/etc/report/credentials", "--secret-dir=/secrets/extra", "--target=extra"}}),
+			test: "unit",
+			expectedArgs: []string{
+				"--report-credentials-file=/etc/report/credentials",
+				"--secret-dir=/secrets/ci-pull-credentials",
+				"--secret-dir=/secrets/extra",
+				"--target=extra",
+				"--target=unit",
+			},
+			expectedLabels: map[string]string{"existing": "label"},
+		},
+		{
+			name:           "arguments with a different value are reported",
+			info:           info(config.PodSpecRule{Name: "reports", Args: []string{"--report-credentials-file=/etc/other/credentials"}}),
+			test:           "unit",
+			expectedArgs:   []string{"--report-credentials-file=/etc/report/credentials", "--secret-dir=/secrets/ci-pull-credentials", "--target=unit"},
+			expectedLabels: map[string]string{"existing": "label"},
+			expectedErr:    `pod spec rule "reports" conflicts with the generated job pull-ci-org-repo-master-unit: argument '--report-credentials-file' added with different value`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			job := prowconfig.JobBase{
+				Name:   "pull-ci-org-repo-master-unit",
+				Labels: map[string]string{"existing": "label"},
+				Spec: &corev1.PodSpec{Containers: []corev1.Container{{
+					Args: []string{"--report-credentials-file=/etc/report/credentials", "--secret-dir=/secrets/ci-pull-credentials", "--target=unit"},
+				}}},
+			}
+			var actualErr string
+			if err := applyPodSpecRules(tc.info, tc.test, tc.clusterProfile, &job); err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedEnv, job.Spec.Containers[0].Env); diff != "" {
+				t.Errorf("unexpected env: %s", diff)
+			}
+			if tc.expectedArgs == nil {
+				tc.expectedArgs = []string{"--report-credentials-file=/etc/report/credentials", "--secret-dir=/secrets/ci-pull-credentials", "--target=unit"}
+			}
+			if diff := cmp.Diff(tc.expectedArgs, job.Spec.Containers[0].Args); diff != "" {
+				t.Errorf("unexpected args: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedLabels, job.Labels); diff != "" {
+				t.Errorf("unexpected labels: %s", diff)
+			}
+		})
+	}
+}
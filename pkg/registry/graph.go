@@ -25,6 +25,10 @@ var nodeTypes = [4]string{
 	Observer:  "observer",
 }
 
+func (t Type) String() string {
+	return nodeTypes[t]
+}
+
 // Node is an interface that allows a user to identify ancestors and descendants of a step registry element
 type Node interface {
 	// Name returns the name of the registry element a Node refers to
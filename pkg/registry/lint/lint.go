@@ -0,0 +1,543 @@
+// Package lint checks the commands of step registry references against the
+// rest of their definition: the parameters, dependencies and credentials
+// they declare and the directories ci-operator preserves between steps.
+//
+// The checks work on the text of the script rather than on a full shell
+// parse, so findings can be suppressed with a comment:
+//
+//	# step-lint: ignore=undeclared-env,write-outside-dirs
+//
+// on the offending line, or on a line of its own before it, or for the whole
+// script with:
+//
+//	# step-lint: ignore-file=unused-parameter
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+// Rule names a check. Rules are referenced by name in suppression comments
+// and in the report.
+type Rule string
+
+const (
+	// UndeclaredEnv flags variables that are read without being declared as a
+	// parameter, dependency or lease of the step, or set by the script.
+	UndeclaredEnv Rule = "undeclared-env"
+	// UnusedParameter flags parameters of the step that the script never
+	// reads.
+	UnusedParameter Rule = "unused-parameter"
+	// WriteOutsideDirs flags writes to absolute paths that are not preserved
+	// when the step ends.
+	WriteOutsideDirs Rule = "write-outside-dirs"
+	// UndeclaredCredential flags paths under secret mount locations that are
+	// not mounted by any of the credentials of the step.
+	UndeclaredCredential Rule = "undeclared-credential"
+	// MissingErrexit flags scripts that carry on after a command fails.
+	MissingErrexit Rule = "missing-errexit"
+)
+
+// Rules lists every check the linter performs
+var Rules = []Rule{UndeclaredEnv, UnusedParameter, WriteOutsideDirs, UndeclaredCredential, MissingErrexit}
+
+// Finding is a problem found in the commands of a step. Line is the 1-based
+// line of the commands, or zero when the finding is about the whole step.
+type Finding struct {
+	Step    string `json:"step"`
+	Line    int    `json:"line,omitempty"`
+	Rule    Rule   `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", f.Step, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s (%s)", f.Step, f.Line, f.Message, f.Rule)
+}
+
+// Report holds the outcome of linting. Suppressed findings are reported so
+// that suppressions can be audited, but do not fail the lint.
+type Report struct {
+	Findings   []Finding `json:"findings"`
+	Suppressed []Finding `json:"suppressed,omitempty"`
+}
+
+// Failed determines if any findings were not suppressed
+func (r Report) Failed() bool {
+	return len(r.Findings) > 0
+}
+
+// providedEnv is the environment every step runs with, set either by
+// ci-operator, by Prow or by the shell.
+var providedEnv = sets.New[string](
+	// ci-operator
+	"SHARED_DIR", "ARTIFACT_DIR", "NAMESPACE", "JOB_NAME_SAFE", "JOB_NAME_HASH", "UNIQUE_HASH",
+	"KUBECONFIG", "KUBECONFIGMINIMAL", "KUBEADMIN_PASSWORD_FILE", "CLUSTER_PROFILE_DIR",
+	"CLUSTER_PROFILE_NAME", "CLUSTER_TYPE", api.DefaultLeaseEnv, api.CliEnv, "IMAGE_FORMAT", "OPENSHIFT_CI",
+	// Prow
+	downwardapi.CI, downwardapi.JobSpecEnv, downwardapi.JobNameEnv, downwardapi.JobTypeEnv, downwardapi.ProwJobIDEnv,
+	downwardapi.BuildIDEnv, downwardapi.ProwBuildIDEnv, downwardapi.RepoOwnerEnv, downwardapi.RepoNameEnv,
+	downwardapi.PullBaseRefEnv, downwardapi.PullBaseShaEnv, downwardapi.PullRefsEnv, downwardapi.PullNumberEnv,
+	downwardapi.PullPullShaEnv, downwardapi.PullHeadRefEnv, downwardapi.PullTitleEnv,
+	// shell
+	"HOME", "PATH", "PWD", "OLDPWD", "USER", "HOSTNAME", "HOSTTYPE", "OSTYPE", "MACHTYPE", "SHELL", "SHLVL",
+	"TERM", "TMPDIR", "LANG", "LC_ALL", "IFS", "PS4", "RANDOM", "SECONDS", "EPOCHSECONDS", "EPOCHREALTIME",
+	"LINENO", "OPTARG", "OPTIND", "REPLY", "UID", "EUID", "PPID", "GROUPS", "FUNCNAME", "PIPESTATUS",
+	"BASHPID", "BASH_SOURCE", "BASH_LINENO", "BASH_REMATCH", "BASH_VERSION", "BASH_COMMAND", "BASH_XTRACEFD",
+)
+
+// providedEnvPrefixes are prefixes of variables ci-operator sets depending
+// on the releases of the test
+var providedEnvPrefixes = []string{"RELEASE_IMAGE_"}
+
+// scratchDirs are absolute paths where writes are expected to be discarded
+var scratchDirs = []string{"/tmp", "/dev"}
+
+// secretRoots are locations where secrets are conventionally mounted, and
+// systemSecrets are the mounts under them that ci-operator and Kubernetes
+// set up without the step declaring them
+var (
+	secretRoots   = sets.New[string]("/var/run/vault", "/var/run/secrets")
+	systemSecrets = []string{
+		"/var/run/secrets/kubernetes.io",
+		"/var/run/secrets/ci.openshift.io/cluster-profile",
+		"/var/run/secrets/ci.openshift.io/multi-stage",
+	}
+)
+
+var (
+	suppressionPattern = regexp.MustCompile(`step-lint:\s*(ignore|ignore-file)=([a-z,-]+)`)
+	heredocPattern     = regexp.MustCompile(`(?:^|[^<])<<-?\s*(?:'([A-Za-z_]\w*)'|"([A-Za-z_]\w*)"|\\([A-Za-z_]\w*)|([A-Za-z_]\w*))`)
+	readPattern        = regexp.MustCompile(`\$(?:\{[#!]?([A-Za-z_]\w*)(:?[-=+])?|([A-Za-z_]\w*))`)
+	assignPattern      = regexp.MustCompile(`(?:^|[\s;&|(])([A-Za-z_]\w*)(?:\[[^]]*\])?\+?=`)
+	declarePattern     = regexp.MustCompile(`(?:^|[\s;&|(])(?:export|local|declare|readonly|typeset|read|mapfile|readarray|unset)\s+([^;&|<>()]*)`)
+	loopPattern        = regexp.MustCompile(`(?:^|[\s;&|(])(?:for|select)\s+([A-Za-z_]\w*)\s`)
+	optionVarPattern   = regexp.MustCompile(`(?:^|[\s;&|(])(?:getopts\s+\S+|printf\s+-v)\s+([A-Za-z_]\w*)`)
+	redirectPattern    = regexp.MustCompile(`(?:^|[^<>&$(=\d])(?:\d|&)?>>?\|?\s*([^\s;&|<>()]+)`)
+	teePattern         = regexp.MustCompile(`(?:^|[\s;&|(])tee\s+([^;&|<>()]*)`)
+	secretPathPattern  = regexp.MustCompile(`/var/run/(?:vault|secrets)(?:/[^\s"'$;:,)}\]]*)?`)
+	setPattern         = regexp.MustCompile(`(?:^|[\s;&|(])set\s+([^;&|]*)`)
+	namePattern        = regexp.MustCompile(`^[A-Za-z_]\w*`)
+	wordPattern        = regexp.MustCompile(`\w+`)
+)
+
+// line is a line of the script split into the parts the checks look at
+type line struct {
+	number int
+	// text is the line without its comment
+	text string
+	// code is the text without the content of single-quoted strings, which
+	// the shell does not expand
+	code string
+	// bare is the code with operators in double-quoted strings masked, so
+	// that they are not mistaken for redirections
+	bare string
+	// comment is the comment at the end of the line, if any
+	comment string
+	// literal lines are the content of a here-document whose delimiter is
+	// quoted, and expanded lines are the content of any other
+	// here-document; neither holds commands
+	literal, expanded bool
+}
+
+// scanner splits a script into lines, keeping track of the quotes and
+// here-documents that span lines
+type scanner struct {
+	inSingle, inDouble bool
+	heredoc            string
+	heredocLiteral     bool
+	heredocIndented    bool
+}
+
+func (s *scanner) scan(number int, raw string) line {
+	l := line{number: number}
+	if s.heredoc != "" {
+		l.text, l.code, l.literal, l.expanded = raw, raw, s.heredocLiteral, !s.heredocLiteral
+		if s.heredocLiteral {
+			l.code = ""
+		}
+		trimmed := raw
+		if s.heredocIndented {
+			trimmed = strings.TrimLeft(raw, "\t")
+		}
+		if trimmed == s.heredoc {
+			s.heredoc = ""
+		}
+		return l
+	}
+	var text, code, bare strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case s.inSingle:
+			text.WriteByte(c)
+			if c == '\'' {
+				s.inSingle = false
+				code.WriteByte(c)
+				bare.WriteByte(c)
+			}
+			continue
+		case s.inDouble:
+			text.WriteByte(c)
+			code.WriteByte(c)
+			switch c {
+			case '\\':
+				if i+1 < len(raw) {
+					i++
+					text.WriteByte(raw[i])
+					code.WriteByte(raw[i])
+				}
+				bare.WriteString("__")
+				continue
+			case '"':
+				s.inDouble = false
+				bare.WriteByte(c)
+			case '>', '<', '|', '&', ';', '(', ')', ' ', '\t':
+				bare.WriteByte('_')
+			default:
+				bare.WriteByte(c)
+			}
+			continue
+		}
+		switch c {
+		case '\\':
+			text.WriteByte(c)
+			code.WriteByte(c)
+			bare.WriteByte(c)
+			if i+1 < len(raw) {
+				i++
+				text.WriteByte(raw[i])
+				code.WriteByte(raw[i])
+				bare.WriteByte(raw[i])
+			}
+			continue
+		case '\'':
+			s.inSingle = true
+		case '"':
+			s.inDouble = true
+		case '#':
+			if i == 0 || strings.ContainsRune(" \t;&|(", rune(raw[i-1])) {
+				l.comment = raw[i:]
+				i = len(raw)
+				continue
+			}
+		}
+		text.WriteByte(c)
+		code.WriteByte(c)
+		bare.WriteByte(c)
+	}
+	l.text, l.code, l.bare = text.String(), code.String(), bare.String()
+	if match := heredocPattern.FindStringSubmatch(l.text); match != nil && !strings.Contains(l.text, "<<<") {
+		for i, delimiter := range match[1:] {
+			if delimiter != "" {
+				s.heredoc, s.heredocLiteral = delimiter, i < 3
+				s.heredocIndented = strings.Contains(match[0], "<<-")
+				break
+			}
+		}
+	}
+	return l
+}
+
+func scan(commands string) []line {
+	var lines []line
+	s := scanner{}
+	for i, raw := range strings.Split(commands, "\n") {
+		lines = append(lines, s.scan(i+1, raw))
+	}
+	return lines
+}
+
+// suppressions holds the rules suppressed for lines and for the whole script
+type suppressions struct {
+	lines map[int]sets.Set[Rule]
+	file  sets.Set[Rule]
+}
+
+func suppressionsFor(lines []line) suppressions {
+	ret := suppressions{lines: map[int]sets.Set[Rule]{}, file: sets.New[Rule]()}
+	for _, l := range lines {
+		match := suppressionPattern.FindStringSubmatch(l.comment)
+		if match == nil {
+			continue
+		}
+		var rules []Rule
+		for _, rule := range strings.Split(match[2], ",") {
+			rules = append(rules, Rule(rule))
+		}
+		if match[1] == "ignore-file" {
+			ret.file.Insert(rules...)
+			continue
+		}
+		target := l.number
+		if strings.TrimSpace(l.text) == "" {
+			target++
+		}
+		if ret.lines[target] == nil {
+			ret.lines[target] = sets.New[Rule]()
+		}
+		ret.lines[target].Insert(rules...)
+	}
+	return ret
+}
+
+func (s suppressions) has(f Finding) bool {
+	return s.file.Has(f.Rule) || s.lines[f.Line].Has(f.Rule)
+}
+
+// Step lints the commands of a step
+func Step(step api.LiteralTestStep) Report {
+	lines := scan(step.Commands)
+	suppressed := suppressionsFor(lines)
+	var findings []Finding
+	add := func(number int, rule Rule, format string, args ...interface{}) {
+		findings = append(findings, Finding{Step: step.As, Line: number, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	declared := sets.New[string]()
+	for _, parameter := range step.Environment {
+		declared.Insert(parameter.Name)
+	}
+	for _, dependency := range step.Dependencies {
+		declared.Insert(dependency.Env)
+	}
+	for _, lease := range step.Leases {
+		declared.Insert(lease.Env)
+	}
+	defined := definedVariables(lines)
+
+	read, mentioned := sets.New[string](), sets.New[string]()
+	// errexit holds whether errexit is enabled after every line, and
+	// disabledAt the line that last disabled it
+	errexit, disabledAt := shebangHasErrexit(step.Commands), 0
+	for _, l := range lines {
+		if l.literal {
+			continue
+		}
+		for _, match := range readPattern.FindAllStringSubmatchIndex(l.code, -1) {
+			if match[0] > 0 && l.code[match[0]-1] == '\\' {
+				// escaped, as when generating a script
+				continue
+			}
+			var name string
+			switch {
+			case match[2] >= 0:
+				name = l.code[match[2]:match[3]]
+			default:
+				name = l.code[match[6]:match[7]]
+			}
+			withDefault := match[4] >= 0
+			if !read.Has(name) && !withDefault && !declared.Has(name) && !defined.Has(name) && !provided(name) {
+				add(l.number, UndeclaredEnv, "$%s is read but not declared in env or dependencies of the step", name)
+			}
+			read.Insert(name)
+		}
+		for _, word := range wordPattern.FindAllString(l.text, -1) {
+			mentioned.Insert(word)
+		}
+		if l.expanded {
+			continue
+		}
+		for _, match := range setPattern.FindAllStringSubmatch(l.text, -1) {
+			if enabled, set := errexitSetting(strings.Fields(match[1])); set {
+				errexit = enabled
+				if !enabled {
+					disabledAt = l.number
+				}
+			}
+		}
+		for _, target := range writeTargets(l) {
+			if !preserved(target) {
+				add(l.number, WriteOutsideDirs, "writes to %s, which is outside of $SHARED_DIR and $ARTIFACT_DIR and is lost when the step ends", target)
+			}
+		}
+	}
+	for _, l := range lines {
+		for _, path := range secretPathPattern.FindAllString(l.text, -1) {
+			path = strings.TrimSuffix(path, "/")
+			if !secretRoots.Has(path) && !underAny(path, systemSecrets) && !mountedBy(path, step.Credentials) {
+				add(l.number, UndeclaredCredential, "%s is not under the mount path of any credentials of the step", path)
+			}
+		}
+	}
+	for _, parameter := range step.Environment {
+		if !read.Has(parameter.Name) && !mentioned.Has(parameter.Name) {
+			add(0, UnusedParameter, "parameter %s is declared but never read", parameter.Name)
+		}
+	}
+	switch {
+	case errexit:
+	case disabledAt > 0:
+		add(disabledAt, MissingErrexit, "disables errexit and does not enable it again, so failing commands after it do not fail the step")
+	default:
+		add(0, MissingErrexit, "commands do not set -o errexit, so failing commands do not fail the step")
+	}
+
+	var report Report
+	for _, finding := range findings {
+		if suppressed.has(finding) {
+			report.Suppressed = append(report.Suppressed, finding)
+		} else {
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+	return report
+}
+
+// Registry lints the commands of every reference in the registry
+func Registry(references registry.ReferenceByName) Report {
+	names := make([]string, 0, len(references))
+	for name := range references {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var report Report
+	for _, name := range names {
+		step := references[name]
+		step.As = name
+		stepReport := Step(step)
+		report.Findings = append(report.Findings, stepReport.Findings...)
+		report.Suppressed = append(report.Suppressed, stepReport.Suppressed...)
+	}
+	return report
+}
+
+// definedVariables collects the variables the script sets itself
+func definedVariables(lines []line) sets.Set[string] {
+	defined := sets.New[string]()
+	for _, l := range lines {
+		if l.literal || l.expanded {
+			continue
+		}
+		for _, pattern := range []*regexp.Regexp{assignPattern, loopPattern, optionVarPattern} {
+			for _, match := range pattern.FindAllStringSubmatch(l.code, -1) {
+				defined.Insert(match[1])
+			}
+		}
+		for _, match := range declarePattern.FindAllStringSubmatch(l.code, -1) {
+			for _, field := range strings.Fields(match[1]) {
+				if name := namePattern.FindString(field); name != "" {
+					defined.Insert(name)
+				}
+			}
+		}
+	}
+	return defined
+}
+
+func provided(name string) bool {
+	if providedEnv.Has(name) {
+		return true
+	}
+	for _, prefix := range providedEnvPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTargets returns the files the line redirects output to or passes to tee
+func writeTargets(l line) []string {
+	var targets []string
+	for _, match := range redirectPattern.FindAllStringSubmatch(l.bare, -1) {
+		targets = append(targets, match[1])
+	}
+	for _, match := range teePattern.FindAllStringSubmatch(l.bare, -1) {
+		for _, field := range strings.Fields(match[1]) {
+			if !strings.HasPrefix(field, "-") {
+				targets = append(targets, field)
+			}
+		}
+	}
+	for i := range targets {
+		targets[i] = strings.Trim(targets[i], `"`)
+	}
+	return targets
+}
+
+// preserved determines if a write to the target is either kept after the
+// step ends or obviously meant to be discarded. Targets that are relative or
+// that start with a variable other than the preserved directories are not
+// known well enough to be judged.
+func preserved(target string) bool {
+	if !strings.HasPrefix(target, "/") {
+		return true
+	}
+	return underAny(target, scratchDirs)
+}
+
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func mountedBy(path string, credentials []api.CredentialReference) bool {
+	for _, credential := range credentials {
+		mountPath := strings.TrimSuffix(credential.MountPath, "/")
+		// a script may look for secrets in a parent of the mount
+		if underAny(path, []string{mountPath}) || strings.HasPrefix(mountPath, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// shebangHasErrexit determines if the interpreter line of the script enables
+// errexit, as in #!/bin/bash -e
+func shebangHasErrexit(commands string) bool {
+	first, _, _ := strings.Cut(commands, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return false
+	}
+	enabled, _ := errexitSetting(strings.Fields(first)[1:])
+	return enabled
+}
+
+// errexitSetting determines whether the shell options change errexit and
+// whether they leave it enabled. The options are parsed the way set parses
+// them: -e and -o errexit enable errexit, +e and +o errexit disable it, and
+// o in a cluster of short options takes the next argument, as in -euo pipefail.
+// The options end at -- or at the first argument that is not an option.
+func errexitSetting(options []string) (enabled, set bool) {
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		if option == "--" || len(option) < 2 || option[0] != '-' && option[0] != '+' {
+			break
+		}
+		if strings.HasPrefix(option, "--") {
+			// long options of the interpreter, as in #!/bin/bash --norc
+			continue
+		}
+		enable := option[0] == '-'
+		for _, flag := range option[1:] {
+			switch flag {
+			case 'e':
+				enabled, set = enable, true
+			case 'o':
+				if i+1 < len(options) {
+					i++
+					if options[i] == "errexit" {
+						enabled, set = enable, true
+					}
+				}
+			}
+		}
+	}
+	return enabled, set
+}
@@ -0,0 +1,231 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func TestStep(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		step     api.LiteralTestStep
+		expected Report
+	}{{
+		name: "clean step",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `#!/bin/bash
+set -o nounset
+set -o errexit
+set -o pipefail
+
+REGION="${LEASED_RESOURCE}"
+cp "${CLUSTER_PROFILE_DIR}/pull-secret" /tmp/pull-secret
+for file in "${SHARED_DIR}"/*; do
+  echo "${file}" >> "${ARTIFACT_DIR}/files"
+done
+oc adm release info "${RELEASE_IMAGE_LATEST}" --output=json > /tmp/release.json 2>&1
+cat /var/run/vault/aws/key | tee -a "${SHARED_DIR}/key" >/dev/null
+echo "${REGION} ${INSTALLER} ${SUITE:-default} ${BIN_IMAGE}" "a > b" '$NOT_EXPANDED > /etc/file'
+`,
+			Environment:  []api.StepParameter{{Name: "INSTALLER"}},
+			Dependencies: []api.StepDependency{{Name: "bin", Env: "BIN_IMAGE"}},
+			Credentials:  []api.CredentialReference{{Name: "aws", Namespace: "test-credentials", MountPath: "/var/run/vault/aws"}},
+		},
+	}, {
+		name: "every rule is violated",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `echo "${UNDECLARED}"
+echo "$UNDECLARED $OTHER"
+echo done > /etc/result
+cat /var/run/secrets/other/token
+`,
+			Environment: []api.StepParameter{{Name: "UNUSED"}},
+		},
+		expected: Report{Findings: []Finding{
+			{Step: "step", Line: 1, Rule: UndeclaredEnv, Message: "$UNDECLARED is read but not declared in env or dependencies of the step"},
+			{Step: "step", Line: 2, Rule: UndeclaredEnv, Message: "$OTHER is read but not declared in env or dependencies of the step"},
+			{Step: "step", Line: 3, Rule: WriteOutsideDirs, Message: "writes to /etc/result, which is outside of $SHARED_DIR and $ARTIFACT_DIR and is lost when the step ends"},
+			{Step: "step", Line: 4, Rule: UndeclaredCredential, Message: "/var/run/secrets/other/token is not under the mount path of any credentials of the step"},
+			{Step: "step", Rule: UnusedParameter, Message: "parameter UNUSED is declared but never read"},
+			{Step: "step", Rule: MissingErrexit, Message: "commands do not set -o errexit, so failing commands do not fail the step"},
+		}},
+	}, {
+		name: "here-documents",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `set -euo pipefail
+cat > "${SHARED_DIR}/script.sh" <<'EOF'
+echo "$INSIDE" > /etc/inside
+EOF
+cat >> "${SHARED_DIR}/config" <<-EOF
+	value: ${VALUE} > /etc/not-a-write
+	EOF
+echo "\$ESCAPED"
+`,
+		},
+		expected: Report{Findings: []Finding{
+			{Step: "step", Line: 6, Rule: UndeclaredEnv, Message: "$VALUE is read but not declared in env or dependencies of the step"},
+		}},
+	}, {
+		name: "variables set by the script",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `#!/bin/bash -ex
+export A=1 B
+local C
+declare -a D=()
+read -r E F < /tmp/input
+for G in 1 2; do :; done
+while getopts "ab" H; do :; done
+printf -v I "%s" x
+J+=(x)
+echo "$A $B $C ${D[@]} $E $F $G $H $I ${J[0]} ${#J[@]}"
+`,
+		},
+	}, {
+		name: "parameters mentioned without being expanded",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `set -e
+if [[ -v EXTRA_ARGS ]]; then echo extra; fi
+envsubst '$TEMPLATE_VAR' < "${SHARED_DIR}/template"
+`,
+			Environment: []api.StepParameter{{Name: "EXTRA_ARGS"}, {Name: "TEMPLATE_VAR"}},
+		},
+	}, {
+		name: "parents of credentials and system secrets",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `set -e
+ls /var/run/vault/ /var/run/secrets/ci.openshift.io/
+cat /var/run/secrets/kubernetes.io/serviceaccount/token
+cat /var/run/secrets/ci.openshift.io/cluster-profile/ssh-publickey
+`,
+			Credentials: []api.CredentialReference{{Name: "creds", Namespace: "test-credentials", MountPath: "/var/run/secrets/ci.openshift.io/creds"}},
+		},
+	}, {
+		name: "errexit disabled temporarily",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `#!/bin/bash -e
+set +e
+false
+set -o errexit
+`,
+		},
+	}, {
+		name: "errexit disabled and not enabled again",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `set -euo pipefail
+echo setup
+set +o errexit
+false
+`,
+		},
+		expected: Report{Findings: []Finding{
+			{Step: "step", Line: 3, Rule: MissingErrexit, Message: "disables errexit and does not enable it again, so failing commands after it do not fail the step"},
+		}},
+	}, {
+		name: "short options without errexit",
+		step: api.LiteralTestStep{
+			As:       "step",
+			Commands: "#!/bin/bash -x\nset -uo pipefail\nset -- -e\n",
+		},
+		expected: Report{Findings: []Finding{
+			{Step: "step", Rule: MissingErrexit, Message: "commands do not set -o errexit, so failing commands do not fail the step"},
+		}},
+	}, {
+		name: "suppressions",
+		step: api.LiteralTestStep{
+			As: "step",
+			Commands: `# step-lint: ignore-file=missing-errexit,unused-parameter
+echo "$FROM_IMAGE" # step-lint: ignore=undeclared-env
+# step-lint: ignore=write-outside-dirs,undeclared-env
+echo "$OTHER" > /etc/result
+echo "$NOT_SUPPRESSED"
+`,
+			Environment: []api.StepParameter{{Name: "UNUSED"}},
+		},
+		expected: Report{
+			Findings: []Finding{
+				{Step: "step", Line: 5, Rule: UndeclaredEnv, Message: "$NOT_SUPPRESSED is read but not declared in env or dependencies of the step"},
+			},
+			Suppressed: []Finding{
+				{Step: "step", Line: 2, Rule: UndeclaredEnv, Message: "$FROM_IMAGE is read but not declared in env or dependencies of the step"},
+				{Step: "step", Line: 4, Rule: UndeclaredEnv, Message: "$OTHER is read but not declared in env or dependencies of the step"},
+				{Step: "step", Line: 4, Rule: WriteOutsideDirs, Message: "writes to /etc/result, which is outside of $SHARED_DIR and $ARTIFACT_DIR and is lost when the step ends"},
+				{Step: "step", Rule: UnusedParameter, Message: "parameter UNUSED is declared but never read"},
+				{Step: "step", Rule: MissingErrexit, Message: "commands do not set -o errexit, so failing commands do not fail the step"},
+			},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, Step(tc.step)); diff != "" {
+				t.Errorf("unexpected report: %s", diff)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	references := registry.ReferenceByName{
+		"b": {Commands: "set -e\necho $B"},
+		"a": {Commands: "echo a"},
+		"c": {Commands: "set -e\n# step-lint: ignore=undeclared-env\necho $C"},
+	}
+	expected := Report{
+		Findings: []Finding{
+			{Step: "a", Rule: MissingErrexit, Message: "commands do not set -o errexit, so failing commands do not fail the step"},
+			{Step: "b", Line: 2, Rule: UndeclaredEnv, Message: "$B is read but not declared in env or dependencies of the step"},
+		},
+		Suppressed: []Finding{
+			{Step: "c", Line: 3, Rule: UndeclaredEnv, Message: "$C is read but not declared in env or dependencies of the step"},
+		},
+	}
+	report := Registry(references)
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("unexpected report: %s", diff)
+	}
+	if !report.Failed() {
+		t.Error("expected the report to fail")
+	}
+}
+
+func TestErrexitSetting(t *testing.T) {
+	for _, tc := range []struct {
+		options         []string
+		expectedEnabled bool
+		expectedSet     bool
+	}{
+		{options: []string{"-e"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"-eux"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"-euo", "pipefail"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"-o", "errexit"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"-uo", "errexit"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"+e"}, expectedSet: true},
+		{options: []string{"+o", "errexit"}, expectedSet: true},
+		{options: []string{"+xe"}, expectedSet: true},
+		{options: []string{"-e", "+e"}, expectedSet: true},
+		{options: []string{"+e", "-o", "errexit"}, expectedEnabled: true, expectedSet: true},
+		{options: []string{"-o", "pipefail"}},
+		{options: []string{"-uo", "pipefail"}},
+		{options: []string{"-x"}},
+		{options: []string{"+o", "nounset"}},
+		{options: []string{"--", "-e"}},
+		{options: []string{"value", "-e"}},
+		{options: []string{"--norc", "-e"}, expectedEnabled: true, expectedSet: true},
+		{options: nil},
+	} {
+		enabled, set := errexitSetting(tc.options)
+		if enabled != tc.expectedEnabled || set != tc.expectedSet {
+			t.Errorf("%q: expected enabled=%v set=%v, got enabled=%v set=%v", tc.options, tc.expectedEnabled, tc.expectedSet, enabled, set)
+		}
+	}
+}
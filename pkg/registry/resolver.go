@@ -117,6 +117,9 @@ func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]a
 	if config.AllowBestEffortPostSteps == nil {
 		config.AllowBestEffortPostSteps = workflow.AllowBestEffortPostSteps
 	}
+	if config.Retries == nil {
+		config.Retries = workflow.Retries
+	}
 	return overridden, errs
 }
 
@@ -130,6 +133,7 @@ func (r *registry) resolveTest(
 		ClusterProfile:           config.ClusterProfile,
 		AllowSkipOnSuccess:       config.AllowSkipOnSuccess,
 		AllowBestEffortPostSteps: config.AllowBestEffortPostSteps,
+		Retries:                  config.Retries,
 		Leases:                   config.Leases,
 		DependencyOverrides:      config.DependencyOverrides,
 	}
@@ -777,6 +777,42 @@ func TestResolve(t *testing.T) {
 				},
 			}},
 		},
+	}, {
+		name: "Workflow retries are inherited by the test",
+		config: api.MultiStageTestConfiguration{
+			Workflow: &awsWorkflow,
+		},
+		workflowMap: WorkflowByName{
+			awsWorkflow: {
+				ClusterProfile: api.ClusterProfileAWS,
+				Retries:        &api.RetryPolicy{Attempts: 2},
+				Pre: []api.TestStep{{
+					LiteralTestStep: &api.LiteralTestStep{
+						As:       "ipi-install",
+						From:     "installer",
+						Commands: "openshift-cluster install",
+						Retries:  &api.RetryPolicy{Attempts: 3},
+						Resources: api.ResourceRequirements{
+							Requests: api.ResourceList{"cpu": "1000m"},
+							Limits:   api.ResourceList{"memory": "2Gi"},
+						}},
+				}},
+			},
+		},
+		expectedRes: api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Retries:        &api.RetryPolicy{Attempts: 2},
+			Pre: []api.LiteralTestStep{{
+				As:       "ipi-install",
+				From:     "installer",
+				Commands: "openshift-cluster install",
+				Retries:  &api.RetryPolicy{Attempts: 3},
+				Resources: api.ResourceRequirements{
+					Requests: api.ResourceList{"cpu": "1000m"},
+					Limits:   api.ResourceList{"memory": "2Gi"},
+				},
+			}},
+		},
 	}, {
 		name: "Workflow with invalid parameter",
 		config: api.MultiStageTestConfiguration{
@@ -21,6 +21,8 @@ type ResolverClient interface {
 	Config(*api.Metadata) (*api.ReleaseBuildConfiguration, error)
 	ConfigWithTest(base *api.Metadata, testSource *api.MetadataWithTest, multipleSources bool) (*api.ReleaseBuildConfiguration, error)
 	Resolve([]byte) (*api.ReleaseBuildConfiguration, error)
+	Query(Query) ([]QueryResult, error)
+	SecretUsage(SecretUsageQuery) ([]SecretUsage, error)
 }
 
 func NewResolverClient(address string) ResolverClient {
@@ -99,6 +101,40 @@ func (r *resolverClient) Resolve(raw []byte) (*api.ReleaseBuildConfiguration, er
 	return configFromResolverRequest(req)
 }
 
+func (r *resolverClient) Query(q Query) ([]QueryResult, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/query", r.Address), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for configresolver: %w", err)
+	}
+	req.URL.RawQuery = q.Values().Encode()
+	data, err := doResolverRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var results []QueryResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal query results from configresolver: %w", err)
+	}
+	return results, nil
+}
+
+func (r *resolverClient) SecretUsage(q SecretUsageQuery) ([]SecretUsage, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/secretUsage", r.Address), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for configresolver: %w", err)
+	}
+	req.URL.RawQuery = q.Values().Encode()
+	data, err := doResolverRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var usage []SecretUsage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secret usage from configresolver: %w", err)
+	}
+	return usage, nil
+}
+
 type adapter struct{}
 
 func (a adapter) format(s string, i ...interface{}) string {
@@ -130,6 +166,19 @@ func (a adapter) Warn(s string, i ...interface{}) {
 var _ retryablehttp.LeveledLogger = adapter{}
 
 func configFromResolverRequest(req *http.Request) (*api.ReleaseBuildConfiguration, error) {
+	data, err := doResolverRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	configSpecHTTP := &api.ReleaseBuildConfiguration{}
+	err = json.Unmarshal(data, configSpecHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config from configresolver: invalid configuration: %w\nvalue:\n%s", err, string(data))
+	}
+	return configSpecHTTP, nil
+}
+
+func doResolverRequest(req *http.Request) ([]byte, error) {
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = 5
 	retryClient.Logger = adapter{}
@@ -153,10 +202,5 @@ func configFromResolverRequest(req *http.Request) (*api.ReleaseBuildConfiguratio
 	if err != nil {
 		return nil, fmt.Errorf("failed to read configresolver response body: %w", err)
 	}
-	configSpecHTTP := &api.ReleaseBuildConfiguration{}
-	err = json.Unmarshal(data, configSpecHTTP)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config from configresolver: invalid configuration: %w\nvalue:\n%s", err, string(data))
-	}
-	return configSpecHTTP, nil
+	return data, nil
 }
@@ -0,0 +1,367 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	StepQuery           = "step"
+	ChainQuery          = "chain"
+	WorkflowQuery       = "workflow"
+	ClusterProfileQuery = "cluster_profile"
+	EnvQuery            = "env"
+	ImageQuery          = "image"
+	LeaseQuery          = "lease"
+)
+
+// Query selects tests by what they use. Every field that is set must match
+// for a test to be selected.
+type Query struct {
+	// Step selects tests that run the registry step, directly or through a
+	// chain or workflow.
+	Step string `json:"step,omitempty"`
+	// Chain selects tests that run the chain, directly, through another chain
+	// or through a workflow.
+	Chain string `json:"chain,omitempty"`
+	// Workflow selects tests that use the workflow.
+	Workflow string `json:"workflow,omitempty"`
+	// ClusterProfile selects tests that run with the cluster profile.
+	ClusterProfile string `json:"cluster_profile,omitempty"`
+	// Env selects tests that set the variable, either as NAME to match any
+	// value or as NAME=VALUE to match a specific one.
+	Env string `json:"env,omitempty"`
+	// Image selects tests with a step that runs in or depends on the
+	// pipeline image tag.
+	Image string `json:"image,omitempty"`
+	// Lease selects tests that acquire a lease of the resource type.
+	Lease string `json:"lease,omitempty"`
+}
+
+func (q *Query) fields() map[string]*string {
+	return map[string]*string{
+		StepQuery:           &q.Step,
+		ChainQuery:          &q.Chain,
+		WorkflowQuery:       &q.Workflow,
+		ClusterProfileQuery: &q.ClusterProfile,
+		EnvQuery:            &q.Env,
+		ImageQuery:          &q.Image,
+		LeaseQuery:          &q.Lease,
+	}
+}
+
+// Empty determines if the query would select every test
+func (q Query) Empty() bool {
+	for _, value := range q.fields() {
+		if *value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Values encodes the query as URL query parameters
+func (q Query) Values() url.Values {
+	values := url.Values{}
+	for name, value := range q.fields() {
+		if *value != "" {
+			values.Set(name, *value)
+		}
+	}
+	return values
+}
+
+// QueryFromValues decodes a query from URL query parameters
+func QueryFromValues(values url.Values) Query {
+	var q Query
+	for name, field := range q.fields() {
+		*field = values.Get(name)
+	}
+	return q
+}
+
+// QueryResult is a test selected by a query
+type QueryResult struct {
+	Metadata api.Metadata `json:"metadata"`
+	Test     string       `json:"test"`
+	// Workflow is the workflow the test uses, if any
+	Workflow string `json:"workflow,omitempty"`
+	// ClusterProfile is the resolved cluster profile of the test, if any
+	ClusterProfile api.ClusterProfile `json:"cluster_profile,omitempty"`
+}
+
+// ConfigLister exposes all loaded configurations
+type ConfigLister interface {
+	GetAll() config.ByOrgRepo
+	GetGeneration() int
+}
+
+// QueryRegistry resolves tests against the registry and exposes its content
+type QueryRegistry interface {
+	Resolve(name string, config api.MultiStageTestConfiguration) (api.MultiStageTestConfigurationLiteral, error)
+	GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata)
+}
+
+// ResolvedTest is a test of a configuration with its multi-stage test
+// resolved against the registry
+type ResolvedTest struct {
+	Metadata api.Metadata
+	Test     api.TestStepConfiguration
+	// Literal is the resolved multi-stage test, or the literal one, if the
+	// test is a multi-stage test
+	Literal *api.MultiStageTestConfigurationLiteral
+}
+
+// ResolveTests resolves the tests in all configurations against the registry.
+// Tests that fail to resolve are skipped.
+func ResolveTests(configs config.ByOrgRepo, reg QueryRegistry) []ResolvedTest {
+	var ret []ResolvedTest
+	for _, repos := range configs {
+		for _, repoConfigs := range repos {
+			for _, c := range repoConfigs {
+				for _, test := range c.Tests {
+					resolved := ResolvedTest{Metadata: c.Metadata, Test: test, Literal: test.MultiStageTestConfigurationLiteral}
+					if test.MultiStageTestConfiguration != nil {
+						literal, err := reg.Resolve(test.As, *test.MultiStageTestConfiguration)
+						if err != nil {
+							logrus.WithFields(api.LogFieldsFor(c.Metadata)).WithField("test", test.As).WithError(err).Debug("Failed to resolve test, skipping it.")
+							continue
+						}
+						resolved.Literal = &literal
+					}
+					ret = append(ret, resolved)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// ReloadingQueryRegistry is a QueryRegistry that is reloaded over time
+type ReloadingQueryRegistry interface {
+	QueryRegistry
+	GetGeneration() int
+}
+
+// Generations identify the content of the configurations and the registry
+// that tests were resolved from
+type Generations struct {
+	Config   int
+	Registry int
+}
+
+// ResolvedTestCache resolves the tests in all configurations once and keeps
+// them until either the configurations or the registry are reloaded, as
+// resolving every test is too expensive to be done on every request
+type ResolvedTestCache struct {
+	configs ConfigLister
+	reg     ReloadingQueryRegistry
+
+	lock        sync.Mutex
+	generations Generations
+	tests       []ResolvedTest
+	resolved    bool
+}
+
+func NewResolvedTestCache(configs ConfigLister, reg ReloadingQueryRegistry) *ResolvedTestCache {
+	return &ResolvedTestCache{configs: configs, reg: reg}
+}
+
+// Get returns the resolved tests and the generations they were resolved from,
+// resolving them again if anything was reloaded since the last call
+func (c *ResolvedTestCache) Get() ([]ResolvedTest, Generations) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// the generations are read before the content, so that content that is
+	// reloaded in between is resolved again on the next call
+	current := Generations{Config: c.configs.GetGeneration(), Registry: c.reg.GetGeneration()}
+	if !c.resolved || current != c.generations {
+		c.tests = ResolveTests(c.configs.GetAll(), c.reg)
+		c.generations = current
+		c.resolved = true
+	}
+	return c.tests, c.generations
+}
+
+// RunQuery selects the resolved tests that match the query
+func RunQuery(tests []ResolvedTest, reg QueryRegistry, q Query) []QueryResult {
+	_, chains, workflows, _, _ := reg.GetRegistryComponents()
+	envName, envValue, matchEnvValue := strings.Cut(q.Env, "=")
+	var ret []QueryResult
+	for _, resolved := range tests {
+		test, literal := resolved.Test, resolved.Literal
+		result := QueryResult{Metadata: resolved.Metadata, Test: test.As}
+		var used registry.Usage
+		var env api.TestEnvironment
+		switch {
+		case test.MultiStageTestConfiguration != nil:
+			used = registry.UsedBy(*test.MultiStageTestConfiguration, chains, workflows)
+			env = environmentFor(*test.MultiStageTestConfiguration, workflows)
+			if test.MultiStageTestConfiguration.Workflow != nil {
+				result.Workflow = *test.MultiStageTestConfiguration.Workflow
+			}
+		case literal != nil:
+			env = literal.Environment
+		}
+		if literal != nil {
+			result.ClusterProfile = literal.ClusterProfile
+		}
+
+		if q.Step != "" && !used.Steps.Has(q.Step) ||
+			q.Chain != "" && !used.Chains.Has(q.Chain) ||
+			q.Workflow != "" && result.Workflow != q.Workflow ||
+			q.ClusterProfile != "" && string(result.ClusterProfile) != q.ClusterProfile ||
+			q.Lease != "" && !usesLease(literal, q.Lease) ||
+			q.Image != "" && !usesImage(test, literal, q.Image) {
+			continue
+		}
+		if q.Env != "" {
+			values, set := environmentValues(env, literal)[envName]
+			if !set || matchEnvValue && !values.Has(envValue) {
+				continue
+			}
+		}
+		ret = append(ret, result)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Metadata.AsString() != ret[j].Metadata.AsString() {
+			return ret[i].Metadata.AsString() < ret[j].Metadata.AsString()
+		}
+		return ret[i].Test < ret[j].Test
+	})
+	return ret
+}
+
+// environmentFor returns the variables the test sets, including the ones set
+// by its workflow
+func environmentFor(test api.MultiStageTestConfiguration, workflows registry.WorkflowByName) api.TestEnvironment {
+	env := api.TestEnvironment{}
+	if test.Workflow != nil {
+		for name, value := range workflows[*test.Workflow].Environment {
+			env[name] = value
+		}
+	}
+	for name, value := range test.Environment {
+		env[name] = value
+	}
+	return env
+}
+
+// environmentValues returns the values of the variables the test sets and of
+// the parameters of its steps that are left to their defaults. A parameter
+// with different defaults in different steps has all of them as values.
+func environmentValues(env api.TestEnvironment, literal *api.MultiStageTestConfigurationLiteral) map[string]sets.Set[string] {
+	values := map[string]sets.Set[string]{}
+	for name, value := range env {
+		values[name] = sets.New[string](value)
+	}
+	if literal == nil {
+		return values
+	}
+	for _, phase := range [][]api.LiteralTestStep{literal.Pre, literal.Test, literal.Post} {
+		for _, step := range phase {
+			for _, parameter := range step.Environment {
+				if _, set := env[parameter.Name]; set || parameter.Default == nil {
+					continue
+				}
+				if values[parameter.Name] == nil {
+					values[parameter.Name] = sets.New[string]()
+				}
+				values[parameter.Name].Insert(*parameter.Default)
+			}
+		}
+	}
+	return values
+}
+
+func usesLease(literal *api.MultiStageTestConfigurationLiteral, resourceType string) bool {
+	if literal == nil {
+		return false
+	}
+	for _, lease := range api.LeasesForTest(literal) {
+		if lease.ResourceType == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// usesImage determines if any step of the test runs in or depends on the
+// pipeline image tag, which may be given with or without the pipeline prefix
+func usesImage(test api.TestStepConfiguration, literal *api.MultiStageTestConfigurationLiteral, image string) bool {
+	normalize := func(tag string) string {
+		return strings.TrimPrefix(tag, api.PipelineImageStream+":")
+	}
+	image = normalize(image)
+	if test.ContainerTestConfiguration != nil && string(test.ContainerTestConfiguration.From) == image {
+		return true
+	}
+	if literal == nil {
+		return false
+	}
+	for _, step := range append(literal.Pre, append(literal.Test, literal.Post...)...) {
+		if step.From == image {
+			return true
+		}
+		for _, dependency := range step.Dependencies {
+			if normalize(dependency.Name) == image {
+				return true
+			}
+		}
+	}
+	for _, dependency := range literal.Dependencies {
+		if normalize(dependency) == image {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryConfigs answers queries against the resolved tests
+func QueryConfigs(tests *ResolvedTestCache, reg QueryRegistry, resolverMetrics *metrics.Metrics) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		q := QueryFromValues(r.URL.Query())
+		if q.Empty() {
+			metrics.RecordError("invalid query", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "at least one of the %s queries is required", strings.Join(sets.List(sets.KeySet(q.fields())), ", "))
+			return
+		}
+		resolved, _ := tests.Get()
+		results := RunQuery(resolved, reg, q)
+		if results == nil {
+			results = []QueryResult{}
+		}
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			metrics.RecordError("failed to marshal query results", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal query results to JSON: %v", err)
+			logrus.WithError(err).Errorf("failed to marshal query results to JSON")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(raw); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+type fakeQueryRegistry struct {
+	registry.Resolver
+	references registry.ReferenceByName
+	chains     registry.ChainByName
+	workflows  registry.WorkflowByName
+	generation int
+}
+
+func (r *fakeQueryRegistry) GetRegistryComponents() (registry.ReferenceByName, registry.ChainByName, registry.WorkflowByName, map[string]string, api.RegistryMetadata) {
+	return r.references, r.chains, r.workflows, nil, nil
+}
+
+func (r *fakeQueryRegistry) GetGeneration() int {
+	return r.generation
+}
+
+type fakeConfigLister config.ByOrgRepo
+
+func (l fakeConfigLister) GetAll() config.ByOrgRepo {
+	return config.ByOrgRepo(l)
+}
+
+func (l fakeConfigLister) GetGeneration() int {
+	return 0
+}
+
+func queryFixtures() (config.ByOrgRepo, *fakeQueryRegistry) {
+	ref := func(name string) api.TestStep {
+		return api.TestStep{Reference: &name}
+	}
+	chain := func(name string) api.TestStep {
+		return api.TestStep{Chain: &name}
+	}
+	str := func(s string) *string {
+		return &s
+	}
+	references := registry.ReferenceByName{
+		"ipi-install": {
+			As: "ipi-install", From: "installer", Commands: "install", Leases: []api.StepLease{{ResourceType: "aws-ip", Env: "IP"}},
+			Environment: []api.StepParameter{{Name: "ARCH", Default: str("amd64")}},
+		},
+		"ipi-deprovision": {As: "ipi-deprovision", From: "installer", Commands: "deprovision"},
+		"openshift-e2e-test": {
+			As: "openshift-e2e-test", From: "tests", Commands: "test",
+			Environment: []api.StepParameter{{Name: "TEST_SUITE", Default: str("openshift/conformance")}},
+		},
+		"unit": {As: "unit", From: "src", Commands: "make test", Dependencies: []api.StepDependency{{Name: "pipeline:bin", Env: "BIN"}}},
+	}
+	chains := registry.ChainByName{
+		"ipi-pre":  {As: "ipi-pre", Steps: []api.TestStep{ref("ipi-install")}},
+		"ipi-post": {As: "ipi-post", Steps: []api.TestStep{ref("ipi-deprovision")}},
+		"ipi":      {As: "ipi", Steps: []api.TestStep{chain("ipi-pre")}},
+	}
+	workflows := registry.WorkflowByName{
+		"openshift-e2e": {
+			Pre:         []api.TestStep{chain("ipi")},
+			Test:        []api.TestStep{ref("openshift-e2e-test")},
+			Post:        []api.TestStep{chain("ipi-post")},
+			Environment: api.TestEnvironment{"TEST_SUITE": "openshift/conformance"},
+		},
+	}
+	reg := &fakeQueryRegistry{
+		Resolver:   registry.NewResolver(references, chains, workflows, nil),
+		references: references,
+		chains:     chains,
+		workflows:  workflows,
+	}
+	configs := config.ByOrgRepo{
+		"org": {
+			"repo": {{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e-aws",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+						ClusterProfile: api.ClusterProfileAWS,
+						Workflow:       str("openshift-e2e"),
+					},
+				}, {
+					As: "e2e-aws-serial",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+						ClusterProfile: api.ClusterProfileAWS,
+						Workflow:       str("openshift-e2e"),
+						Test:           []api.TestStep{ref("unit")},
+						Environment:    api.TestEnvironment{"TEST_SUITE": "openshift/conformance/serial", "ARCH": "arm64"},
+					},
+				}, {
+					As:                         "unit",
+					ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"},
+				}, {
+					As: "broken",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+						Test: []api.TestStep{ref("missing")},
+					},
+				}},
+			}},
+			"other": {{
+				Metadata: api.Metadata{Org: "org", Repo: "other", Branch: "release-4.15", Variant: "gcp"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e-gcp",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						ClusterProfile: api.ClusterProfileGCP,
+						Test:           []api.LiteralTestStep{{As: "test", From: "tests", Commands: "test"}},
+						Environment:    api.TestEnvironment{"TEST_SUITE": "openshift/conformance"},
+					},
+				}},
+			}},
+		},
+	}
+	return configs, reg
+}
+
+func TestRunQuery(t *testing.T) {
+	configs, reg := queryFixtures()
+	master := api.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+	gcp := api.Metadata{Org: "org", Repo: "other", Branch: "release-4.15", Variant: "gcp"}
+	e2eAWS := QueryResult{Metadata: master, Test: "e2e-aws", Workflow: "openshift-e2e", ClusterProfile: api.ClusterProfileAWS}
+	e2eAWSSerial := QueryResult{Metadata: master, Test: "e2e-aws-serial", Workflow: "openshift-e2e", ClusterProfile: api.ClusterProfileAWS}
+	e2eGCP := QueryResult{Metadata: gcp, Test: "e2e-gcp", ClusterProfile: api.ClusterProfileGCP}
+	unit := QueryResult{Metadata: master, Test: "unit"}
+	for _, tc := range []struct {
+		name     string
+		query    Query
+		expected []QueryResult
+	}{{
+		name:     "step used through nested chains of a workflow",
+		query:    Query{Step: "ipi-install"},
+		expected: []QueryResult{e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "step overridden by the test is not used",
+		query:    Query{Step: "openshift-e2e-test"},
+		expected: []QueryResult{e2eAWS},
+	}, {
+		name:     "chain used through another chain",
+		query:    Query{Chain: "ipi-pre"},
+		expected: []QueryResult{e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "workflow",
+		query:    Query{Workflow: "openshift-e2e"},
+		expected: []QueryResult{e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "cluster profile",
+		query:    Query{ClusterProfile: "gcp"},
+		expected: []QueryResult{e2eGCP},
+	}, {
+		name:     "env set to any value",
+		query:    Query{Env: "TEST_SUITE"},
+		expected: []QueryResult{e2eGCP, e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "env set to a value, including through the workflow",
+		query:    Query{Env: "TEST_SUITE=openshift/conformance"},
+		expected: []QueryResult{e2eGCP, e2eAWS},
+	}, {
+		name:     "env left to the default of a step parameter",
+		query:    Query{Env: "ARCH"},
+		expected: []QueryResult{e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "env with the value of a step parameter default",
+		query:    Query{Env: "ARCH=amd64"},
+		expected: []QueryResult{e2eAWS},
+	}, {
+		name:     "image used by a container test and as a step dependency",
+		query:    Query{Image: "src"},
+		expected: []QueryResult{e2eAWSSerial, unit},
+	}, {
+		name:     "image given with the pipeline prefix",
+		query:    Query{Image: "pipeline:bin"},
+		expected: []QueryResult{e2eAWSSerial},
+	}, {
+		name:     "lease from a step",
+		query:    Query{Lease: "aws-ip"},
+		expected: []QueryResult{e2eAWS, e2eAWSSerial},
+	}, {
+		name:     "lease from the cluster profile",
+		query:    Query{Lease: "gcp-quota-slice"},
+		expected: []QueryResult{e2eGCP},
+	}, {
+		name:     "all fields must match",
+		query:    Query{Workflow: "openshift-e2e", Image: "src"},
+		expected: []QueryResult{e2eAWSSerial},
+	}, {
+		name:  "nothing matches",
+		query: Query{Step: "unknown"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, RunQuery(ResolveTests(configs, reg), reg, tc.query)); diff != "" {
+				t.Errorf("unexpected results: %s", diff)
+			}
+		})
+	}
+}
+
+func TestQueryConfigs(t *testing.T) {
+	configs, reg := queryFixtures()
+	srv := httptest.NewServer(QueryConfigs(NewResolvedTestCache(fakeConfigLister(configs), reg), reg, metrics.NewMetrics("test")))
+	defer srv.Close()
+
+	results, err := NewResolverClient(srv.URL).Query(Query{Workflow: "openshift-e2e", Env: "TEST_SUITE=openshift/conformance"})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	expected := []QueryResult{{
+		Metadata:       api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+		Test:           "e2e-aws",
+		Workflow:       "openshift-e2e",
+		ClusterProfile: api.ClusterProfileAWS,
+	}}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results: %s", diff)
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an empty query to be rejected, got status %d", resp.StatusCode)
+	}
+}
+
+type countingQueryRegistry struct {
+	*fakeQueryRegistry
+	resolved int
+}
+
+func (r *countingQueryRegistry) Resolve(name string, config api.MultiStageTestConfiguration) (api.MultiStageTestConfigurationLiteral, error) {
+	r.resolved++
+	return r.fakeQueryRegistry.Resolve(name, config)
+}
+
+func TestResolvedTestCache(t *testing.T) {
+	configs, fake := queryFixtures()
+	reg := &countingQueryRegistry{fakeQueryRegistry: fake}
+	cache := NewResolvedTestCache(fakeConfigLister(configs), reg)
+
+	tests, generations := cache.Get()
+	var names []string
+	for _, test := range tests {
+		if test.Test.MultiStageTestConfiguration != nil && test.Literal == nil {
+			t.Errorf("test %s was not resolved", test.Test.As)
+		}
+		names = append(names, test.Test.As)
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff([]string{"e2e-aws", "e2e-aws-serial", "e2e-gcp", "unit"}, names); diff != "" {
+		t.Errorf("unexpected tests: %s", diff)
+	}
+	// e2e-aws, e2e-aws-serial and the broken test
+	if reg.resolved != 3 {
+		t.Errorf("expected 3 tests to be resolved, got %d", reg.resolved)
+	}
+
+	cache.Get()
+	if reg.resolved != 3 {
+		t.Errorf("expected the tests to be resolved only once, got %d resolutions", reg.resolved)
+	}
+
+	fake.generation++
+	_, reloaded := cache.Get()
+	if reg.resolved != 6 {
+		t.Errorf("expected the tests to be resolved again after a reload, got %d resolutions", reg.resolved)
+	}
+	if diff := cmp.Diff(Generations{Registry: generations.Registry + 1}, reloaded); diff != "" {
+		t.Errorf("unexpected generations: %s", diff)
+	}
+}
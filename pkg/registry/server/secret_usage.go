@@ -0,0 +1,487 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+const (
+	// ciNamespace is where the secrets mounted into the pods of the jobs live,
+	// which include the ones of the cluster profiles
+	ciNamespace = "ci"
+	// testCredentialsNamespace is where the secrets mounted into steps live
+	testCredentialsNamespace = "test-credentials"
+
+	clusterProfileSecretPrefix = "cluster-secrets-"
+)
+
+// SecretReference identifies a secret in the build clusters
+type SecretReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (r SecretReference) String() string {
+	return r.Namespace + "/" + r.Name
+}
+
+// testOnly determines whether the secret exists only to be consumed by tests.
+// Other secrets may be used by the infrastructure, so they cannot be assumed
+// to be unused when no test consumes them.
+func (r SecretReference) testOnly() bool {
+	return r.Namespace == testCredentialsNamespace || r.Namespace == ciNamespace && strings.HasPrefix(r.Name, clusterProfileSecretPrefix)
+}
+
+// SecretSource is a field of a Vault item that ci-secret-bootstrap populates
+// a key of a secret from
+type SecretSource struct {
+	Item  string `json:"item"`
+	Field string `json:"field"`
+	// Key is the key of the secret holding the content of the field
+	Key string `json:"key"`
+	// Clusters are the clusters the secret is populated in
+	Clusters []string `json:"clusters"`
+}
+
+// SecretConsumer is a test that gets a secret
+type SecretConsumer struct {
+	Metadata api.Metadata `json:"metadata"`
+	Test     string       `json:"test"`
+	// Step is the step the secret is mounted into, if the secret is a
+	// credential of a step
+	Step string `json:"step,omitempty"`
+	// ClusterProfile is the cluster profile of the test, if the secret is
+	// the one of the cluster profile
+	ClusterProfile api.ClusterProfile `json:"cluster_profile,omitempty"`
+}
+
+// SecretUsage links a secret to the Vault fields it is populated from and to
+// the tests that get it
+type SecretUsage struct {
+	Secret    SecretReference  `json:"secret"`
+	Sources   []SecretSource   `json:"sources,omitempty"`
+	Consumers []SecretConsumer `json:"consumers,omitempty"`
+}
+
+// SecretUsageGraph links the Vault items distributed by ci-secret-bootstrap
+// to the tests that consume the secrets populated from them
+type SecretUsageGraph struct {
+	secrets map[SecretReference]*SecretUsage
+}
+
+func (g *SecretUsageGraph) usage(ref SecretReference) *SecretUsage {
+	usage, ok := g.secrets[ref]
+	if !ok {
+		usage = &SecretUsage{Secret: ref}
+		g.secrets[ref] = usage
+	}
+	return usage
+}
+
+// BuildSecretUsageGraph collects the secrets populated by ci-secret-bootstrap
+// and the secrets that the resolved tests get through the
+// credentials of their steps, their own secrets or their cluster profiles.
+func BuildSecretUsageGraph(tests []ResolvedTest, bootstrap secretbootstrap.Config) *SecretUsageGraph {
+	g := &SecretUsageGraph{secrets: map[SecretReference]*SecretUsage{}}
+	for _, secret := range bootstrap.Secrets {
+		clusters := map[SecretReference]sets.Set[string]{}
+		for _, to := range secret.To {
+			ref := SecretReference{Namespace: to.Namespace, Name: to.Name}
+			if clusters[ref] == nil {
+				clusters[ref] = sets.New[string]()
+			}
+			clusters[ref].Insert(to.Cluster)
+		}
+		for ref, inClusters := range clusters {
+			usage := g.usage(ref)
+			for key, from := range secret.From {
+				source := SecretSource{Key: key, Clusters: sets.List(inClusters)}
+				if from.Item != "" {
+					source.Item, source.Field = from.Item, from.Field
+					usage.Sources = append(usage.Sources, source)
+				}
+				for _, data := range from.DockerConfigJSONData {
+					for _, field := range []string{data.AuthField, data.EmailField} {
+						if field != "" {
+							source.Item, source.Field = data.Item, field
+							usage.Sources = append(usage.Sources, source)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, resolved := range tests {
+		test := resolved.Test
+		consumer := SecretConsumer{Metadata: resolved.Metadata, Test: test.As}
+		add := func(ref SecretReference, consumer SecretConsumer) {
+			usage := g.usage(ref)
+			usage.Consumers = append(usage.Consumers, consumer)
+		}
+		if test.ContainerTestConfiguration != nil {
+			for _, secret := range append([]*api.Secret{test.Secret}, test.Secrets...) {
+				if secret != nil {
+					add(SecretReference{Namespace: ciNamespace, Name: secret.Name}, consumer)
+				}
+			}
+		}
+		profile := test.GetClusterProfile()
+		if literal := resolved.Literal; literal != nil {
+			profile = literal.ClusterProfile
+			for _, step := range append(literal.Pre, append(literal.Test, literal.Post...)...) {
+				for _, credential := range step.Credentials {
+					stepConsumer := consumer
+					stepConsumer.Step = step.As
+					add(SecretReference{Namespace: credential.Namespace, Name: credential.Name}, stepConsumer)
+				}
+			}
+		}
+		if profile != "" {
+			profileConsumer := consumer
+			profileConsumer.ClusterProfile = profile
+			add(SecretReference{Namespace: ciNamespace, Name: profile.Secret()}, profileConsumer)
+		}
+	}
+
+	for _, usage := range g.secrets {
+		sort.Slice(usage.Sources, func(i, j int) bool {
+			a, b := usage.Sources[i], usage.Sources[j]
+			if a.Key != b.Key {
+				return a.Key < b.Key
+			}
+			if a.Item != b.Item {
+				return a.Item < b.Item
+			}
+			return a.Field < b.Field
+		})
+		sort.Slice(usage.Consumers, func(i, j int) bool {
+			a, b := usage.Consumers[i], usage.Consumers[j]
+			if a.Metadata.AsString() != b.Metadata.AsString() {
+				return a.Metadata.AsString() < b.Metadata.AsString()
+			}
+			if a.Test != b.Test {
+				return a.Test < b.Test
+			}
+			if a.Step != b.Step {
+				return a.Step < b.Step
+			}
+			return a.ClusterProfile < b.ClusterProfile
+		})
+	}
+	return g
+}
+
+// sorted returns the usage of the secrets selected by the filter, sorted by
+// namespace and name
+func (g *SecretUsageGraph) sorted(filter func(SecretUsage) (SecretUsage, bool)) []SecretUsage {
+	var ret []SecretUsage
+	for _, usage := range g.secrets {
+		if filtered, ok := filter(*usage); ok {
+			ret = append(ret, filtered)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Secret.String() < ret[j].Secret.String()
+	})
+	return ret
+}
+
+// Secret returns the usage of the secret, if it is known to the graph
+func (g *SecretUsageGraph) Secret(ref SecretReference) *SecretUsage {
+	return g.secrets[ref]
+}
+
+// ItemUsage returns the secrets populated from the Vault item, or only from
+// its field if one is given. Only the sources of the secrets that match are
+// returned alongside every test consuming them.
+func (g *SecretUsageGraph) ItemUsage(item, field string) []SecretUsage {
+	return g.sorted(func(usage SecretUsage) (SecretUsage, bool) {
+		var sources []SecretSource
+		for _, source := range usage.Sources {
+			if source.Item == item && (field == "" || source.Field == field) {
+				sources = append(sources, source)
+			}
+		}
+		usage.Sources = sources
+		return usage, len(sources) > 0
+	})
+}
+
+// TestSecrets returns the secrets the test gets. Only the ways the test gets
+// every secret are returned as its consumers.
+func (g *SecretUsageGraph) TestSecrets(metadata api.Metadata, test string) []SecretUsage {
+	return g.sorted(func(usage SecretUsage) (SecretUsage, bool) {
+		var consumers []SecretConsumer
+		for _, consumer := range usage.Consumers {
+			if consumer.Metadata == metadata && consumer.Test == test {
+				consumers = append(consumers, consumer)
+			}
+		}
+		usage.Consumers = consumers
+		return usage, len(consumers) > 0
+	})
+}
+
+// consumed determines whether the secret is, or may be, used by anything
+func (u SecretUsage) consumed() bool {
+	return len(u.Consumers) > 0 || !u.Secret.testOnly()
+}
+
+// UnusedField is a field of a Vault item that nothing consumes
+type UnusedField struct {
+	Item  string `json:"item"`
+	Field string `json:"field"`
+	// DistributedTo are the secrets ci-secret-bootstrap populates from the
+	// field, if any. No test consumes any of them.
+	DistributedTo []SecretReference `json:"distributed_to,omitempty"`
+}
+
+// UnusedFields lists the fields of the Vault items that are either not
+// distributed by ci-secret-bootstrap at all or only distributed to secrets
+// that exist for tests and that no test consumes. The items are keyed by
+// their path in the Vault KV store, which includes the DPTP prefix of the
+// bootstrap config like the items the loaded bootstrap config refers to, as
+// returned by secrets.ReadOnlyClient.GetInUseInformationForAllItems.
+func (g *SecretUsageGraph) UnusedFields(items map[string]secrets.SecretUsageComparer) []UnusedField {
+	consumed := map[string]sets.Set[string]{}
+	distributedTo := map[string]map[string]sets.Set[SecretReference]{}
+	for _, usage := range g.secrets {
+		for _, source := range usage.Sources {
+			if usage.consumed() {
+				if consumed[source.Item] == nil {
+					consumed[source.Item] = sets.New[string]()
+				}
+				consumed[source.Item].Insert(source.Field)
+				continue
+			}
+			if distributedTo[source.Item] == nil {
+				distributedTo[source.Item] = map[string]sets.Set[SecretReference]{}
+			}
+			if distributedTo[source.Item][source.Field] == nil {
+				distributedTo[source.Item][source.Field] = sets.New[SecretReference]()
+			}
+			distributedTo[source.Item][source.Field].Insert(usage.Secret)
+		}
+	}
+
+	var ret []UnusedField
+	for _, name := range sets.List(sets.KeySet(items)) {
+		item := items[name]
+		inUse := consumed[name]
+		if inUse == nil {
+			inUse = sets.New[string]()
+		}
+		// this marks the consumed fields as in use, fields that are
+		// consumed but missing from the item are of no concern here
+		item.UnusedFields(inUse)
+		for _, field := range sets.List(item.SuperfluousFields()) {
+			unused := UnusedField{Item: name, Field: field}
+			if refs := distributedTo[name][field]; refs.Len() > 0 {
+				unused.DistributedTo = refs.UnsortedList()
+				sort.Slice(unused.DistributedTo, func(i, j int) bool {
+					return unused.DistributedTo[i].String() < unused.DistributedTo[j].String()
+				})
+			}
+			ret = append(ret, unused)
+		}
+	}
+	return ret
+}
+
+const (
+	SecretItemQuery      = "item"
+	SecretFieldQuery     = "field"
+	SecretNamespaceQuery = "namespace"
+	SecretNameQuery      = "name"
+	TestQuery            = "test"
+)
+
+// SecretUsageQuery selects what to return from the secret usage graph: either
+// the usage of a Vault item, the usage of a secret or the secrets of a test
+type SecretUsageQuery struct {
+	// Item selects the secrets populated from the Vault item
+	Item string `json:"item,omitempty"`
+	// Field narrows an Item query down to a single field
+	Field string `json:"field,omitempty"`
+	// Namespace and Name select a secret
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Metadata and Test select the secrets a test gets
+	Metadata api.Metadata `json:"metadata,omitempty"`
+	Test     string       `json:"test,omitempty"`
+}
+
+// Validate ensures that the query selects exactly one kind of result
+func (q SecretUsageQuery) Validate() error {
+	var kinds int
+	if q.Item != "" {
+		kinds++
+	} else if q.Field != "" {
+		return errors.New("field can only be queried together with item")
+	}
+	if q.Namespace != "" || q.Name != "" {
+		kinds++
+		if q.Namespace == "" || q.Name == "" {
+			return errors.New("namespace and name must be queried together")
+		}
+	}
+	if q.Test != "" || q.Metadata != (api.Metadata{}) {
+		kinds++
+		if q.Test == "" || q.Metadata.Org == "" || q.Metadata.Repo == "" || q.Metadata.Branch == "" {
+			return errors.New("org, repo, branch and test must be queried together")
+		}
+	}
+	if kinds != 1 {
+		return errors.New("exactly one of item, namespace and name, or org, repo, branch and test must be queried")
+	}
+	return nil
+}
+
+// Values encodes the query as URL query parameters
+func (q SecretUsageQuery) Values() url.Values {
+	values := url.Values{}
+	for name, value := range map[string]string{
+		SecretItemQuery:      q.Item,
+		SecretFieldQuery:     q.Field,
+		SecretNamespaceQuery: q.Namespace,
+		SecretNameQuery:      q.Name,
+		OrgQuery:             q.Metadata.Org,
+		RepoQuery:            q.Metadata.Repo,
+		BranchQuery:          q.Metadata.Branch,
+		VariantQuery:         q.Metadata.Variant,
+		TestQuery:            q.Test,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// SecretUsageQueryFromValues decodes a query from URL query parameters
+func SecretUsageQueryFromValues(values url.Values) SecretUsageQuery {
+	return SecretUsageQuery{
+		Item:      values.Get(SecretItemQuery),
+		Field:     values.Get(SecretFieldQuery),
+		Namespace: values.Get(SecretNamespaceQuery),
+		Name:      values.Get(SecretNameQuery),
+		Metadata: api.Metadata{
+			Org:     values.Get(OrgQuery),
+			Repo:    values.Get(RepoQuery),
+			Branch:  values.Get(BranchQuery),
+			Variant: values.Get(VariantQuery),
+		},
+		Test: values.Get(TestQuery),
+	}
+}
+
+// Run answers the query from the graph
+func (g *SecretUsageGraph) Run(q SecretUsageQuery) []SecretUsage {
+	switch {
+	case q.Item != "":
+		return g.ItemUsage(q.Item, q.Field)
+	case q.Test != "":
+		return g.TestSecrets(q.Metadata, q.Test)
+	default:
+		if usage := g.Secret(SecretReference{Namespace: q.Namespace, Name: q.Name}); usage != nil {
+			return []SecretUsage{*usage}
+		}
+		return nil
+	}
+}
+
+// secretUsageCache keeps the secret usage graph until the resolved tests or
+// the bootstrap config change
+type secretUsageCache struct {
+	tests               *ResolvedTestCache
+	bootstrapConfigPath string
+
+	lock        sync.Mutex
+	generations Generations
+	modified    time.Time
+	size        int64
+	graph       *SecretUsageGraph
+}
+
+func (c *secretUsageCache) get() (*SecretUsageGraph, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	info, err := os.Stat(c.bootstrapConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	tests, generations := c.tests.Get()
+	if c.graph != nil && generations == c.generations && info.ModTime().Equal(c.modified) && info.Size() == c.size {
+		return c.graph, nil
+	}
+	var bootstrap secretbootstrap.Config
+	if err := secretbootstrap.LoadConfigFromFile(c.bootstrapConfigPath, &bootstrap); err != nil {
+		return nil, err
+	}
+	c.graph = BuildSecretUsageGraph(tests, bootstrap)
+	c.generations, c.modified, c.size = generations, info.ModTime(), info.Size()
+	return c.graph, nil
+}
+
+// SecretUsageHandler answers queries against the secret usage graph built
+// from the resolved tests and from the bootstrap config at the path. The
+// graph is only built again when either of them changes.
+func SecretUsageHandler(tests *ResolvedTestCache, bootstrapConfigPath string, resolverMetrics *metrics.Metrics) http.HandlerFunc {
+	cache := &secretUsageCache{tests: tests, bootstrapConfigPath: bootstrapConfigPath}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNotImplemented)
+			_, _ = w.Write([]byte(http.StatusText(http.StatusNotImplemented)))
+			return
+		}
+		q := SecretUsageQueryFromValues(r.URL.Query())
+		if err := q.Validate(); err != nil {
+			metrics.RecordError("invalid query", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "invalid query: %v", err)
+			return
+		}
+		graph, err := cache.get()
+		if err != nil {
+			metrics.RecordError("failed to load bootstrap config", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to load the ci-secret-bootstrap config: %v", err)
+			logrus.WithError(err).Error("failed to load the ci-secret-bootstrap config")
+			return
+		}
+		results := graph.Run(q)
+		if results == nil {
+			results = []SecretUsage{}
+		}
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			metrics.RecordError("failed to marshal secret usage", resolverMetrics.ErrorRate)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "failed to marshal secret usage to JSON: %v", err)
+			logrus.WithError(err).Errorf("failed to marshal secret usage to JSON")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(raw); err != nil {
+			logrus.WithError(err).Error("Failed to write response")
+		}
+	}
+}
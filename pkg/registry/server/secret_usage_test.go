@@ -0,0 +1,318 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/test-infra/prow/metrics"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/secretbootstrap"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/secrets"
+)
+
+func secretUsageFixtures() (config.ByOrgRepo, *fakeQueryRegistry, secretbootstrap.Config) {
+	ref := func(name string) api.TestStep {
+		return api.TestStep{Reference: &name}
+	}
+	workflow := "ipi-aws"
+	references := registry.ReferenceByName{
+		"ipi-install": {
+			As: "ipi-install", From: "installer", Commands: "install",
+			Credentials: []api.CredentialReference{{Namespace: "test-credentials", Name: "aws-creds", MountPath: "/var/run/aws"}},
+		},
+		"ipi-deprovision": {As: "ipi-deprovision", From: "installer", Commands: "deprovision"},
+	}
+	workflows := registry.WorkflowByName{
+		workflow: {
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre:            []api.TestStep{ref("ipi-install")},
+			Post:           []api.TestStep{ref("ipi-deprovision")},
+		},
+	}
+	reg := &fakeQueryRegistry{
+		Resolver:   registry.NewResolver(references, nil, workflows, nil),
+		references: references,
+		workflows:  workflows,
+	}
+	configs := config.ByOrgRepo{
+		"org": {
+			"repo": {{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{{
+					As:                          "e2e-aws",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: &workflow},
+				}, {
+					As:                         "unit",
+					Secret:                     &api.Secret{Name: "github-token", MountPath: "/var/run/github"},
+					ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"},
+				}, {
+					As: "broken",
+					MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+						Test: []api.TestStep{ref("missing")},
+					},
+				}},
+			}},
+			"other": {{
+				Metadata: api.Metadata{Org: "org", Repo: "other", Branch: "master", Variant: "gcp"},
+				Tests: []api.TestStepConfiguration{{
+					As: "e2e-gcp",
+					MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+						ClusterProfile: api.ClusterProfileGCP,
+						Test:           []api.LiteralTestStep{{As: "test", From: "tests", Commands: "test"}},
+					},
+				}},
+			}},
+		},
+	}
+	bootstrap := secretbootstrap.Config{
+		VaultDPTPPrefix: "dptp",
+		Secrets: []secretbootstrap.SecretConfig{{
+			From: map[string]secretbootstrap.ItemContext{"credentials": {Item: "dptp/aws", Field: "credentials"}},
+			To: []secretbootstrap.SecretContext{
+				{Cluster: "build01", Namespace: "test-credentials", Name: "aws-creds"},
+				{Cluster: "build02", Namespace: "test-credentials", Name: "aws-creds"},
+			},
+		}, {
+			From: map[string]secretbootstrap.ItemContext{
+				".awscred": {Item: "dptp/aws", Field: "credentials"},
+				"ssh-key":  {Item: "dptp/ssh", Field: "private"},
+			},
+			To: []secretbootstrap.SecretContext{{Cluster: "build01", Namespace: "ci", Name: "cluster-secrets-aws"}},
+		}, {
+			From: map[string]secretbootstrap.ItemContext{"token": {Item: "dptp/github", Field: "token"}},
+			To: []secretbootstrap.SecretContext{
+				{Cluster: "build01", Namespace: "ci", Name: "github-token"},
+				{Cluster: "build01", Namespace: "ci", Name: "deck-token"},
+			},
+		}, {
+			From: map[string]secretbootstrap.ItemContext{"sa.json": {Item: "dptp/azure", Field: "sa"}},
+			To:   []secretbootstrap.SecretContext{{Cluster: "build01", Namespace: "ci", Name: "cluster-secrets-azure4"}},
+		}, {
+			From: map[string]secretbootstrap.ItemContext{"token": {Item: "dptp/slack", Field: "token"}},
+			To:   []secretbootstrap.SecretContext{{Cluster: "app.ci", Namespace: "ci", Name: "slack-token"}},
+		}},
+	}
+	return configs, reg, bootstrap
+}
+
+func TestSecretUsageGraph(t *testing.T) {
+	configs, reg, bootstrap := secretUsageFixtures()
+	graph := BuildSecretUsageGraph(ResolveTests(configs, reg), bootstrap)
+	master := api.Metadata{Org: "org", Repo: "repo", Branch: "master"}
+	gcp := api.Metadata{Org: "org", Repo: "other", Branch: "master", Variant: "gcp"}
+	awsCreds := SecretUsage{
+		Secret:    SecretReference{Namespace: "test-credentials", Name: "aws-creds"},
+		Sources:   []SecretSource{{Item: "dptp/aws", Field: "credentials", Key: "credentials", Clusters: []string{"build01", "build02"}}},
+		Consumers: []SecretConsumer{{Metadata: master, Test: "e2e-aws", Step: "ipi-install"}},
+	}
+	awsProfile := SecretUsage{
+		Secret: SecretReference{Namespace: "ci", Name: "cluster-secrets-aws"},
+		Sources: []SecretSource{
+			{Item: "dptp/aws", Field: "credentials", Key: ".awscred", Clusters: []string{"build01"}},
+			{Item: "dptp/ssh", Field: "private", Key: "ssh-key", Clusters: []string{"build01"}},
+		},
+		Consumers: []SecretConsumer{{Metadata: master, Test: "e2e-aws", ClusterProfile: api.ClusterProfileAWS}},
+	}
+	gcpProfile := SecretUsage{
+		Secret:    SecretReference{Namespace: "ci", Name: "cluster-secrets-gcp"},
+		Consumers: []SecretConsumer{{Metadata: gcp, Test: "e2e-gcp", ClusterProfile: api.ClusterProfileGCP}},
+	}
+	githubToken := SecretUsage{
+		Secret:    SecretReference{Namespace: "ci", Name: "github-token"},
+		Sources:   []SecretSource{{Item: "dptp/github", Field: "token", Key: "token", Clusters: []string{"build01"}}},
+		Consumers: []SecretConsumer{{Metadata: master, Test: "unit"}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		query    SecretUsageQuery
+		expected []SecretUsage
+	}{{
+		name:  "item used by a step credential and a cluster profile",
+		query: SecretUsageQuery{Item: "dptp/aws"},
+		expected: []SecretUsage{{
+			Secret:    awsProfile.Secret,
+			Sources:   awsProfile.Sources[:1],
+			Consumers: awsProfile.Consumers,
+		}, awsCreds},
+	}, {
+		name:  "field of an item",
+		query: SecretUsageQuery{Item: "dptp/ssh", Field: "private"},
+		expected: []SecretUsage{{
+			Secret:    awsProfile.Secret,
+			Sources:   awsProfile.Sources[1:],
+			Consumers: awsProfile.Consumers,
+		}},
+	}, {
+		name:  "item distributed to a secret nothing in CI uses",
+		query: SecretUsageQuery{Item: "dptp/slack"},
+		expected: []SecretUsage{{
+			Secret:  SecretReference{Namespace: "ci", Name: "slack-token"},
+			Sources: []SecretSource{{Item: "dptp/slack", Field: "token", Key: "token", Clusters: []string{"app.ci"}}},
+		}},
+	}, {
+		name:     "secret",
+		query:    SecretUsageQuery{Namespace: "ci", Name: "github-token"},
+		expected: []SecretUsage{githubToken},
+	}, {
+		name:     "secrets of a multi-stage test resolved from its workflow",
+		query:    SecretUsageQuery{Metadata: master, Test: "e2e-aws"},
+		expected: []SecretUsage{awsProfile, awsCreds},
+	}, {
+		name:     "secrets of a container test",
+		query:    SecretUsageQuery{Metadata: master, Test: "unit"},
+		expected: []SecretUsage{githubToken},
+	}, {
+		name:     "secret of a cluster profile that is not distributed",
+		query:    SecretUsageQuery{Metadata: gcp, Test: "e2e-gcp"},
+		expected: []SecretUsage{gcpProfile},
+	}, {
+		name:  "unknown item",
+		query: SecretUsageQuery{Item: "dptp/unknown"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.query.Validate(); err != nil {
+				t.Fatalf("invalid query: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, graph.Run(tc.query)); diff != "" {
+				t.Errorf("unexpected usage: %s", diff)
+			}
+		})
+	}
+}
+
+type fakeUsageComparer struct {
+	allFields, inUseFields sets.Set[string]
+}
+
+func (f *fakeUsageComparer) LastChanged() time.Time { return time.Time{} }
+func (f *fakeUsageComparer) Version() int           { return 1 }
+func (f *fakeUsageComparer) UnusedFields(inUse sets.Set[string]) sets.Set[string] {
+	f.inUseFields.Insert(inUse.UnsortedList()...)
+	return inUse.Difference(f.allFields)
+}
+func (f *fakeUsageComparer) SuperfluousFields() sets.Set[string] {
+	return f.allFields.Difference(f.inUseFields)
+}
+
+func TestSecretUsageGraphUnusedFields(t *testing.T) {
+	configs, reg, bootstrap := secretUsageFixtures()
+	graph := BuildSecretUsageGraph(ResolveTests(configs, reg), bootstrap)
+	items := map[string]secrets.SecretUsageComparer{}
+	for name, fields := range map[string][]string{
+		"dptp/aws":    {"credentials", "old-credentials"},
+		"dptp/ssh":    {"private"},
+		"dptp/github": {"token"},
+		"dptp/azure":  {"sa"},
+		"dptp/slack":  {"token"},
+		"dptp/orphan": {"password", "user"},
+	} {
+		items[name] = &fakeUsageComparer{allFields: sets.New[string](fields...), inUseFields: sets.New[string]()}
+	}
+	expected := []UnusedField{
+		{Item: "dptp/aws", Field: "old-credentials"},
+		{Item: "dptp/azure", Field: "sa", DistributedTo: []SecretReference{{Namespace: "ci", Name: "cluster-secrets-azure4"}}},
+		{Item: "dptp/orphan", Field: "password"},
+		{Item: "dptp/orphan", Field: "user"},
+	}
+	if diff := cmp.Diff(expected, graph.UnusedFields(items)); diff != "" {
+		t.Errorf("unexpected unused fields: %s", diff)
+	}
+}
+
+func TestSecretUsageQueryValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		query       SecretUsageQuery
+		expectedErr string
+	}{{
+		name:        "empty",
+		expectedErr: "exactly one of item, namespace and name, or org, repo, branch and test must be queried",
+	}, {
+		name:        "field without item",
+		query:       SecretUsageQuery{Field: "token"},
+		expectedErr: "field can only be queried together with item",
+	}, {
+		name:        "namespace without name",
+		query:       SecretUsageQuery{Namespace: "ci"},
+		expectedErr: "namespace and name must be queried together",
+	}, {
+		name:        "test without branch",
+		query:       SecretUsageQuery{Metadata: api.Metadata{Org: "org", Repo: "repo"}, Test: "unit"},
+		expectedErr: "org, repo, branch and test must be queried together",
+	}, {
+		name:        "item and secret",
+		query:       SecretUsageQuery{Item: "dptp/aws", Namespace: "ci", Name: "token"},
+		expectedErr: "exactly one of item, namespace and name, or org, repo, branch and test must be queried",
+	}, {
+		name:  "test with variant",
+		query: SecretUsageQuery{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master", Variant: "v"}, Test: "unit"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var actualErr string
+			if err := tc.query.Validate(); err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestSecretUsageHandler(t *testing.T) {
+	configs, reg, bootstrap := secretUsageFixtures()
+	path := filepath.Join(t.TempDir(), "_config.yaml")
+	if err := secretbootstrap.SaveConfigToFile(path, &bootstrap); err != nil {
+		t.Fatalf("failed to save the bootstrap config: %v", err)
+	}
+	srv := httptest.NewServer(SecretUsageHandler(NewResolvedTestCache(fakeConfigLister(configs), reg), path, metrics.NewMetrics("secretusagetest")))
+	defer srv.Close()
+
+	usage, err := NewResolverClient(srv.URL).SecretUsage(SecretUsageQuery{Item: "dptp/github"})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	expected := []SecretUsage{{
+		Secret:  SecretReference{Namespace: "ci", Name: "deck-token"},
+		Sources: []SecretSource{{Item: "dptp/github", Field: "token", Key: "token", Clusters: []string{"build01"}}},
+	}, {
+		Secret:    SecretReference{Namespace: "ci", Name: "github-token"},
+		Sources:   []SecretSource{{Item: "dptp/github", Field: "token", Key: "token", Clusters: []string{"build01"}}},
+		Consumers: []SecretConsumer{{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}, Test: "unit"}},
+	}}
+	if diff := cmp.Diff(expected, usage); diff != "" {
+		t.Errorf("unexpected usage: %s", diff)
+	}
+
+	// the graph is built again once the bootstrap config changes
+	bootstrap.Secrets[2].To = bootstrap.Secrets[2].To[:1]
+	if err := secretbootstrap.SaveConfigToFile(path, &bootstrap); err != nil {
+		t.Fatalf("failed to save the bootstrap config: %v", err)
+	}
+	usage, err = NewResolverClient(srv.URL).SecretUsage(SecretUsageQuery{Item: "dptp/github"})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if diff := cmp.Diff(expected[1:], usage); diff != "" {
+		t.Errorf("unexpected usage after the bootstrap config changed: %s", diff)
+	}
+
+	resp, err := http.Get(srv.URL + "?field=token")
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an invalid query to be rejected, got status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// Usage holds the names of the registry components a test runs
+type Usage struct {
+	Steps, Chains sets.Set[string]
+}
+
+// UsedBy collects the steps and chains that are run by the test, taking
+// into account the phases it overrides from its workflow
+func UsedBy(test api.MultiStageTestConfiguration, chains ChainByName, workflows WorkflowByName) Usage {
+	used := Usage{Steps: sets.New[string](), Chains: sets.New[string]()}
+	var walk func([]api.TestStep)
+	walk = func(steps []api.TestStep) {
+		for _, step := range steps {
+			switch {
+			case step.Reference != nil:
+				used.Steps.Insert(*step.Reference)
+			case step.Chain != nil:
+				if used.Chains.Has(*step.Chain) {
+					continue
+				}
+				used.Chains.Insert(*step.Chain)
+				walk(chains[*step.Chain].Steps)
+			}
+		}
+	}
+	pre, tests, post := test.Pre, test.Test, test.Post
+	if test.Workflow != nil {
+		workflow := workflows[*test.Workflow]
+		if pre == nil {
+			pre = workflow.Pre
+		}
+		if tests == nil {
+			tests = workflow.Test
+		}
+		if post == nil {
+			post = workflow.Post
+		}
+	}
+	for _, phase := range [][]api.TestStep{pre, tests, post} {
+		walk(phase)
+	}
+	return used
+}
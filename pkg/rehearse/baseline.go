@@ -0,0 +1,205 @@
+package rehearse
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/kube"
+	"k8s.io/test-infra/prow/pod-utils/decorate"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/jobconfig"
+)
+
+// baselineRunCount is how many of the most recent runs of a job are
+// considered to decide whether it is failing without the rehearsed change
+const baselineRunCount = 3
+
+// RehearsalOutcome classifies the result of a rehearsal against the result
+// of the job it rehearses without the change
+type RehearsalOutcome string
+
+const (
+	// OutcomeNewlyFailing means the rehearsal failed while the job passes without the change
+	OutcomeNewlyFailing RehearsalOutcome = "newly failing"
+	// OutcomeAlreadyFailing means both the rehearsal and the job without the change fail
+	OutcomeAlreadyFailing RehearsalOutcome = "already failing on base"
+	// OutcomeFixed means the rehearsal passed while the job fails without the change
+	OutcomeFixed RehearsalOutcome = "fixed"
+	// OutcomePassing means both the rehearsal and the job without the change pass
+	OutcomePassing RehearsalOutcome = "passing"
+	// OutcomeAborted means the rehearsal was aborted before it could pass or
+	// fail, so it says nothing about the change
+	OutcomeAborted RehearsalOutcome = "aborted"
+	// OutcomeNoBaseline means there are no recent runs of the job without the
+	// change, so it is unknown how the rehearsal compares to them
+	OutcomeNoBaseline RehearsalOutcome = "no baseline"
+)
+
+// outcomeOrder sorts the outcomes that need the attention of reviewers first
+var outcomeOrder = map[RehearsalOutcome]int{
+	OutcomeNewlyFailing:   0,
+	OutcomeAborted:        1,
+	OutcomeNoBaseline:     2,
+	OutcomeAlreadyFailing: 3,
+	OutcomeFixed:          4,
+	OutcomePassing:        5,
+}
+
+// BaselineComparison holds the result of a rehearsal together with the
+// recent runs of the job it rehearses on the base branch
+type BaselineComparison struct {
+	// Job is the name of the rehearsed job
+	Job string
+	// Rehearsal is the finished rehearsal
+	Rehearsal pjapi.ProwJob
+	// Baseline are the most recent finished runs of the job, newest first
+	Baseline []pjapi.ProwJob
+	// Outcome classifies the rehearsal against its baseline
+	Outcome RehearsalOutcome
+}
+
+// BaselinePassed returns how many of the baseline runs passed
+func (c BaselineComparison) BaselinePassed() int {
+	return passedRuns(c.Baseline)
+}
+
+func passedRuns(runs []pjapi.ProwJob) int {
+	var passed int
+	for _, run := range runs {
+		if run.Status.State == pjapi.SuccessState {
+			passed++
+		}
+	}
+	return passed
+}
+
+// classify determines the outcome of a rehearsal. The job is considered to
+// be failing on the base branch when most of its recent runs failed.
+func classify(rehearsal pjapi.ProwJob, baseline []pjapi.ProwJob) RehearsalOutcome {
+	if rehearsal.Status.State == pjapi.AbortedState {
+		return OutcomeAborted
+	}
+	if len(baseline) == 0 {
+		return OutcomeNoBaseline
+	}
+	failingOnBase := 2*passedRuns(baseline) < len(baseline)
+	switch {
+	case rehearsal.Status.State == pjapi.SuccessState && failingOnBase:
+		return OutcomeFixed
+	case rehearsal.Status.State == pjapi.SuccessState:
+		return OutcomePassing
+	case failingOnBase:
+		return OutcomeAlreadyFailing
+	default:
+		return OutcomeNewlyFailing
+	}
+}
+
+// baseRef returns the branch a ProwJob tests
+func baseRef(spec pjapi.ProwJobSpec) string {
+	switch {
+	case spec.Refs != nil:
+		return spec.Refs.BaseRef
+	case len(spec.ExtraRefs) > 0:
+		return spec.ExtraRefs[0].BaseRef
+	}
+	return ""
+}
+
+// sourceBranch returns the branch the job a rehearsal was created from tests.
+// Rehearsals of jobs from other repositories carry the refs of the rehearsed
+// job as the first extra refs.
+func sourceBranch(rehearsal pjapi.ProwJob) string {
+	if len(rehearsal.Spec.ExtraRefs) > 0 {
+		return rehearsal.Spec.ExtraRefs[0].BaseRef
+	}
+	if rehearsal.Spec.Refs != nil {
+		return rehearsal.Spec.Refs.BaseRef
+	}
+	return ""
+}
+
+// baselineJobs returns the names of the jobs whose runs show how the test of
+// the job does without the rehearsed change. These are the runs of the job
+// itself and, for presubmits, also those of the postsubmit and the periodic
+// of the same test, which run on the branch alone.
+func baselineJobs(job string) []string {
+	rest := strings.TrimPrefix(job, jobconfig.PresubmitPrefix+"-")
+	if rest == job {
+		return []string{job}
+	}
+	return []string{job, jobconfig.PostsubmitPrefix + "-" + rest, jobconfig.PeriodicPrefix + "-" + rest}
+}
+
+// baselineRuns returns the most recent finished runs of the job without the
+// rehearsed change on the branch, newest first. Aborted runs neither passed
+// nor failed, so they are not part of the baseline.
+func (e *Executor) baselineRuns(job, branch string) ([]pjapi.ProwJob, error) {
+	var finished []pjapi.ProwJob
+	for _, baselineJob := range baselineJobs(job) {
+		// Prow truncates the job name when it is used as a label value, so
+		// the label is taken from the labels Prow sets
+		labels, _ := decorate.LabelsAndAnnotationsForSpec(pjapi.ProwJobSpec{Job: baselineJob}, nil, nil)
+		label, ok := labels[kube.ProwJobAnnotation]
+		if !ok {
+			logrus.Debugf("Runs of %s cannot be listed as its name is not a valid label value.", baselineJob)
+			continue
+		}
+		runs := &pjapi.ProwJobList{}
+		if err := e.pjclient.List(context.Background(), runs, ctrlruntimeclient.MatchingLabels{kube.ProwJobAnnotation: label}, ctrlruntimeclient.InNamespace(e.namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list runs of %s: %w", baselineJob, err)
+		}
+		for _, run := range runs.Items {
+			if run.Spec.Job != baselineJob || !run.Complete() || run.Status.State == pjapi.AbortedState {
+				continue
+			}
+			if branch != "" && baseRef(run.Spec) != branch {
+				continue
+			}
+			finished = append(finished, run)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Status.CompletionTime.After(finished[j].Status.CompletionTime.Time)
+	})
+	if len(finished) > baselineRunCount {
+		finished = finished[:baselineRunCount]
+	}
+	return finished, nil
+}
+
+// CompareWithBaseline classifies every finished rehearsal against the most
+// recent runs of the job it rehearses on the base branch. Rehearsals whose
+// baseline cannot be determined have no baseline.
+func (e *Executor) CompareWithBaseline() []BaselineComparison {
+	prefix := fmt.Sprintf("rehearse-%d-", e.prNumber)
+	var comparisons []BaselineComparison
+	for _, rehearsal := range e.finished {
+		job := strings.TrimPrefix(rehearsal.Spec.Job, prefix)
+		baseline, err := e.baselineRuns(job, sourceBranch(rehearsal))
+		if err != nil {
+			e.logger.WithError(err).WithField("job", job).Warn("Could not determine the baseline of the rehearsal")
+		}
+		comparison := BaselineComparison{
+			Job:       job,
+			Rehearsal: rehearsal,
+			Baseline:  baseline,
+			Outcome:   classify(rehearsal, baseline),
+		}
+		e.logger.WithFields(logrus.Fields{"job": job, "outcome": comparison.Outcome}).Info("Compared rehearsal with baseline")
+		comparisons = append(comparisons, comparison)
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		if outcomeOrder[comparisons[i].Outcome] != outcomeOrder[comparisons[j].Outcome] {
+			return outcomeOrder[comparisons[i].Outcome] < outcomeOrder[comparisons[j].Outcome]
+		}
+		return comparisons[i].Job < comparisons[j].Job
+	})
+	return comparisons
+}
@@ -0,0 +1,163 @@
+package rehearse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	pjapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/decorate"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClassify(t *testing.T) {
+	run := func(state pjapi.ProwJobState) pjapi.ProwJob {
+		return pjapi.ProwJob{Status: pjapi.ProwJobStatus{State: state}}
+	}
+	success, failure := run(pjapi.SuccessState), run(pjapi.FailureState)
+	for _, tc := range []struct {
+		name      string
+		rehearsal pjapi.ProwJob
+		baseline  []pjapi.ProwJob
+		expected  RehearsalOutcome
+	}{{
+		name:      "passing rehearsal of a passing job",
+		rehearsal: success,
+		baseline:  []pjapi.ProwJob{success, failure, success},
+		expected:  OutcomePassing,
+	}, {
+		name:      "passing rehearsal of a failing job",
+		rehearsal: success,
+		baseline:  []pjapi.ProwJob{failure, failure, success},
+		expected:  OutcomeFixed,
+	}, {
+		name:      "failing rehearsal of a passing job",
+		rehearsal: failure,
+		baseline:  []pjapi.ProwJob{success, failure},
+		expected:  OutcomeNewlyFailing,
+	}, {
+		name:      "failing rehearsal of a failing job",
+		rehearsal: run(pjapi.ErrorState),
+		baseline:  []pjapi.ProwJob{failure, failure, success},
+		expected:  OutcomeAlreadyFailing,
+	}, {
+		name:      "aborted rehearsal of a passing job",
+		rehearsal: run(pjapi.AbortedState),
+		baseline:  []pjapi.ProwJob{success},
+		expected:  OutcomeAborted,
+	}, {
+		name:      "aborted rehearsal without any baseline",
+		rehearsal: run(pjapi.AbortedState),
+		expected:  OutcomeAborted,
+	}, {
+		name:      "failing rehearsal without any baseline",
+		rehearsal: failure,
+		expected:  OutcomeNoBaseline,
+	}, {
+		name:      "passing rehearsal without any baseline",
+		rehearsal: success,
+		expected:  OutcomeNoBaseline,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := classify(tc.rehearsal, tc.baseline); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompareWithBaseline(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	// the names of the jobs of this test are truncated right after an
+	// underscore when used as label values
+	longTest := strings.Repeat("x", 36) + "_upgrade"
+	prowJob := func(name, job, branch string, state pjapi.ProwJobState, age time.Duration) *pjapi.ProwJob {
+		completion := metav1.NewTime(now.Add(-age))
+		labels, _ := decorate.LabelsAndAnnotationsForSpec(pjapi.ProwJobSpec{Job: job}, nil, nil)
+		pj := &pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ci", Labels: labels},
+			Spec:       pjapi.ProwJobSpec{Job: job, Refs: &pjapi.Refs{Org: "org", Repo: "repo", BaseRef: branch}},
+			Status:     pjapi.ProwJobStatus{State: state, URL: "https://prow/" + name},
+		}
+		if state != pjapi.PendingState {
+			pj.Status.CompletionTime = &completion
+		}
+		return pj
+	}
+	periodic := func(name, job, branch string, state pjapi.ProwJobState, age time.Duration) *pjapi.ProwJob {
+		pj := prowJob(name, job, branch, state, age)
+		pj.Spec.ExtraRefs, pj.Spec.Refs = []pjapi.Refs{*pj.Spec.Refs}, nil
+		return pj
+	}
+	runs := []runtime.Object{
+		prowJob("unit-1", "branch-ci-org-repo-master-unit", "master", pjapi.SuccessState, time.Hour),
+		periodic("unit-2", "periodic-ci-org-repo-master-unit", "master", pjapi.FailureState, 2*time.Hour),
+		prowJob("unit-3", "branch-ci-org-repo-master-unit", "master", pjapi.SuccessState, 3*time.Hour),
+		periodic("unit-4", "periodic-ci-org-repo-master-unit", "master", pjapi.FailureState, 4*time.Hour),
+		prowJob("unit-pending", "branch-ci-org-repo-master-unit", "master", pjapi.PendingState, 0),
+		prowJob("unit-other-branch", "branch-ci-org-repo-master-unit", "release-4.1", pjapi.FailureState, 0),
+		prowJob("unit-presubmit", "pull-ci-org-repo-master-unit", "master", pjapi.SuccessState, 30*time.Minute),
+		prowJob("e2e-aborted", "pull-ci-org-repo-master-e2e", "master", pjapi.AbortedState, 0),
+		prowJob("e2e-1", "branch-ci-org-repo-master-e2e", "master", pjapi.FailureState, time.Hour),
+		prowJob("e2e-2", "branch-ci-org-repo-master-e2e", "master", pjapi.FailureState, 2*time.Hour),
+		prowJob("images-1", "pull-ci-org-repo-master-images", "master", pjapi.SuccessState, time.Hour),
+		prowJob("images-other-branch", "pull-ci-org-repo-master-images", "release-4.1", pjapi.FailureState, 0),
+		prowJob("lint-1", "pull-ci-org-repo-master-lint", "master", pjapi.SuccessState, time.Hour),
+		periodic("nightly-1", "periodic-ci-org-repo-master-nightly", "master", pjapi.SuccessState, time.Hour),
+		prowJob("long-1", "branch-ci-org-repo-master-"+longTest, "master", pjapi.SuccessState, time.Hour),
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithRuntimeObjects(runs...).Build()
+	executor := NewExecutor(nil, 123, "", &pjapi.Refs{}, false, logrus.NewEntry(logrus.StandardLogger()), client, "ci")
+	rehearsal := func(job string, state pjapi.ProwJobState) pjapi.ProwJob {
+		return pjapi.ProwJob{
+			ObjectMeta: metav1.ObjectMeta{Name: job},
+			Spec: pjapi.ProwJobSpec{
+				Job:       "rehearse-123-" + job,
+				Refs:      &pjapi.Refs{Org: "openshift", Repo: "release", BaseRef: "master"},
+				ExtraRefs: []pjapi.Refs{{Org: "org", Repo: "repo", BaseRef: "master"}},
+			},
+			Status: pjapi.ProwJobStatus{State: state},
+		}
+	}
+	executor.finished = []pjapi.ProwJob{
+		rehearsal("pull-ci-org-repo-master-unit", pjapi.SuccessState),
+		rehearsal("pull-ci-org-repo-master-e2e", pjapi.FailureState),
+		rehearsal("pull-ci-org-repo-master-images", pjapi.FailureState),
+		rehearsal("pull-ci-org-repo-master-lint", pjapi.AbortedState),
+		rehearsal("pull-ci-org-repo-master-verify", pjapi.FailureState),
+		rehearsal("periodic-ci-org-repo-master-nightly", pjapi.FailureState),
+		rehearsal("pull-ci-org-repo-master-"+longTest, pjapi.SuccessState),
+	}
+
+	type result struct {
+		Job      string
+		Outcome  RehearsalOutcome
+		Baseline []string
+		Passed   int
+	}
+	var actual []result
+	for _, comparison := range executor.CompareWithBaseline() {
+		r := result{Job: comparison.Job, Outcome: comparison.Outcome, Passed: comparison.BaselinePassed()}
+		for _, run := range comparison.Baseline {
+			r.Baseline = append(r.Baseline, run.Name)
+		}
+		actual = append(actual, r)
+	}
+	expected := []result{
+		{Job: "periodic-ci-org-repo-master-nightly", Outcome: OutcomeNewlyFailing, Baseline: []string{"nightly-1"}, Passed: 1},
+		{Job: "pull-ci-org-repo-master-images", Outcome: OutcomeNewlyFailing, Baseline: []string{"images-1"}, Passed: 1},
+		{Job: "pull-ci-org-repo-master-lint", Outcome: OutcomeAborted, Baseline: []string{"lint-1"}, Passed: 1},
+		{Job: "pull-ci-org-repo-master-verify", Outcome: OutcomeNoBaseline},
+		{Job: "pull-ci-org-repo-master-e2e", Outcome: OutcomeAlreadyFailing, Baseline: []string{"e2e-1", "e2e-2"}},
+		{Job: "pull-ci-org-repo-master-unit", Outcome: OutcomePassing, Baseline: []string{"unit-presubmit", "unit-1", "unit-2"}, Passed: 2},
+		{Job: "pull-ci-org-repo-master-" + longTest, Outcome: OutcomePassing, Baseline: []string{"long-1"}, Passed: 1},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected comparisons: %s", diff)
+	}
+}
@@ -544,10 +544,10 @@ func (jc *JobConfigurer) ConvertPeriodicsToPresubmits(periodics []prowconfig.Per
 	return presubmits, nil
 }
 
-// AddRandomJobsForChangedTemplates finds jobs from the PR config that are using a specific template with a specific cluster type.
-// The job selection is done by iterating in an unspecified order, which avoids picking the same job
+// SelectJobsForChangedTemplates finds jobs from the PR config that are using a specific template with a specific cluster type.
 // So if a template will be changed, find the jobs that are using a template in combination with the `aws`,`openstack`,`gcs` and `libvirt` cluster types.
-func AddRandomJobsForChangedTemplates(templates sets.Set[string], toBeRehearsed config.Presubmits, prConfigPresubmits map[string][]prowconfig.Presubmit, logger *logrus.Entry) config.Presubmits {
+// Out of the jobs using the template on a cluster type, the one with the lowest estimated cost according to the job statistics is picked.
+func SelectJobsForChangedTemplates(templates sets.Set[string], toBeRehearsed config.Presubmits, prConfigPresubmits map[string][]prowconfig.Presubmit, stats JobStatsByName, logger *logrus.Entry) config.Presubmits {
 	clusterTypes := getClusterTypes(prConfigPresubmits)
 	rehearsals := make(config.Presubmits)
 
@@ -557,7 +557,7 @@ func AddRandomJobsForChangedTemplates(templates sets.Set[string], toBeRehearsed
 				continue
 			}
 
-			if repo, job := pickTemplateJob(prConfigPresubmits, template, clusterType); job != nil {
+			if repo, job := pickTemplateJob(prConfigPresubmits, template, clusterType, stats); job != nil {
 				selectionFields := logrus.Fields{diffs.LogRepo: repo, diffs.LogJobName: job.Name, diffs.LogReasons: fmt.Sprintf("template %s changed", template)}
 				logger.WithFields(selectionFields).Info(diffs.ChosenJob)
 				rehearsals[repo] = append(rehearsals[repo], *job)
@@ -781,24 +781,31 @@ func isAlreadyRehearsed(toBeRehearsed config.Presubmits, clusterType, templateFi
 	return false
 }
 
-func pickTemplateJob(presubmits map[string][]prowconfig.Presubmit, templateFile, clusterType string) (string, *prowconfig.Presubmit) {
+func pickTemplateJob(presubmits map[string][]prowconfig.Presubmit, templateFile, clusterType string, stats JobStatsByName) (string, *prowconfig.Presubmit) {
 	var keys []string
 	for k := range presubmits {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
+	var pickedRepo string
+	var picked *prowconfig.Presubmit
+	var pickedCost time.Duration
 	for _, repo := range keys {
-		for _, job := range presubmits[repo] {
+		for i := range presubmits[repo] {
+			job := presubmits[repo][i]
 			if job.Agent != string(pjapi.KubernetesAgent) || job.Hidden || !hasRehearsableLabel(job.Labels) {
 				continue
 			}
 
 			if hasClusterType(job, clusterType) && UsesConfigMap(job.JobBase, templateFile) {
-				return repo, &job
+				jobStats, _ := stats.statsFor(job.Name)
+				if cost := jobStats.cost(); picked == nil || cost < pickedCost {
+					pickedRepo, picked, pickedCost = repo, &job, cost
+				}
 			}
 		}
 	}
-	return "", nil
+	return pickedRepo, picked
 }
 
 func hasClusterType(job prowconfig.Presubmit, clusterType string) bool {
@@ -864,6 +871,8 @@ type Executor struct {
 	logger     *logrus.Entry
 	pjclient   ctrlruntimeclient.Client
 	namespace  string
+	// finished holds the rehearsal ProwJobs that reached a final state
+	finished []pjapi.ProwJob
 	// Allow faking this in tests
 	pollFunc func(interval, timeout time.Duration, condition wait.ConditionFunc) error
 }
@@ -966,6 +975,7 @@ func (e *Executor) waitForJobs(jobs sets.Set[string], selector ctrlruntimeclient
 			default:
 				continue
 			}
+			e.finished = append(e.finished, pj)
 			jobs.Delete(pj.Name)
 			if jobs.Len() == 0 {
 				return true, nil
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -63,6 +62,10 @@ type RehearsalConfig struct {
 
 	StickyLabelAuthors sets.Set[string]
 
+	// JobStats provides the historical durations and pass rates of jobs
+	// used to select the cheapest rehearsals when there are too many
+	JobStats JobStatsSource
+
 	GCSBucket          string
 	GCSCredentialsFile string
 	GCSBrowserPrefix   string
@@ -175,8 +178,8 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 		if err != nil {
 			return nil, nil, nil, nil, fmt.Errorf("could not determine changed templates: %w", err)
 		}
-		randomJobsForChangedTemplates := AddRandomJobsForChangedTemplates(changedTemplates.ProductionNames, presubmits, prConfig.Prow.JobConfig.PresubmitsStatic, logger)
-		presubmits.AddAll(randomJobsForChangedTemplates, config.ChangedTemplate)
+		jobsForChangedTemplates := SelectJobsForChangedTemplates(changedTemplates.ProductionNames, presubmits, prConfig.Prow.JobConfig.PresubmitsStatic, r.loadJobStats(logger), logger)
+		presubmits.AddAll(jobsForChangedTemplates, config.ChangedTemplate)
 	}
 
 	var changedClusterProfiles *ConfigMaps
@@ -192,14 +195,14 @@ func (r RehearsalConfig) DetermineAffectedJobs(candidate RehearsalCandidate, can
 	return filterPresubmits(presubmits, logger), filterPeriodics(periodics, logger), changedTemplates, changedClusterProfiles, nil
 }
 
-func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, logger *logrus.Entry) (*config.ReleaseRepoConfig, *pjapi.Refs, apihelper.ImageStreamTagMap, []*prowconfig.Presubmit, error) {
+func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath string, presubmits config.Presubmits, periodics config.Periodics, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, logger *logrus.Entry) (*config.ReleaseRepoConfig, *pjapi.Refs, apihelper.ImageStreamTagMap, []*prowconfig.Presubmit, *RehearsalPlan, error) {
 	resolver, err := r.createResolver(candidatePath)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 	prConfig, err := config.GetAllConfigs(candidatePath)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 	org := candidate.org
 	repo := candidate.repo
@@ -209,31 +212,35 @@ func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath s
 	jobConfigurer := NewJobConfigurer(prConfig.CiOperator, prConfig.Prow, resolver, prNumber, logger, rehearsalTemplates.Names, rehearsalClusterProfiles.Names, prRefs)
 	imageStreamTags, presubmitsToRehearse, err := jobConfigurer.ConfigurePresubmitRehearsals(presubmits)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 
 	periodicImageStreamTags, periodicsToRehearse, err := jobConfigurer.ConfigurePeriodicRehearsals(periodics)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 	apihelper.MergeImageStreamTagMaps(imageStreamTags, periodicImageStreamTags)
 
 	periodicPresubmits, err := jobConfigurer.ConvertPeriodicsToPresubmits(periodicsToRehearse)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, err
 	}
 	presubmitsToRehearse = append(presubmitsToRehearse, periodicPresubmits...)
 
+	var plan *RehearsalPlan
 	if rehearsals := len(presubmitsToRehearse); rehearsals == 0 {
 		logger.Info("no jobs to rehearse have been found")
-		return nil, nil, nil, nil, nil
+		return nil, nil, nil, nil, nil, nil
 	} else if rehearsals > limit {
 		jobCountFields := logrus.Fields{
 			"rehearsal-threshold": limit,
 			"rehearsal-jobs":      rehearsals,
 		}
 		logger.WithFields(jobCountFields).Info("Would rehearse too many jobs, selecting a subset")
-		presubmitsToRehearse = determineSubsetToRehearse(presubmitsToRehearse, limit)
+		presubmitsToRehearse, plan, err = r.planRehearsals(candidate, candidatePath, prConfig, presubmitsToRehearse, rehearsalTemplates, rehearsalClusterProfiles, limit, logger)
+		if err != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("could not select rehearsals: %w", err)
+		}
 	}
 
 	if prConfig.Prow.JobConfig.PresubmitsStatic == nil {
@@ -243,7 +250,7 @@ func (r RehearsalConfig) SetupJobs(candidate RehearsalCandidate, candidatePath s
 		prConfig.Prow.JobConfig.PresubmitsStatic[org+"/"+repo] = append(prConfig.Prow.JobConfig.PresubmitsStatic[org+"/"+repo], *presubmit)
 	}
 
-	return prConfig, prRefs, imageStreamTags, presubmitsToRehearse, nil
+	return prConfig, prRefs, imageStreamTags, presubmitsToRehearse, plan, nil
 }
 
 func (r RehearsalConfig) createResolver(candidatePath string) (registry.Resolver, error) {
@@ -307,8 +314,11 @@ func labelSelectorForRehearsalJobs(org, repo string, prNumber int) ctrlruntimecl
 	}
 }
 
-// RehearseJobs returns true if the jobs were triggered and succeed
-func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePath string, prRefs *pjapi.Refs, imageStreamTags apihelper.ImageStreamTagMap, presubmitsToRehearse []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, logger *logrus.Entry) (bool, error) {
+// RehearseJobs returns true if the jobs were triggered and succeed, together with
+// the comparison of every finished rehearsal against recent runs of the rehearsed job.
+// The comparisons are returned even when waiting for the rehearsals failed, for the
+// rehearsals that finished before.
+func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePath string, prRefs *pjapi.Refs, imageStreamTags apihelper.ImageStreamTagMap, presubmitsToRehearse []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, logger *logrus.Entry) (bool, []BaselineComparison, error) {
 	buildClusterConfigs, prowJobConfig := r.getBuildClusterAndProwJobConfigs(logger)
 	pjclient, err := NewProwJobClient(prowJobConfig, r.DryRun)
 	if err != nil {
@@ -347,14 +357,19 @@ func (r RehearsalConfig) RehearseJobs(candidate RehearsalCandidate, candidatePat
 	success, err := executor.ExecuteJobs()
 	if err != nil {
 		logger.WithError(err).Error("Failed to rehearse jobs")
-		return false, utilerrors.NewAggregate(errs)
+		errs = append(errs, err)
+		success = false
 	} else if !success {
 		logger.Info("Some jobs failed their rehearsal runs")
 	} else {
 		logger.Info("All jobs were rehearsed successfully")
 	}
 
-	return success, utilerrors.NewAggregate(errs)
+	var comparisons []BaselineComparison
+	if !r.DryRun {
+		comparisons = executor.CompareWithBaseline()
+	}
+	return success, comparisons, utilerrors.NewAggregate(errs)
 }
 
 func (r RehearsalConfig) getBuildClusterAndProwJobConfigs(logger *logrus.Entry) (map[string]rest.Config, *rest.Config) {
@@ -446,45 +461,6 @@ func loadConfigUpdaterCfg(candidate string) (ret prowplugins.ConfigUpdater, err
 	return
 }
 
-// determineSubsetToRehearse determines in a sophisticated way which subset of jobs should be chosen to be rehearsed.
-// First, it will create a list of the jobs mapped by the source type and calculates the maximum allowed jobs for each
-// source type. If there are jobs from a specific source type that are under the max allowed number, it will fill the gap
-// from the other not chosen jobs until it reaches the rehearsal limit.
-func determineSubsetToRehearse(presubmitsToRehearse []*prowconfig.Presubmit, rehearsalLimit int) []*prowconfig.Presubmit {
-	if len(presubmitsToRehearse) <= rehearsalLimit {
-		return presubmitsToRehearse
-	}
-
-	presubmitsBySourceType := make(map[config.SourceType][]*prowconfig.Presubmit)
-	for _, p := range presubmitsToRehearse {
-		sourceType := config.GetSourceType(p.Labels)
-		presubmitsBySourceType[sourceType] = append(presubmitsBySourceType[sourceType], p)
-	}
-
-	maxJobsPerSourceType := rehearsalLimit / len(presubmitsBySourceType)
-	var toRehearse []*prowconfig.Presubmit
-	var dropped []*prowconfig.Presubmit
-
-	for _, jobs := range presubmitsBySourceType {
-		if len(jobs) > maxJobsPerSourceType {
-			dropped = append(dropped, jobs[maxJobsPerSourceType:]...)
-			jobs = jobs[:maxJobsPerSourceType]
-		}
-		toRehearse = append(toRehearse, jobs...)
-	}
-
-	// There are two ways that we will hit this check. First, jobs from a specific resource are less than the
-	// maximum allowed, we will end up having less rehearsals than the limit. Second, summary of the maximum allowed jobs
-	// from each source can be lower than the rehearse limit  due to the rounding inherent in integer division.
-	// In both cases, we fill up the gap from the jobs that we didn't pick earlier.
-	if len(toRehearse) <= rehearsalLimit {
-		sort.Slice(dropped, func(a, b int) bool { return dropped[a].Name < dropped[b].Name })
-		toRehearse = append(toRehearse, dropped[:rehearsalLimit-len(toRehearse)]...)
-	}
-
-	return toRehearse
-}
-
 type cleanup func()
 type cleanups []cleanup
 
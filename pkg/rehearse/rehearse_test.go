@@ -3,7 +3,6 @@ package rehearse
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -161,148 +160,6 @@ func (c *creatingClientWithCallBack) Create(ctx context.Context, obj ctrlruntime
 	return c.Client.Create(ctx, obj, opts...)
 }
 
-func TestDetermineSubsetToRehearse(t *testing.T) {
-	allowUnexported := cmp.AllowUnexported(prowconfig.Brancher{}, prowconfig.RegexpChangeMatcher{}, prowconfig.Presubmit{})
-
-	testCases := []struct {
-		id                   string
-		presubmitsToRehearse []*prowconfig.Presubmit
-		rehearsalLimit       int
-		expected             []*prowconfig.Presubmit
-	}{
-		{
-			id: "under the limit - no changes expected",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-			rehearsalLimit: 5,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-		},
-		{
-			id: "equal with the limit - no changes expected",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-			rehearsalLimit: 3,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-		},
-		{
-			id: "over the limit (one source)- changes expected",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-6", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-7", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-8", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-			rehearsalLimit: 5,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-			},
-		},
-		{
-			id: "over the limit (multiple sources)- changes expected",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-6", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-7", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-8", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
-			},
-			rehearsalLimit: 5,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedRegistryContent"}}},
-			},
-		},
-		{
-			id: "summary of the maximum allowed jobs per source is lower that the rehearse limit (rounding inherent in integer division)",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-6", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-7", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-8", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-11", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-12", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-			},
-			rehearsalLimit: 10,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-10", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-11", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-12", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-5", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-6", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-7", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-9", Labels: map[string]string{config.SourceTypeLabel: "changedTemplate"}}},
-			},
-		},
-		{
-			id: "all sources are represented even when initial sets are skewed",
-			presubmitsToRehearse: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-3", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-4", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-			},
-			rehearsalLimit: 2,
-			expected: []*prowconfig.Presubmit{
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-1", Labels: map[string]string{config.SourceTypeLabel: "changedPresubmit"}}},
-				{JobBase: prowconfig.JobBase{Name: "rehearsal-2", Labels: map[string]string{config.SourceTypeLabel: "changedPeriodic"}}},
-			},
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.id, func(t *testing.T) {
-			actual := determineSubsetToRehearse(tc.presubmitsToRehearse, tc.rehearsalLimit)
-			sort.Slice(actual, func(a, b int) bool { return actual[a].Name < actual[b].Name })
-
-			if diff := cmp.Diff(actual, tc.expected, allowUnexported); diff != "" {
-				t.Errorf("Presubmit list differs from expected: %s", diff)
-			}
-
-		})
-	}
-}
-
 func TestFilterJobsByRequested(t *testing.T) {
 	testCases := []struct {
 		name                   string
@@ -0,0 +1,326 @@
+package rehearse
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/jobconfig"
+	"github.com/openshift/ci-tools/pkg/load"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	// minimumPassRate keeps jobs that never pass from having an infinite cost
+	minimumPassRate = 0.1
+)
+
+// defaultJobStats is assumed for jobs without any recorded history
+var defaultJobStats = JobStats{Duration: metav1.Duration{Duration: time.Hour}, PassRate: 0.5}
+
+// JobStats holds the historical statistics of a job that are used to estimate
+// how expensive rehearsing it is
+type JobStats struct {
+	// Duration is the typical duration of a run of the job
+	Duration metav1.Duration `json:"duration"`
+	// PassRate is the fraction of runs of the job that passed, between 0 and 1
+	PassRate float64 `json:"pass_rate"`
+}
+
+// cost estimates how much cluster time it takes to get a passing run of the
+// job, accounting for the runs that are expected to fail and be retried
+func (s JobStats) cost() time.Duration {
+	return time.Duration(float64(s.Duration.Duration) / math.Max(s.PassRate, minimumPassRate))
+}
+
+// JobStatsByName holds the statistics of jobs, by job name
+type JobStatsByName map[string]JobStats
+
+// statsFor returns the statistics of the job and whether they are known
+func (s JobStatsByName) statsFor(name string) (JobStats, bool) {
+	if stats, ok := s[name]; ok {
+		return stats, true
+	}
+	return defaultJobStats, false
+}
+
+// JobStatsSource provides historical statistics about jobs
+type JobStatsSource interface {
+	JobStats() (JobStatsByName, error)
+}
+
+type fileJobStatsSource struct {
+	path string
+}
+
+// NewFileJobStatsSource returns a source that reads the statistics from
+// a YAML file mapping job names to their statistics. The file is read on
+// every use so that it can be refreshed while pj-rehearse runs.
+func NewFileJobStatsSource(path string) JobStatsSource {
+	return &fileJobStatsSource{path: path}
+}
+
+func (s *fileJobStatsSource) JobStats() (JobStatsByName, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job statistics: %w", err)
+	}
+	var stats JobStatsByName
+	if err := yaml.Unmarshal(raw, &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// loadJobStats returns the job statistics from the configured source. Rehearsals
+// can be selected without them, so failures to load them are only logged.
+func (r RehearsalConfig) loadJobStats(logger *logrus.Entry) JobStatsByName {
+	if r.JobStats == nil {
+		return JobStatsByName{}
+	}
+	stats, err := r.JobStats.JobStats()
+	if err != nil {
+		logger.WithError(err).Warn("Could not load job statistics, assuming defaults for all jobs")
+		return JobStatsByName{}
+	}
+	return stats
+}
+
+// RehearsalPlan describes which rehearsals were chosen when more jobs were
+// affected than the limit allows, and why
+type RehearsalPlan struct {
+	// Affected is the number of rehearsals that could have been run
+	Affected int
+	// Limit is the maximum number of rehearsals that could be chosen
+	Limit int
+	// Selected are the chosen rehearsals, in the order they were chosen
+	Selected []PlannedRehearsal
+	// Uncovered are the changes that no chosen rehearsal exercises
+	Uncovered []string
+}
+
+// EstimatedCost is the total cluster time the chosen rehearsals are expected to take
+func (p *RehearsalPlan) EstimatedCost() time.Duration {
+	var total time.Duration
+	for _, rehearsal := range p.Selected {
+		total += rehearsal.Stats.cost()
+	}
+	return total
+}
+
+// PlannedRehearsal is a rehearsal chosen by a RehearsalPlan
+type PlannedRehearsal struct {
+	// Job is the name of the rehearsed job
+	Job string
+	// Stats are the statistics the cost of the job was estimated from
+	Stats JobStats
+	// FromHistory is false when the job had no statistics and defaults were assumed
+	FromHistory bool
+	// Covers are the changes that this rehearsal was the first to exercise
+	Covers []string
+}
+
+// changeCoverage determines which of the changes in a PR a rehearsal exercises
+type changeCoverage struct {
+	prNumber  int
+	tests     map[string]api.TestStepConfiguration
+	chains    registry.ChainByName
+	workflows registry.WorkflowByName
+	nodes     []registry.Node
+	templates *ConfigMaps
+	profiles  *ConfigMaps
+}
+
+func newChangeCoverage(prNumber int, ciopConfigs config.DataByFilename, chains registry.ChainByName, workflows registry.WorkflowByName, nodes []registry.Node, templates, profiles *ConfigMaps) changeCoverage {
+	tests := map[string]api.TestStepConfiguration{}
+	for _, cfg := range ciopConfigs {
+		for _, test := range cfg.Configuration.Tests {
+			switch {
+			case test.Postsubmit:
+				continue
+			case test.IsPeriodic():
+				tests[cfg.Info.JobName(jobconfig.PeriodicPrefix, test.As)] = test
+			default:
+				tests[cfg.Info.JobName(jobconfig.PresubmitPrefix, test.As)] = test
+			}
+		}
+	}
+	return changeCoverage{
+		prNumber:  prNumber,
+		tests:     tests,
+		chains:    chains,
+		workflows: workflows,
+		nodes:     nodes,
+		templates: templates,
+		profiles:  profiles,
+	}
+}
+
+// sourceJob returns the name of the job a rehearsal was created from
+func (c changeCoverage) sourceJob(rehearsal *prowconfig.Presubmit) string {
+	return strings.TrimPrefix(rehearsal.Name, fmt.Sprintf("rehearse-%d-", c.prNumber))
+}
+
+// covers returns the changes the rehearsal exercises: the job itself when its
+// configuration changed, changed registry components, templates and cluster
+// profiles it uses and the cluster type it runs on
+func (c changeCoverage) covers(rehearsal *prowconfig.Presubmit) sets.Set[string] {
+	covered := sets.New[string]()
+	job := c.sourceJob(rehearsal)
+	switch config.GetSourceType(rehearsal.Labels) {
+	case config.ChangedPresubmit, config.ChangedPeriodic, config.ChangedCiopConfig:
+		covered.Insert(fmt.Sprintf("job `%s`", job))
+	}
+
+	if test, ok := c.tests[job]; ok && test.MultiStageTestConfiguration != nil {
+		used := registry.UsedBy(*test.MultiStageTestConfiguration, c.chains, c.workflows)
+		for _, node := range c.nodes {
+			var uses bool
+			switch node.Type() {
+			case registry.Reference:
+				uses = used.Steps.Has(node.Name())
+			case registry.Chain:
+				uses = used.Chains.Has(node.Name())
+			case registry.Workflow:
+				uses = test.MultiStageTestConfiguration.Workflow != nil && *test.MultiStageTestConfiguration.Workflow == node.Name()
+			case registry.Observer:
+				uses = testUsesObserver(test, node.Name())
+			}
+			if uses {
+				covered.Insert(fmt.Sprintf("%s `%s`", node.Type(), node.Name()))
+			}
+		}
+	}
+
+	for kind, cms := range map[string]*ConfigMaps{"template": c.templates, "cluster profile": c.profiles} {
+		if cms == nil {
+			continue
+		}
+		for name := range cms.ProductionNames {
+			if UsesConfigMap(rehearsal.JobBase, name) || UsesConfigMap(rehearsal.JobBase, cms.Names[name]) {
+				covered.Insert(fmt.Sprintf("%s `%s`", kind, name))
+			}
+		}
+	}
+
+	if clusterType := clusterTypeOf(rehearsal); clusterType != "" {
+		covered.Insert(fmt.Sprintf("cluster type `%s`", clusterType))
+	}
+	return covered
+}
+
+// clusterTypeOf returns the cluster type a job runs on, if any
+func clusterTypeOf(job *prowconfig.Presubmit) string {
+	if clusterType := job.Labels[api.CloudLabel]; clusterType != "" {
+		return clusterType
+	}
+	if job.Spec != nil && len(job.Spec.Containers) > 0 {
+		for _, env := range job.Spec.Containers[0].Env {
+			if env.Name == clusterTypeEnvName {
+				return env.Value
+			}
+		}
+	}
+	return ""
+}
+
+// selectRehearsals chooses the cheapest set of rehearsals, within the limit,
+// that together exercise every change covered by any of them. It is a greedy
+// weighted set cover: the rehearsal covering the most not yet covered changes
+// per unit of estimated cost is chosen until everything is covered.
+func selectRehearsals(rehearsals []*prowconfig.Presubmit, coverage changeCoverage, stats JobStatsByName, limit int) ([]*prowconfig.Presubmit, *RehearsalPlan) {
+	type option struct {
+		rehearsal *prowconfig.Presubmit
+		job       string
+		stats     JobStats
+		known     bool
+		covers    sets.Set[string]
+	}
+	var options []option
+	uncovered := sets.New[string]()
+	for _, rehearsal := range rehearsals {
+		job := coverage.sourceJob(rehearsal)
+		jobStats, known := stats.statsFor(job)
+		covers := coverage.covers(rehearsal)
+		uncovered = uncovered.Union(covers)
+		options = append(options, option{rehearsal: rehearsal, job: job, stats: jobStats, known: known, covers: covers})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].job < options[j].job })
+
+	plan := &RehearsalPlan{Affected: len(rehearsals), Limit: limit}
+	var selected []*prowconfig.Presubmit
+	for uncovered.Len() > 0 && len(selected) < limit {
+		best, bestScore := -1, 0.0
+		for i, o := range options {
+			gain := o.covers.Intersection(uncovered).Len()
+			if gain == 0 {
+				continue
+			}
+			score := float64(gain) / math.Max(o.stats.cost().Minutes(), 1)
+			if best == -1 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best == -1 {
+			break
+		}
+		chosen := options[best]
+		newlyCovered := chosen.covers.Intersection(uncovered)
+		uncovered = uncovered.Difference(newlyCovered)
+		selected = append(selected, chosen.rehearsal)
+		plan.Selected = append(plan.Selected, PlannedRehearsal{
+			Job:         chosen.job,
+			Stats:       chosen.stats,
+			FromHistory: chosen.known,
+			Covers:      sets.List(newlyCovered),
+		})
+		options = append(options[:best], options[best+1:]...)
+	}
+	if uncovered.Len() > 0 {
+		plan.Uncovered = sets.List(uncovered)
+	}
+	return selected, plan
+}
+
+// planRehearsals determines which changes of the candidate the rehearsals
+// exercise and selects the cheapest subset of them within the limit
+func (r RehearsalConfig) planRehearsals(candidate RehearsalCandidate, candidatePath string, prConfig *config.ReleaseRepoConfig, rehearsals []*prowconfig.Presubmit, rehearsalTemplates, rehearsalClusterProfiles *ConfigMaps, limit int, logger *logrus.Entry) ([]*prowconfig.Presubmit, *RehearsalPlan, error) {
+	var chains registry.ChainByName
+	var workflows registry.WorkflowByName
+	var changedRegistrySteps []registry.Node
+	if !r.NoRegistry {
+		var err error
+		if _, chains, workflows, _, _, _, err = load.Registry(filepath.Join(candidatePath, config.RegistryPath), load.RegistryFlag(0)); err != nil {
+			return nil, nil, fmt.Errorf("could not load step registry: %w", err)
+		}
+		if changedRegistrySteps, err = determineChangedRegistrySteps(candidatePath, candidate.base.sha, logger); err != nil {
+			return nil, nil, fmt.Errorf("could not determine changed registry steps: %w", err)
+		}
+	}
+	coverage := newChangeCoverage(candidate.prNumber, prConfig.CiOperator, chains, workflows, changedRegistrySteps, rehearsalTemplates, rehearsalClusterProfiles)
+	selected, plan := selectRehearsals(rehearsals, coverage, r.loadJobStats(logger), limit)
+	for _, rehearsal := range plan.Selected {
+		logger.WithFields(logrus.Fields{
+			"job":            rehearsal.Job,
+			"estimated-cost": rehearsal.Stats.cost().Truncate(time.Minute).String(),
+			"covers":         strings.Join(rehearsal.Covers, ", "),
+		}).Info("Selected job to rehearse")
+	}
+	if len(plan.Uncovered) > 0 {
+		logger.WithField("uncovered", strings.Join(plan.Uncovered, ", ")).Info("Some changes are not covered by any rehearsal within the limit")
+	}
+	return selected, plan, nil
+}
@@ -0,0 +1,160 @@
+package rehearse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowconfig "k8s.io/test-infra/prow/config"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+func stats(duration time.Duration, passRate float64) JobStats {
+	return JobStats{Duration: metav1.Duration{Duration: duration}, PassRate: passRate}
+}
+
+func TestSelectRehearsals(t *testing.T) {
+	common := "common"
+	workflow := func(name string) *string { return &name }
+	workflows := registry.WorkflowByName{
+		"aws-e2e": {Pre: []api.TestStep{{Reference: &common}}},
+		"gcp-e2e": {Pre: []api.TestStep{{Reference: &common}}},
+		"special": {Test: []api.TestStep{{Reference: &common}}},
+	}
+	graph, err := registry.NewGraph(registry.ReferenceByName{common: {As: common}}, registry.ChainByName{}, workflows, registry.ObserverByName{})
+	if err != nil {
+		t.Fatalf("failed to create graph: %v", err)
+	}
+	changed := []registry.Node{graph.References[common], graph.Workflows["special"]}
+
+	multiStage := func(name, workflowName string) api.TestStepConfiguration {
+		return api.TestStepConfiguration{As: name, MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: workflow(workflowName)}}
+	}
+	ciopConfigs := config.DataByFilename{
+		"org-repo-master.yaml": {
+			Info: config.Info{Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"}},
+			Configuration: api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{
+				multiStage("e2e-aws", "aws-e2e"),
+				multiStage("e2e-aws-slow", "aws-e2e"),
+				multiStage("e2e-gcp", "gcp-e2e"),
+				multiStage("e2e-special", "special"),
+				{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			}},
+		},
+	}
+	rehearsal := func(test string, source config.SourceType, clusterType string) *prowconfig.Presubmit {
+		labels := map[string]string{config.SourceTypeLabel: string(source)}
+		if clusterType != "" {
+			labels[api.CloudLabel] = clusterType
+		}
+		return &prowconfig.Presubmit{JobBase: prowconfig.JobBase{Name: "rehearse-123-pull-ci-org-repo-master-" + test, Labels: labels}}
+	}
+	rehearsals := []*prowconfig.Presubmit{
+		rehearsal("e2e-aws", config.ChangedRegistryContent, "aws"),
+		rehearsal("e2e-aws-slow", config.ChangedRegistryContent, "aws"),
+		rehearsal("e2e-gcp", config.ChangedRegistryContent, "gcp"),
+		rehearsal("e2e-special", config.ChangedRegistryContent, "aws"),
+		rehearsal("unit", config.ChangedCiopConfig, ""),
+	}
+	jobStats := JobStatsByName{
+		"pull-ci-org-repo-master-e2e-aws":      stats(time.Hour, 1),
+		"pull-ci-org-repo-master-e2e-aws-slow": stats(3*time.Hour, 1),
+		"pull-ci-org-repo-master-e2e-gcp":      stats(2*time.Hour, 0.5),
+		"pull-ci-org-repo-master-unit":         stats(10*time.Minute, 1),
+	}
+	coverage := newChangeCoverage(123, ciopConfigs, registry.ChainByName{}, workflows, changed, nil, nil)
+
+	unit := PlannedRehearsal{Job: "pull-ci-org-repo-master-unit", Stats: stats(10*time.Minute, 1), FromHistory: true, Covers: []string{"job `pull-ci-org-repo-master-unit`"}}
+	aws := PlannedRehearsal{Job: "pull-ci-org-repo-master-e2e-aws", Stats: stats(time.Hour, 1), FromHistory: true, Covers: []string{"cluster type `aws`", "reference `common`"}}
+	special := PlannedRehearsal{Job: "pull-ci-org-repo-master-e2e-special", Stats: defaultJobStats, Covers: []string{"workflow `special`"}}
+	gcp := PlannedRehearsal{Job: "pull-ci-org-repo-master-e2e-gcp", Stats: stats(2*time.Hour, 0.5), FromHistory: true, Covers: []string{"cluster type `gcp`"}}
+
+	for _, tc := range []struct {
+		name         string
+		limit        int
+		expected     []string
+		expectedPlan *RehearsalPlan
+	}{{
+		name:     "every change is covered with fewer rehearsals than the limit",
+		limit:    10,
+		expected: []string{"unit", "e2e-aws", "e2e-special", "e2e-gcp"},
+		expectedPlan: &RehearsalPlan{
+			Affected: 5,
+			Limit:    10,
+			Selected: []PlannedRehearsal{unit, aws, special, gcp},
+		},
+	}, {
+		name:     "the limit leaves changes uncovered",
+		limit:    3,
+		expected: []string{"unit", "e2e-aws", "e2e-special"},
+		expectedPlan: &RehearsalPlan{
+			Affected:  5,
+			Limit:     3,
+			Selected:  []PlannedRehearsal{unit, aws, special},
+			Uncovered: []string{"cluster type `gcp`"},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, plan := selectRehearsals(rehearsals, coverage, jobStats, tc.limit)
+			var names []string
+			for _, job := range selected {
+				names = append(names, job.Name[len("rehearse-123-pull-ci-org-repo-master-"):])
+			}
+			if diff := cmp.Diff(tc.expected, names); diff != "" {
+				t.Errorf("unexpected rehearsals selected: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedPlan, plan); diff != "" {
+				t.Errorf("unexpected plan: %s", diff)
+			}
+		})
+	}
+}
+
+func TestChangeCoverage(t *testing.T) {
+	templates := &ConfigMaps{ProductionNames: sets.New[string]("prow-job-cluster-launch"), Names: map[string]string{"prow-job-cluster-launch": "rehearse-template-cluster-launch"}}
+	profiles := &ConfigMaps{ProductionNames: sets.New[string]("cluster-profile-aws"), Names: map[string]string{"cluster-profile-aws": "rehearse-cluster-profile-aws"}}
+	coverage := newChangeCoverage(1, config.DataByFilename{}, nil, nil, nil, templates, profiles)
+	volume := func(cm string) v1.Volume {
+		return v1.Volume{VolumeSource: v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: cm}}}}}}}
+	}
+	job := &prowconfig.Presubmit{JobBase: prowconfig.JobBase{
+		Name:   "rehearse-1-job",
+		Labels: map[string]string{config.SourceTypeLabel: string(config.ChangedTemplate)},
+		Spec: &v1.PodSpec{
+			Containers: []v1.Container{{Env: []v1.EnvVar{{Name: clusterTypeEnvName, Value: "gcp"}}}},
+			Volumes:    []v1.Volume{volume("rehearse-template-cluster-launch"), volume("cluster-profile-aws")},
+		},
+	}}
+	expected := sets.New[string]("template `prow-job-cluster-launch`", "cluster profile `cluster-profile-aws`", "cluster type `gcp`")
+	if diff := cmp.Diff(sets.List(expected), sets.List(coverage.covers(job))); diff != "" {
+		t.Errorf("unexpected coverage: %s", diff)
+	}
+}
+
+func TestFileJobStatsSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.yaml")
+	raw := "pull-ci-org-repo-master-e2e:\n  duration: 1h30m\n  pass_rate: 0.75\n"
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatalf("failed to write stats: %v", err)
+	}
+	actual, err := NewFileJobStatsSource(path).JobStats()
+	if err != nil {
+		t.Fatalf("failed to load stats: %v", err)
+	}
+	expected := JobStatsByName{"pull-ci-org-repo-master-e2e": stats(90*time.Minute, 0.75)}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected stats: %s", diff)
+	}
+	if cost := actual["pull-ci-org-repo-master-e2e"].cost(); cost != 2*time.Hour {
+		t.Errorf("expected a cost of 2h, got %s", cost)
+	}
+}
@@ -0,0 +1,243 @@
+package results
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Category tells who is responsible for a failure.
+type Category string
+
+const (
+	// CategoryInfra failures are caused by the infrastructure the job ran on.
+	CategoryInfra Category = "infra"
+	// CategoryUser failures are caused by the code or configuration under test.
+	CategoryUser Category = "user"
+	// CategoryFlake failures are intermittent and expected to pass on a retry.
+	CategoryFlake Category = "flake"
+)
+
+// Kinds of workloads whose failures are classified.
+const (
+	KindBuild = "build"
+	KindPod   = "pod"
+)
+
+// Rule classifies failures that match it. Each non-empty matcher must match
+// for the rule to match; a matcher with several values matches if any of them
+// does.
+type Rule struct {
+	// Name identifies the rule in logs.
+	Name string `json:"name"`
+	// Reason is the reason recorded for matching failures, as an attribute
+	// next to the chain of reasons of the failure.
+	Reason Reason `json:"reason"`
+	// Category is the category recorded for matching failures.
+	Category Category `json:"category"`
+
+	// Kinds restricts the rule to failures of builds or pods.
+	Kinds []string `json:"kinds,omitempty"`
+	// BuildReasons match the status reason of a failed build.
+	BuildReasons []string `json:"build_reasons,omitempty"`
+	// PodReasons match the status reason of a failed pod or the termination
+	// reason of any of its failed containers.
+	PodReasons []string `json:"pod_reasons,omitempty"`
+	// ExitCodes match the exit code of any failed container.
+	ExitCodes []int32 `json:"exit_codes,omitempty"`
+	// LogPatterns are regular expressions matching the log of the failure.
+	LogPatterns []string `json:"log_patterns,omitempty"`
+
+	logPatterns []*regexp.Regexp
+}
+
+// Observation describes a failure to classify.
+type Observation struct {
+	// Kind is KindBuild or KindPod
+	Kind string
+	// BuildReason is the status reason of a failed build
+	BuildReason string
+	// PodReasons are the status reason of a failed pod and the termination
+	// reasons of its failed containers
+	PodReasons []string
+	// ExitCodes are the exit codes of the failed containers
+	ExitCodes []int32
+	// Log is the log snippet of the failure
+	Log string
+}
+
+// Classification is the outcome of classifying a failure.
+type Classification struct {
+	Rule     string
+	Reason   Reason
+	Category Category
+}
+
+// Attach records the classification on an error as attributes, leaving the
+// chain of reasons of the error as it is.
+func (c Classification) Attach(err error) error {
+	return WithAttributes(err, map[string]string{
+		AttributeClassifiedReason: string(c.Reason),
+		AttributeCategory:         string(c.Category),
+	})
+}
+
+// Classifier maps failures to reasons and categories with the first rule that
+// matches them.
+type Classifier struct {
+	Rules []Rule `json:"rules"`
+}
+
+//go:embed default_classifier_rules.yaml
+var defaultRules []byte
+
+// NewClassifier loads rules from YAML.
+func NewClassifier(raw []byte) (*Classifier, error) {
+	var classifier Classifier
+	if err := yaml.UnmarshalStrict(raw, &classifier); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rules: %w", err)
+	}
+	var errs []error
+	for i := range classifier.Rules {
+		rule := &classifier.Rules[i]
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("rule %d: name is required", i))
+		}
+		if rule.Reason == "" {
+			errs = append(errs, fmt.Errorf("rule %s: reason is required", rule.Name))
+		}
+		switch rule.Category {
+		case CategoryInfra, CategoryUser, CategoryFlake:
+		default:
+			errs = append(errs, fmt.Errorf("rule %s: category must be one of %s, %s or %s, not %q", rule.Name, CategoryInfra, CategoryUser, CategoryFlake, rule.Category))
+		}
+		for _, kind := range rule.Kinds {
+			if kind != KindBuild && kind != KindPod {
+				errs = append(errs, fmt.Errorf("rule %s: kind must be %s or %s, not %q", rule.Name, KindBuild, KindPod, kind))
+			}
+		}
+		if len(rule.BuildReasons)+len(rule.PodReasons)+len(rule.ExitCodes)+len(rule.LogPatterns) == 0 {
+			errs = append(errs, fmt.Errorf("rule %s: at least one matcher is required", rule.Name))
+		}
+		for _, pattern := range rule.LogPatterns {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: invalid log pattern: %w", rule.Name, err))
+				continue
+			}
+			rule.logPatterns = append(rule.logPatterns, compiled)
+		}
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return nil, err
+	}
+	return &classifier, nil
+}
+
+// LoadClassifier loads rules from a file.
+func LoadClassifier(path string) (*Classifier, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	return NewClassifier(raw)
+}
+
+// Classify returns the classification by the first rule that matches.
+func (c *Classifier) Classify(o Observation) (Classification, bool) {
+	for _, rule := range c.Rules {
+		if rule.matches(o) {
+			return Classification{Rule: rule.Name, Reason: rule.Reason, Category: rule.Category}, true
+		}
+	}
+	return Classification{}, false
+}
+
+func (r *Rule) matches(o Observation) bool {
+	if len(r.Kinds) > 0 && !contains(r.Kinds, o.Kind) {
+		return false
+	}
+	if len(r.BuildReasons) > 0 && !contains(r.BuildReasons, o.BuildReason) {
+		return false
+	}
+	if len(r.PodReasons) > 0 && !containsAny(r.PodReasons, o.PodReasons) {
+		return false
+	}
+	if len(r.ExitCodes) > 0 && !containsAny(r.ExitCodes, o.ExitCodes) {
+		return false
+	}
+	if len(r.logPatterns) > 0 {
+		var matched bool
+		for _, pattern := range r.logPatterns {
+			if pattern.MatchString(o.Log) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny[T comparable](values, candidates []T) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	classifierLock   sync.RWMutex
+	activeClassifier = mustDefaultClassifier()
+)
+
+func mustDefaultClassifier() *Classifier {
+	c, err := NewClassifier(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default classifier rules: %v", err))
+	}
+	return c
+}
+
+// SetClassifier replaces the rules that Classify uses. Processes that let
+// users configure the rules call this once when they start.
+func SetClassifier(c *Classifier) {
+	classifierLock.Lock()
+	defer classifierLock.Unlock()
+	activeClassifier = c
+}
+
+// Classify classifies a failure with the configured rules.
+func Classify(o Observation) (Classification, bool) {
+	classifierLock.RLock()
+	defer classifierLock.RUnlock()
+	return activeClassifier.Classify(o)
+}
+
+// CategoryOf returns the category recorded on an error, if any.
+func CategoryOf(err error) Category {
+	for _, failure := range failures(err) {
+		if category := failure.Attributes[AttributeCategory]; category != "" {
+			return Category(category)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package results
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+const testRules = `rules:
+- name: oom
+  reason: oom_killed
+  category: infra
+  kinds: [pod]
+  pod_reasons: [OOMKilled]
+- name: sigkill
+  reason: killed
+  category: flake
+  exit_codes: [137]
+- name: compilation
+  reason: compilation_failed
+  category: user
+  kinds: [build]
+  build_reasons: [DockerBuildFailed]
+  log_patterns: ['cannot find package', 'undefined: \w+']
+`
+
+func TestClassify(t *testing.T) {
+	classifier, err := NewClassifier([]byte(testRules))
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	for _, tc := range []struct {
+		name        string
+		observation Observation
+		expected    *Classification
+	}{{
+		name:        "container termination reason",
+		observation: Observation{Kind: KindPod, PodReasons: []string{"Error", "OOMKilled"}, ExitCodes: []int32{1, 137}},
+		expected:    &Classification{Rule: "oom", Reason: "oom_killed", Category: CategoryInfra},
+	}, {
+		name:        "rules are restricted to kinds",
+		observation: Observation{Kind: KindBuild, PodReasons: []string{"OOMKilled"}},
+	}, {
+		name:        "exit code",
+		observation: Observation{Kind: KindPod, PodReasons: []string{"Error"}, ExitCodes: []int32{137}},
+		expected:    &Classification{Rule: "sigkill", Reason: "killed", Category: CategoryFlake},
+	}, {
+		name:        "all matchers of a rule need to match",
+		observation: Observation{Kind: KindBuild, BuildReason: "DockerBuildFailed", Log: "error: exit status 1"},
+	}, {
+		name:        "build reason and log pattern",
+		observation: Observation{Kind: KindBuild, BuildReason: "DockerBuildFailed", Log: "pkg/foo.go:12:2: undefined: Bar"},
+		expected:    &Classification{Rule: "compilation", Reason: "compilation_failed", Category: CategoryUser},
+	}, {
+		name:        "no rule matches",
+		observation: Observation{Kind: KindPod, PodReasons: []string{"Error"}, ExitCodes: []int32{1}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := classifier.Classify(tc.observation)
+			if tc.expected == nil {
+				if ok {
+					t.Fatalf("expected no classification, got %#v", actual)
+				}
+				return
+			}
+			if diff := cmp.Diff(*tc.expected, actual); diff != "" {
+				t.Errorf("unexpected classification: %s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClassifierValidation(t *testing.T) {
+	raw := `rules:
+- reason: r
+  category: infra
+  exit_codes: [1]
+- name: bad-category
+  reason: r
+  category: cosmic-rays
+  exit_codes: [1]
+- name: no-matchers
+  reason: r
+  category: user
+- name: bad-pattern
+  reason: r
+  category: user
+  kinds: [job]
+  log_patterns: ['(']
+`
+	_, err := NewClassifier([]byte(raw))
+	expected := errors.New(`[rule 0: name is required, rule bad-category: category must be one of infra, user or flake, not "cosmic-rays", rule no-matchers: at least one matcher is required, rule bad-pattern: kind must be build or pod, not "job", rule bad-pattern: invalid log pattern: error parsing regexp: missing closing ): ` + "`(`]")
+	if diff := cmp.Diff(expected, err, testhelper.EquateErrorMessage); diff != "" {
+		t.Errorf("unexpected error: %s", diff)
+	}
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		observation Observation
+		expected    bool
+	}{{
+		name:        "evicted build pod",
+		observation: Observation{Kind: KindBuild, BuildReason: "BuildPodEvicted"},
+		expected:    true,
+	}, {
+		name:        "mirror failure in the build log",
+		observation: Observation{Kind: KindBuild, BuildReason: "DockerBuildFailed", Log: "[Errno 256] No more mirrors to try."},
+		expected:    true,
+	}, {
+		name:        "failing Dockerfile",
+		observation: Observation{Kind: KindBuild, BuildReason: "DockerBuildFailed", Log: "make: *** [build] Error 2"},
+	}, {
+		name:        "network errors in pods are up to the test",
+		observation: Observation{Kind: KindPod, Log: "connection reset by peer"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			classification, ok := Classify(tc.observation)
+			if ok != tc.expected {
+				t.Fatalf("expected classified=%v, got %v", tc.expected, ok)
+			}
+			if ok && classification.Category != CategoryInfra {
+				t.Errorf("expected an infra failure, got %s", classification.Category)
+			}
+		})
+	}
+}
+
+func TestInstallClassifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	defer SetClassifier(mustDefaultClassifier())
+	o := Options{classificationRules: path}
+	if err := o.InstallClassifier(); err != nil {
+		t.Fatalf("failed to install classifier: %v", err)
+	}
+	classification, ok := Classify(Observation{Kind: KindPod, PodReasons: []string{"OOMKilled"}})
+	if !ok {
+		t.Fatal("expected the installed rules to classify the failure")
+	}
+	err := classification.Attach(fmt.Errorf("the pod failed"))
+	if diff := cmp.Diff(CategoryInfra, CategoryOf(err)); diff != "" {
+		t.Errorf("unexpected category: %s", diff)
+	}
+	if diff := cmp.Diff(CategoryInfra, CategoryOf(ForReason("step_failed").WithError(err).Errorf("step failed: %v", err))); diff != "" {
+		t.Errorf("unexpected category: %s", diff)
+	}
+	expected := []Failure{{Reason: "step_failed:running_pod", Attributes: map[string]string{AttributeCategory: "infra", AttributeClassifiedReason: "oom_killed"}}}
+	if diff := cmp.Diff(expected, Failures(ForReason("step_failed").ForError(ForReason("running_pod").ForError(err)))); diff != "" {
+		t.Errorf("unexpected failures: %s", diff)
+	}
+}
@@ -12,9 +12,10 @@ import (
 //	    return results.ForReason(results.ReasonFoo).WithError(err).Errorf("could not do something for data: %v", data)
 //	}
 type Error struct {
-	reason  Reason
-	message string
-	wrapped error
+	reason     Reason
+	message    string
+	wrapped    error
+	attributes map[string]string
 }
 
 // Error makes an Error an error
@@ -39,26 +40,115 @@ func (e *Error) Is(target error) bool {
 // errors — are recursively expanded, generating a separate chain for each
 // child.
 func Reasons(errs ...error) (ret []string) {
+	for _, failure := range Failures(errs...) {
+		ret = append(ret, failure.Reason)
+	}
+	return
+}
+
+// Failure is a single chain of error reasons along with the attributes
+// recorded on the errors in the chain.
+type Failure struct {
+	// Reason is the chain of reasons, divided by colons
+	Reason string
+	// Attributes holds the attributes of all errors in the chain, with the
+	// innermost error taking precedence
+	Attributes map[string]string
+}
+
+// Failures provides the chains of error reasons like Reasons, along with the
+// attributes of each chain.
+func Failures(errs ...error) (ret []Failure) {
+	for _, failure := range failures(errs...) {
+		if failure.Reason != "" {
+			ret = append(ret, failure)
+		}
+	}
+	return
+}
+
+// failures provides the chains of error reasons along with their attributes,
+// including attributes recorded on errors without any reason
+func failures(errs ...error) (ret []Failure) {
 	for _, err := range errs {
 		switch err := err.(type) {
 		case *Error:
-			children := Reasons(err.Unwrap())
+			children := failures(err.Unwrap())
 			if len(children) == 0 {
-				ret = append(ret, string(err.reason))
+				ret = append(ret, Failure{Reason: string(err.reason), Attributes: mergeAttributes(err.attributes, nil)})
 				break
 			}
-			for _, r := range children {
-				ret = append(ret, fmt.Sprintf("%s:%s", err.reason, r))
+			for _, child := range children {
+				reason := string(err.reason)
+				if child.Reason != "" {
+					reason = fmt.Sprintf("%s:%s", err.reason, child.Reason)
+				}
+				ret = append(ret, Failure{
+					Reason:     reason,
+					Attributes: mergeAttributes(err.attributes, child.Attributes),
+				})
+			}
+		case *attributedError:
+			children := failures(err.wrapped)
+			if len(children) == 0 {
+				// only the attributes are known, the reason is up to the
+				// errors wrapping this one
+				ret = append(ret, Failure{Attributes: mergeAttributes(err.attributes, nil)})
+				break
+			}
+			for _, child := range children {
+				ret = append(ret, Failure{
+					Reason:     child.Reason,
+					Attributes: mergeAttributes(err.attributes, child.Attributes),
+				})
 			}
 		case interface{ Errors() []error }:
-			ret = append(ret, Reasons(err.Errors()...)...)
+			ret = append(ret, failures(err.Errors()...)...)
 		case interface{ Unwrap() error }:
-			ret = append(ret, Reasons(err.Unwrap())...)
+			ret = append(ret, failures(err.Unwrap())...)
 		}
 	}
 	return
 }
 
+func mergeAttributes(outer, inner map[string]string) map[string]string {
+	if len(outer) == 0 && len(inner) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(outer)+len(inner))
+	for key, value := range outer {
+		merged[key] = value
+	}
+	for key, value := range inner {
+		merged[key] = value
+	}
+	return merged
+}
+
+// attributedError records attributes on an error without adding a reason
+type attributedError struct {
+	wrapped    error
+	attributes map[string]string
+}
+
+func (e *attributedError) Error() string {
+	return e.wrapped.Error()
+}
+
+func (e *attributedError) Unwrap() error {
+	return e.wrapped
+}
+
+// WithAttributes records attributes describing the error without adding a
+// reason to its chain of reasons. The attributes are reported along with the
+// reasons of the errors wrapping it.
+func WithAttributes(err error, attributes map[string]string) error {
+	if err == nil {
+		return nil
+	}
+	return &attributedError{wrapped: err, attributes: attributes}
+}
+
 // BuilderWithReason starts the builder chain
 type BuilderWithReason struct {
 	Error
@@ -79,6 +169,18 @@ func ForReason(reason Reason) *BuilderWithReason {
 	}
 }
 
+// WithAttribute records an attribute describing the error, like the step in
+// which it occurred. Attributes are reported along with the reason.
+func (e *BuilderWithReason) WithAttribute(key, value string) *BuilderWithReason {
+	attributes := make(map[string]string, len(e.attributes)+1)
+	for k, v := range e.attributes {
+		attributes[k] = v
+	}
+	attributes[key] = value
+	e.attributes = attributes
+	return e
+}
+
 // BuilderWithReasonAndError adds a child error to the builder
 type BuilderWithReasonAndError struct {
 	Error
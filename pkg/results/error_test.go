@@ -170,3 +170,43 @@ func TestReasons(t *testing.T) {
 		})
 	}
 }
+
+func TestFailures(t *testing.T) {
+	err := ForReason("executing_graph").WithAttribute("scope", "graph").WithError(utilerrors.NewAggregate([]error{
+		ForReason("step_failed").WithAttribute(AttributeStep, "unit").WithAttribute("scope", "step").ForError(errors.New("oops")),
+		ForReason("interrupted").ForError(errors.New("cancelled")),
+	})).Errorf("could not run steps")
+	expected := []Failure{{
+		Reason:     "executing_graph:step_failed",
+		Attributes: map[string]string{AttributeStep: "unit", "scope": "step"},
+	}, {
+		Reason:     "executing_graph:interrupted",
+		Attributes: map[string]string{"scope": "graph"},
+	}}
+	testhelper.Diff(t, "failures", Failures(err), expected)
+}
+
+func TestWithAttributes(t *testing.T) {
+	attributed := WithAttributes(errors.New("oops"), map[string]string{"scope": "attributed"})
+	if attributed.Error() != "oops" {
+		t.Errorf("expected the message of the wrapped error, got %q", attributed.Error())
+	}
+	testhelper.Diff(t, "failures of an error without a reason", Failures(attributed), []Failure(nil))
+
+	err := ForReason("step_failed").WithError(utilerrors.NewAggregate([]error{
+		attributed,
+		WithAttributes(ForReason("interrupted").WithAttribute("scope", "inner").ForError(errors.New("cancelled")), map[string]string{"scope": "attributed", AttributeCategory: "infra"}),
+	})).Errorf("could not run steps")
+	expected := []Failure{{
+		Reason:     "step_failed",
+		Attributes: map[string]string{"scope": "attributed"},
+	}, {
+		Reason:     "step_failed:interrupted",
+		Attributes: map[string]string{"scope": "inner", AttributeCategory: "infra"},
+	}}
+	testhelper.Diff(t, "failures", Failures(err), expected)
+	testhelper.Diff(t, "reasons", Reasons(err), []string{"step_failed", "step_failed:interrupted"})
+	if WithAttributes(nil, map[string]string{"scope": "attributed"}) != nil {
+		t.Error("expected no error when there is nothing to attribute")
+	}
+}
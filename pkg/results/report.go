@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -22,16 +23,41 @@ const (
 	unknownConsoleHost = "unknown"
 )
 
-// Options holds the configuration options for connecting to the remote aggregation server
+// Options holds the configuration options for the sinks results are reported
+// to: the remote aggregation server, a local file, an OpenTelemetry collector
+// and a Prometheus Pushgateway.
 type Options struct {
-	address     string
-	credentials string
+	address            string
+	credentials        string
+	file               string
+	otlpEndpoint       string
+	pushgatewayAddress string
+
+	classificationRules string
 }
 
 // Bind adds flags for the options
 func (o *Options) Bind(flag *flag.FlagSet) {
 	flag.StringVar(&o.address, "report-address", reportAddress, "Address of the aggregate reporting server.")
 	flag.StringVar(&o.credentials, "report-credentials-file", "", "File holding the <username>:<password> for the aggregate reporting server.")
+	flag.StringVar(&o.file, "report-file", "", "If set, results are appended to this file as JSON lines.")
+	flag.StringVar(&o.otlpEndpoint, "report-otlp-endpoint", "", "If set, results are exported as OpenTelemetry logs and metrics to this OTLP/HTTP endpoint, e.g. http://localhost:4318.")
+	flag.StringVar(&o.pushgatewayAddress, "report-pushgateway-address", "", "If set, results are pushed as Prometheus metrics to the Pushgateway at this address, in a bounded number of groups per job. Executions are counted by the changes of the result timestamps.")
+	flag.StringVar(&o.classificationRules, "failure-classification-rules", "", "If set, failures of builds and pods are classified with the rules in this YAML file instead of the default rules.")
+}
+
+// InstallClassifier loads the failure classification rules, if configured,
+// and makes Classify use them.
+func (o *Options) InstallClassifier() error {
+	if o.classificationRules == "" {
+		return nil
+	}
+	classifier, err := LoadClassifier(o.classificationRules)
+	if err != nil {
+		return fmt.Errorf("failed to load failure classification rules from %s: %w", o.classificationRules, err)
+	}
+	SetClassifier(classifier)
+	return nil
 }
 
 // Validate checks if the Options elements are empty
@@ -57,14 +83,50 @@ func getUsernameAndPassword(credentials string) (string, string, error) {
 	return strings.TrimSpace(splits[0]), strings.Trim(splits[1], "\n "), nil
 }
 
-// Client returns an HTTP or HTTPs client, based on the options
+// reporterFactory creates a Reporter for a sink, or returns nil when the
+// options do not configure the sink.
+type reporterFactory func(o *Options, spec *api.JobSpec, consoleHost string) (Reporter, error)
+
+// reporterFactories holds all sinks results can be reported to.
+var reporterFactories = []struct {
+	name   string
+	create reporterFactory
+}{
+	{name: "aggregator", create: aggregatorReporter},
+	{name: "file", create: fileReporterFor},
+	{name: "OpenTelemetry", create: otlpReporterFor},
+	{name: "Pushgateway", create: pushgatewayReporterFor},
+}
+
+// Reporter returns a Reporter sending results to all sinks configured in the
+// options.
 func (o *Options) Reporter(spec *api.JobSpec, consoleHost string) (Reporter, error) {
-	if o.address == "" || o.credentials == "" {
+	if consoleHost == "" {
+		consoleHost = unknownConsoleHost
+	}
+	var reporters multiReporter
+	for _, factory := range reporterFactories {
+		reporter, err := factory.create(o, spec, consoleHost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the %s reporter: %w", factory.name, err)
+		}
+		if reporter != nil {
+			reporters = append(reporters, reporter)
+		}
+	}
+	switch len(reporters) {
+	case 0:
 		return &noopReporter{}, nil
+	case 1:
+		return reporters[0], nil
+	default:
+		return reporters, nil
 	}
+}
 
-	if consoleHost == "" {
-		consoleHost = unknownConsoleHost
+func aggregatorReporter(o *Options, spec *api.JobSpec, consoleHost string) (Reporter, error) {
+	if o.address == "" || o.credentials == "" {
+		return nil, nil
 	}
 
 	username, password, err := getUsernameAndPassword(o.credentials)
@@ -96,6 +158,54 @@ type Request struct {
 	Reason string `json:"reason"`
 }
 
+// Result is a single result of a job, there is one for each reason for
+// failure.
+type Result struct {
+	Request
+	// Time is when the result was reported
+	Time time.Time `json:"time"`
+	// Attributes describe the job and the failure, like the org and repo
+	// under test and the step that failed
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// resultsFor determines the results to report for an error.
+func resultsFor(spec *api.JobSpec, consoleHost string, err error, now time.Time) []Result {
+	state := StateSucceeded
+	if err != nil {
+		state = StateFailed
+	}
+	failures := Failures(err)
+	if len(failures) == 0 {
+		failures = []Failure{{Reason: string(ReasonUnknown)}}
+	}
+	jobAttributes := map[string]string{}
+	refs := spec.Refs
+	if refs == nil && len(spec.ExtraRefs) > 0 {
+		refs = &spec.ExtraRefs[0]
+	}
+	if refs != nil {
+		jobAttributes[AttributeOrg] = refs.Org
+		jobAttributes[AttributeRepo] = refs.Repo
+		jobAttributes[AttributeBranch] = refs.BaseRef
+	}
+	var ret []Result
+	for _, failure := range failures {
+		ret = append(ret, Result{
+			Request: Request{
+				JobName: spec.Job,
+				Type:    string(spec.Type),
+				Cluster: consoleHost,
+				State:   state,
+				Reason:  failure.Reason,
+			},
+			Time:       now,
+			Attributes: mergeAttributes(jobAttributes, failure.Attributes),
+		})
+	}
+	return ret
+}
+
 // PodScalerRequest holds the data from pod-scaler used to report a result to an aggregation server
 type PodScalerRequest struct {
 	WorkloadName     string
@@ -121,6 +231,15 @@ type noopReporter struct{}
 
 func (r *noopReporter) Report(err error) {}
 
+// multiReporter fans out reports to several sinks.
+type multiReporter []Reporter
+
+func (r multiReporter) Report(err error) {
+	for _, reporter := range r {
+		reporter.Report(err)
+	}
+}
+
 type reporter struct {
 	client             *http.Client
 	username, password string
@@ -131,22 +250,8 @@ type reporter struct {
 }
 
 func (r *reporter) Report(err error) {
-	state := StateSucceeded
-	if err != nil {
-		state = StateFailed
-	}
-	reasons := Reasons(err)
-	if len(reasons) == 0 {
-		reasons = []string{string(ReasonUnknown)}
-	}
-	for _, reason := range reasons {
-		r.report(Request{
-			JobName: r.spec.Job,
-			Type:    string(r.spec.Type),
-			Cluster: r.consoleHost,
-			State:   state,
-			Reason:  reason,
-		})
+	for _, result := range resultsFor(r.spec, r.consoleHost, err, time.Now()) {
+		r.report(result.Request)
 	}
 }
 
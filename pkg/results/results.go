@@ -7,3 +7,15 @@ const (
 	// indicate a bug, a failure to identify the reason for an error somewhere.
 	ReasonUnknown Reason = "unknown"
 )
+
+// Attributes which are commonly recorded for results.
+const (
+	AttributeOrg    = "org"
+	AttributeRepo   = "repo"
+	AttributeBranch = "branch"
+	AttributeStep   = "step"
+	// AttributeCategory holds the Category of a classified failure
+	AttributeCategory = "category"
+	// AttributeClassifiedReason holds the Reason of a classified failure
+	AttributeClassifiedReason = "classified_reason"
+)
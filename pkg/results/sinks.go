@@ -0,0 +1,359 @@
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// fileReporter appends results to a local file, one JSON object per line.
+type fileReporter struct {
+	path        string
+	spec        *api.JobSpec
+	consoleHost string
+	now         func() time.Time
+}
+
+func fileReporterFor(o *Options, spec *api.JobSpec, consoleHost string) (Reporter, error) {
+	if o.file == "" {
+		return nil, nil
+	}
+	return &fileReporter{path: o.file, spec: spec, consoleHost: consoleHost, now: time.Now}, nil
+}
+
+func (r *fileReporter) Report(err error) {
+	var lines []byte
+	for _, result := range resultsFor(r.spec, r.consoleHost, err, r.now()) {
+		line, err := json.Marshal(result)
+		if err != nil {
+			logrus.Tracef("could not marshal result: %v", err)
+			return
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logrus.Tracef("could not open results file: %v", err)
+		return
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logrus.Tracef("could not close results file: %v", err)
+		}
+	}()
+	if _, err := file.Write(lines); err != nil {
+		logrus.Tracef("could not write results file: %v", err)
+	}
+}
+
+// otlpReporter exports results to an OpenTelemetry collector using the
+// JSON encoding of the OTLP/HTTP protocol: each result is sent as a log
+// record and counted in a metric, both carrying the result's attributes.
+type otlpReporter struct {
+	client      *http.Client
+	endpoint    string
+	spec        *api.JobSpec
+	consoleHost string
+	now         func() time.Time
+}
+
+func otlpReporterFor(o *Options, spec *api.JobSpec, consoleHost string) (Reporter, error) {
+	if o.otlpEndpoint == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(o.otlpEndpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	return &otlpReporter{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		endpoint:    strings.TrimSuffix(o.otlpEndpoint, "/"),
+		spec:        spec,
+		consoleHost: consoleHost,
+		now:         time.Now,
+	}, nil
+}
+
+const otlpScope = "github.com/openshift/ci-tools/pkg/results"
+
+// The types below are the subset of the OTLP JSON encoding needed to export
+// results, see https://github.com/open-telemetry/opentelemetry-proto.
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeInfo struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	SeverityText string         `json:"severityText"`
+	Body         otlpValue      `json:"body"`
+	Attributes   []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScopeInfo   `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogs struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsInt             string         `json:"asInt"`
+}
+
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Sum         struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+		// AggregationTemporality is DELTA, each result is counted once
+		AggregationTemporality int  `json:"aggregationTemporality"`
+		IsMonotonic            bool `json:"isMonotonic"`
+	} `json:"sum"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScopeInfo `json:"scope"`
+	Metrics []otlpMetric  `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+const otlpAggregationTemporalityDelta = 1
+
+// resultAttributes returns all attributes of a result, sorted by key.
+func resultAttributes(result Result) []otlpKeyValue {
+	attributes := map[string]string{
+		"job_name": result.JobName,
+		"type":     result.Type,
+		"cluster":  result.Cluster,
+		"state":    result.State,
+		"reason":   result.Reason,
+	}
+	for key, value := range result.Attributes {
+		attributes[key] = value
+	}
+	var keys []string
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var ret []otlpKeyValue
+	for _, key := range keys {
+		ret = append(ret, otlpKeyValue{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return ret
+}
+
+func (r *otlpReporter) Report(err error) {
+	now := r.now()
+	timestamp := strconv.FormatInt(now.UnixNano(), 10)
+	resource := otlpResource{Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpValue{StringValue: "ci-operator"}}}}
+	scope := otlpScopeInfo{Name: otlpScope}
+
+	var records []otlpLogRecord
+	metric := otlpMetric{Name: "ci_operator.results", Description: "Results of ci-operator executions, by reason.", Unit: "1"}
+	metric.Sum.AggregationTemporality = otlpAggregationTemporalityDelta
+	metric.Sum.IsMonotonic = true
+
+	for _, result := range resultsFor(r.spec, r.consoleHost, err, now) {
+		attributes := resultAttributes(result)
+		severity, body := "INFO", fmt.Sprintf("Job %s %s", result.JobName, result.State)
+		if result.State != StateSucceeded {
+			severity, body = "ERROR", fmt.Sprintf("Job %s %s with reason %s", result.JobName, result.State, result.Reason)
+		}
+		records = append(records, otlpLogRecord{
+			TimeUnixNano: timestamp,
+			SeverityText: severity,
+			Body:         otlpValue{StringValue: body},
+			Attributes:   attributes,
+		})
+		metric.Sum.DataPoints = append(metric.Sum.DataPoints, otlpDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: timestamp,
+			TimeUnixNano:      timestamp,
+			AsInt:             "1",
+		})
+	}
+
+	r.export("/v1/logs", otlpLogs{ResourceLogs: []otlpResourceLogs{{
+		Resource:  resource,
+		ScopeLogs: []otlpScopeLogs{{Scope: scope, LogRecords: records}},
+	}}})
+	r.export("/v1/metrics", otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     resource,
+		ScopeMetrics: []otlpScopeMetrics{{Scope: scope, Metrics: []otlpMetric{metric}}},
+	}}})
+}
+
+func (r *otlpReporter) export(path string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logrus.Tracef("could not marshal OTLP payload: %v", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, r.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		logrus.Tracef("could not create OTLP request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		logrus.Tracef("could not send OTLP request: %v", err)
+		return
+	}
+	if err := resp.Body.Close(); err != nil {
+		logrus.Tracef("could not close OTLP response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		logrus.Tracef("response for OTLP export was not 200: %s", resp.Status)
+	}
+}
+
+// pushgatewayAttributes are the attributes pushed as labels; others are not
+// pushed, as Prometheus metrics need a fixed set of labels.
+var pushgatewayAttributes = []string{AttributeOrg, AttributeRepo, AttributeBranch, AttributeStep, AttributeCategory}
+
+// pushgatewaySlots is the number of groups the results of a job are spread
+// over in the Pushgateway.
+const pushgatewaySlots = 8
+
+// pushgatewayReporter pushes results as metrics to a Prometheus Pushgateway.
+// The Pushgateway never forgets a group, so results are grouped by the job and
+// one of a few slots picked by the build ID, which bounds the groups by the
+// number of jobs: executions of a job run concurrently, for example for
+// different pull requests, and a single group per job would be replaced by
+// each execution before it was scraped. Results are pushed as the time they
+// were reported at, so that the executions can be counted with changes().
+type pushgatewayReporter struct {
+	client      *http.Client
+	address     string
+	spec        *api.JobSpec
+	consoleHost string
+	now         func() time.Time
+}
+
+func pushgatewayReporterFor(o *Options, spec *api.JobSpec, consoleHost string) (Reporter, error) {
+	if o.pushgatewayAddress == "" {
+		return nil, nil
+	}
+	if _, err := url.ParseRequestURI(o.pushgatewayAddress); err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	return &pushgatewayReporter{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		address:     strings.TrimSuffix(o.pushgatewayAddress, "/"),
+		spec:        spec,
+		consoleHost: consoleHost,
+		now:         time.Now,
+	}, nil
+}
+
+func (r *pushgatewayReporter) Report(err error) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ci_operator_last_result_timestamp_seconds",
+		Help: "Time of the last result of a ci-operator job, by reason.",
+	}, append([]string{"type", "cluster", "state", "reason"}, pushgatewayAttributes...))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(gauge)
+	now := r.now()
+	for _, result := range resultsFor(r.spec, r.consoleHost, err, now) {
+		labels := prometheus.Labels{
+			"type":    result.Type,
+			"cluster": result.Cluster,
+			"state":   result.State,
+			"reason":  result.Reason,
+		}
+		for _, label := range pushgatewayAttributes {
+			labels[label] = result.Attributes[label]
+		}
+		gauge.With(labels).Set(float64(now.Unix()))
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		logrus.Tracef("could not gather metrics: %v", err)
+		return
+	}
+	body := &bytes.Buffer{}
+	encoder := expfmt.NewEncoder(body, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			logrus.Tracef("could not encode metrics: %v", err)
+			return
+		}
+	}
+	jobName := r.spec.Job
+	if jobName == "" {
+		jobName = "unknown"
+	}
+	address := fmt.Sprintf("%s/metrics/job/ci-operator/job_name/%s/slot/%d", r.address, url.PathEscape(jobName), pushgatewaySlot(r.spec.BuildID))
+	req, err := http.NewRequest(http.MethodPut, address, body)
+	if err != nil {
+		logrus.Warnf("could not create Pushgateway request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtText))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		logrus.Warnf("could not push results to the Pushgateway: %v", err)
+		return
+	}
+	if err := resp.Body.Close(); err != nil {
+		logrus.Tracef("could not close Pushgateway response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		logrus.Warnf("response for Pushgateway push was not 200: %s", resp.Status)
+	}
+}
+
+// pushgatewaySlot spreads the builds of a job over the slots.
+func pushgatewaySlot(buildID string) uint32 {
+	hash := fnv.New32a()
+	// writing to a hash never fails
+	_, _ = hash.Write([]byte(buildID))
+	return hash.Sum32() % pushgatewaySlots
+}
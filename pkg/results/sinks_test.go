@@ -0,0 +1,152 @@
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+var (
+	sinkSpec = &api.JobSpec{JobSpec: downwardapi.JobSpec{
+		Job:     "pull-ci-org-repo-master-unit",
+		Type:    v1.PresubmitJob,
+		BuildID: "1234",
+		Refs:    &v1.Refs{Org: "org", Repo: "repo", BaseRef: "master"},
+	}}
+	sinkErr = ForReason("executing_graph").WithError(
+		ForReason("step_failed").WithAttribute(AttributeStep, "unit").ForError(errors.New("oops")),
+	).Errorf("could not run steps")
+	sinkTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestFileReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	reporter := &fileReporter{path: path, spec: sinkSpec, consoleHost: "foo.com", now: func() time.Time { return sinkTime }}
+	reporter.Report(nil)
+	reporter.Report(sinkErr)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read results: %v", err)
+	}
+	expected := `{"job_name":"pull-ci-org-repo-master-unit","type":"presubmit","cluster":"foo.com","state":"succeeded","reason":"unknown","time":"2023-01-01T00:00:00Z","attributes":{"branch":"master","org":"org","repo":"repo"}}
+{"job_name":"pull-ci-org-repo-master-unit","type":"presubmit","cluster":"foo.com","state":"failed","reason":"executing_graph:step_failed","time":"2023-01-01T00:00:00Z","attributes":{"branch":"master","org":"org","repo":"repo","step":"unit"}}
+`
+	if diff := cmp.Diff(expected, string(raw)); diff != "" {
+		t.Errorf("unexpected results file: %s", diff)
+	}
+}
+
+func TestOTLPReporter(t *testing.T) {
+	var lock sync.Mutex
+	requests := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		lock.Lock()
+		requests[r.URL.Path] = raw
+		lock.Unlock()
+	}))
+	defer server.Close()
+	reporter, err := (&Options{otlpEndpoint: server.URL + "/"}).Reporter(sinkSpec, "foo.com")
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	reporter.(*otlpReporter).now = func() time.Time { return sinkTime }
+	reporter.Report(sinkErr)
+
+	expectedAttributes := []otlpKeyValue{
+		{Key: "branch", Value: otlpValue{StringValue: "master"}},
+		{Key: "cluster", Value: otlpValue{StringValue: "foo.com"}},
+		{Key: "job_name", Value: otlpValue{StringValue: "pull-ci-org-repo-master-unit"}},
+		{Key: "org", Value: otlpValue{StringValue: "org"}},
+		{Key: "reason", Value: otlpValue{StringValue: "executing_graph:step_failed"}},
+		{Key: "repo", Value: otlpValue{StringValue: "repo"}},
+		{Key: "state", Value: otlpValue{StringValue: "failed"}},
+		{Key: "step", Value: otlpValue{StringValue: "unit"}},
+		{Key: "type", Value: otlpValue{StringValue: "presubmit"}},
+	}
+	var logs otlpLogs
+	if err := json.Unmarshal(requests["/v1/logs"], &logs); err != nil {
+		t.Fatalf("failed to unmarshal logs: %v", err)
+	}
+	expectedRecords := []otlpLogRecord{{
+		TimeUnixNano: "1672531200000000000",
+		SeverityText: "ERROR",
+		Body:         otlpValue{StringValue: "Job pull-ci-org-repo-master-unit failed with reason executing_graph:step_failed"},
+		Attributes:   expectedAttributes,
+	}}
+	if diff := cmp.Diff(expectedRecords, logs.ResourceLogs[0].ScopeLogs[0].LogRecords); diff != "" {
+		t.Errorf("unexpected log records: %s", diff)
+	}
+	var metrics otlpMetrics
+	if err := json.Unmarshal(requests["/v1/metrics"], &metrics); err != nil {
+		t.Fatalf("failed to unmarshal metrics: %v", err)
+	}
+	expectedPoints := []otlpDataPoint{{
+		Attributes:        expectedAttributes,
+		StartTimeUnixNano: "1672531200000000000",
+		TimeUnixNano:      "1672531200000000000",
+		AsInt:             "1",
+	}}
+	if diff := cmp.Diff(expectedPoints, metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Sum.DataPoints); diff != "" {
+		t.Errorf("unexpected data points: %s", diff)
+	}
+}
+
+func TestPushgatewayReporter(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		path, body = r.URL.Path, string(raw)
+	}))
+	defer server.Close()
+	reporter, err := (&Options{pushgatewayAddress: server.URL}).Reporter(sinkSpec, "foo.com")
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	reporter.(*pushgatewayReporter).now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	reporter.Report(sinkErr)
+	if expected := "/metrics/job/ci-operator/job_name/pull-ci-org-repo-master-unit/slot/5"; path != expected {
+		t.Errorf("expected push to %s, got %s", expected, path)
+	}
+	expected := `ci_operator_last_result_timestamp_seconds{branch="master",category="",cluster="foo.com",org="org",reason="executing_graph:step_failed",repo="repo",state="failed",step="unit",type="presubmit"} 1.6725312e+09`
+	if !strings.Contains(body, expected) {
+		t.Errorf("expected pushed metrics to contain %s, got:\n%s", expected, body)
+	}
+}
+
+func TestOptionsReporterFanOut(t *testing.T) {
+	dir := t.TempDir()
+	options := &Options{file: filepath.Join(dir, "results.jsonl"), otlpEndpoint: "http://127.0.0.1:0"}
+	reporter, err := options.Reporter(sinkSpec, "")
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	reporters, ok := reporter.(multiReporter)
+	if !ok || len(reporters) != 2 {
+		t.Fatalf("expected to fan out to two reporters, got %#v", reporter)
+	}
+	if _, err := (&Options{pushgatewayAddress: "not a URL"}).Reporter(sinkSpec, ""); err == nil {
+		t.Error("expected an error for an invalid Pushgateway address")
+	}
+}
@@ -12,6 +12,10 @@ const (
 
 type backoffCache interface {
 	check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string)
+	// refused determines whether the retester refused to retest the HEAD of the PR
+	refused(pr tide.PullRequest) bool
+	// refuse records that the retester refused to retest the HEAD of the PR
+	refuse(pr tide.PullRequest)
 	load() error
 	save() error
 }
@@ -99,6 +99,31 @@ func (b *fileBackoffCache) check(pr tide.PullRequest, baseSha string, policy Ret
 	return check(&b.cache, pr, baseSha, policy)
 }
 
+func (b *fileBackoffCache) refused(pr tide.PullRequest) bool {
+	return refused(b.cache, pr)
+}
+
+func (b *fileBackoffCache) refuse(pr tide.PullRequest) {
+	refuse(&b.cache, pr)
+}
+
+// refused determines whether the cache records a refusal to retest the HEAD of the PR
+func refused(cache map[string]*pullRequest, pr tide.PullRequest) bool {
+	record, has := cache[prKey(&pr)]
+	return has && record.RefusedPrSha == string(pr.HeadRefOID)
+}
+
+// refuse records in the cache a refusal to retest the HEAD of the PR
+func refuse(cache *map[string]*pullRequest, pr tide.PullRequest) {
+	key := prKey(&pr)
+	if _, has := (*cache)[key]; !has {
+		(*cache)[key] = &pullRequest{}
+	}
+	record := (*cache)[key]
+	record.LastConsideredTime = metav1.Now()
+	record.RefusedPrSha = string(pr.HeadRefOID)
+}
+
 // check updates the cache and returns a retestBackoffAction according to baseSha, policy, and number of retests performed for the PR.
 func check(cache *map[string]*pullRequest, pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
 	key := prKey(&pr)
@@ -0,0 +1,131 @@
+package retester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PassRateSource provides the recent pass rate of jobs, so that the retester
+// can tell jobs that flake from jobs that are broken.
+type PassRateSource interface {
+	// PassRate returns the fraction of recent runs of the job that passed, between
+	// 0 and 1. If the source does not know the job well enough to tell, ok is false.
+	PassRate(job string) (rate float64, ok bool, err error)
+}
+
+// filePassRateSource serves pass rates from a file mapping job names to the
+// fraction of their recent runs that passed.
+type filePassRateSource struct {
+	rates map[string]float64
+}
+
+// NewFilePassRateSource loads pass rates from a YAML or JSON file.
+func NewFilePassRateSource(path string) (PassRateSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pass rates %w", err)
+	}
+	var rates map[string]float64
+	if err := yaml.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pass rates %w", err)
+	}
+	for job, rate := range rates {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("pass rate for job %s must be between 0 and 1, not %v", job, rate)
+		}
+	}
+	return &filePassRateSource{rates: rates}, nil
+}
+
+func (s *filePassRateSource) PassRate(job string) (float64, bool, error) {
+	rate, ok := s.rates[job]
+	return rate, ok, nil
+}
+
+const (
+	// sippyMinRuns is the number of recent runs a job needs before we trust its pass rate
+	sippyMinRuns = 5
+	// sippyRefreshInterval is how long we hold on to pass rates fetched from Sippy
+	sippyRefreshInterval = time.Hour
+)
+
+// sippyJob is the subset of a job report from the Sippy API that we use
+type sippyJob struct {
+	Name                  string  `json:"name"`
+	CurrentPassPercentage float64 `json:"current_pass_percentage"`
+	CurrentRuns           int     `json:"current_runs"`
+}
+
+// sippyPassRateSource serves pass rates from the job reports of a Sippy
+// instance. Reports are fetched at most once per refresh interval.
+type sippyPassRateSource struct {
+	client  *http.Client
+	baseURL string
+	release string
+
+	lock    sync.Mutex
+	fetched time.Time
+	jobs    map[string]sippyJob
+	now     func() time.Time
+}
+
+// NewSippyPassRateSource serves pass rates for jobs in the release from the Sippy
+// instance at the URL. Presubmits are reported by Sippy under the "Presubmits" release.
+func NewSippyPassRateSource(baseURL, release string) PassRateSource {
+	return &sippyPassRateSource{
+		client:  &http.Client{Timeout: time.Minute},
+		baseURL: baseURL,
+		release: release,
+		now:     time.Now,
+	}
+}
+
+func (s *sippyPassRateSource) PassRate(job string) (float64, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.jobs == nil || s.now().Sub(s.fetched) > sippyRefreshInterval {
+		jobs, err := s.fetch()
+		if err != nil {
+			return 0, false, err
+		}
+		s.jobs, s.fetched = jobs, s.now()
+	}
+	report, ok := s.jobs[job]
+	if !ok || report.CurrentRuns < sippyMinRuns {
+		return 0, false, nil
+	}
+	return report.CurrentPassPercentage / 100, true, nil
+}
+
+func (s *sippyPassRateSource) fetch() (map[string]sippyJob, error) {
+	address := fmt.Sprintf("%s/api/jobs?%s", s.baseURL, url.Values{"release": []string{s.release}}.Encode())
+	resp, err := s.client.Get(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GET %s: %w", address, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got unexpected http status code %d for url %s", resp.StatusCode, address)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body for request to %s: %w", address, err)
+	}
+	var reports []sippyJob
+	if err := json.Unmarshal(raw, &reports); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job reports from %s: %w", address, err)
+	}
+	jobs := make(map[string]sippyJob, len(reports))
+	for _, report := range reports {
+		jobs[report.Name] = report
+	}
+	return jobs, nil
+}
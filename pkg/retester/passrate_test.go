@@ -0,0 +1,172 @@
+package retester
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shurcooL/githubv4"
+	"github.com/sirupsen/logrus"
+
+	configflagutil "k8s.io/test-infra/prow/flagutil/config"
+	"k8s.io/test-infra/prow/github"
+	"k8s.io/test-infra/prow/github/fakegithub"
+	"k8s.io/test-infra/prow/tide"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestFilePassRateSource(t *testing.T) {
+	source, err := NewFilePassRateSource("testdata/passrates/rates.yaml")
+	if err != nil {
+		t.Fatalf("failed to load pass rates: %v", err)
+	}
+	if rate, ok, err := source.PassRate("flaky-presubmit"); err != nil || !ok || rate != 0.9 {
+		t.Errorf("expected a pass rate of 0.9, got %v, %t, %v", rate, ok, err)
+	}
+	if _, ok, err := source.PassRate("unknown"); err != nil || ok {
+		t.Errorf("expected an unknown job, got %t, %v", ok, err)
+	}
+
+	_, err = NewFilePassRateSource("testdata/passrates/invalid.yaml")
+	if diff := cmp.Diff(fmt.Errorf("pass rate for job test-presubmit must be between 0 and 1, not 20"), err, testhelper.EquateErrorMessage); diff != "" {
+		t.Errorf("Error differs from expected:\n%s", diff)
+	}
+}
+
+func TestSippyPassRateSource(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/jobs" || r.URL.Query().Get("release") != "Presubmits" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"broken","current_pass_percentage":12.5,"current_runs":40},{"name":"new","current_pass_percentage":0,"current_runs":1}]`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := NewSippyPassRateSource(server.URL, "Presubmits").(*sippyPassRateSource)
+	source.now = func() time.Time { return now }
+
+	if rate, ok, err := source.PassRate("broken"); err != nil || !ok || rate != 0.125 {
+		t.Errorf("expected a pass rate of 0.125, got %v, %t, %v", rate, ok, err)
+	}
+	if _, ok, err := source.PassRate("new"); err != nil || ok {
+		t.Errorf("expected a job with too few runs to be unknown, got %t, %v", ok, err)
+	}
+	if requests != 1 {
+		t.Errorf("expected job reports to be fetched once, got %d requests", requests)
+	}
+	now = now.Add(2 * sippyRefreshInterval)
+	if _, _, err := source.PassRate("broken"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected job reports to be refreshed, got %d requests", requests)
+	}
+}
+
+func TestRetestOrBackoffWithPassRates(t *testing.T) {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	configOpts := configflagutil.ConfigOptions{
+		ConfigPath:    filepath.Join("testdata", "prowconfig", "simple.yaml"),
+		JobConfigPath: filepath.Join("testdata", "jobconfig", "simple.yaml"),
+	}
+	configAgent, err := configOpts.ConfigAgent()
+	if err != nil {
+		t.Fatalf("Error starting config agent: %v", err)
+	}
+	pr := tide.PullRequest{
+		Number:     1,
+		HeadRefOID: "a",
+		Repository: struct {
+			Name          githubv4.String
+			NameWithOwner githubv4.String
+			Owner         struct{ Login githubv4.String }
+		}{Name: "ci-tools", NameWithOwner: "openshift/ci-tools", Owner: struct{ Login githubv4.String }{Login: "openshift"}},
+	}
+	brokenSource, err := NewFilePassRateSource("testdata/passrates/rates.yaml")
+	if err != nil {
+		t.Fatalf("failed to load pass rates: %v", err)
+	}
+	flakySource := &filePassRateSource{rates: map[string]float64{"test-presubmit": 0.9}}
+
+	testCases := []struct {
+		name        string
+		passRates   PassRateSource
+		minPassRate float64
+		attempts    int
+		expected    []string
+	}{
+		{
+			name:        "broken job is not retested",
+			passRates:   brokenSource,
+			minPassRate: 0.5,
+			// the second attempt must not explain again why the PR is not retested,
+			// even when the retester is restarted in between
+			attempts: 2,
+			expected: []string{"The following required jobs passed less than 50% of their recent runs, so they are most likely broken and not flaking:\n\n" +
+				"- `test-presubmit` passed 20% of its recent runs\n\n" +
+				"The retester only retests jobs that pass often enough and will not retest this PR. Please investigate the failures; you can still `/retest` manually.\n"},
+		},
+		{
+			name:        "flaky job is retested",
+			passRates:   flakySource,
+			minPassRate: 0.5,
+			attempts:    1,
+			expected:    []string{"/retest-required\n\nRemaining retests: 2 against base HEAD abcde and 8 for PR HEAD a in total\n"},
+		},
+		{
+			name:      "without a minimum pass rate, broken jobs are retested",
+			passRates: brokenSource,
+			attempts:  1,
+			expected:  []string{"/retest-required\n\nRemaining retests: 2 against base HEAD abcde and 8 for PR HEAD a in total\n"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ghc := &MyFakeClient{fakegithub.NewFakeClient()}
+			ghc.CombinedStatuses = map[string]*github.CombinedStatus{
+				"a": {Statuses: []github.Status{{State: "failure", Context: "test-presubmit", Description: "Job failed"}}},
+			}
+			cacheFile := filepath.Join(t.TempDir(), "cache.yaml")
+			for i := 0; i < tc.attempts; i++ {
+				backoff := &fileBackoffCache{cache: map[string]*pullRequest{}, file: cacheFile, cacheRecordAge: time.Hour, logger: logger}
+				if err := backoff.load(); err != nil {
+					t.Fatalf("failed to load the cache: %v", err)
+				}
+				c := &RetestController{
+					ghClient:     ghc,
+					configGetter: configAgent.Config,
+					logger:       logger,
+					backoff:      backoff,
+					passRates:    tc.passRates,
+					config: &Config{Retester: Retester{
+						RetesterPolicy: RetesterPolicy{MaxRetestsForShaAndBase: 3, MaxRetestsForSha: 9},
+						Oranizations:   map[string]Oranization{"openshift": {RetesterPolicy: RetesterPolicy{Enabled: &True}}},
+						MinPassRate:    tc.minPassRate,
+					}},
+				}
+				if err := c.retestOrBackoff(pr); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err := backoff.save(); err != nil {
+					t.Fatalf("failed to save the cache: %v", err)
+				}
+			}
+			var actual []string
+			for _, comment := range ghc.IssueComments[1] {
+				actual = append(actual, comment.Body)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("comments differ from expected:\n%s", diff)
+			}
+		})
+	}
+}
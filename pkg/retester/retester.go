@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -36,6 +37,8 @@ type pullRequest struct {
 	RetestsForPrSha    int         `json:"retests_for_pr_sha,omitempty"`
 	RetestsForBaseSha  int         `json:"retests_for_base_sha,omitempty"`
 	LastConsideredTime metav1.Time `json:"last_considered_time,omitempty"`
+	// RefusedPrSha is the PR sha the retester refused to retest and explained why
+	RefusedPrSha string `json:"refused_pr_sha,omitempty"`
 }
 
 var (
@@ -64,6 +67,11 @@ type Config struct {
 type Retester struct {
 	RetesterPolicy `json:",inline"`
 	Oranizations   map[string]Oranization `json:"orgs,omitempty"`
+	// MinPassRate is the recent pass rate, between 0 and 1, a failing required job
+	// must have for the retester to retest it. Jobs failing more often than that are
+	// most likely broken rather than flaky, so retesting them only wastes capacity.
+	// Only applies when the retester is configured with a source of pass rates.
+	MinPassRate float64 `json:"min_pass_rate,omitempty"`
 }
 
 // Oranization is org level configuration for retester configuration.
@@ -100,6 +108,9 @@ func LoadConfig(configFilePath string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config %w", err)
 	}
+	if config.Retester.MinPassRate < 0 || config.Retester.MinPassRate > 1 {
+		return nil, fmt.Errorf("min_pass_rate must be between 0 and 1, not %v", config.Retester.MinPassRate)
+	}
 
 	return &config, nil
 }
@@ -114,6 +125,7 @@ type RetestController struct {
 
 	usesGitHubApp bool
 	backoff       backoffCache
+	passRates     PassRateSource
 
 	config *Config
 }
@@ -186,7 +198,7 @@ func validatePolicies(policy RetesterPolicy) []error {
 }
 
 // NewController generates a retest controller.
-func NewController(ghClient githubClient, cfg config.Getter, gitClient git.ClientFactory, usesApp bool, cacheFile string, cacheRecordAge time.Duration, config *Config, awsSession *session.Session) *RetestController {
+func NewController(ghClient githubClient, cfg config.Getter, gitClient git.ClientFactory, usesApp bool, cacheFile string, cacheRecordAge time.Duration, config *Config, awsSession *session.Session, passRates PassRateSource) *RetestController {
 	logger := logrus.NewEntry(logrus.StandardLogger())
 	var backoff backoffCache
 	if awsSession != nil {
@@ -202,6 +214,7 @@ func NewController(ghClient githubClient, cfg config.Getter, gitClient git.Clien
 		logger:        logger,
 		usesGitHubApp: usesApp,
 		backoff:       backoff,
+		passRates:     passRates,
 		config:        config,
 	}
 	if err := ret.backoff.load(); err != nil {
@@ -282,6 +295,15 @@ func (c *RetestController) retestOrBackoff(pr tide.PullRequest) error {
 		return fmt.Errorf("failed to validate retester policy: %v", validationErrors)
 	}
 
+	broken, err := c.brokenJobs(pr)
+	if err != nil {
+		return err
+	}
+	if len(broken) != 0 {
+		c.refuseRetest(pr, broken)
+		return nil
+	}
+
 	action, message := c.backoff.check(pr, baseSha, policy)
 	switch action {
 	case retestBackoffHold:
@@ -294,6 +316,66 @@ func (c *RetestController) retestOrBackoff(pr tide.PullRequest) error {
 	return nil
 }
 
+// brokenJob is a failing required job that rarely passes
+type brokenJob struct {
+	name     string
+	passRate float64
+}
+
+// brokenJobs determines the failing required jobs of the PR whose recent pass
+// rate is below the configured minimum. Jobs the pass rate source does not know
+// are assumed to be flaky, as are all jobs when the source is not available.
+func (c *RetestController) brokenJobs(pr tide.PullRequest) ([]brokenJob, error) {
+	if c.passRates == nil || c.config.Retester.MinPassRate == 0 {
+		return nil, nil
+	}
+	presubmits := c.presubmitsForPRByContext(pr)
+	contexts, err := headContexts(c.ghClient, pr)
+	if err != nil {
+		return nil, err
+	}
+	var broken []brokenJob
+	for _, ctx := range contexts {
+		if ctx.State != githubql.StatusStateFailure {
+			continue
+		}
+		ps, required := presubmits[string(ctx.Context)]
+		if !required {
+			continue
+		}
+		rate, known, err := c.passRates.PassRate(ps.Name)
+		if err != nil {
+			c.logger.WithError(err).Warnf("Failed to get the pass rate of %s, assuming it is flaky", ps.Name)
+			return nil, nil
+		}
+		if known && rate < c.config.Retester.MinPassRate {
+			broken = append(broken, brokenJob{name: ps.Name, passRate: rate})
+		}
+	}
+	sort.Slice(broken, func(i, j int) bool {
+		return broken[i].name < broken[j].name
+	})
+	return broken, nil
+}
+
+// refuseRetest explains on the PR why it is not retested, once for every HEAD
+func (c *RetestController) refuseRetest(pr tide.PullRequest, broken []brokenJob) {
+	if c.backoff.refused(pr) {
+		return
+	}
+	var jobs []string
+	for _, job := range broken {
+		jobs = append(jobs, fmt.Sprintf("- `%s` passed %.0f%% of its recent runs", job.name, job.passRate*100))
+	}
+	comment := fmt.Sprintf("The following required jobs passed less than %.0f%% of their recent runs, so they are most likely broken and not flaking:\n\n%s\n\nThe retester only retests jobs that pass often enough and will not retest this PR. Please investigate the failures; you can still `/retest` manually.\n",
+		c.config.Retester.MinPassRate*100, strings.Join(jobs, "\n"))
+	if err := c.ghClient.CreateComment(string(pr.Repository.Owner.Login), string(pr.Repository.Name), int(pr.Number), comment); err != nil {
+		c.logger.WithField("comment", comment).WithError(err).Error("failed to create a comment")
+		return
+	}
+	c.backoff.refuse(pr)
+}
+
 func findCandidates(config config.Getter, gc githubClient, usesGitHubAppsAuth bool, logger *logrus.Entry) (map[string]tide.PullRequest, error) {
 	prs, err := query(config, gc, usesGitHubAppsAuth, logger)
 	if err != nil {
@@ -90,3 +90,11 @@ func (b *s3BackOffCache) save() error {
 func (b *s3BackOffCache) check(pr tide.PullRequest, baseSha string, policy RetesterPolicy) (retestBackoffAction, string) {
 	return check(&b.cache, pr, baseSha, policy)
 }
+
+func (b *s3BackOffCache) refused(pr tide.PullRequest) bool {
+	return refused(b.cache, pr)
+}
+
+func (b *s3BackOffCache) refuse(pr tide.PullRequest) {
+	refuse(&b.cache, pr)
+}
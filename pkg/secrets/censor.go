@@ -2,6 +2,7 @@ package secrets
 
 import (
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -17,28 +18,65 @@ type DynamicCensor struct {
 	sync.RWMutex
 	*secretutil.ReloadingCensorer
 	secrets sets.Set[string]
+	// names holds the name of the secret each value was added for, if any
+	names map[string]string
+	// patterns holds the secrets and their encodings matched in streams
+	patterns []pattern
+	// redactions holds the names of the secrets censored in each stream
+	redactions map[string]sets.Set[string]
 }
 
 func NewDynamicCensor() DynamicCensor {
 	return DynamicCensor{
 		ReloadingCensorer: secretutil.NewCensorer(),
 		secrets:           sets.New[string](),
+		names:             map[string]string{},
+		redactions:        map[string]sets.Set[string]{},
 	}
 }
 
 // AddSecrets adds the content of one or more secrets to the censor list.
 func (c *DynamicCensor) AddSecrets(s ...string) {
+	c.AddNamedSecrets("", s...)
+}
+
+// AddNamedSecrets adds the content of one or more secrets to the censor list,
+// identifying them by `name` in redaction reports.
+func (c *DynamicCensor) AddNamedSecrets(name string, s ...string) {
 	c.Lock()
 	defer c.Unlock()
 	c.secrets.Insert(s...)
+	if name != "" {
+		for _, value := range s {
+			c.names[value] = name
+		}
+	}
 	c.ReloadingCensorer.Refresh(sets.List(c.secrets)...)
+	// streams being censored hold on to the previous patterns, so they must
+	// not be modified in place
+	var patterns []pattern
+	for _, value := range sets.List(c.secrets) {
+		name := c.names[value]
+		if name == "" {
+			name = unnamedSecret
+		}
+		for _, encoded := range encodings(value) {
+			patterns = append(patterns, pattern{name: name, value: []byte(encoded)})
+		}
+	}
+	// match longer patterns first so that a secret containing another is
+	// reported under its own name
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return len(patterns[i].value) > len(patterns[j].value)
+	})
+	c.patterns = patterns
 }
 
 // ReadFromEnv loads an environment variable and adds it to the censor list.
 func ReadFromEnv(name string, censor *DynamicCensor) string {
 	ret := os.Getenv(name)
 	if ret != "" {
-		censor.AddSecrets(ret)
+		censor.AddNamedSecrets(name, ret)
 	}
 	return ret
 }
@@ -50,6 +88,6 @@ func ReadFromFile(path string, censor *DynamicCensor) (string, error) {
 		return "", err
 	}
 	ret := strings.TrimSpace(string(bytes))
-	censor.AddSecrets(ret)
+	censor.AddNamedSecrets(path, ret)
 	return ret, nil
 }
@@ -1,10 +1,14 @@
 package secrets
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/vaultclient"
 )
 
 type ReadOnlyClient interface {
@@ -22,6 +26,23 @@ type Client interface {
 
 type SecretUsageComparer interface {
 	LastChanged() time.Time
+	// Version is the current version of the item in the secret store
+	Version() int
 	UnusedFields(inUse sets.Set[string]) (Difference sets.Set[string])
 	SuperfluousFields() sets.Set[string]
 }
+
+type fieldNotFoundError struct {
+	path, key string
+}
+
+func (e *fieldNotFoundError) Error() string {
+	return fmt.Sprintf("item at path %q has no key %q", e.path, e.key)
+}
+
+// IsNotFound determines whether the error was caused by the requested item
+// or field not existing in the secret store
+func IsNotFound(err error) bool {
+	var fieldNotFound *fieldNotFoundError
+	return errors.As(err, &fieldNotFound) || vaultclient.IsNotFound(err)
+}
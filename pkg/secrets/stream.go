@@ -0,0 +1,326 @@
+package secrets
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+// unnamedSecret identifies secrets added without a name in redaction reports.
+const unnamedSecret = "<unnamed>"
+
+const (
+	// RedactionSuiteName is the name of the test suite reporting on the
+	// secrets censored in streams.
+	RedactionSuiteName = "secret redaction"
+	// RedactionTestName is the name of the test case reporting on the secrets
+	// censored in streams.
+	RedactionTestName = "Redact secrets from collected artifacts and logs"
+)
+
+// pattern is a sequence of bytes to censor, with line breaks removed.
+type pattern struct {
+	name  string
+	value []byte
+}
+
+// encodings returns the forms in which a secret commonly leaks: verbatim,
+// base64-encoded, URL-encoded or escaped in a JSON string. Line breaks are
+// removed, as they are ignored when matching: a secret is also found when it
+// is wrapped over multiple lines.
+func encodings(secret string) []string {
+	raw := []byte(secret)
+	candidates := []string{
+		secret,
+		// only the secret encoded on its own is matched: when it is part of a
+		// longer encoded value, its encoding depends on the bytes around it.
+		// The padding is omitted so that the encoding is also matched where
+		// the padding was stripped.
+		base64.RawStdEncoding.EncodeToString(raw),
+		base64.RawURLEncoding.EncodeToString(raw),
+		url.QueryEscape(secret),
+		url.PathEscape(secret),
+	}
+	if quoted, err := json.Marshal(secret); err == nil {
+		candidates = append(candidates, string(quoted[1:len(quoted)-1]))
+	}
+	encoded := sets.New[string]()
+	for _, candidate := range candidates {
+		if stripped := stripLineBreaks(candidate); stripped != "" {
+			encoded.Insert(stripped)
+		}
+	}
+	return sets.List(encoded)
+}
+
+func stripLineBreaks(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
+
+func isLineBreak(b byte) bool {
+	return b == '\n' || b == '\r'
+}
+
+// censoringWriter censors the data written to it and passes it on. The data
+// is not changed in length, secrets are replaced by the same number of X
+// characters. Enough data is held back to match secrets which are split
+// across writes; Close must be called to flush it.
+type censoringWriter struct {
+	out      io.Writer
+	patterns []pattern
+	// retain is the number of bytes, not counting line breaks, held back
+	retain  int
+	buf     []byte
+	matched func(name string)
+}
+
+func newCensoringWriter(out io.Writer, patterns []pattern, matched func(name string)) *censoringWriter {
+	w := &censoringWriter{out: out, patterns: patterns, matched: matched}
+	for _, p := range patterns {
+		if len(p.value) > w.retain+1 {
+			w.retain = len(p.value) - 1
+		}
+	}
+	return w
+}
+
+func (w *censoringWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if err := w.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flushes the data held back, it does not close the underlying writer.
+func (w *censoringWriter) Close() error {
+	return w.flush(true)
+}
+
+func (w *censoringWriter) flush(final bool) error {
+	// offsets maps positions in the data without line breaks to positions in
+	// the buffer
+	offsets := make([]int, 0, len(w.buf))
+	stripped := make([]byte, 0, len(w.buf))
+	for i, b := range w.buf {
+		if isLineBreak(b) {
+			continue
+		}
+		offsets = append(offsets, i)
+		stripped = append(stripped, b)
+	}
+	for _, p := range w.patterns {
+		for start := 0; ; {
+			index := bytes.Index(stripped[start:], p.value)
+			if index == -1 {
+				break
+			}
+			index += start
+			for i := index; i < index+len(p.value); i++ {
+				w.buf[offsets[i]] = 'X'
+				stripped[i] = 'X'
+			}
+			w.matched(p.name)
+			start = index + len(p.value)
+		}
+	}
+	// any match starting before the retained data ends before it, so the
+	// data up to there is final
+	cut := len(w.buf)
+	if !final {
+		if len(stripped) <= w.retain {
+			return nil
+		}
+		cut = offsets[len(stripped)-w.retain]
+	}
+	if _, err := w.out.Write(w.buf[:cut]); err != nil {
+		return err
+	}
+	w.buf = append([]byte(nil), w.buf[cut:]...)
+	return nil
+}
+
+// CensorStream copies `src` to `dst`, censoring the secrets and their common
+// encodings. Gzip-compressed data and tar archives are censored transparently,
+// member by member; files in tar archives are censored verbatim as their size
+// may not change. The names of the secrets found are recorded for `name` in
+// the redaction report.
+func (c *DynamicCensor) CensorStream(name string, dst io.Writer, src io.Reader) error {
+	c.RLock()
+	patterns := c.patterns
+	c.RUnlock()
+	reader := bufio.NewReader(src)
+	header, err := peekHeader(name, reader)
+	if err != nil {
+		return err
+	}
+	var redacted bool
+	if !isGzip(header) && !isTar(header) {
+		return c.censorStream(name, dst, reader, patterns, false, &redacted)
+	}
+	return c.censorRewritten(name, dst, reader, patterns)
+}
+
+// censorRewritten censors compressed data and archives, which are written
+// anew and so are not the same bytes even when nothing was censored. The
+// original data is passed through unchanged unless a secret was found in it;
+// both are held in temporary files until that is known.
+func (c *DynamicCensor) censorRewritten(name string, dst io.Writer, src io.Reader, patterns []pattern) (err error) {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			// the files are discarded, so a failure to close them loses nothing
+			_ = f.Close()
+			if removeErr := os.Remove(f.Name()); removeErr != nil && err == nil {
+				err = fmt.Errorf("failed to remove temporary file: %w", removeErr)
+			}
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		f, err := os.CreateTemp("", "censor-")
+		if err != nil {
+			return fmt.Errorf("failed to create a temporary file to censor %s: %w", name, err)
+		}
+		files = append(files, f)
+	}
+	original, censored := files[0], files[1]
+	tee := io.TeeReader(src, original)
+	var redacted bool
+	if err := c.censorStream(name, censored, tee, patterns, false, &redacted); err != nil {
+		return err
+	}
+	// the original is only complete once everything was read
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	result := original
+	if redacted {
+		result = censored
+	}
+	if _, err := result.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read the censored %s: %w", name, err)
+	}
+	if _, err := io.Copy(dst, result); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// peekHeader returns the first bytes of the data: a tar header is a 512 byte
+// block, with the format magic at offset 257.
+func peekHeader(name string, reader *bufio.Reader) ([]byte, error) {
+	header, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return header, nil
+}
+
+func isGzip(header []byte) bool {
+	return len(header) >= 2 && header[0] == 0x1f && header[1] == 0x8b
+}
+
+func isTar(header []byte) bool {
+	return len(header) == 512 && bytes.HasPrefix(header[257:], []byte("ustar"))
+}
+
+// censorStream censors src into dst and sets redacted when a secret was found.
+func (c *DynamicCensor) censorStream(name string, dst io.Writer, src io.Reader, patterns []pattern, inArchive bool, redacted *bool) error {
+	reader := bufio.NewReader(src)
+	header, err := peekHeader(name, reader)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !inArchive && isGzip(header):
+		compressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", name, err)
+		}
+		out := gzip.NewWriter(dst)
+		if err := c.censorStream(name, out, compressed, patterns, false, redacted); err != nil {
+			return err
+		}
+		if err := compressed.Close(); err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", name, err)
+		}
+		return out.Close()
+	case isTar(header):
+		archive, out := tar.NewReader(reader), tar.NewWriter(dst)
+		for {
+			h, err := archive.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read archive %s: %w", name, err)
+			}
+			if err := out.WriteHeader(h); err != nil {
+				return fmt.Errorf("failed to write archive %s: %w", name, err)
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := c.censorStream(path.Join(name, h.Name), out, archive, patterns, true, redacted); err != nil {
+				return err
+			}
+		}
+		return out.Close()
+	default:
+		out := newCensoringWriter(dst, patterns, func(secret string) {
+			*redacted = true
+			c.recordRedaction(name, secret)
+		})
+		if _, err := io.Copy(out, reader); err != nil {
+			return fmt.Errorf("failed to censor %s: %w", name, err)
+		}
+		return out.Close()
+	}
+}
+
+func (c *DynamicCensor) recordRedaction(name, secret string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.redactions[name]; !ok {
+		c.redactions[name] = sets.New[string]()
+	}
+	c.redactions[name].Insert(secret)
+}
+
+// RedactionReport returns a test suite listing the secrets censored in each
+// stream passed through CensorStream, or nil if no secrets were censored.
+func (c *DynamicCensor) RedactionReport() *junit.TestSuite {
+	c.RLock()
+	defer c.RUnlock()
+	if len(c.redactions) == 0 {
+		return nil
+	}
+	var lines []string
+	for name, secrets := range c.redactions {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, strings.Join(sets.List(secrets), ", ")))
+	}
+	sort.Strings(lines)
+	return &junit.TestSuite{
+		Name:     RedactionSuiteName,
+		NumTests: 1,
+		TestCases: []*junit.TestCase{{
+			Name:      RedactionTestName,
+			SystemOut: fmt.Sprintf("Secrets were censored in %d files:\n%s", len(lines), strings.Join(lines, "\n")),
+		}},
+	}
+}
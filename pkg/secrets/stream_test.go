@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+func TestCensorStream(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		expected string
+	}{{
+		name:     "no secrets",
+		input:    "nothing to see here",
+		expected: "nothing to see here",
+	}, {
+		name:     "verbatim secret",
+		input:    "token=s3cr3t/value",
+		expected: "token=XXXXXXXXXXXX",
+	}, {
+		name:     "base64-encoded secret",
+		input:    "token: czNjcjN0L3ZhbHVl\n",
+		expected: "token: XXXXXXXXXXXXXXXX\n",
+	}, {
+		name:     "URL-encoded secret",
+		input:    "https://host/?token=s3cr3t%2Fvalue&other=1",
+		expected: "https://host/?token=XXXXXXXXXXXXXX&other=1",
+	}, {
+		name:     "secret wrapped over lines",
+		input:    "s3cr3\nt/value\r\nend",
+		expected: "XXXXX\nXXXXXXX\r\nend",
+	}, {
+		name:     "multi-line secret escaped in JSON",
+		input:    `{"key":"-----BEGIN KEY-----\nabc\n-----END KEY-----"}`,
+		expected: `{"key":"XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX"}`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			censor := NewDynamicCensor()
+			censor.AddNamedSecrets("token", "s3cr3t/value")
+			censor.AddNamedSecrets("key", "-----BEGIN KEY-----\nabc\n-----END KEY-----")
+			out := &bytes.Buffer{}
+			// read one byte at a time to exercise matches split across writes
+			if err := censor.CensorStream("file", out, iotest.OneByteReader(bytes.NewBufferString(tc.input))); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, out.String()); diff != "" {
+				t.Errorf("unexpected output: %s", diff)
+			}
+		})
+	}
+}
+
+func TestCensorStreamArchives(t *testing.T) {
+	censor := NewDynamicCensor()
+	censor.AddNamedSecrets("ns/token", "s3cr3t")
+	censor.AddSecrets("other")
+
+	archive := &bytes.Buffer{}
+	compressed := gzip.NewWriter(archive)
+	tw := tar.NewWriter(compressed)
+	for name, content := range map[string]string{"a.log": "token s3cr3t", "b.log": "clean", "c.log": "other"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := censor.CensorStream("artifacts.tar.gz", out, archive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decompressed, err := gzip.NewReader(out)
+	if err != nil {
+		t.Fatalf("output is not gzip-compressed: %v", err)
+	}
+	contents := map[string]string{}
+	tr := tar.NewReader(decompressed)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("output is not a tar archive: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[h.Name] = string(content)
+	}
+	if diff := cmp.Diff(map[string]string{"a.log": "token XXXXXX", "b.log": "clean", "c.log": "XXXXX"}, contents); diff != "" {
+		t.Errorf("unexpected archive contents: %s", diff)
+	}
+
+	expected := &junit.TestSuite{
+		Name:     RedactionSuiteName,
+		NumTests: 1,
+		TestCases: []*junit.TestCase{{
+			Name:      RedactionTestName,
+			SystemOut: "Secrets were censored in 2 files:\nartifacts.tar.gz/a.log: ns/token\nartifacts.tar.gz/c.log: <unnamed>",
+		}},
+	}
+	if diff := cmp.Diff(expected, censor.RedactionReport()); diff != "" {
+		t.Errorf("unexpected report: %s", diff)
+	}
+}
+
+func TestCensorStreamPassesUntouchedArchivesThrough(t *testing.T) {
+	censor := NewDynamicCensor()
+	censor.AddSecrets("s3cr3t")
+	archive := &bytes.Buffer{}
+	compressed, err := gzip.NewWriterLevel(archive, gzip.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed.Name = "clean.log"
+	if _, err := compressed.Write([]byte("nothing to see here")); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	original := append([]byte(nil), archive.Bytes()...)
+
+	out := &bytes.Buffer{}
+	if err := censor.CensorStream("clean.log.gz", out, archive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(original, out.Bytes()) {
+		t.Error("expected the compressed data to be passed through unchanged")
+	}
+	if report := censor.RedactionReport(); report != nil {
+		t.Errorf("expected no redactions, got %v", report)
+	}
+}
+
+func TestRedactionReport(t *testing.T) {
+	censor := NewDynamicCensor()
+	censor.AddNamedSecrets("token", "s3cr3t")
+	censor.AddSecrets("other")
+	censorStream := func(name, content string) {
+		if err := censor.CensorStream(name, io.Discard, bytes.NewBufferString(content)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	censorStream("clean.log", "nothing to see here")
+	if report := censor.RedactionReport(); report != nil {
+		t.Errorf("expected no report when no secrets were censored, got %v", report)
+	}
+
+	censorStream("b.log", "s3cr3t other czNjcjN0")
+	censorStream("a.log", "s3cr3t")
+	expected := &junit.TestSuite{
+		Name:     RedactionSuiteName,
+		NumTests: 1,
+		TestCases: []*junit.TestCase{{
+			Name:      RedactionTestName,
+			SystemOut: "Secrets were censored in 2 files:\na.log: token\nb.log: <unnamed>, token",
+		}},
+	}
+	if diff := cmp.Diff(expected, censor.RedactionReport()); diff != "" {
+		t.Errorf("unexpected report: %s", diff)
+	}
+}
@@ -84,7 +84,7 @@ func (c *vaultClient) getKeyAtPath(path, key string) ([]byte, error) {
 	}
 	val, ok := response.Data[key]
 	if !ok {
-		return nil, fmt.Errorf("item at path %q has no key %q", path, key)
+		return nil, &fieldNotFoundError{path: path, key: key}
 	}
 
 	return []byte(val), nil
@@ -93,7 +93,7 @@ func (c *vaultClient) getKeyAtPath(path, key string) ([]byte, error) {
 func (c *vaultClient) getSecretAtPath(path, key string) ([]byte, error) {
 	ret, err := c.getKeyAtPath(path, key)
 	if err == nil {
-		c.censor.AddSecrets(string(ret))
+		c.censor.AddNamedSecrets(path+"."+key, string(ret))
 	}
 	return ret, err
 }
@@ -110,7 +110,7 @@ func (c *vaultClient) setItemAtPath(path, field string, content string) error {
 		data = current.Data
 		data[field] = content
 	}
-	c.censor.AddSecrets(content)
+	c.censor.AddNamedSecrets(path+"."+field, content)
 	return c.upstream.UpsertKV(path, data)
 }
 
@@ -249,6 +249,10 @@ func (v *vaultSecretUsageComparer) LastChanged() time.Time {
 	return v.item.Metadata.CreatedTime
 }
 
+func (v *vaultSecretUsageComparer) Version() int {
+	return v.item.Metadata.Version
+}
+
 func (v *vaultSecretUsageComparer) markInUse(fields sets.Set[string]) (absent sets.Set[string]) {
 	v.inUseFields.Insert(sets.List(fields)...)
 	return fields.Difference(v.allFields)
@@ -0,0 +1,198 @@
+// Package stepgraphanalyzer explains where the time in a ci-operator execution
+// went, based on the step graph ci-operator records in its artifacts.
+package stepgraphanalyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/kataras/tablewriter"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// TraceJSONFilename is the name of the artifact holding the trace events for
+// the step graph.
+const TraceJSONFilename = "ci-operator-step-graph-trace.json"
+
+// StepTiming describes how long a step spent waiting and running.
+type StepTiming struct {
+	Name string
+	// Wait is the time between the last of the step's dependencies finishing
+	// (or the start of the execution, for steps without dependencies) and the
+	// step starting.
+	Wait time.Duration
+	// Run is the time between the step starting and finishing.
+	Run time.Duration
+	// Critical is set for steps on the critical path.
+	Critical bool
+}
+
+// Analysis is the timing analysis of a step graph.
+type Analysis struct {
+	// Start and Finish bound the execution of all steps.
+	Start, Finish time.Time
+	// Steps holds the timing of every step that ran, ordered by start time.
+	Steps []StepTiming
+	// CriticalPath lists the chain of steps that determined the duration of
+	// the execution, from the first to the last.
+	CriticalPath []string
+}
+
+// Duration is the duration of the whole execution.
+func (a *Analysis) Duration() time.Duration {
+	return a.Finish.Sub(a.Start)
+}
+
+// Analyze computes per-step wait and run times and the critical path of the
+// graph. Steps that did not start or finish are ignored.
+func Analyze(graph api.CIOperatorStepGraph) *Analysis {
+	byName := map[string]api.CIOperatorStepDetails{}
+	var ran []api.CIOperatorStepDetails
+	for _, step := range graph {
+		if step.StartedAt == nil || step.FinishedAt == nil {
+			continue
+		}
+		byName[step.StepName] = step
+		ran = append(ran, step)
+	}
+	analysis := &Analysis{}
+	if len(ran) == 0 {
+		return analysis
+	}
+	sort.SliceStable(ran, func(i, j int) bool {
+		return ran[i].StartedAt.Before(*ran[j].StartedAt)
+	})
+	analysis.Start = *ran[0].StartedAt
+	var last api.CIOperatorStepDetails
+	for _, step := range ran {
+		if step.FinishedAt.After(analysis.Finish) {
+			analysis.Finish = *step.FinishedAt
+			last = step
+		}
+	}
+
+	critical := map[string]bool{}
+	for step, ok := last, true; ok; step, ok = latestDependency(step, byName) {
+		critical[step.StepName] = true
+		analysis.CriticalPath = append([]string{step.StepName}, analysis.CriticalPath...)
+	}
+
+	for _, step := range ran {
+		ready := analysis.Start
+		if dependency, ok := latestDependency(step, byName); ok {
+			ready = *dependency.FinishedAt
+		}
+		wait := step.StartedAt.Sub(ready)
+		if wait < 0 {
+			wait = 0
+		}
+		analysis.Steps = append(analysis.Steps, StepTiming{
+			Name:     step.StepName,
+			Wait:     wait,
+			Run:      step.FinishedAt.Sub(*step.StartedAt),
+			Critical: critical[step.StepName],
+		})
+	}
+	return analysis
+}
+
+// latestDependency returns the dependency of the step that finished last.
+func latestDependency(step api.CIOperatorStepDetails, byName map[string]api.CIOperatorStepDetails) (api.CIOperatorStepDetails, bool) {
+	var latest api.CIOperatorStepDetails
+	var found bool
+	for _, name := range step.Dependencies {
+		dependency, ok := byName[name]
+		if !ok {
+			continue
+		}
+		if !found || dependency.FinishedAt.After(*latest.FinishedAt) {
+			latest, found = dependency, true
+		}
+	}
+	return latest, found
+}
+
+// Print writes a human-readable report of the analysis.
+func (a *Analysis) Print(out io.Writer) {
+	_, _ = fmt.Fprintf(out, "Execution took %s, critical path: %v\n", a.Duration().Truncate(time.Second), a.CriticalPath)
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"step", "wait", "run", "critical"})
+	for _, step := range a.Steps {
+		critical := ""
+		if step.Critical {
+			critical = "*"
+		}
+		table.Append([]string{step.Name, step.Wait.Truncate(time.Second).String(), step.Run.Truncate(time.Second).String(), critical})
+	}
+	table.Render()
+}
+
+// traceEvent is a complete event in the Chrome trace event format, which
+// Perfetto and chrome://tracing can load.
+type traceEvent struct {
+	Name      string            `json:"name"`
+	Category  string            `json:"cat"`
+	Phase     string            `json:"ph"`
+	Timestamp int64             `json:"ts"`
+	Duration  int64             `json:"dur"`
+	PID       int               `json:"pid"`
+	TID       int               `json:"tid"`
+	Args      map[string]string `json:"args,omitempty"`
+}
+
+type trace struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// Trace serializes the graph as trace events. Every step is placed on its own
+// track, with its sub-steps nested under it.
+func Trace(graph api.CIOperatorStepGraph, analysis *Analysis) ([]byte, error) {
+	timings := map[string]StepTiming{}
+	for _, step := range analysis.Steps {
+		timings[step.Name] = step
+	}
+	t := trace{TraceEvents: []traceEvent{}, DisplayTimeUnit: "ms"}
+	for i, step := range graph {
+		timing, ok := timings[step.StepName]
+		if !ok {
+			continue
+		}
+		category := "step"
+		if timing.Critical {
+			category = "step,critical"
+		}
+		t.TraceEvents = append(t.TraceEvents, event(step.CIOperatorStepDetailInfo, category, i, map[string]string{
+			"description": step.Description,
+			"wait":        timing.Wait.String(),
+			"critical":    fmt.Sprintf("%t", timing.Critical),
+		}))
+		for _, sub := range step.Substeps {
+			if sub.StartedAt == nil || sub.FinishedAt == nil {
+				continue
+			}
+			t.TraceEvents = append(t.TraceEvents, event(sub, "substep", i, map[string]string{"description": sub.Description}))
+		}
+	}
+	return json.MarshalIndent(t, "", "  ")
+}
+
+func event(info api.CIOperatorStepDetailInfo, category string, tid int, args map[string]string) traceEvent {
+	if info.Failed != nil && *info.Failed {
+		args["failed"] = "true"
+	}
+	return traceEvent{
+		Name:      info.StepName,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: info.StartedAt.UnixMicro(),
+		Duration:  info.FinishedAt.Sub(*info.StartedAt).Microseconds(),
+		PID:       1,
+		TID:       tid,
+		Args:      args,
+	}
+}
@@ -0,0 +1,107 @@
+package stepgraphanalyzer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestAnalyze(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		ret := base.Add(time.Duration(minutes) * time.Minute)
+		return &ret
+	}
+	step := func(name string, start, finish int, deps ...string) api.CIOperatorStepDetails {
+		return api.CIOperatorStepDetails{CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{
+			StepName:     name,
+			Dependencies: deps,
+			StartedAt:    at(start),
+			FinishedAt:   at(finish),
+		}}
+	}
+	for _, tc := range []struct {
+		name     string
+		graph    api.CIOperatorStepGraph
+		expected *Analysis
+	}{{
+		name:     "empty graph",
+		expected: &Analysis{},
+	}, {
+		name: "critical path follows the dependency that finished last",
+		graph: api.CIOperatorStepGraph{
+			step("src", 0, 5),
+			step("bin", 5, 20, "src"),
+			step("test-bin", 6, 10, "src"),
+			step("images", 22, 40, "bin", "test-bin"),
+			step("unit", 11, 15, "test-bin"),
+			{CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{StepName: "never-ran", Dependencies: []string{"images"}}},
+		},
+		expected: &Analysis{
+			Start:  *at(0),
+			Finish: *at(40),
+			Steps: []StepTiming{
+				{Name: "src", Run: 5 * time.Minute, Critical: true},
+				{Name: "bin", Run: 15 * time.Minute, Critical: true},
+				{Name: "test-bin", Wait: time.Minute, Run: 4 * time.Minute},
+				{Name: "unit", Wait: time.Minute, Run: 4 * time.Minute},
+				{Name: "images", Wait: 2 * time.Minute, Run: 18 * time.Minute, Critical: true},
+			},
+			CriticalPath: []string{"src", "bin", "images"},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, Analyze(tc.graph)); diff != "" {
+				t.Errorf("unexpected analysis: %s", diff)
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	finish := start.Add(time.Second)
+	yes := true
+	graph := api.CIOperatorStepGraph{{
+		CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{StepName: "e2e", StartedAt: &start, FinishedAt: &finish, Failed: &yes},
+		Substeps: []api.CIOperatorStepDetailInfo{
+			{StepName: "e2e-test", StartedAt: &start, FinishedAt: &finish},
+			{StepName: "e2e-skipped"},
+		},
+	}}
+	raw, err := Trace(graph, Analyze(graph))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actual trace
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		t.Fatalf("failed to unmarshal trace: %v", err)
+	}
+	expected := trace{
+		DisplayTimeUnit: "ms",
+		TraceEvents: []traceEvent{{
+			Name:      "e2e",
+			Category:  "step,critical",
+			Phase:     "X",
+			Timestamp: start.UnixMicro(),
+			Duration:  1000000,
+			PID:       1,
+			Args:      map[string]string{"description": "", "wait": "0s", "critical": "true", "failed": "true"},
+		}, {
+			Name:      "e2e-test",
+			Category:  "substep",
+			Phase:     "X",
+			Timestamp: start.UnixMicro(),
+			Duration:  1000000,
+			PID:       1,
+			Args:      map[string]string{"description": ""},
+		}},
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected trace: %s", diff)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/util"
 )
 
@@ -164,7 +165,16 @@ func waitForContainer(podClient kubernetes.PodClient, ns, name, containerName st
 	return kubernetes.WaitForConditionOnObject(ctx, podClient, ctrlruntimeclient.ObjectKey{Namespace: ns, Name: name}, &corev1.PodList{}, &corev1.Pod{}, evaluatorFunc, 300*5*time.Second)
 }
 
-func copyArtifacts(podClient kubernetes.PodClient, into, ns, name, containerName string, paths []string) error {
+// copyCensored copies src into dst, censoring secrets if a censor is set.
+func copyCensored(censor *secrets.DynamicCensor, name string, dst io.Writer, src io.Reader) error {
+	if censor == nil {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	return censor.CensorStream(name, dst, src)
+}
+
+func copyArtifacts(podClient kubernetes.PodClient, censor *secrets.DynamicCensor, into, ns, name, containerName string, paths []string) error {
 	logrus.Tracef("Copying artifacts from %s into %s", name, into)
 	var args []string
 	for _, s := range paths {
@@ -230,7 +240,7 @@ func copyArtifacts(podClient kubernetes.PodClient, into, ns, name, containerName
 		if err != nil {
 			return fmt.Errorf("could not create target file %s for artifact: %w", p, err)
 		}
-		if _, err := io.Copy(f, tr); err != nil {
+		if err := copyCensored(censor, p, f, tr); err != nil {
 			f.Close()
 			return fmt.Errorf("could not copy contents of file %s: %w", p, err)
 		}
@@ -378,6 +388,7 @@ type ArtifactWorker struct {
 	dir       string
 	podClient kubernetes.PodClient
 	namespace string
+	censor    *secrets.DynamicCensor
 
 	// Processing this requires the lock, so it must not be held
 	// when writing into it.
@@ -389,12 +400,15 @@ type ArtifactWorker struct {
 	hasArtifacts sets.Set[string]
 }
 
-func NewArtifactWorker(podClient kubernetes.PodClient, artifactDir, namespace string) *ArtifactWorker {
+// NewArtifactWorker creates a worker gathering artifacts into artifactDir. If
+// censor is set, secrets are censored from the artifacts and logs.
+func NewArtifactWorker(podClient kubernetes.PodClient, censor *secrets.DynamicCensor, artifactDir, namespace string) *ArtifactWorker {
 	// stream artifacts in the background
 	w := &ArtifactWorker{
 		podClient: podClient,
 		namespace: namespace,
 		dir:       artifactDir,
+		censor:    censor,
 
 		remaining:    make(podWaitRecord),
 		required:     make(podContainersMap),
@@ -431,7 +445,7 @@ func (w *ArtifactWorker) downloadArtifacts(podName string, hasArtifacts bool) er
 		return fmt.Errorf("unable to create artifact directory %s: %w", w.dir, err)
 	}
 	logger.Trace("Downloading container logs for Pod.")
-	if err := gatherContainerLogsOutput(w.podClient, filepath.Join(w.dir, "container-logs"), w.namespace, podName); err != nil {
+	if err := gatherContainerLogsOutput(w.podClient, w.censor, filepath.Join(w.dir, "container-logs"), w.namespace, podName); err != nil {
 		logrus.WithError(err).Warn("Unable to gather container logs.")
 	}
 
@@ -457,7 +471,7 @@ func (w *ArtifactWorker) downloadArtifacts(podName string, hasArtifacts bool) er
 	}
 
 	logger.Trace("Copying artifacts from Pod.")
-	if err := copyArtifacts(w.podClient, w.dir, w.namespace, podName, "artifacts", []string{"/tmp/artifacts"}); err != nil {
+	if err := copyArtifacts(w.podClient, w.censor, w.dir, w.namespace, podName, "artifacts", []string{"/tmp/artifacts"}); err != nil {
 		return fmt.Errorf("unable to retrieve artifacts from pod %s: %w", podName, err)
 	}
 	return nil
@@ -624,7 +638,7 @@ func hasMountsArtifactsVolume(pod *coreapi.Pod) bool {
 	return false
 }
 
-func gatherContainerLogsOutput(podClient kubernetes.PodClient, artifactDir, namespace, podName string) error {
+func gatherContainerLogsOutput(podClient kubernetes.PodClient, censor *secrets.DynamicCensor, artifactDir, namespace, podName string) error {
 	logger := logrus.WithFields(logrus.Fields{"pod": podName, "namespace": namespace, "artifactDir": artifactDir})
 	logger.Trace("Gathering container logs.")
 	var validationErrors []error
@@ -652,7 +666,8 @@ func gatherContainerLogsOutput(podClient kubernetes.PodClient, artifactDir, name
 		logger.Trace("Processing container.")
 		if status.State.Terminated != nil {
 			logger.Trace("Container is terminated.")
-			file, err := os.Create(fmt.Sprintf("%s/%s.log.gz", artifactDir, status.Name))
+			logPath := fmt.Sprintf("%s/%s.log.gz", artifactDir, status.Name)
+			file, err := os.Create(logPath)
 			if err != nil {
 				validationErrors = append(validationErrors, fmt.Errorf("cannot create file: %w", err))
 				continue
@@ -662,7 +677,7 @@ func gatherContainerLogsOutput(podClient kubernetes.PodClient, artifactDir, name
 			w := gzip.NewWriter(file)
 			logger.Trace("Fetching container logs.")
 			if s, err := podClient.GetLogs(namespace, podName, &coreapi.PodLogOptions{Container: status.Name}).Stream(context.TODO()); err == nil {
-				if _, err := io.Copy(w, s); err != nil {
+				if err := copyCensored(censor, logPath, w, s); err != nil {
 					validationErrors = append(validationErrors, fmt.Errorf("error: Unable to copy log output from pod container %s: %w", status.Name, err))
 				}
 				s.Close()
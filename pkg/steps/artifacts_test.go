@@ -523,7 +523,7 @@ func TestArtifactWorker(t *testing.T) {
 		Namespace: "namespace",
 		Name:      pod,
 	}
-	w := NewArtifactWorker(podClient, tmp, "namespace")
+	w := NewArtifactWorker(podClient, nil, tmp, "namespace")
 	w.CollectFromPod(pod, []string{"container"}, nil)
 	w.Complete(pod)
 	select {
@@ -9,6 +9,8 @@ import (
 	buildapi "github.com/openshift/api/build/v1"
 	"github.com/openshift/client-go/build/clientset/versioned/scheme"
 
+	"github.com/openshift/ci-tools/pkg/buildcache"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
 )
 
@@ -18,6 +20,10 @@ type BuildClient interface {
 	NodeArchitectures() []string
 	ManifestToolDockerCfg() string
 	LocalRegistryDNS() string
+	// BuildCache returns the cache shared between namespaces, if builds should use one.
+	BuildCache() *buildcache.Cache
+	// Builder returns the backend that runs builds.
+	Builder(podClient kubernetes.PodClient) ImageBuilder
 }
 
 type buildClient struct {
@@ -26,15 +32,19 @@ type buildClient struct {
 	nodeArchitectures     []string
 	manifestToolDockerCfg string
 	localRegistryDNS      string
+	buildCache            *buildcache.Cache
+	buildah               *BuildahConfig
 }
 
-func NewBuildClient(client loggingclient.LoggingClient, restClient rest.Interface, nodeArchitectures []string, manifestToolDockerCfg, localRegistryDNS string) BuildClient {
+func NewBuildClient(client loggingclient.LoggingClient, restClient rest.Interface, nodeArchitectures []string, manifestToolDockerCfg, localRegistryDNS string, buildCache *buildcache.Cache, buildah *BuildahConfig) BuildClient {
 	return &buildClient{
 		LoggingClient:         client,
 		client:                restClient,
 		nodeArchitectures:     nodeArchitectures,
 		manifestToolDockerCfg: manifestToolDockerCfg,
 		localRegistryDNS:      localRegistryDNS,
+		buildCache:            buildCache,
+		buildah:               buildah,
 	}
 }
 
@@ -59,3 +69,16 @@ func (c *buildClient) ManifestToolDockerCfg() string {
 func (c *buildClient) LocalRegistryDNS() string {
 	return c.localRegistryDNS
 }
+
+func (c *buildClient) BuildCache() *buildcache.Cache {
+	return c.buildCache
+}
+
+// Builder runs builds in pods with buildah when configured to, and as OpenShift
+// Builds otherwise.
+func (c *buildClient) Builder(podClient kubernetes.PodClient) ImageBuilder {
+	if c.buildah != nil {
+		return &buildahBuilder{config: *c.buildah, podClient: podClient}
+	}
+	return &openShiftBuilder{client: c, podClient: podClient}
+}
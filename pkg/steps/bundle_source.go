@@ -128,6 +128,8 @@ func (s *bundleSourceStep) Objects() []ctrlruntimeclient.Object {
 
 func (s *bundleSourceStep) Name() string { return s.config.TargetName() }
 
+func (*bundleSourceStep) Resumable() {}
+
 func (s *bundleSourceStep) Description() string {
 	return fmt.Sprintf("Build image %s from the repository", api.PipelineImageStreamTagReferenceBundleSource)
 }
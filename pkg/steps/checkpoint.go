@@ -0,0 +1,243 @@
+package steps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// CheckpointConfigMapName is the prefix of the names of the ConfigMaps in
+	// the test namespace that record which steps of the graph have completed.
+	// Each execution has its own ConfigMap, see CheckpointExecution.
+	CheckpointConfigMapName = "ci-operator-checkpoint"
+	// CheckpointExecutionAnnotation records the execution a checkpoint
+	// ConfigMap belongs to.
+	CheckpointExecutionAnnotation = "ci.openshift.io/checkpoint-execution"
+)
+
+// Checkpoint persists the progress of a graph execution so that an
+// interrupted execution can be resumed without re-running steps that
+// already completed.
+type Checkpoint interface {
+	// Completed returns the names of steps that completed in a previous
+	// execution.
+	Completed(ctx context.Context) (sets.Set[string], error)
+	// Record marks the step as completed, along with the links it created.
+	Record(ctx context.Context, step api.Step) error
+	// Created determines whether the objects the links describe exist, so
+	// that a step recorded as completed can be skipped.
+	Created(ctx context.Context, links []api.StepLink) (bool, error)
+}
+
+// ResumableStep is implemented by steps that can be skipped when resuming an
+// execution. Their dependents must only read what the step created in the
+// cluster, never state the step keeps in memory while it runs, as a skipped
+// step is never run. Image builds qualify, as later steps resolve the images
+// from the pipeline ImageStream.
+type ResumableStep interface {
+	api.Step
+	// Resumable is a marker, it has no behavior.
+	Resumable()
+}
+
+// CheckpointExecution identifies the execution of a job that a checkpoint is
+// recorded for. A job that is interrupted and restarted keeps its build and
+// ProwJob IDs, while a retest or any other job sharing the namespace does not,
+// so only the same execution resumes from a checkpoint.
+func CheckpointExecution(spec *api.JobSpec) string {
+	return fmt.Sprintf("%s/%s/%s", spec.Job, spec.BuildID, spec.ProwJobID)
+}
+
+// NewConfigMapCheckpoint returns a Checkpoint for the execution backed by a
+// ConfigMap in the namespace. Each completed step has a key in the ConfigMap,
+// with a human-readable record of its name, when it finished and what it
+// created as the value. Step names are not valid ConfigMap keys, see
+// checkpointKey. Step links themselves are not serialized: on resume, the
+// graph is rebuilt from the same configuration, so the links are recovered
+// from the steps.
+func NewConfigMapCheckpoint(client ctrlruntimeclient.Client, namespace, execution string) Checkpoint {
+	return &configMapCheckpoint{client: client, namespace: namespace, execution: execution, now: time.Now}
+}
+
+type configMapCheckpoint struct {
+	client    ctrlruntimeclient.Client
+	namespace string
+	execution string
+	now       func() time.Time
+}
+
+// name returns the name of the ConfigMap for the execution, which is not a
+// valid name itself, so it is hashed.
+func (c *configMapCheckpoint) name() string {
+	hash := sha256.Sum256([]byte(c.execution))
+	return CheckpointConfigMapName + "-" + hex.EncodeToString(hash[:])[:12]
+}
+
+func (c *configMapCheckpoint) Completed(ctx context.Context) (sets.Set[string], error) {
+	cm := &coreapi.ConfigMap{}
+	if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: c.namespace, Name: c.name()}, cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return sets.New[string](), nil
+		}
+		return nil, fmt.Errorf("could not get checkpoint: %w", err)
+	}
+	if execution := cm.Annotations[CheckpointExecutionAnnotation]; execution != c.execution {
+		logrus.Warnf("Ignoring checkpoint %s recorded for execution %q instead of %q.", cm.Name, execution, c.execution)
+		return sets.New[string](), nil
+	}
+	completed := sets.New[string]()
+	for key, value := range cm.Data {
+		name, found := checkpointStepName(value)
+		if !found {
+			logrus.Warnf("Ignoring malformed checkpoint record %s: %q", key, value)
+			continue
+		}
+		completed.Insert(name)
+	}
+	return completed, nil
+}
+
+func (c *configMapCheckpoint) Record(ctx context.Context, step api.Step) error {
+	key, value := checkpointKey(step.Name()), describeCheckpoint(step.Name(), c.now(), step.Creates())
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		cm := &coreapi.ConfigMap{}
+		err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: c.namespace, Name: c.name()}, cm)
+		if kerrors.IsNotFound(err) {
+			cm = &coreapi.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{
+					Namespace:   c.namespace,
+					Name:        c.name(),
+					Annotations: map[string]string{CheckpointExecutionAnnotation: c.execution},
+				},
+				Data: map[string]string{key: value},
+			}
+			return c.client.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = value
+		return c.client.Update(ctx, cm)
+	})
+}
+
+func (c *configMapCheckpoint) Created(ctx context.Context, links []api.StepLink) (bool, error) {
+	for _, link := range links {
+		stream, tag, found := api.ImageStreamFor(link)
+		if !found {
+			return false, nil
+		}
+		var obj ctrlruntimeclient.Object = &imagev1.ImageStream{}
+		name := stream
+		if tag != "" {
+			obj, name = &imagev1.ImageStreamTag{}, fmt.Sprintf("%s:%s", stream, tag)
+		}
+		if err := c.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: c.namespace, Name: name}, obj); err != nil {
+			if kerrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("could not get %s: %w", name, err)
+		}
+	}
+	return true, nil
+}
+
+// checkpointKey returns the ConfigMap key for a step. Step names like
+// [images] or [output:stable:cli] contain characters that are not allowed
+// in keys, so those are replaced and a hash of the name keeps keys unique.
+func checkpointKey(name string) string {
+	sanitized := strings.Trim(invalidCheckpointKeyChars.ReplaceAllString(name, "-"), "-.")
+	hash := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(hash[:])[:12]
+	if max := validation.DNS1123SubdomainMaxLength - len(suffix) - 1; len(sanitized) > max {
+		sanitized = sanitized[:max]
+	}
+	if sanitized == "" {
+		return suffix
+	}
+	return sanitized + "-" + suffix
+}
+
+var invalidCheckpointKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+
+const checkpointStepPrefix = "step="
+
+func describeCheckpoint(name string, finished time.Time, links []api.StepLink) string {
+	var created []string
+	for _, link := range links {
+		created = append(created, strings.TrimPrefix(fmt.Sprintf("%+v", link), "&"))
+	}
+	return fmt.Sprintf("%s%s finished=%s creates=%s", checkpointStepPrefix, name, finished.UTC().Format(time.RFC3339), strings.Join(created, ","))
+}
+
+// checkpointStepName returns the name of the step a checkpoint record is for
+func checkpointStepName(value string) (string, bool) {
+	if !strings.HasPrefix(value, checkpointStepPrefix) {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(value, checkpointStepPrefix), " ")
+	return name, name != ""
+}
+
+// resumable determines whether a step is recorded in and skipped from a
+// checkpoint: only steps that create links and opt in with ResumableStep, like
+// image builds, have results that later steps can reuse. Tests are always
+// executed again.
+func resumable(step api.Step) bool {
+	_, ok := step.(ResumableStep)
+	return ok && len(step.Creates()) > 0
+}
+
+// resume determines whether a step recorded in the checkpoint can be skipped,
+// which is only the case when everything it created still exists. Errors are
+// not fatal: at worst, the step is executed again.
+func resume(ctx context.Context, checkpoint Checkpoint, step api.Step) bool {
+	created, err := checkpoint.Created(ctx, step.Creates())
+	if err != nil {
+		logrus.WithError(err).Warnf("Could not determine whether the results of step %s exist, it will be executed.", step.Name())
+		return false
+	}
+	if !created {
+		logrus.Infof("Results of step %s completed previously no longer exist, it will be executed.", step.Name())
+	}
+	return created
+}
+
+// loadCompleted returns the steps a checkpoint reports as completed. Errors
+// are not fatal: at worst, steps are executed again.
+func loadCompleted(ctx context.Context, checkpoint Checkpoint) sets.Set[string] {
+	if checkpoint == nil {
+		return sets.New[string]()
+	}
+	completed, err := checkpoint.Completed(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Could not load the checkpoint, all steps will be executed.")
+		return sets.New[string]()
+	}
+	if completed.Len() > 0 {
+		logrus.Infof("Resuming execution, skipping steps completed previously: %s", strings.Join(sets.List(completed), ", "))
+	}
+	return completed
+}
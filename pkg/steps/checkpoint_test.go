@@ -0,0 +1,265 @@
+package steps
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestConfigMapCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	client := fakectrlruntimeclient.NewClientBuilder().Build()
+	checkpoint := &configMapCheckpoint{
+		client:    client,
+		namespace: "ns",
+		execution: "job/1/id",
+		now:       func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	completed, err := checkpoint.Completed(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error loading an absent checkpoint: %v", err)
+	}
+	if completed.Len() != 0 {
+		t.Fatalf("expected no completed steps, got %v", sets.List(completed))
+	}
+
+	for _, step := range []*fakeStep{
+		{name: "src", creates: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceSource)}},
+		{name: "bin"},
+	} {
+		if err := checkpoint.Record(ctx, step); err != nil {
+			t.Fatalf("unexpected error recording %s: %v", step.name, err)
+		}
+	}
+
+	completed, err = checkpoint.Completed(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error loading the checkpoint: %v", err)
+	}
+	if diff := cmp.Diff([]string{"bin", "src"}, sets.List(completed)); diff != "" {
+		t.Errorf("unexpected completed steps: %s", diff)
+	}
+
+	cm := &coreapi.ConfigMap{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: checkpoint.name()}, cm); err != nil {
+		t.Fatalf("could not get checkpoint ConfigMap: %v", err)
+	}
+	if expected, actual := "step=bin finished=2023-01-01T00:00:00Z creates=", cm.Data[checkpointKey("bin")]; actual != expected {
+		t.Errorf("expected record %q, got %q", expected, actual)
+	}
+	if expected, actual := "job/1/id", cm.Annotations[CheckpointExecutionAnnotation]; actual != expected {
+		t.Errorf("expected the checkpoint to be annotated with execution %q, got %q", expected, actual)
+	}
+}
+
+func TestCheckpointExecution(t *testing.T) {
+	ctx := context.Background()
+	record := func(name string, execution string) *coreapi.ConfigMap {
+		return &coreapi.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace:   "ns",
+				Name:        name,
+				Annotations: map[string]string{CheckpointExecutionAnnotation: execution},
+			},
+			Data: map[string]string{checkpointKey("src"): "step=src finished=2023-01-01T00:00:00Z creates="},
+		}
+	}
+	spec := func(job, buildID, prowJobID string) *api.JobSpec {
+		ret := &api.JobSpec{}
+		ret.Job, ret.BuildID, ret.ProwJobID = job, buildID, prowJobID
+		return ret
+	}
+	execution := CheckpointExecution(spec("job", "1", "id"))
+	name := (&configMapCheckpoint{execution: execution}).name()
+	for _, tc := range []struct {
+		name      string
+		objects   []ctrlruntimeclient.Object
+		execution string
+		expected  []string
+	}{{
+		name:      "checkpoint of the same execution is used",
+		objects:   []ctrlruntimeclient.Object{record(name, execution)},
+		execution: execution,
+		expected:  []string{"src"},
+	}, {
+		name:      "checkpoint of another job is ignored",
+		objects:   []ctrlruntimeclient.Object{record(name, execution)},
+		execution: CheckpointExecution(spec("other", "1", "id")),
+	}, {
+		name:      "checkpoint of another build of the job is ignored",
+		objects:   []ctrlruntimeclient.Object{record(name, execution)},
+		execution: CheckpointExecution(spec("job", "2", "retest")),
+	}, {
+		name:      "checkpoint annotated with another execution is ignored",
+		objects:   []ctrlruntimeclient.Object{record(name, CheckpointExecution(spec("job", "2", "retest")))},
+		execution: execution,
+	}, {
+		name:      "checkpoint written before executions were recorded is ignored",
+		objects:   []ctrlruntimeclient.Object{record(CheckpointConfigMapName, "")},
+		execution: execution,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.objects...).Build()
+			completed, err := NewConfigMapCheckpoint(client, "ns", tc.execution).Completed(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error loading the checkpoint: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, sets.List(completed), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("unexpected completed steps: %s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckpointKey(t *testing.T) {
+	names := []string{
+		"[input:root]",
+		"[images]",
+		"[release:latest]",
+		"[output:stable:cli]",
+		"[output-images]",
+		"parameters/write",
+		"src",
+		"..",
+		strings.Repeat("[long-step-name]", 20),
+	}
+	keys := sets.New[string]()
+	for _, name := range names {
+		key := checkpointKey(name)
+		if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+			t.Errorf("key %q for step %q is invalid: %v", key, name, errs)
+		}
+		keys.Insert(key)
+	}
+	if keys.Len() != len(names) {
+		t.Errorf("expected %d unique keys, got %v", len(names), sets.List(keys))
+	}
+
+	ctx := context.Background()
+	checkpoint := &configMapCheckpoint{client: fakectrlruntimeclient.NewClientBuilder().Build(), namespace: "ns", execution: "job/1/id", now: time.Now}
+	for _, name := range names {
+		if err := checkpoint.Record(ctx, &fakeStep{name: name}); err != nil {
+			t.Fatalf("unexpected error recording %s: %v", name, err)
+		}
+	}
+	completed, err := checkpoint.Completed(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error loading the checkpoint: %v", err)
+	}
+	if diff := cmp.Diff(sets.List(sets.New(names...)), sets.List(completed)); diff != "" {
+		t.Errorf("unexpected completed steps: %s", diff)
+	}
+}
+
+// resumableFakeStep opts a fakeStep in to being skipped on resume
+type resumableFakeStep struct {
+	*fakeStep
+}
+
+func (resumableFakeStep) Resumable() {}
+
+func TestRunWithCheckpoint(t *testing.T) {
+	root := &fakeStep{
+		name:    "root",
+		creates: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceRoot)},
+	}
+	src := &fakeStep{
+		name:     "src",
+		requires: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceRoot)},
+		creates:  []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceSource)},
+	}
+	bin := &fakeStep{
+		name:     "bin",
+		requires: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceSource)},
+		creates:  []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceBinaries)},
+	}
+	release := &fakeStep{
+		name:     "release",
+		requires: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceSource)},
+		creates:  []api.StepLink{api.ReleasePayloadImageLink(api.LatestReleaseName)},
+	}
+	test := &fakeStep{
+		name:     "test",
+		requires: []api.StepLink{api.InternalImageLink(api.PipelineImageStreamTagReferenceBinaries)},
+	}
+	graph := func() api.StepGraph {
+		return api.BuildGraph([]api.Step{resumableFakeStep{root}, resumableFakeStep{src}, resumableFakeStep{bin}, release, test})
+	}
+	record := func(name string) string {
+		return "step=" + name + " finished=2023-01-01T00:00:00Z creates="
+	}
+	ctx := context.Background()
+	execution := "job/1/id"
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace:   "ns",
+				Name:        (&configMapCheckpoint{execution: execution}).name(),
+				Annotations: map[string]string{CheckpointExecutionAnnotation: execution},
+			},
+			Data: map[string]string{
+				checkpointKey("root"):    record("root"),
+				checkpointKey("src"):     record("src"),
+				checkpointKey("bin"):     record("bin"),
+				checkpointKey("release"): record("release"),
+				checkpointKey("test"):    record("test"),
+			},
+		},
+		&imagev1.ImageStreamTag{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "pipeline:root"}},
+		&imagev1.ImageStreamTag{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "pipeline:src"}},
+	).Build()
+	checkpoint := NewConfigMapCheckpoint(client, "ns", execution)
+
+	suites, _, errs := RunWithCheckpoint(ctx, graph(), checkpoint)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	// bin was recorded but its image no longer exists, while release and
+	// test do not opt in to being resumed, so they are executed
+	for step, expected := range map[*fakeStep]int{root: 0, src: 0, bin: 1, release: 1, test: 1} {
+		if step.numRuns != expected {
+			t.Errorf("expected step %s to run %d times, ran %d times", step.name, expected, step.numRuns)
+		}
+	}
+	if suite := suites.Suites[0]; suite.NumTests != 5 || suite.NumSkipped != 2 {
+		t.Errorf("expected five tests with two skipped, got %d with %d skipped", suite.NumTests, suite.NumSkipped)
+	}
+
+	// another build of the job does not resume from the checkpoint
+	for _, step := range []*fakeStep{root, src, bin, release, test} {
+		step.numRuns = 0
+	}
+	other := NewConfigMapCheckpoint(client, "ns", "job/2/other")
+	if _, _, errs := RunWithCheckpoint(ctx, graph(), other); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for _, step := range []*fakeStep{root, src, bin, release, test} {
+		if step.numRuns != 1 {
+			t.Errorf("expected step %s to run once for another build, ran %d times", step.name, step.numRuns)
+		}
+	}
+	// only resumable steps are recorded
+	completed, err := other.Completed(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error loading the checkpoint: %v", err)
+	}
+	if diff := cmp.Diff([]string{"bin", "root", "src"}, sets.List(completed)); diff != "" {
+		t.Errorf("unexpected completed steps: %s", diff)
+	}
+}
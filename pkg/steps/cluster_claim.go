@@ -62,6 +62,10 @@ func (s *clusterClaimStep) Creates() []api.StepLink             { return s.wrapp
 func (s *clusterClaimStep) Objects() []ctrlruntimeclient.Object { return s.wrapped.Objects() }
 func (s *clusterClaimStep) Provides() api.ParameterMap          { return s.wrapped.Provides() }
 
+func (s *clusterClaimStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	return planWrapped(s.wrapped)
+}
+
 func (s *clusterClaimStep) Run(ctx context.Context) error {
 	return results.ForReason("utilizing_cluster_claim").ForError(s.run(ctx))
 }
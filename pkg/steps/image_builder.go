@@ -0,0 +1,302 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilpointer "k8s.io/utils/pointer"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+)
+
+// ImageBuilder runs a single-architecture image build to completion. Builds
+// are described as OpenShift Builds, but implementations are free to run them
+// by other means as long as the output image ends up in the registry.
+type ImageBuilder interface {
+	Build(ctx context.Context, build buildapi.Build) error
+}
+
+// openShiftBuilder runs builds with the OpenShift build subsystem.
+type openShiftBuilder struct {
+	client    BuildClient
+	podClient kubernetes.PodClient
+}
+
+func (b *openShiftBuilder) Build(ctx context.Context, build buildapi.Build) error {
+	return handleBuild(ctx, b.client, b.podClient, build)
+}
+
+// BuildahConfig configures builds that run in pods with buildah instead of
+// as OpenShift Builds, so that they can run on clusters without the OpenShift
+// build subsystem. This does not make ci-operator run on plain Kubernetes:
+// inputs and outputs of builds are still image stream tags, which the other
+// steps resolve with the OpenShift image API, so the builds push to the
+// registry that serves the image streams and it has to be reachable from the
+// build pods.
+type BuildahConfig struct {
+	// Image is the buildah image the build pods run.
+	Image string
+	// Registry is the registry that serves the image streams: ImageStreamTag
+	// references are resolved to <registry>/<namespace>/<stream>:<tag> and
+	// outputs are pushed there, so that they show up in the image streams.
+	Registry string
+	// AuthSecret is the name of a secret of type kubernetes.io/dockerconfigjson
+	// in the build namespace holding credentials for pulling and pushing images.
+	// When unset, the build's pull secret is used, if any.
+	AuthSecret string
+	// TLSVerify determines whether buildah verifies the certificates of registries.
+	TLSVerify bool
+	// Privileged runs the build pods privileged. Otherwise, they run buildah
+	// as root with chroot isolation and the SETFCAP capability, which the
+	// baseline Pod Security Standard allows; on OpenShift, the anyuid SCC
+	// needs to be granted to the service account of the build pods.
+	Privileged bool
+}
+
+const (
+	// DefaultBuildahImage is the image that runs builds when no other is configured.
+	DefaultBuildahImage = "quay.io/buildah/stable:latest"
+
+	buildahWorkspace = "/workspace"
+	buildahAuthDir   = "/auth"
+	buildahAuthFile  = buildahAuthDir + "/" + corev1.DockerConfigJsonKey
+	buildahSecretDir = "/secrets"
+	dockerfileEnv    = "DOCKERFILE"
+)
+
+// buildahBuilder runs builds in pods with buildah.
+type buildahBuilder struct {
+	config    BuildahConfig
+	podClient kubernetes.PodClient
+}
+
+func (b *buildahBuilder) Build(ctx context.Context, build buildapi.Build) error {
+	pod, err := b.podFor(&build)
+	if err != nil {
+		return fmt.Errorf("could not create build pod for %s: %w", build.Name, err)
+	}
+	if _, err := RunPod(ctx, b.podClient, pod); err != nil {
+		return fmt.Errorf("the build %s failed: %w", build.Name, err)
+	}
+	return nil
+}
+
+func (b *buildahBuilder) podFor(build *buildapi.Build) (*corev1.Pod, error) {
+	script, err := b.script(build)
+	if err != nil {
+		return nil, err
+	}
+	env := []corev1.EnvVar{
+		{Name: "BUILDAH_ISOLATION", Value: "chroot"},
+		{Name: "STORAGE_DRIVER", Value: "vfs"},
+	}
+	if build.Spec.Source.Dockerfile != nil {
+		// passing the Dockerfile through the environment saves us from quoting it
+		env = append(env, corev1.EnvVar{Name: dockerfileEnv, Value: *build.Spec.Source.Dockerfile})
+	}
+	// buildah needs to run as root to unpack images and SETFCAP to keep the
+	// file capabilities in them, while chroot isolation spares it the mounts
+	// and namespaces only a privileged container may create
+	securityContext := &corev1.SecurityContext{
+		RunAsUser:    utilpointer.Int64(0),
+		Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"SETFCAP"}},
+	}
+	if b.config.Privileged {
+		securityContext = &corev1.SecurityContext{Privileged: utilpointer.Bool(true)}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-buildah", build.Name),
+			Namespace:       build.Namespace,
+			Labels:          build.Labels,
+			Annotations:     build.Annotations,
+			OwnerReferences: build.OwnerReferences,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			NodeSelector:  build.Spec.NodeSelector,
+			Containers: []corev1.Container{{
+				Name:            "build",
+				Image:           b.config.Image,
+				Command:         []string{"/bin/bash", "-c", script},
+				Env:             env,
+				Resources:       build.Spec.Resources,
+				SecurityContext: securityContext,
+				VolumeMounts:    []corev1.VolumeMount{{Name: "workspace", MountPath: buildahWorkspace}},
+			}},
+			Volumes: []corev1.Volume{{Name: "workspace", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+	}
+	if secret := b.authSecret(build); secret != "" {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: "auth", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret}}})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "auth", MountPath: buildahAuthDir, ReadOnly: true})
+	}
+	for i, secret := range build.Spec.Source.Secrets {
+		name := fmt.Sprintf("secret-%d", i)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret.Secret.Name}}})
+		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: name, MountPath: path.Join(buildahSecretDir, secret.Secret.Name), ReadOnly: true})
+	}
+	return pod, nil
+}
+
+func (b *buildahBuilder) authSecret(build *buildapi.Build) string {
+	if b.config.AuthSecret != "" {
+		return b.config.AuthSecret
+	}
+	if strategy := build.Spec.Strategy.DockerStrategy; strategy != nil && strategy.PullSecret != nil {
+		return strategy.PullSecret.Name
+	}
+	return ""
+}
+
+// script renders the shell script that runs the build with the semantics of
+// the OpenShift Docker build strategy: files from input images and secrets are
+// copied into the context, stages named after an input image are built from
+// that image, the last stage is built from the strategy's base image and the
+// environment is added to the image. The build runs on a node of the target
+// architecture, so buildah builds for the native platform unless a stage asks
+// for another one with FROM --platform.
+func (b *buildahBuilder) script(build *buildapi.Build) (string, error) {
+	strategy := build.Spec.Strategy.DockerStrategy
+	if strategy == nil {
+		return "", fmt.Errorf("only builds with the Docker strategy are supported")
+	}
+	source := build.Spec.Source
+	if source.Git != nil || source.Binary != nil || len(source.ConfigMaps) > 0 {
+		return "", fmt.Errorf("only builds from Dockerfiles, images and secrets are supported")
+	}
+	if build.Spec.Output.To == nil {
+		return "", fmt.Errorf("build has no output")
+	}
+	output, err := b.pullSpecFor(*build.Spec.Output.To, build.Namespace)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve output: %w", err)
+	}
+
+	var flags []string
+	if secret := b.authSecret(build); secret != "" {
+		flags = append(flags, "--authfile", buildahAuthFile)
+	}
+	flags = append(flags, fmt.Sprintf("--tls-verify=%t", b.config.TLSVerify))
+	common := strings.Join(flags, " ")
+
+	context := path.Join(buildahWorkspace, "context")
+	contextDir := path.Join(context, source.ContextDir)
+	dockerfilePath := strategy.DockerfilePath
+	if dockerfilePath == "" {
+		dockerfilePath = "Dockerfile"
+	}
+	dockerfile := path.Join(contextDir, dockerfilePath)
+
+	lines := []string{
+		"set -euo pipefail",
+		fmt.Sprintf("mkdir -p %s", shellQuote(context)),
+		fmt.Sprintf("cd %s", shellQuote(context)),
+	}
+	for _, image := range source.Images {
+		if len(image.Paths) == 0 {
+			continue
+		}
+		pullSpec, err := b.pullSpecFor(image.From, build.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve input image: %w", err)
+		}
+		lines = append(lines,
+			fmt.Sprintf("ctr=$(buildah from --pull-always %s %s)", common, shellQuote(pullSpec)),
+			`mnt=$(buildah mount "${ctr}")`,
+		)
+		for _, p := range image.Paths {
+			destination := path.Join(context, p.DestinationDir)
+			lines = append(lines,
+				fmt.Sprintf("mkdir -p %s", shellQuote(destination)),
+				fmt.Sprintf(`cp -a "${mnt}"%s %s`, shellQuote(p.SourcePath), shellQuote(destination)),
+			)
+		}
+		lines = append(lines, `buildah umount "${ctr}"`, `buildah rm "${ctr}"`)
+	}
+	for _, secret := range source.Secrets {
+		destination := path.Join(context, secret.DestinationDir)
+		// the keys of mounted secrets are links into hidden directories
+		lines = append(lines,
+			fmt.Sprintf("mkdir -p %s", shellQuote(destination)),
+			fmt.Sprintf("cp -L %s/* %s", shellQuote(path.Join(buildahSecretDir, secret.Secret.Name)), shellQuote(destination)),
+		)
+	}
+	if source.Dockerfile != nil {
+		lines = append(lines,
+			fmt.Sprintf("mkdir -p %s", shellQuote(path.Dir(dockerfile))),
+			fmt.Sprintf(`printf '%%s' "${%s}" > %s`, dockerfileEnv, shellQuote(dockerfile)),
+		)
+	}
+	rewrite := func(program string, vars ...string) string {
+		return fmt.Sprintf("awk %s %s %s > /tmp/Dockerfile && mv /tmp/Dockerfile %s", strings.Join(vars, " "), shellQuote(program), shellQuote(dockerfile), shellQuote(dockerfile))
+	}
+	if strategy.From != nil {
+		base, err := b.pullSpecFor(*strategy.From, build.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve base image: %w", err)
+		}
+		lines = append(lines,
+			fmt.Sprintf(`last=$(awk 'toupper($1)=="FROM" {n=NR} END {print n}' %s)`, shellQuote(dockerfile)),
+			rewrite(`NR==last {i=2; while ($i ~ /^--/) i++; $i=image} {print}`, `-v last="${last}"`, "-v image="+shellQuote(base)),
+		)
+	}
+	for _, image := range source.Images {
+		if len(image.As) == 0 {
+			continue
+		}
+		pullSpec, err := b.pullSpecFor(image.From, build.Namespace)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve input image: %w", err)
+		}
+		for _, as := range image.As {
+			lines = append(lines, rewrite(`toupper($1)=="FROM" {i=2; while ($i ~ /^--/) i++; if ($i==name) $i=image} {print}`, "-v name="+shellQuote(as), "-v image="+shellQuote(pullSpec)))
+		}
+	}
+
+	buildFlags := []string{common, "--pull-always", "--layers=false", "-f", shellQuote(dockerfile), "-t", shellQuote(output)}
+	for _, arg := range strategy.BuildArgs {
+		buildFlags = append(buildFlags, "--build-arg", shellQuote(fmt.Sprintf("%s=%s", arg.Name, arg.Value)))
+	}
+	for _, env := range strategy.Env {
+		buildFlags = append(buildFlags, "--env", shellQuote(fmt.Sprintf("%s=%s", env.Name, env.Value)))
+	}
+	labels := make([]string, 0, len(build.Spec.Output.ImageLabels))
+	for _, label := range build.Spec.Output.ImageLabels {
+		labels = append(labels, fmt.Sprintf("%s=%s", label.Name, label.Value))
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		buildFlags = append(buildFlags, "--label", shellQuote(label))
+	}
+	lines = append(lines,
+		fmt.Sprintf("buildah build %s %s", strings.Join(buildFlags, " "), shellQuote(contextDir)),
+		fmt.Sprintf("buildah push %s %s", common, shellQuote(output)),
+	)
+	return strings.Join(lines, "\n"), nil
+}
+
+// pullSpecFor resolves a reference from a build to a pull spec in the registry
+func (b *buildahBuilder) pullSpecFor(ref corev1.ObjectReference, namespace string) (string, error) {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	switch ref.Kind {
+	case "DockerImage":
+		return ref.Name, nil
+	case "ImageStreamTag", "ImageStreamImage":
+		return fmt.Sprintf("%s/%s/%s", b.config.Registry, namespace, ref.Name), nil
+	}
+	return "", fmt.Errorf("unsupported image reference kind %q", ref.Kind)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,98 @@
+package steps
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestBuildahBuilderPodFor(t *testing.T) {
+	dockerfile := "FROM builder AS build\nRUN make\nFROM base\nCOPY --from=build /bin/app /bin/app"
+	source := func() buildapi.BuildSource {
+		return buildapi.BuildSource{
+			Type:       buildapi.BuildSourceDockerfile,
+			Dockerfile: &dockerfile,
+			Images: []buildapi.ImageSource{{
+				From:  corev1.ObjectReference{Kind: "ImageStreamTag", Name: "pipeline:src"},
+				Paths: []buildapi.ImageSourcePath{{SourcePath: "/go/src/github.com/org/repo/.", DestinationDir: "."}},
+			}, {
+				From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "pipeline:builder"},
+				As:   []string{"builder"},
+			}},
+		}
+	}
+	jobSpec := &api.JobSpec{}
+	jobSpec.SetNamespace("test-namespace")
+	for _, tc := range []struct {
+		name    string
+		config  BuildahConfig
+		build   func() buildapi.Build
+		wantErr bool
+	}{{
+		name:   "image build with inputs, build args and a base image",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com", AuthSecret: "registry-auth", TLSVerify: true},
+		build: func() buildapi.Build {
+			build := buildFromSource(jobSpec, "root", "app", source(), "sha256:root", "", api.ResourceConfiguration{}, nil, []api.BuildArg{{Name: "VERSION", Value: "it's 1.0"}}, "")
+			return constructMultiArchBuilds(*build, []string{"arm64"})[0]
+		},
+	}, {
+		name:   "pull secret is used when no auth secret is configured",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com"},
+		build: func() buildapi.Build {
+			build := buildFromSource(jobSpec, "", "app", buildapi.BuildSource{Type: buildapi.BuildSourceDockerfile, Dockerfile: &dockerfile, ContextDir: "images/app"}, "", "", api.ResourceConfiguration{}, &corev1.Secret{}, nil, "")
+			return *build
+		},
+	}, {
+		name:   "secrets are copied into the context",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com"},
+		build: func() buildapi.Build {
+			src := source()
+			src.Secrets = []buildapi.SecretBuildSource{
+				{Secret: corev1.LocalObjectReference{Name: "entitlement"}, DestinationDir: "etc-pki-entitlement"},
+				{Secret: corev1.LocalObjectReference{Name: "ssh-key"}},
+			}
+			return *buildFromSource(jobSpec, "root", "app", src, "", "", api.ResourceConfiguration{}, nil, nil, "")
+		},
+	}, {
+		name:   "stages with FROM --platform are rewritten",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com"},
+		build: func() buildapi.Build {
+			dockerfile := "FROM --platform=linux/amd64 builder AS build\nRUN make\nFROM --platform=${TARGETPLATFORM} base\nCOPY --from=build /bin/app /bin/app"
+			src := source()
+			src.Dockerfile = &dockerfile
+			build := buildFromSource(jobSpec, "root", "app", src, "", "", api.ResourceConfiguration{}, nil, nil, "")
+			return constructMultiArchBuilds(*build, []string{"arm64"})[0]
+		},
+	}, {
+		name:   "privileged build pods",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com", Privileged: true},
+		build: func() buildapi.Build {
+			return *buildFromSource(jobSpec, "", "app", buildapi.BuildSource{Type: buildapi.BuildSourceDockerfile, Dockerfile: &dockerfile}, "", "", api.ResourceConfiguration{}, nil, nil, "")
+		},
+	}, {
+		name:   "builds from git are not supported",
+		config: BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com"},
+		build: func() buildapi.Build {
+			return *buildFromSource(jobSpec, "", "app", buildapi.BuildSource{Type: buildapi.BuildSourceGit, Git: &buildapi.GitBuildSource{URI: "https://github.com/org/repo"}}, "", "", api.ResourceConfiguration{}, nil, nil, "")
+		},
+		wantErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := &buildahBuilder{config: tc.config}
+			build := tc.build()
+			pod, err := builder.podFor(&build)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error: %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr {
+				return
+			}
+			testhelper.CompareWithFixture(t, pod)
+		})
+	}
+}
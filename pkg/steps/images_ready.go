@@ -22,6 +22,11 @@ func (s *imagesReadyStep) Run(ctx context.Context) error {
 	return nil
 }
 
+// Plan is trivial, as the step only joins the graph
+func (s *imagesReadyStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	return nil, nil
+}
+
 func (s *imagesReadyStep) Requires() []api.StepLink {
 	return s.links
 }
@@ -43,6 +43,11 @@ func (s *inputEnvironmentStep) Run(ctx context.Context) error {
 	return nil
 }
 
+// Plan is trivial, as the step stands in for one whose outputs are known
+func (s *inputEnvironmentStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	return nil, nil
+}
+
 func (s *inputEnvironmentStep) Name() string {
 	return s.name
 }
@@ -69,25 +69,7 @@ func (s *inputImageTagStep) run(ctx context.Context) error {
 		return fmt.Errorf("could not resolve inputs for image tag step: %w", err)
 	}
 
-	ist := &imagev1.ImageStreamTag{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s:%s", api.PipelineImageStream, s.config.To),
-			Namespace: s.jobSpec.Namespace(),
-		},
-		Tag: &imagev1.TagReference{
-			ReferencePolicy: imagev1.TagReferencePolicy{
-				Type: imagev1.LocalTagReferencePolicy,
-			},
-			From: &coreapi.ObjectReference{
-				Kind:      "ImageStreamImage",
-				Name:      fmt.Sprintf("%s@%s", s.config.BaseImage.Name, s.imageName),
-				Namespace: s.config.BaseImage.Namespace,
-			},
-			ImportPolicy: imagev1.TagImportPolicy{
-				ImportMode: imagev1.ImportModePreserveOriginal,
-			},
-		},
-	}
+	ist := s.imageStreamTag(s.imageName)
 
 	if err := s.client.Create(ctx, ist); err != nil && !kerrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create imagestreamtag for input image: %w", err)
@@ -113,6 +95,36 @@ func (s *inputImageTagStep) run(ctx context.Context) error {
 	return nil
 }
 
+func (s *inputImageTagStep) imageStreamTag(imageName string) *imagev1.ImageStreamTag {
+	return &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s:%s", api.PipelineImageStream, s.config.To),
+			Namespace: s.jobSpec.Namespace(),
+		},
+		Tag: &imagev1.TagReference{
+			ReferencePolicy: imagev1.TagReferencePolicy{
+				Type: imagev1.LocalTagReferencePolicy,
+			},
+			From: &coreapi.ObjectReference{
+				Kind:      "ImageStreamImage",
+				Name:      fmt.Sprintf("%s@%s", s.config.BaseImage.Name, imageName),
+				Namespace: s.config.BaseImage.Namespace,
+			},
+			ImportPolicy: imagev1.TagImportPolicy{
+				ImportMode: imagev1.ImportModePreserveOriginal,
+			},
+		},
+	}
+}
+
+func (s *inputImageTagStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	imageName := s.imageName
+	if imageName == "" {
+		imageName = PlanPlaceholder
+	}
+	return []ctrlruntimeclient.Object{s.imageStreamTag(imageName)}, nil
+}
+
 func (s *inputImageTagStep) Requires() []api.StepLink {
 	return nil
 }
@@ -130,6 +142,8 @@ func (s *inputImageTagStep) Provides() api.ParameterMap {
 
 func (s *inputImageTagStep) Name() string { return s.config.TargetName() }
 
+func (*inputImageTagStep) Resumable() {}
+
 func (s *inputImageTagStep) Description() string {
 	return fmt.Sprintf("Find the input image %s and tag it into the pipeline", s.config.To)
 }
@@ -64,6 +64,10 @@ func (s *leaseStep) Requires() []api.StepLink            { return s.wrapped.Requ
 func (s *leaseStep) Creates() []api.StepLink             { return s.wrapped.Creates() }
 func (s *leaseStep) Objects() []ctrlruntimeclient.Object { return s.wrapped.Objects() }
 
+func (s *leaseStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	return planWrapped(s.wrapped)
+}
+
 func (s *leaseStep) Provides() api.ParameterMap {
 	parameters := s.wrapped.Provides()
 	if parameters == nil {
@@ -140,6 +144,13 @@ func acquireLeases(
 	cancel context.CancelFunc,
 	leases []stepLease,
 ) error {
+	// Acquire waits for each resource in turn and gives up what it holds
+	// only when the step fails, so any step that needs more than one resource
+	// acquires all of them at once, in a fixed order, never holding part of a
+	// type while waiting for the rest.
+	if len(leases) > 1 || (len(leases) == 1 && leases[0].Count > 1) {
+		return acquireLeasesAtomically(client, ctx, cancel, leases)
+	}
 	// Sort by resource type to avoid a(n unlikely and temporary) deadlock.
 	var sorted []int
 	for i := range leases {
@@ -154,7 +165,7 @@ func acquireLeases(
 		logrus.Debugf("Acquiring %d lease(s) for %s", l.Count, l.ResourceType)
 		names, err := client.Acquire(l.ResourceType, l.Count, ctx, cancel)
 		if err != nil {
-			if err == lease.ErrNotFound {
+			if errors.Is(err, lease.ErrNotFound) {
 				printResourceMetrics(client, l.ResourceType)
 			}
 			errs = append(errs, results.ForReason(results.Reason("acquiring_lease")).WithError(err).Errorf("failed to acquire lease for %q: %v", l.ResourceType, err))
@@ -171,6 +182,35 @@ func acquireLeases(
 	return utilerrors.NewAggregate(errs)
 }
 
+// acquireLeasesAtomically acquires leases of several types at once, so that
+// either all of them or none are held once the acquisition is done.
+func acquireLeasesAtomically(
+	client lease.Client,
+	ctx context.Context,
+	cancel context.CancelFunc,
+	leases []stepLease,
+) error {
+	var requests []lease.Request
+	for _, l := range leases {
+		requests = append(requests, lease.Request{ResourceType: l.ResourceType, Count: l.Count})
+	}
+	logrus.Debugf("Acquiring leases atomically: %v", requests)
+	names, err := client.AcquireAll(requests, ctx, cancel)
+	if err != nil {
+		if errors.Is(err, lease.ErrNotFound) {
+			for _, l := range leases {
+				printResourceMetrics(client, l.ResourceType)
+			}
+		}
+		return results.ForReason(results.Reason("acquiring_lease")).WithError(err).Errorf("failed to acquire leases: %v", err)
+	}
+	for i := range leases {
+		logrus.Infof("Acquired %d lease(s) for %s: %v", leases[i].Count, leases[i].ResourceType, names[i])
+		leases[i].resources = names[i]
+	}
+	return nil
+}
+
 func releaseLeases(client lease.Client, leases []stepLease) error {
 	var errs []error
 	for _, l := range leases {
@@ -51,6 +51,8 @@ func (s *multiStageTestStep) generateObservers(
 
 type generatePodOptions struct {
 	IsObserver bool
+	// Plan generates pods without resolving the pull specs of dependencies
+	Plan bool
 }
 
 func defaultGeneratePodOptions() *generatePodOptions {
@@ -188,7 +190,7 @@ func (s *multiStageTestStep) generatePods(
 		}...)
 		container.Env = append(container.Env, env...)
 		container.Env = append(container.Env, s.generateParams(step.Environment)...)
-		depEnv, depErrs := s.envForDependencies(step)
+		depEnv, depErrs := s.envForDependencies(step, genPodOpts)
 		if len(depErrs) != 0 {
 			errs = append(errs, depErrs...)
 			continue
@@ -385,7 +387,7 @@ func (s *multiStageTestStep) generateParams(env []api.StepParameter) []coreapi.E
 	return ret
 }
 
-func (s *multiStageTestStep) envForDependencies(step api.LiteralTestStep) ([]coreapi.EnvVar, []error) {
+func (s *multiStageTestStep) envForDependencies(step api.LiteralTestStep, genPodOpts *generatePodOptions) ([]coreapi.EnvVar, []error) {
 	var env []coreapi.EnvVar
 	var errs []error
 	var claimRelease *api.ClaimRelease
@@ -398,6 +400,8 @@ func (s *multiStageTestStep) envForDependencies(step api.LiteralTestStep) ([]cor
 		// correctly as it could possibly point to an external registry that ci-operator will itself not have access to.
 		if dependency.PullSpec != "" {
 			ref = dependency.PullSpec
+		} else if genPodOpts.Plan {
+			ref = base_steps.PlanPlaceholder
 		} else {
 			imageStream, name, _ := s.config.DependencyParts(dependency, claimRelease)
 			depRef, err := utils.ImageDigestFor(s.client, s.jobSpec.Namespace, imageStream, name)()
@@ -29,20 +29,53 @@ var (
 
 func (s *multiStageTestStep) createSharedDirSecret(ctx context.Context) error {
 	logrus.Debugf("Creating multi-stage test shared directory %q", s.name)
-	secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
-		Namespace: s.jobSpec.Namespace(),
-		Name:      s.name,
-		Labels:    map[string]string{api.SkipCensoringLabel: "true"},
-	}}
+	secret := s.sharedDirSecret()
 	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
 		return fmt.Errorf("cannot delete shared directory %q: %w", s.name, err)
 	}
 	return s.client.Create(ctx, secret)
 }
 
+func (s *multiStageTestStep) sharedDirSecret() *coreapi.Secret {
+	return &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
+		Namespace: s.jobSpec.Namespace(),
+		Name:      s.name,
+		Labels:    map[string]string{api.SkipCensoringLabel: "true"},
+	}}
+}
+
 func (s *multiStageTestStep) createCredentials(ctx context.Context) error {
 	logrus.Debugf("Creating multi-stage test credentials for %q", s.name)
 	toCreate := map[string]*coreapi.Secret{}
+	for name, credential := range s.credentialCopies() {
+		raw := &coreapi.Secret{}
+		if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: credential.Namespace, Name: credential.Name}, raw); err != nil {
+			return fmt.Errorf("could not read source credential: %w", err)
+		}
+		toCreate[name] = &coreapi.Secret{
+			TypeMeta: raw.TypeMeta,
+			ObjectMeta: meta.ObjectMeta{
+				Name:      name,
+				Namespace: s.jobSpec.Namespace(),
+			},
+			Type:       raw.Type,
+			Data:       raw.Data,
+			StringData: raw.StringData,
+		}
+	}
+
+	for name := range toCreate {
+		if err := s.client.Create(ctx, toCreate[name]); err != nil && !kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("could not create source credential: %w", err)
+		}
+	}
+	return nil
+}
+
+// credentialCopies maps the names of the secrets created in the test namespace
+// to the credentials they are copied from.
+func (s *multiStageTestStep) credentialCopies() map[string]api.CredentialReference {
+	copies := map[string]api.CredentialReference{}
 	for _, step := range append(s.pre, append(s.test, s.post...)...) {
 		for _, credential := range step.Credentials {
 			// we don't want secrets imported from separate namespaces to collide
@@ -50,61 +83,55 @@ func (s *multiStageTestStep) createCredentials(ctx context.Context) error {
 			// chance we get a second-level collision (ns-a, name) and (ns, a-name) is
 			// small, so we can get away with this string prefixing
 			name := fmt.Sprintf("%s-%s", credential.Namespace, credential.Name)
-			if _, ok := toCreate[name]; ok {
+			if _, ok := copies[name]; ok {
 				continue
 			}
-			raw := &coreapi.Secret{}
-			if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: credential.Namespace, Name: credential.Name}, raw); err != nil {
-				return fmt.Errorf("could not read source credential: %w", err)
-			}
-			toCreate[name] = &coreapi.Secret{
-				TypeMeta: raw.TypeMeta,
-				ObjectMeta: meta.ObjectMeta{
-					Name:      name,
-					Namespace: s.jobSpec.Namespace(),
-				},
-				Type:       raw.Type,
-				Data:       raw.Data,
-				StringData: raw.StringData,
-			}
+			copies[name] = credential
 		}
 	}
+	return copies
+}
 
-	for name := range toCreate {
-		if err := s.client.Create(ctx, toCreate[name]); err != nil && !kerrors.IsAlreadyExists(err) {
-			return fmt.Errorf("could not create source credential: %w", err)
-		}
+func (s *multiStageTestStep) createCommandConfigMaps(ctx context.Context) error {
+	logrus.Debugf("Creating multi-stage test commands configmap for %q", s.name)
+	commands := s.commandConfigMap()
+	name := commands.Name
+	// delete old command configmap if it exists
+	if err := s.client.Delete(ctx, commands); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete command configmap %s: %w", name, err)
+	}
+	if err := s.client.Create(ctx, commands); err != nil {
+		return fmt.Errorf("could not create command configmap %s: %w", name, err)
 	}
 	return nil
 }
 
-func (s *multiStageTestStep) createCommandConfigMaps(ctx context.Context) error {
-	logrus.Debugf("Creating multi-stage test commands configmap for %q", s.name)
+func (s *multiStageTestStep) commandConfigMap() *coreapi.ConfigMap {
 	data := make(map[string]string)
 	for _, step := range append(s.pre, append(s.test, s.post...)...) {
 		data[step.As] = step.Commands
 	}
-	name := commandConfigMapForTest(s.name)
 	yes := true
-	commands := &coreapi.ConfigMap{
+	return &coreapi.ConfigMap{
 		ObjectMeta: meta.ObjectMeta{
-			Name:      name,
+			Name:      commandConfigMapForTest(s.name),
 			Namespace: s.jobSpec.Namespace(),
 		},
 		Data:      data,
 		Immutable: &yes,
 	}
-	// delete old command configmap if it exists
-	if err := s.client.Delete(ctx, commands); err != nil && !kerrors.IsNotFound(err) {
-		return fmt.Errorf("could not delete command configmap %s: %w", name, err)
-	}
-	if err := s.client.Create(ctx, commands); err != nil {
-		return fmt.Errorf("could not create command configmap %s: %w", name, err)
+}
+
+func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
+	sa, role, bindings := s.rbac()
+	if err := util.CreateRBACs(ctx, sa, role, bindings, s.client, 1*time.Second, 1*time.Minute); err != nil {
+		return err
 	}
+
 	return nil
 }
 
-func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
+func (s *multiStageTestStep) rbac() (*coreapi.ServiceAccount, *rbacapi.Role, []rbacapi.RoleBinding) {
 	labels := map[string]string{MultiStageTestLabel: s.name}
 	ns := s.jobSpec.Namespace()
 	m := meta.ObjectMeta{Namespace: ns, Name: s.name, Labels: labels}
@@ -160,11 +187,7 @@ func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
 			Subjects: subj,
 		})
 	}
-	if err := util.CreateRBACs(ctx, sa, role, bindings, s.client, 1*time.Second, 1*time.Minute); err != nil {
-		return err
-	}
-
-	return nil
+	return sa, role, bindings
 }
 
 // getNamespaceUID retrieves the base UID configured for the test namespace.
@@ -99,6 +99,7 @@ type multiStageTestStep struct {
 	subTests        []*junit.TestCase
 	subSteps        []api.CIOperatorStepDetailInfo
 	flags           stepFlag
+	retries         *api.RetryPolicy
 	leases          []api.StepLease
 	clusterClaim    *api.ClusterClaim
 	vpnConf         *vpnConf
@@ -150,6 +151,7 @@ func newMultiStageTestStep(
 		test:             ms.Test,
 		post:             ms.Post,
 		flags:            flags,
+		retries:          ms.Retries,
 		leases:           leases,
 		clusterClaim:     testConfig.ClusterClaim,
 		subLock:          &sync.Mutex{},
@@ -355,9 +357,15 @@ func (s *multiStageTestStep) readVPNData(secret *coreapi.Secret) error {
 }
 
 func (s *multiStageTestStep) environment() ([]coreapi.EnvVar, error) {
+	return s.environmentFrom(func(name string) (string, error) {
+		return s.params.Get(name)
+	})
+}
+
+func (s *multiStageTestStep) environmentFrom(get func(string) (string, error)) ([]coreapi.EnvVar, error) {
 	var ret []coreapi.EnvVar
 	for _, l := range s.leases {
-		val, err := s.params.Get(l.Env)
+		val, err := get(l.Env)
 		if err != nil {
 			return nil, err
 		}
@@ -366,7 +374,7 @@ func (s *multiStageTestStep) environment() ([]coreapi.EnvVar, error) {
 
 	if s.profile != "" {
 		for _, e := range envForProfile {
-			val, err := s.params.Get(e)
+			val, err := get(e)
 			if err != nil {
 				return nil, err
 			}
@@ -0,0 +1,70 @@
+package multi_stage
+
+import (
+	"fmt"
+	"sort"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// Plan renders the objects the test creates without contacting the cluster.
+// Credentials are rendered without the data copied from their source at
+// runtime, and pods do not mount the secrets that are only mounted so that
+// their content is censored, as those depend on the namespace.
+func (s *multiStageTestStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	env, err := s.environmentFrom(func(string) (string, error) {
+		return base_steps.PlanPlaceholder, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	objects := []ctrlruntimeclient.Object{s.sharedDirSecret(), s.commandConfigMap()}
+	copies := s.credentialCopies()
+	names := make([]string, 0, len(copies))
+	for name := range copies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		objects = append(objects, &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
+			Name:      name,
+			Namespace: s.jobSpec.Namespace(),
+		}})
+	}
+	sa, role, bindings := s.rbac()
+	objects = append(objects, sa, role)
+	for i := range bindings {
+		objects = append(objects, &bindings[i])
+	}
+
+	observers, err := s.generateObservers(s.observers, nil, nil, &generatePodOptions{IsObserver: true, Plan: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate observer pods: %w", err)
+	}
+	// steps that are skipped when everything before them succeeded are still
+	// part of the plan
+	flags := s.flags
+	s.flags |= hasPrevErrs
+	defer func() { s.flags = flags }()
+	for _, phase := range []struct {
+		name  string
+		steps []api.LiteralTestStep
+	}{{name: "pre", steps: s.pre}, {name: "test", steps: s.test}, {name: "post", steps: s.post}} {
+		pods, _, err := s.generatePods(phase.steps, env, nil, nil, &generatePodOptions{Plan: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s pods: %w", phase.name, err)
+		}
+		for i := range pods {
+			objects = append(objects, &pods[i])
+		}
+	}
+	for i := range observers {
+		objects = append(objects, &observers[i])
+	}
+	return objects, nil
+}
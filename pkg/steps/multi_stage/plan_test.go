@@ -0,0 +1,60 @@
+package multi_stage
+
+import (
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestPlan(t *testing.T) {
+	yes := true
+	credentials := []api.CredentialReference{{Namespace: "test-credentials", Name: "cred", MountPath: "/cred"}}
+	config := api.ReleaseBuildConfiguration{
+		Tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Pre: []api.LiteralTestStep{{As: "setup", From: "src", Commands: "setup", Credentials: credentials}},
+				Test: []api.LiteralTestStep{{
+					As: "test", From: "src", Commands: "test", Credentials: credentials,
+					Dependencies: []api.StepDependency{{Name: "pipeline:bin", Env: "BIN_IMAGE"}},
+				}},
+				Post:               []api.LiteralTestStep{{As: "gather", From: "src", Commands: "gather", OptionalOnSuccess: &yes}},
+				AllowSkipOnSuccess: &yes,
+			},
+		}},
+	}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build id",
+			ProwJobID: "prow job id",
+			Refs: &prowapi.Refs{
+				Org:     "org",
+				Repo:    "repo",
+				BaseRef: "base ref",
+				BaseSHA: "base sha",
+			},
+			Type: "postsubmit",
+			DecorationConfig: &prowapi.DecorationConfig{
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("ci-op-plan")
+	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, []api.StepLease{{ResourceType: "aws-quota-slice", Env: "LEASED_RESOURCE"}}, "", "")
+	objects, err := step.Plan()
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	testhelper.CompareWithFixture(t, objects)
+	if step.flags&hasPrevErrs != 0 {
+		t.Error("planning changed the flags of the step")
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
 	base_steps "github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/util"
 )
@@ -44,7 +45,7 @@ func (s *multiStageTestStep) runSteps(
 			s.flags |= hasPrevErrs
 		}
 	}()
-	if err := s.runPods(ctx, pods, bestEffortSteps); err != nil {
+	if err := s.runPods(ctx, pods, bestEffortSteps, s.retryPolicies(steps)); err != nil {
 		errs = append(errs, err)
 	}
 	select {
@@ -71,6 +72,7 @@ func (s *multiStageTestStep) runSteps(
 		verb = "failed"
 		testCase.FailureOutput = &junit.FailureOutput{
 			Output: err.Error(),
+			Type:   string(results.CategoryOf(err)),
 		}
 	}
 	s.subTests = append(s.subTests, testCase)
@@ -79,10 +81,10 @@ func (s *multiStageTestStep) runSteps(
 	return err
 }
 
-func (s *multiStageTestStep) runPods(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.Set[string]) error {
+func (s *multiStageTestStep) runPods(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.Set[string], retries map[string]*api.RetryPolicy) error {
 	var errs []error
 	for _, pod := range pods {
-		err := s.runPod(ctx, &pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+		err := s.runPodWithRetries(ctx, &pod, retries[pod.Name])
 		if err == nil {
 			continue
 		}
@@ -132,7 +134,84 @@ func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []c
 	done <- struct{}{}
 }
 
+// retryPolicies maps the names of the pods generated for the steps to their
+// retry policy: either the one set for the step or the one set for the test.
+func (s *multiStageTestStep) retryPolicies(steps []api.LiteralTestStep) map[string]*api.RetryPolicy {
+	ret := map[string]*api.RetryPolicy{}
+	for _, step := range steps {
+		policy := step.Retries
+		if policy == nil {
+			policy = s.retries
+		}
+		if policy != nil {
+			ret[fmt.Sprintf("%s-%s", s.name, step.As)] = policy
+		}
+	}
+	return ret
+}
+
+// runPodWithRetries runs the pod, recreating it after a failure as long as the
+// retry policy allows it. Each attempt of a retried pod, including the last
+// one, is reported as a separate sub-test.
+func (s *multiStageTestStep) runPodWithRetries(ctx context.Context, pod *coreapi.Pod, policy *api.RetryPolicy) error {
+	if policy == nil {
+		return s.runPod(ctx, pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+	}
+	var backoff time.Duration
+	if policy.Backoff != nil {
+		backoff = policy.Backoff.Duration
+	}
+	for attempt := uint(0); ; attempt++ {
+		start := time.Now()
+		err := s.runPodAttempt(ctx, pod.DeepCopy(), base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0), attempt)
+		last := err == nil || attempt == policy.Attempts || ctx.Err() != nil
+		// a step that passes on its first attempt was not retried, so it
+		// only has the sub-tests of its containers
+		if !last || attempt > 0 {
+			testCase := &junit.TestCase{
+				Name:     fmt.Sprintf("%s - %s attempt %d", s.Description(), pod.Name, attempt+1),
+				Duration: time.Since(start).Seconds(),
+			}
+			if err != nil {
+				testCase.FailureOutput = &junit.FailureOutput{Output: err.Error(), Type: string(results.CategoryOf(err))}
+			}
+			s.subLock.Lock()
+			s.subTests = append(s.subTests, testCase)
+			s.subLock.Unlock()
+		}
+		if last {
+			return err
+		}
+		logrus.Infof("Step %s failed, retrying in %s (retry %d of %d).", pod.Name, backoff, attempt+1, policy.Attempts)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// recordSubStep records the details of a pod, replacing those of the previous
+// attempt when the pod is retried, so that each step is reported only once
+// with the outcome of its last attempt. The caller must hold subLock.
+func (s *multiStageTestStep) recordSubStep(subStep api.CIOperatorStepDetailInfo, attempt uint) {
+	if attempt > 0 {
+		for i := len(s.subSteps) - 1; i >= 0; i-- {
+			if s.subSteps[i].StepName == subStep.StepName {
+				s.subSteps[i] = subStep
+				return
+			}
+		}
+	}
+	s.subSteps = append(s.subSteps, subStep)
+}
+
 func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) error {
+	return s.runPodAttempt(ctx, pod, notifier, flags, 0)
+}
+
+func (s *multiStageTestStep) runPodAttempt(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag, attempt uint) error {
 	start := time.Now()
 	logrus.Infof("Running step %s.", pod.Name)
 	client := s.client.WithNewLoggingClient()
@@ -150,8 +229,7 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		verb = "failed"
 	}
 	logrus.Infof("Step %s %s after %s.", pod.Name, verb, duration.Truncate(time.Second))
-	s.subLock.Lock()
-	s.subSteps = append(s.subSteps, api.CIOperatorStepDetailInfo{
+	subStep := api.CIOperatorStepDetailInfo{
 		StepName:    pod.Name,
 		Description: fmt.Sprintf("Run pod %s", pod.Name),
 		StartedAt:   &start,
@@ -159,8 +237,14 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		Duration:    &duration,
 		Failed:      utilpointer.Bool(err != nil),
 		Manifests:   client.Objects(),
-	})
-	s.subTests = append(s.subTests, notifier.SubTests(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))...)
+	}
+	s.subLock.Lock()
+	s.recordSubStep(subStep, attempt)
+	prefix := fmt.Sprintf("%s - %s ", s.Description(), pod.Name)
+	if attempt > 0 {
+		prefix = fmt.Sprintf("%s - %s attempt %d ", s.Description(), pod.Name, attempt+1)
+	}
+	s.subTests = append(s.subTests, notifier.SubTests(prefix)...)
 	s.subLock.Unlock()
 	if err != nil {
 		linksText := strings.Builder{}
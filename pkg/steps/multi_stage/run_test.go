@@ -214,6 +214,7 @@ func TestJUnit(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
 		failures sets.Set[string]
+		retries  *api.RetryPolicy
 		expected []string
 	}{{
 		name: "no step fails",
@@ -265,6 +266,22 @@ func TestJUnit(t *testing.T) {
 			"Run multi-stage test test - test-post1 container test",
 			"Run multi-stage test post phase",
 		},
+	}, {
+		name:     "failure in a pre step with retries",
+		failures: sets.New[string]("test-pre0"),
+		retries:  &api.RetryPolicy{Attempts: 2},
+		expected: []string{
+			"Run multi-stage test test - test-pre0 container test",
+			"Run multi-stage test test - test-pre0 attempt 1",
+			"Run multi-stage test test - test-pre0 attempt 2 container test",
+			"Run multi-stage test test - test-pre0 attempt 2",
+			"Run multi-stage test test - test-pre0 attempt 3 container test",
+			"Run multi-stage test test - test-pre0 attempt 3",
+			"Run multi-stage test pre phase",
+			"Run multi-stage test test - test-post0 container test",
+			"Run multi-stage test test - test-post1 container test",
+			"Run multi-stage test post phase",
+		},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"ci.openshift.io/multi-stage-test": "test"}}}
@@ -299,9 +316,10 @@ func TestJUnit(t *testing.T) {
 			step := MultiStageTestStep(api.TestStepConfiguration{
 				As: "test",
 				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
-					Pre:  []api.LiteralTestStep{{As: "pre0"}, {As: "pre1"}},
-					Test: []api.LiteralTestStep{{As: "test0"}, {As: "test1"}},
-					Post: []api.LiteralTestStep{{As: "post0"}, {As: "post1"}},
+					Pre:     []api.LiteralTestStep{{As: "pre0"}, {As: "pre1"}},
+					Test:    []api.LiteralTestStep{{As: "test0"}, {As: "test1"}},
+					Post:    []api.LiteralTestStep{{As: "post0"}, {As: "post1"}},
+					Retries: tc.retries,
 				},
 			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "")
 			if err := step.Run(context.Background()); tc.failures == nil && err != nil {
@@ -319,6 +337,78 @@ func TestJUnit(t *testing.T) {
 	}
 }
 
+func TestSubStepsOfRetriedStep(t *testing.T) {
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"ci.openshift.io/multi-stage-test": "test"}}}
+	crclient := &testhelper_kube.FakePodExecutor{
+		Lock: sync.RWMutex{},
+		LoggingClient: loggingclient.New(
+			fakectrlruntimeclient.NewClientBuilder().
+				WithIndex(&v1.Pod{}, "metadata.name", fakePodNameIndexer).
+				WithObjects(sa).
+				Build()),
+		Flakes: map[string]int{"test-test0": 1},
+	}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build_id",
+			ProwJobID: "prow_job_id",
+			Type:      prowapi.PeriodicJob,
+			DecorationConfig: &prowapi.DecorationConfig{
+				Timeout:     &prowapi.Duration{Duration: time.Minute},
+				GracePeriod: &prowapi.Duration{Duration: time.Second},
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("test-namespace")
+	client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+	step := MultiStageTestStep(api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test:    []api.LiteralTestStep{{As: "test0"}, {As: "test1"}},
+			Retries: &api.RetryPolicy{Attempts: 2},
+		},
+	}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "")
+	if err := step.Run(context.Background()); err != nil {
+		t.Fatalf("expected the step to pass after a retry, got: %v", err)
+	}
+
+	type subStep struct {
+		Name   string
+		Failed bool
+	}
+	var actual []subStep
+	for _, s := range step.(steps.SubStepReporter).SubSteps() {
+		actual = append(actual, subStep{Name: s.StepName, Failed: s.Failed != nil && *s.Failed})
+	}
+	expected := []subStep{{Name: "test-test0"}, {Name: "test-test1"}}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected sub-steps: %s", diff)
+	}
+
+	var names []string
+	for _, t := range step.(steps.SubtestReporter).SubTests() {
+		names = append(names, t.Name)
+	}
+	expectedNames := []string{
+		"Run multi-stage test pre phase",
+		"Run multi-stage test test - test-test0 container test",
+		"Run multi-stage test test - test-test0 attempt 1",
+		"Run multi-stage test test - test-test0 attempt 2 container test",
+		"Run multi-stage test test - test-test0 attempt 2",
+		"Run multi-stage test test - test-test1 container test",
+		"Run multi-stage test test phase",
+		"Run multi-stage test post phase",
+	}
+	if diff := cmp.Diff(expectedNames, names); diff != "" {
+		t.Errorf("unexpected sub-tests: %s", diff)
+	}
+}
+
 func fakePodNameIndexer(object ctrlruntimeclient.Object) []string {
 	p, ok := object.(*v1.Pod)
 	if !ok {
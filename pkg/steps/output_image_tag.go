@@ -162,6 +162,10 @@ func (s *outputImageTagStep) imageStreamTag(fromImage string) *imagev1.ImageStre
 	}
 }
 
+func (s *outputImageTagStep) Plan() ([]crclient.Object, error) {
+	return []crclient.Object{s.imageStreamTag(PlanPlaceholder)}, nil
+}
+
 func OutputImageTagStep(config api.OutputImageTagStepConfiguration, client loggingclient.LoggingClient, jobSpec *api.JobSpec) api.Step {
 	return &outputImageTagStep{
 		config:  config,
@@ -41,12 +41,16 @@ func (s *pipelineImageCacheStep) Run(ctx context.Context) error {
 }
 
 func (s *pipelineImageCacheStep) run(ctx context.Context) error {
-	dockerfile := rawCommandDockerfile(s.config.From, s.config.Commands)
 	fromDigest, err := resolvePipelineImageStreamTagReference(ctx, s.client, s.config.From, s.jobSpec)
 	if err != nil {
 		return err
 	}
-	return handleBuilds(ctx, s.client, s.podClient, *buildFromSource(
+	return handleBuilds(ctx, s.client, s.podClient, *s.build(fromDigest))
+}
+
+func (s *pipelineImageCacheStep) build(fromDigest string) *buildapi.Build {
+	dockerfile := rawCommandDockerfile(s.config.From, s.config.Commands)
+	return buildFromSource(
 		s.jobSpec, s.config.From, s.config.To,
 		buildapi.BuildSource{
 			Type:       buildapi.BuildSourceDockerfile,
@@ -58,7 +62,11 @@ func (s *pipelineImageCacheStep) run(ctx context.Context) error {
 		s.pullSecret,
 		nil,
 		s.config.Ref,
-	))
+	)
+}
+
+func (s *pipelineImageCacheStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	return planBuilds(s.client, *s.build(PlanPlaceholder))
 }
 
 func (s *pipelineImageCacheStep) Requires() []api.StepLink {
@@ -80,6 +88,8 @@ func (s *pipelineImageCacheStep) Provides() api.ParameterMap {
 
 func (s *pipelineImageCacheStep) Name() string { return s.config.TargetName() }
 
+func (*pipelineImageCacheStep) Resumable() {}
+
 func (s *pipelineImageCacheStep) Description() string {
 	return fmt.Sprintf("Store build results into a layer on top of %s and save as %s", s.config.From, s.config.To)
 }
@@ -0,0 +1,66 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// PlanPlaceholder stands in for values that are only known once the step runs,
+// like the digests of images resolved from the cluster.
+const PlanPlaceholder = "RESOLVED-AT-RUNTIME"
+
+// Planner is implemented by steps that can render the objects they create in
+// the test namespace without contacting the cluster.
+type Planner interface {
+	Plan() ([]ctrlruntimeclient.Object, error)
+}
+
+// errNotPlannable is returned by steps that wrap a step which cannot be planned.
+var errNotPlannable = errors.New("step cannot be planned")
+
+// Plan renders the objects a step would create. The second return value is
+// false when the step does not support planning.
+func Plan(step api.Step) ([]ctrlruntimeclient.Object, bool, error) {
+	planner, ok := step.(Planner)
+	if !ok {
+		return nil, false, nil
+	}
+	objects, err := planner.Plan()
+	if errors.Is(err, errNotPlannable) {
+		return nil, false, nil
+	}
+	return objects, true, err
+}
+
+func planWrapped(wrapped api.Step) ([]ctrlruntimeclient.Object, error) {
+	objects, ok, err := Plan(wrapped)
+	if !ok {
+		return nil, errNotPlannable
+	}
+	return objects, err
+}
+
+// planBuilds renders the builds handleBuilds would run for a build, or the pods
+// that run them when the builds do not run as OpenShift Builds.
+func planBuilds(client BuildClient, build buildapi.Build) ([]ctrlruntimeclient.Object, error) {
+	var objects []ctrlruntimeclient.Object
+	for _, b := range constructMultiArchBuilds(build, client.NodeArchitectures()) {
+		b := b
+		if builder, ok := client.Builder(nil).(*buildahBuilder); ok {
+			pod, err := builder.podFor(&b)
+			if err != nil {
+				return nil, fmt.Errorf("could not create build pod for %s: %w", b.Name, err)
+			}
+			objects = append(objects, pod)
+			continue
+		}
+		objects = append(objects, &b)
+	}
+	return objects, nil
+}
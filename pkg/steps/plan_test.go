@@ -0,0 +1,76 @@
+package steps
+
+import (
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestPlan(t *testing.T) {
+	jobSpec := &api.JobSpec{
+		JobSpec: downwardapi.JobSpec{
+			Job:       "job",
+			BuildID:   "buildId",
+			ProwJobID: "prowJobId",
+			Refs: &prowapi.Refs{
+				Org:     "org",
+				Repo:    "repo",
+				BaseRef: "master",
+				BaseSHA: "masterSHA",
+			},
+		},
+	}
+	jobSpec.SetNamespace("ci-op-plan")
+	cacheStep := func(buildah *BuildahConfig) api.Step {
+		return &pipelineImageCacheStep{
+			config: api.PipelineImageCacheStepConfiguration{
+				From:     api.PipelineImageStreamTagReferenceSource,
+				To:       api.PipelineImageStreamTagReferenceBinaries,
+				Commands: "make build",
+			},
+			resources: api.ResourceConfiguration{"*": {Requests: map[string]string{"cpu": "100m"}}},
+			client:    NewBuildClient(nil, nil, []string{"amd64", "arm64"}, "", "", nil, buildah),
+			jobSpec:   jobSpec,
+		}
+	}
+	for _, tc := range []struct {
+		name    string
+		step    api.Step
+		planned bool
+	}{{
+		name:    "builds for every architecture",
+		step:    cacheStep(nil),
+		planned: true,
+	}, {
+		name:    "builds in buildah pods",
+		step:    cacheStep(&BuildahConfig{Image: DefaultBuildahImage, Registry: "registry.example.com"}),
+		planned: true,
+	}, {
+		name:    "wrapped step is planned",
+		step:    LeaseStep(nil, nil, cacheStep(nil), jobSpec.Namespace),
+		planned: true,
+	}, {
+		name: "step without a planner",
+		step: WriteParametersStep(nil, ""),
+	}, {
+		name: "wrapped step without a planner",
+		step: LeaseStep(nil, nil, WriteParametersStep(nil, ""), jobSpec.Namespace),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, planned, err := Plan(tc.step)
+			if err != nil {
+				t.Fatalf("failed to plan: %v", err)
+			}
+			if planned != tc.planned {
+				t.Fatalf("expected planned=%v, got %v", tc.planned, planned)
+			}
+			if planned {
+				testhelper.CompareWithFixture(t, objects)
+			}
+		})
+	}
+}
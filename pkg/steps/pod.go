@@ -86,19 +86,9 @@ func (s *podStep) run(ctx context.Context) error {
 	if !util.IsBitSet(s.config.WaitFlags, util.SkipLogs) {
 		logrus.Infof("Executing %s %s", s.name, s.config.As)
 	}
-	containerResources, err := ResourcesFor(s.resources.RequirementsForStep(s.config.As))
-	if err != nil {
-		return fmt.Errorf("unable to calculate %s pod resources for %s: %w", s.name, s.config.As, err)
-	}
-
-	if s.config.From.Namespace != "" {
-		return errors.New("pod step does not support an image stream tag reference outside the namespace")
-	}
-	image := fmt.Sprintf("%s:%s", s.config.From.Name, s.config.From.Tag)
-
-	pod, err := s.generatePodForStep(image, containerResources, s.config.Clone)
+	pod, err := s.pod()
 	if err != nil {
-		return fmt.Errorf("pod step was invalid: %w", err)
+		return err
 	}
 	testCaseNotifier := NewTestCaseNotifier(util.NopNotifier)
 
@@ -128,6 +118,32 @@ func (s *podStep) run(ctx context.Context) error {
 	return nil
 }
 
+func (s *podStep) pod() (*coreapi.Pod, error) {
+	containerResources, err := ResourcesFor(s.resources.RequirementsForStep(s.config.As))
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate %s pod resources for %s: %w", s.name, s.config.As, err)
+	}
+
+	if s.config.From.Namespace != "" {
+		return nil, errors.New("pod step does not support an image stream tag reference outside the namespace")
+	}
+	image := fmt.Sprintf("%s:%s", s.config.From.Name, s.config.From.Tag)
+
+	pod, err := s.generatePodForStep(image, containerResources, s.config.Clone)
+	if err != nil {
+		return nil, fmt.Errorf("pod step was invalid: %w", err)
+	}
+	return pod, nil
+}
+
+func (s *podStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	pod, err := s.pod()
+	if err != nil {
+		return nil, err
+	}
+	return []ctrlruntimeclient.Object{pod}, nil
+}
+
 func (s *podStep) SubTests() []*junit.TestCase {
 	return s.subTests
 }
@@ -40,17 +40,27 @@ func (s *projectDirectoryImageBuildStep) Run(ctx context.Context) error {
 }
 
 func (s *projectDirectoryImageBuildStep) run(ctx context.Context) error {
-	sourceTag, images, err := imagesFor(s.config, func(tag string) (string, error) {
+	build, err := s.build(func(tag string) (string, error) {
 		return getWorkingDir(s.client, tag, s.jobSpec.Namespace())
-	}, s.releaseBuildConfig.IsBundleImage)
+	}, func(tag api.PipelineImageStreamTagReference) (string, error) {
+		return resolvePipelineImageStreamTagReference(ctx, s.client, tag, s.jobSpec)
+	})
 	if err != nil {
 		return err
 	}
-	fromDigest, err := resolvePipelineImageStreamTagReference(ctx, s.client, sourceTag, s.jobSpec)
+	return handleBuilds(ctx, s.client, s.podClient, *build)
+}
+
+func (s *projectDirectoryImageBuildStep) build(workingDir workingDir, resolveDigest func(api.PipelineImageStreamTagReference) (string, error)) (*buildapi.Build, error) {
+	sourceTag, images, err := imagesFor(s.config, workingDir, s.releaseBuildConfig.IsBundleImage)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	fromDigest, err := resolveDigest(sourceTag)
+	if err != nil {
+		return nil, err
 	}
-	build := buildFromSource(
+	return buildFromSource(
 		s.jobSpec, s.config.From, s.config.To,
 		buildapi.BuildSource{
 			Type:       buildapi.BuildSourceImage,
@@ -63,8 +73,19 @@ func (s *projectDirectoryImageBuildStep) run(ctx context.Context) error {
 		s.pullSecret,
 		s.config.BuildArgs,
 		s.config.Ref,
-	)
-	return handleBuilds(ctx, s.client, s.podClient, *build)
+	), nil
+}
+
+func (s *projectDirectoryImageBuildStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	build, err := s.build(func(string) (string, error) {
+		return "/" + PlanPlaceholder, nil
+	}, func(api.PipelineImageStreamTagReference) (string, error) {
+		return PlanPlaceholder, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return planBuilds(s.client, *build)
 }
 
 type workingDir func(tag string) (string, error)
@@ -179,6 +200,8 @@ func (s *projectDirectoryImageBuildStep) Provides() api.ParameterMap {
 
 func (s *projectDirectoryImageBuildStep) Name() string { return s.config.TargetName() }
 
+func (*projectDirectoryImageBuildStep) Resumable() {}
+
 func (s *projectDirectoryImageBuildStep) Description() string {
 	return fmt.Sprintf("Build image %s from the repository", s.config.To)
 }
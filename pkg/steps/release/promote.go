@@ -3,6 +3,7 @@ package release
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"sort"
@@ -24,6 +25,7 @@ import (
 	imagev1 "github.com/openshift/api/image/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/kubernetes/pkg/credentialprovider"
 	"github.com/openshift/ci-tools/pkg/results"
@@ -106,29 +108,111 @@ func (s *promotionStep) run(ctx context.Context) error {
 	if _, err := steps.RunPod(ctx, s.client, getPromotionPod(imageMirrorTarget, s.jobSpec.Namespace(), s.name, s.nodeArchitectures)); err != nil {
 		return fmt.Errorf("unable to run promotion pod: %w", err)
 	}
+
+	// the images are promoted at this point, failing to record that must not fail the job
+	if err := s.recordPromotion(ctx, tags, pipeline); err != nil {
+		logger.WithError(err).Warn("Failed to record the promotion in the promotion ledger.")
+	}
 	return nil
 }
 
-func (s *promotionStep) ensureNamespaces(ctx context.Context, namespaces sets.Set[string]) error {
-	if len(namespaces) == 0 {
+// recordPromotion records the promoted tags in the promotion ledger on app.ci, so
+// they can be rolled back. Only promotions into the image streams there are recorded,
+// together with the tags in quay.io that the promotion to quay.io, which follows with
+// the same images, points to them.
+func (s *promotionStep) recordPromotion(ctx context.Context, tags map[string][]api.ImageStreamTagReference, pipeline *imagev1.ImageStream) error {
+	if s.registry == api.QuayOpenShiftCIRepo || s.configuration.PromotionConfiguration.RegistryOverride != "" {
 		return nil
 	}
-	// Used primarily (only?) by the chatbot and we likely do not have the permission to create
-	// namespaces (nor are we expected to).
-	if s.configuration.PromotionConfiguration.RegistryOverride != "" {
+	record := promotionRecord(s.configuration.Metadata, s.jobSpec, tags, pipeline, time.Now())
+	if record == nil {
 		return nil
 	}
+	appCIKubeconfig, err := s.appCIKubeconfig()
+	if err != nil {
+		return err
+	}
+	client, err := ctrlruntimeclient.New(appCIKubeconfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to construct client: %w", err)
+	}
+	if err := client.Create(ctx, record); err != nil {
+		return fmt.Errorf("failed to create promotion record: %w", err)
+	}
+	logrus.WithField("name", s.name).Debugf("Recorded the promotion in %s/%s", record.Namespace, record.Name)
+	return nil
+}
+
+// promotionRecord returns the record of the promotion of the tags from the pipeline
+// image stream, or nil if none of them exist there
+func promotionRecord(metadata api.Metadata, jobSpec *api.JobSpec, tags map[string][]api.ImageStreamTagReference, pipeline *imagev1.ImageStream, now time.Time) *ledgerv1.PromotionRecord {
+	var promoted []ledgerv1.PromotedTag
+	for src, dsts := range tags {
+		digest := findImageDigest(pipeline, src)
+		if digest == "" {
+			continue
+		}
+		for _, dst := range dsts {
+			promoted = append(promoted, ledgerv1.PromotedTag{Namespace: dst.Namespace, Name: dst.Name, Tag: dst.Tag, Digest: digest, QuayImage: api.QuayImage(dst)})
+		}
+	}
+	if len(promoted) == 0 {
+		return nil
+	}
+	sort.Slice(promoted, func(i, j int) bool {
+		return promoted[i].ISTagName() < promoted[j].ISTagName()
+	})
+
+	source := &ledgerv1.PromotionSource{Org: metadata.Org, Repo: metadata.Repo, Branch: metadata.Branch}
+	if refs := mainRefs(jobSpec.Refs, jobSpec.ExtraRefs); refs != nil {
+		source.Commit = refs.BaseSHA
+	}
+	return &ledgerv1.PromotionRecord{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:    ledgerv1.LedgerNamespace,
+			GenerateName: "promotion-",
+			Labels:       ledgerv1.LabelsForSource(metadata.Org, metadata.Repo, metadata.Branch),
+		},
+		Spec: ledgerv1.PromotionRecordSpec{
+			Time:   meta.NewTime(now),
+			Source: source,
+			Job:    &ledgerv1.PromotionJob{Name: jobSpec.Job, BuildID: jobSpec.BuildID, ProwJobID: jobSpec.ProwJobID},
+			Tags:   promoted,
+		},
+	}
+}
+
+// appCIKubeconfig returns a kubeconfig for app.ci with the token in the push secret
+func (s *promotionStep) appCIKubeconfig() (*rest.Config, error) {
+	if s.pushSecret == nil {
+		return nil, errors.New("no push secret")
+	}
 	var dockercfg credentialprovider.DockerConfigJSON
 	if err := json.Unmarshal(s.pushSecret.Data[coreapi.DockerConfigJsonKey], &dockercfg); err != nil {
-		return fmt.Errorf("failed to deserialize push secret: %w", err)
+		return nil, fmt.Errorf("failed to deserialize push secret: %w", err)
 	}
 
 	appCIDockercfg, hasAppCIDockercfg := dockercfg.Auths[api.ServiceDomainAPPCIRegistry]
 	if !hasAppCIDockercfg {
-		return fmt.Errorf("push secret has no entry for %s", api.ServiceDomainAPPCIRegistry)
+		return nil, fmt.Errorf("push secret has no entry for %s", api.ServiceDomainAPPCIRegistry)
 	}
 
-	appCIKubeconfig := &rest.Config{Host: api.APPCIKubeAPIURL, BearerToken: appCIDockercfg.Password}
+	return &rest.Config{Host: api.APPCIKubeAPIURL, BearerToken: appCIDockercfg.Password}, nil
+}
+
+func (s *promotionStep) ensureNamespaces(ctx context.Context, namespaces sets.Set[string]) error {
+	if len(namespaces) == 0 {
+		return nil
+	}
+	// Used primarily (only?) by the chatbot and we likely do not have the permission to create
+	// namespaces (nor are we expected to).
+	if s.configuration.PromotionConfiguration.RegistryOverride != "" {
+		return nil
+	}
+	appCIKubeconfig, err := s.appCIKubeconfig()
+	if err != nil {
+		return err
+	}
 	client, err := corev1client.NewForConfig(appCIKubeconfig)
 	if err != nil {
 		return fmt.Errorf("failed to construct kubeconfig: %w", err)
@@ -258,6 +342,20 @@ func getPromotionPod(imageMirrorTarget map[string]string, namespace string, name
 	}
 }
 
+// findImageDigest returns the digest of the image a tag in the ImageStream's status points to
+func findImageDigest(is *imagev1.ImageStream, tag string) string {
+	for _, t := range is.Status.Tags {
+		if t.Tag != tag {
+			continue
+		}
+		if len(t.Items) == 0 {
+			return ""
+		}
+		return t.Items[0].Image
+	}
+	return ""
+}
+
 // findDockerImageReference returns DockerImageReference, the string that can be used to pull this image,
 // to a tag if it exists in the ImageStream's Spec
 func findDockerImageReference(is *imagev1.ImageStream, tag string) string {
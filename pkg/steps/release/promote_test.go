@@ -4,16 +4,21 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
 	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
 	"k8s.io/utils/diff"
 
 	imageapi "github.com/openshift/api/image/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	ledgerv1 "github.com/openshift/ci-tools/pkg/api/promotionledger/v1"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
@@ -925,3 +930,71 @@ func TestGetPublicImageReference(t *testing.T) {
 		})
 	}
 }
+
+func TestPromotionRecord(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	metadata := api.Metadata{Org: "openshift", Repo: "installer", Branch: "release-4.14"}
+	jobSpec := &api.JobSpec{JobSpec: downwardapi.JobSpec{
+		Job:       "branch-ci-openshift-installer-release-4.14-images",
+		BuildID:   "1234",
+		ProwJobID: "uuid",
+		Refs:      &prowapi.Refs{Org: "openshift", Repo: "installer", BaseRef: "release-4.14", BaseSHA: "abcdef"},
+	}}
+	pipeline := &imageapi.ImageStream{
+		Status: imageapi.ImageStreamStatus{
+			Tags: []imageapi.NamedTagEventList{
+				{Tag: "installer", Items: []imageapi.TagEvent{{Image: "sha256:installer"}}},
+				{Tag: "tests", Items: []imageapi.TagEvent{{Image: "sha256:tests"}}},
+			},
+		},
+	}
+	var testCases = []struct {
+		name     string
+		tags     map[string][]api.ImageStreamTagReference
+		expected *ledgerv1.PromotionRecord
+	}{
+		{
+			name: "promoted tags are recorded",
+			tags: map[string][]api.ImageStreamTagReference{
+				"tests":     {{Namespace: "ocp", Name: "4.14", Tag: "tests"}},
+				"installer": {{Namespace: "ocp", Name: "4.14", Tag: "installer"}, {Namespace: "ocp", Name: "installer", Tag: "abcdef"}},
+				"missing":   {{Namespace: "ocp", Name: "4.14", Tag: "missing"}},
+			},
+			expected: &ledgerv1.PromotionRecord{
+				ObjectMeta: meta.ObjectMeta{
+					Namespace:    "ci",
+					GenerateName: "promotion-",
+					Labels: map[string]string{
+						"ci.openshift.io/promotion-org":    "openshift",
+						"ci.openshift.io/promotion-repo":   "installer",
+						"ci.openshift.io/promotion-branch": "release-4.14",
+					},
+				},
+				Spec: ledgerv1.PromotionRecordSpec{
+					Time:   meta.NewTime(now),
+					Source: &ledgerv1.PromotionSource{Org: "openshift", Repo: "installer", Branch: "release-4.14", Commit: "abcdef"},
+					Job:    &ledgerv1.PromotionJob{Name: "branch-ci-openshift-installer-release-4.14-images", BuildID: "1234", ProwJobID: "uuid"},
+					Tags: []ledgerv1.PromotedTag{
+						{Namespace: "ocp", Name: "4.14", Tag: "installer", Digest: "sha256:installer", QuayImage: "quay.io/openshift/ci:ocp_4.14_installer"},
+						{Namespace: "ocp", Name: "4.14", Tag: "tests", Digest: "sha256:tests", QuayImage: "quay.io/openshift/ci:ocp_4.14_tests"},
+						{Namespace: "ocp", Name: "installer", Tag: "abcdef", Digest: "sha256:installer", QuayImage: "quay.io/openshift/ci:ocp_installer_abcdef"},
+					},
+				},
+			},
+		},
+		{
+			name: "nothing is recorded without images",
+			tags: map[string][]api.ImageStreamTagReference{
+				"missing": {{Namespace: "ocp", Name: "4.14", Tag: "missing"}},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual := promotionRecord(metadata, jobSpec, testCase.tags, pipeline, now)
+			if diff := cmp.Diff(testCase.expected, actual); diff != "" {
+				t.Errorf("unexpected record: %s", diff)
+			}
+		})
+	}
+}
@@ -79,6 +79,8 @@ func (s *rpmImageInjectionStep) Provides() api.ParameterMap {
 
 func (s *rpmImageInjectionStep) Name() string { return s.config.TargetName() }
 
+func (*rpmImageInjectionStep) Resumable() {}
+
 func (s *rpmImageInjectionStep) Description() string {
 	return "Inject an RPM repository that will point at the RPM server"
 }
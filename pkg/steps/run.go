@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/results"
@@ -17,10 +19,34 @@ type message struct {
 	err             error
 	additionalTests []*junit.TestCase
 	stepDetails     api.CIOperatorStepDetails
+	skipped         bool
 }
 
 func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIOperatorStepDetails, []error) {
+	return RunWithCheckpoint(ctx, graph, nil)
+}
+
+// RunWithCheckpoint executes the graph like Run, recording the resumable steps
+// that complete in the checkpoint, see ResumableStep. Those steps are not run
+// again if the checkpoint reports them as completed by a previous attempt of
+// the same execution and the objects they created still exist; their links are
+// considered created and their dependents are scheduled as usual.
+func RunWithCheckpoint(ctx context.Context, graph api.StepGraph, checkpoint Checkpoint) (*junit.TestSuites, []api.CIOperatorStepDetails, []error) {
 	var seen []api.StepLink
+	completed := loadCompleted(ctx, checkpoint)
+	schedule := func(node *api.StepNode, out chan<- message) {
+		if !completed.Has(node.Step.Name()) || !resumable(node.Step) {
+			go runStep(ctx, node, out)
+			return
+		}
+		go func() {
+			if resume(ctx, checkpoint, node.Step) {
+				skipStep(node, out)
+			} else {
+				runStep(ctx, node, out)
+			}
+		}()
+	}
 	executionResults := make(chan message)
 	done := make(chan bool)
 	ctxDone := ctx.Done()
@@ -34,7 +60,7 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 
 	start := time.Now()
 	for _, root := range graph {
-		go runStep(ctx, root, executionResults)
+		schedule(root, executionResults)
 	}
 
 	suites := &junit.TestSuites{
@@ -57,10 +83,15 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 			testCase := &junit.TestCase{Name: out.node.Step.Description(), Duration: out.duration.Seconds()}
 			stepDetails = append(stepDetails, out.stepDetails)
 			if out.err != nil {
-				testCase.FailureOutput = &junit.FailureOutput{Output: out.err.Error()}
-				executionErrors = append(executionErrors, results.ForReason("step_failed").WithError(out.err).Errorf("step %s failed: %v", out.node.Step.Name(), out.err))
+				testCase.FailureOutput = &junit.FailureOutput{Output: out.err.Error(), Type: string(results.CategoryOf(out.err))}
+				executionErrors = append(executionErrors, results.ForReason("step_failed").WithAttribute(results.AttributeStep, out.node.Step.Name()).WithError(out.err).Errorf("step %s failed: %v", out.node.Step.Name(), out.err))
 			} else {
 				seen = append(seen, out.node.Step.Creates()...)
+				if checkpoint != nil && !out.skipped && resumable(out.node.Step) {
+					if err := checkpoint.Record(ctx, out.node.Step); err != nil {
+						logrus.WithError(err).Warnf("Could not record step %s in the checkpoint.", out.node.Step.Name())
+					}
+				}
 				if !interrupted {
 					for _, child := range out.node.Children {
 						// we can trigger a child if all of it's pre-requisites
@@ -70,7 +101,7 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 						// when the last of its parents finishes.
 						if api.HasAllLinks(child.Step.Requires(), seen) {
 							wg.Add(1)
-							go runStep(ctx, child, executionResults)
+							schedule(child, executionResults)
 						}
 					}
 				}
@@ -151,3 +182,24 @@ func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 		},
 	}
 }
+
+// skipStep reports a step that completed in a previous execution without
+// running it again.
+func skipStep(node *api.StepNode, out chan<- message) {
+	failed := false
+	out <- message{
+		node:    node,
+		skipped: true,
+		additionalTests: []*junit.TestCase{{
+			Name:        node.Step.Description(),
+			SkipMessage: &junit.SkipMessage{Message: "Step completed in a previous execution."},
+		}},
+		stepDetails: api.CIOperatorStepDetails{
+			CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{
+				StepName:    node.Step.Name(),
+				Description: node.Step.Description(),
+				Failed:      &failed,
+			},
+		},
+	}
+}
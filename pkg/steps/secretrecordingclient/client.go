@@ -106,7 +106,7 @@ func (c *client) record(obj ctrlruntimeclient.Object) {
 }
 
 func (c *client) recordSecret(secret *v1.Secret) {
-	c.censor.AddSecrets(valuesToCensor(secret)...)
+	c.censor.AddNamedSecrets(secret.Namespace+"/"+secret.Name, valuesToCensor(secret)...)
 }
 
 func valuesToCensor(secret *v1.Secret) []string {
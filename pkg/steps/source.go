@@ -184,6 +184,15 @@ func (s *sourceStep) run(ctx context.Context) error {
 	return handleBuilds(ctx, s.client, s.podClient, *createBuild(s.config, s.jobSpec, clonerefsRef, s.resources, s.cloneAuthConfig, s.pullSecret, fromDigest))
 }
 
+func (s *sourceStep) Plan() ([]ctrlruntimeclient.Object, error) {
+	clonerefsRef := corev1.ObjectReference{
+		Kind:      "ImageStreamTag",
+		Namespace: s.config.ClonerefsImage.Namespace,
+		Name:      fmt.Sprintf("%s:%s", s.config.ClonerefsImage.Name, s.config.ClonerefsImage.Tag),
+	}
+	return planBuilds(s.client, *createBuild(s.config, s.jobSpec, clonerefsRef, s.resources, s.cloneAuthConfig, s.pullSecret, PlanPlaceholder))
+}
+
 func createBuild(config api.SourceStepConfiguration, jobSpec *api.JobSpec, clonerefsRef corev1.ObjectReference, resources api.ResourceConfiguration, cloneAuthConfig *CloneAuthConfig, pullSecret *corev1.Secret, fromDigest string) *buildapi.Build {
 	var refs []prowv1.Refs
 	if jobSpec.Refs != nil {
@@ -408,7 +417,7 @@ func handleFailedBuild(ctx context.Context, client BuildClient, ns, name string,
 		return err
 	}
 
-	if !(isInfraReason(b.Status.Reason) || hintsAtInfraReason(b.Status.LogSnippet)) {
+	if classification, ok := results.Classify(buildObservation(b)); !ok || (classification.Category != results.CategoryInfra && classification.Category != results.CategoryFlake) {
 		logrus.Debugf("Build %q (created at %v) classified as legitimate failure, will not be retried", name, b.CreationTimestamp)
 		return err
 	}
@@ -441,8 +450,30 @@ func isBuildPhaseTerminated(phase buildapi.BuildPhase) bool {
 }
 
 func handleBuilds(ctx context.Context, buildClient BuildClient, podClient kubernetes.PodClient, build buildapi.Build) error {
+	cache, cacheKey := buildClient.BuildCache(), ""
+	if cache != nil {
+		logger := logrus.WithField("build", build.Name)
+		key, cacheable, err := cache.Key(ctx, &build, buildClient.NodeArchitectures())
+		switch {
+		case err != nil:
+			logger.WithError(err).Warn("Could not determine the build cache key, building without the cache.")
+		case !cacheable:
+			logger.Debug("Build is not cacheable.")
+		default:
+			restored, err := cache.Restore(ctx, key, build.Spec.Output.To)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to restore the build from the cache, building it instead.")
+			} else if restored {
+				logrus.Infof("Reused the result of an identical build of %s from the build cache.", build.Name)
+				return nil
+			}
+			cacheKey = key
+		}
+	}
+
 	var wg sync.WaitGroup
 
+	builder := buildClient.Builder(podClient)
 	builds := constructMultiArchBuilds(build, buildClient.NodeArchitectures())
 	errChan := make(chan error, len(builds))
 
@@ -450,7 +481,7 @@ func handleBuilds(ctx context.Context, buildClient BuildClient, podClient kubern
 	for _, build := range builds {
 		go func(b buildapi.Build) {
 			defer wg.Done()
-			if err := handleBuild(ctx, buildClient, podClient, b); err != nil {
+			if err := builder.Build(ctx, b); err != nil {
 				errChan <- fmt.Errorf("error occurred handling build %s: %w", b.Name, err)
 			}
 		}(build)
@@ -468,6 +499,11 @@ func handleBuilds(ctx context.Context, buildClient BuildClient, podClient kubern
 		manifestPusher := manifestpusher.NewManifestPusher(logrus.WithField("for-build", build.Name), buildClient.LocalRegistryDNS(), buildClient.ManifestToolDockerCfg())
 		if err := manifestPusher.PushImageWithManifest(builds, fmt.Sprintf("%s/%s", build.Spec.Output.To.Namespace, build.Spec.Output.To.Name)); err != nil {
 			errs = append(errs, err)
+		} else if cacheKey != "" {
+			if err := cache.Publish(ctx, cacheKey, build.Spec.Output.To); err != nil {
+				// the build itself succeeded, so there is no reason to fail the step
+				logrus.WithError(err).Warnf("Failed to publish the result of build %s to the build cache.", build.Name)
+			}
 		}
 	}
 
@@ -550,39 +586,9 @@ func waitForBuildDeletion(ctx context.Context, client ctrlruntimeclient.Client,
 	}
 }
 
-func isInfraReason(reason buildapi.StatusReason) bool {
-	infraReasons := []buildapi.StatusReason{
-		buildapi.StatusReasonBuildPodEvicted,
-		buildapi.StatusReasonBuildPodDeleted,
-		buildapi.StatusReasonBuildPodExists,
-		buildapi.StatusReasonCannotCreateBuildPod,
-		buildapi.StatusReasonCannotRetrieveServiceAccount,
-		buildapi.StatusReasonExceededRetryTimeout,
-		buildapi.StatusReasonFailedContainer,
-		buildapi.StatusReasonFetchImageContentFailed,
-		buildapi.StatusReasonFetchSourceFailed,
-		buildapi.StatusReasonGenericBuildFailed,
-		buildapi.StatusReasonNoBuildContainerStatus,
-		buildapi.StatusReasonOutOfMemoryKilled,
-		buildapi.StatusReasonPullBuilderImageFailed,
-		buildapi.StatusReasonPushImageToRegistryFailed,
-	}
-	for _, option := range infraReasons {
-		if reason == option {
-			return true
-		}
-	}
-	return false
-}
-
-func hintsAtInfraReason(logSnippet string) bool {
-	return strings.Contains(logSnippet, "error: build error: no such image") ||
-		strings.Contains(logSnippet, "[Errno 256] No more mirrors to try.") ||
-		strings.Contains(logSnippet, "Error: Failed to synchronize cache for repo") ||
-		strings.Contains(logSnippet, "Could not resolve host: ") ||
-		strings.Contains(logSnippet, "net/http: TLS handshake timeout") ||
-		strings.Contains(logSnippet, "All mirrors were tried") ||
-		strings.Contains(logSnippet, "connection reset by peer")
+// buildObservation describes a failed build for classification
+func buildObservation(build *buildapi.Build) results.Observation {
+	return results.Observation{Kind: results.KindBuild, BuildReason: string(build.Status.Reason), Log: build.Status.LogSnippet}
 }
 
 func waitForBuildOrTimeout(
@@ -665,7 +671,11 @@ func waitForBuild(
 			case buildapi.BuildPhaseFailed, buildapi.BuildPhaseCancelled, buildapi.BuildPhaseError:
 				logrus.Infof("Build %s failed, printing logs:", build.Name)
 				printBuildLogs(buildClient, build.Namespace, build.Name)
-				return true, util.AppendLogToError(fmt.Errorf("the build %s failed after %s with reason %s: %s", build.Name, buildDuration(build).Truncate(time.Second), build.Status.Reason, build.Status.Message), build.Status.LogSnippet)
+				err := util.AppendLogToError(fmt.Errorf("the build %s failed after %s with reason %s: %s", build.Name, buildDuration(build).Truncate(time.Second), build.Status.Reason, build.Status.Message), build.Status.LogSnippet)
+				if classification, ok := results.Classify(buildObservation(build)); ok {
+					err = classification.Attach(err)
+				}
+				return true, err
 			}
 			return false, nil
 		}, 0)
@@ -756,6 +766,8 @@ func (s *sourceStep) Provides() api.ParameterMap {
 
 func (s *sourceStep) Name() string { return s.config.TargetName() }
 
+func (*sourceStep) Resumable() {}
+
 func (s *sourceStep) Description() string {
 	return fmt.Sprintf("Clone the correct source code into an image and tag it as %s", s.config.To)
 }
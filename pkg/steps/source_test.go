@@ -25,6 +25,8 @@ import (
 	buildv1 "github.com/openshift/api/build/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/buildcache"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
@@ -456,7 +458,7 @@ func TestWaitForBuild(t *testing.T) {
 							CompletionTimestamp: &end,
 						},
 					},
-				).Build()), nil, nil, "", ""),
+				).Build()), nil, nil, "", "", nil, nil),
 			expected: fmt.Errorf("build didn't start running within 0s (phase: Pending)"),
 		},
 		{
@@ -485,7 +487,7 @@ func TestWaitForBuild(t *testing.T) {
 							Namespace: ns,
 						},
 					},
-				).Build()), nil, nil, "", ""),
+				).Build()), nil, nil, "", "", nil, nil),
 			expected: fmt.Errorf("build didn't start running within 0s (phase: Pending):\nFound 0 events for Pod some-build-build:"),
 		},
 		{
@@ -526,7 +528,7 @@ func TestWaitForBuild(t *testing.T) {
 							}},
 						},
 					},
-				).Build()), nil, nil, "", ""),
+				).Build()), nil, nil, "", "", nil, nil),
 			expected: fmt.Errorf(`build didn't start running within 0s (phase: Pending):
 * Container the-container is not ready with reason the_reason and message the_message
 Found 0 events for Pod some-build-build:`),
@@ -545,7 +547,7 @@ Found 0 events for Pod some-build-build:`),
 						StartTimestamp:      &start,
 						CompletionTimestamp: &end,
 					},
-				}).Build()), nil, nil, "", ""),
+				}).Build()), nil, nil, "", "", nil, nil),
 			timeout: 30 * time.Minute,
 		},
 		{
@@ -589,7 +591,7 @@ Found 0 events for Pod some-build-build:`),
 							Time: now.Add(-59 * time.Minute),
 						},
 					},
-				}).Build()), nil, nil, "", ""),
+				}).Build()), nil, nil, "", "", nil, nil),
 			timeout: 30 * time.Minute,
 		},
 		{
@@ -772,6 +774,14 @@ func (c *fakeBuildClient) LocalRegistryDNS() string {
 	return ""
 }
 
+func (c *fakeBuildClient) BuildCache() *buildcache.Cache {
+	return nil
+}
+
+func (c *fakeBuildClient) Builder(podClient kubernetes.PodClient) ImageBuilder {
+	return &openShiftBuilder{client: c, podClient: podClient}
+}
+
 func Test_constructMultiArchBuilds(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -26,6 +26,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
 	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
 	"github.com/openshift/ci-tools/pkg/steps/utils"
 	"github.com/openshift/ci-tools/pkg/util"
@@ -59,6 +60,7 @@ type templateExecutionStep struct {
 	podClient kubernetes.PodClient
 	client    TemplateClient
 	jobSpec   *api.JobSpec
+	censor    *secrets.DynamicCensor
 
 	subTests []*junit.TestCase
 }
@@ -146,7 +148,7 @@ func (s *templateExecutionStep) run(ctx context.Context) error {
 	// now that the pods have been resolved by the template, add them to the artifact map
 	var notifier util.ContainerNotifier = util.NopNotifier
 	if artifactDir, artifactsRequested := api.Artifacts(); artifactsRequested {
-		artifacts := NewArtifactWorker(s.podClient, filepath.Join(artifactDir, s.template.Name), s.jobSpec.Namespace())
+		artifacts := NewArtifactWorker(s.podClient, s.censor, filepath.Join(artifactDir, s.template.Name), s.jobSpec.Namespace())
 		for _, ref := range instance.Status.Objects {
 			switch {
 			case ref.Ref.Kind == "Pod" && ref.Ref.APIVersion == "v1":
@@ -278,7 +280,7 @@ func (s *templateExecutionStep) Objects() []ctrlruntimeclient.Object {
 	return s.client.Objects()
 }
 
-func TemplateExecutionStep(template *templateapi.Template, params api.Parameters, podClient kubernetes.PodClient, templateClient TemplateClient, jobSpec *api.JobSpec, resources api.ResourceConfiguration) api.Step {
+func TemplateExecutionStep(template *templateapi.Template, params api.Parameters, podClient kubernetes.PodClient, templateClient TemplateClient, jobSpec *api.JobSpec, resources api.ResourceConfiguration, censor *secrets.DynamicCensor) api.Step {
 	return &templateExecutionStep{
 		template:  template,
 		resources: resources,
@@ -286,6 +288,7 @@ func TemplateExecutionStep(template *templateapi.Template, params api.Parameters
 		podClient: podClient,
 		client:    templateClient,
 		jobSpec:   jobSpec,
+		censor:    censor,
 	}
 }
 
@@ -94,7 +94,10 @@ func NewPodPayloadRunner(payload PodPayload, env PodRunnerEnv) *PodPayloadRunner
 type FakePodExecutor struct {
 	Lock sync.RWMutex
 	loggingclient.LoggingClient
-	Failures          sets.Set[string]
+	Failures sets.Set[string]
+	// Flakes maps the names of pods to the number of times they fail before
+	// they succeed when they are created again
+	Flakes            map[string]int
 	CreatedPods       []*coreapi.Pod
 	PodPayloadRunners map[string]*PodPayloadRunner
 }
@@ -155,6 +158,9 @@ func (f *FakePodExecutor) process(pod *coreapi.Pod, ch chan watch.Event) bool {
 	}
 
 	fail := f.Failures.Has(pod.Name)
+	if flakes, ok := f.Flakes[pod.Name]; ok {
+		fail = f.created(pod.Name) <= flakes
+	}
 	if fail {
 		pod.Status.Phase = coreapi.PodFailed
 	} else {
@@ -172,6 +178,17 @@ func (f *FakePodExecutor) process(pod *coreapi.Pod, ch chan watch.Event) bool {
 	return true
 }
 
+// created returns how many times a pod with the name was created
+func (f *FakePodExecutor) created(name string) int {
+	var n int
+	for _, pod := range f.CreatedPods {
+		if pod.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
 // The fake client version we use (v0.12.3) does not implement field selectors.
 func filter(list ctrlruntimeclient.ObjectList, opts ...ctrlruntimeclient.ListOption) {
 	var o ctrlruntimeclient.ListOptions
@@ -212,11 +212,30 @@ func processPodEvent(
 		return true, nil
 	}
 	if podJobIsFailed(pod) {
-		return true, AppendLogToError(fmt.Errorf("the pod %s/%s failed after %s (failed containers: %s): %s", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", "), podReason(pod)), podMessages(pod))
+		err := AppendLogToError(fmt.Errorf("the pod %s/%s failed after %s (failed containers: %s): %s", pod.Namespace, pod.Name, podDuration(pod).Truncate(time.Second), strings.Join(failedContainerNames(pod), ", "), podReason(pod)), podMessages(pod))
+		if classification, ok := results.Classify(podObservation(pod)); ok {
+			err = classification.Attach(err)
+		}
+		return true, err
 	}
 	return false, nil
 }
 
+// podObservation describes a failed pod for classification
+func podObservation(pod *corev1.Pod) results.Observation {
+	observation := results.Observation{Kind: results.KindPod, Log: podMessages(pod)}
+	if pod.Status.Reason != "" {
+		observation.PodReasons = append(observation.PodReasons, pod.Status.Reason)
+	}
+	for _, status := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if state := status.State.Terminated; state != nil && state.ExitCode != 0 {
+			observation.PodReasons = append(observation.PodReasons, state.Reason)
+			observation.ExitCodes = append(observation.ExitCodes, state.ExitCode)
+		}
+	}
+	return observation
+}
+
 // podReason returns the pod's reason and message for exit or tries to find one from the pod.
 func podReason(pod *corev1.Pod) string {
 	reason := pod.Status.Reason
@@ -5,9 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
@@ -235,3 +238,25 @@ func TestCheckPending(t *testing.T) {
 		})
 	}
 }
+
+func TestPodObservation(t *testing.T) {
+	pod := &corev1.Pod{Status: corev1.PodStatus{
+		Reason: "Evicted",
+		InitContainerStatuses: []corev1.ContainerStatus{
+			{Name: "cp-secret-wrapper", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}}},
+		},
+		ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "test", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}}},
+			{Name: "sidecar", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		},
+	}}
+	expected := results.Observation{
+		Kind:       results.KindPod,
+		PodReasons: []string{"Evicted", "OOMKilled"},
+		ExitCodes:  []int32{137},
+		Log:        "Container test exited with code 137, reason OOMKilled",
+	}
+	if diff := cmp.Diff(expected, podObservation(pod)); diff != "" {
+		t.Errorf("unexpected observation: %s", diff)
+	}
+}
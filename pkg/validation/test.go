@@ -565,6 +565,7 @@ func (v *Validator) validateTestConfigurationType(
 		}
 		context := newContext(fieldPath(fieldRoot), testConfig.Environment, releases, inputImagesSeen)
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateRetryPolicy(context.addField("retries"), testConfig.Retries)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("pre"), testStagePre, testConfig.Pre, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("test"), testStageTest, testConfig.Test, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("post"), testStagePost, testConfig.Post, claimRelease)...)
@@ -577,6 +578,7 @@ func (v *Validator) validateTestConfigurationType(
 			validationErrors = append(validationErrors, v.validateClusterProfile(fieldRoot, testConfig.ClusterProfile, metadata)...)
 		}
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateRetryPolicy(context.addField("retries"), testConfig.Retries)...)
 		for i, s := range testConfig.Pre {
 			validationErrors = append(validationErrors, v.validateLiteralTestStep(context.addField("pre").addIndex(i), testStagePre, s, claimRelease)...)
 		}
@@ -680,6 +682,7 @@ func (v *Validator) validateLiteralTestStep(context *context, stage testStage, s
 	}
 	ret = append(ret, validateDependencies(string(context.field), step.Dependencies)...)
 	ret = append(ret, validateLeases(context.addField("leases"), step.Leases)...)
+	ret = append(ret, validateRetryPolicy(context.addField("retries"), step.Retries)...)
 	switch stage {
 	case testStagePre, testStageTest:
 		if step.OptionalOnSuccess != nil {
@@ -853,6 +856,25 @@ func validateDNSConfig(fieldRoot string, dnsConfig []api.StepDNSConfig) (ret []e
 	return errs
 }
 
+// maxRetryAttempts limits how many times a single step can be retried, so
+// that a consistently failing step cannot hold a test for too long.
+const maxRetryAttempts = 5
+
+func validateRetryPolicy(context *context, policy *api.RetryPolicy) (ret []error) {
+	if policy == nil {
+		return nil
+	}
+	if policy.Attempts == 0 {
+		ret = append(ret, context.errorf("'attempts' must be positive"))
+	} else if policy.Attempts > maxRetryAttempts {
+		ret = append(ret, context.errorf("'attempts' cannot be larger than %d", maxRetryAttempts))
+	}
+	if policy.Backoff != nil && policy.Backoff.Duration < 0 {
+		ret = append(ret, context.errorf("'backoff' cannot be negative"))
+	}
+	return
+}
+
 func validateLeases(context *context, leases []api.StepLease) (ret []error) {
 	for i, l := range leases {
 		if l.ResourceType == "" {
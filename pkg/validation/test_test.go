@@ -1413,6 +1413,57 @@ func TestValidateLeases(t *testing.T) {
 	}
 }
 
+func TestValidateRetryPolicy(t *testing.T) {
+	step := api.LiteralTestStep{
+		As:       "as",
+		From:     "from",
+		Commands: "commands",
+		Resources: api.ResourceRequirements{
+			Requests: api.ResourceList{"cpu": "1"},
+			Limits:   api.ResourceList{"memory": "1m"},
+		},
+	}
+	for _, tc := range []struct {
+		name    string
+		retries *api.RetryPolicy
+		step    *api.RetryPolicy
+		err     []error
+	}{{
+		name:    "valid retries",
+		retries: &api.RetryPolicy{Attempts: 2, Backoff: &prowv1.Duration{Duration: time.Minute}},
+		step:    &api.RetryPolicy{Attempts: 5},
+	}, {
+		name:    "invalid zero attempts",
+		retries: &api.RetryPolicy{},
+		err: []error{
+			errors.New("tests[0].steps.retries: 'attempts' must be positive"),
+		},
+	}, {
+		name: "invalid step retries",
+		step: &api.RetryPolicy{Attempts: 6, Backoff: &prowv1.Duration{Duration: -time.Minute}},
+		err: []error{
+			errors.New("tests[0].steps.test[0].retries: 'attempts' cannot be larger than 5"),
+			errors.New("tests[0].steps.test[0].retries: 'backoff' cannot be negative"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := step
+			s.Retries = tc.step
+			test := api.TestStepConfiguration{
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Retries: tc.retries,
+					Test:    []api.LiteralTestStep{s},
+				},
+			}
+			v := NewValidator(nil)
+			err := v.validateTestConfigurationType("tests[0]", test, nil, nil, nil, make(testInputImages), true)
+			if diff := diff.ObjectReflectDiff(tc.err, err); diff != "<no diffs>" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
 func TestValidateTestConfigurationType(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
@@ -0,0 +1,453 @@
+package webreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	htmlformatter "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+)
+
+const (
+	referenceType         = "reference"
+	chainType             = "chain"
+	workflowComponentType = "workflow"
+
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeModified = "modified"
+)
+
+const historyPage = `
+<h2 id="title"><a href="#title">Registry History</a></h2>
+<p>The registry is reloaded whenever it changes. The last {{ len .Generations }} generations loaded by this server are retained.</p>
+<form class="form-inline" action="/history" method="get">
+  <label class="mr-sm-2" for="from">Compare generation</label>
+  <select class="form-control mr-sm-2" id="from" name="from">
+  {{ range .Generations }}<option value="{{ .Generation }}"{{ if eq .Generation $.From }} selected{{ end }}>{{ .Generation }} (loaded {{ formatTime .Loaded }})</option>{{ end }}
+  </select>
+  <label class="mr-sm-2" for="to">to</label>
+  <select class="form-control mr-sm-2" id="to" name="to">
+  {{ range .Generations }}<option value="{{ .Generation }}"{{ if eq .Generation $.To }} selected{{ end }}>{{ .Generation }} (loaded {{ formatTime .Loaded }})</option>{{ end }}
+  </select>
+  <button class="btn btn-outline-success" type="submit">Compare</button>
+</form>
+{{ with .Diff }}
+<h3 id="components"><a href="#components">Changed Components</a></h3>
+{{ if .Components }}
+<table class="table">
+  <thead><tr><th title="The type of the component" class="info">Type</th><th title="The name of the component" class="info">Name</th><th title="How the component changed" class="info">Change</th></tr></thead>
+  <tbody>
+  {{ range .Components }}<tr><td>{{ .Type }}</td><td><a href="#{{ .Type }}-{{ .Name }}" style="font-family:monospace">{{ .Name }}</a></td><td>{{ .Change }}</td></tr>{{ end }}
+  </tbody>
+</table>
+{{ range .Components }}
+<h4 id="{{ .Type }}-{{ .Name }}"><a href="#{{ .Type }}-{{ .Name }}">{{ .Type }}:</a> <a href="/{{ .Type }}/{{ .Name }}" style="font-family:monospace">{{ .Name }}</a> ({{ .Change }})</h4>
+{{ syntaxedDiff .Diff }}
+{{ end }}
+{{ else }}
+<p>No components changed between these generations.</p>
+{{ end }}
+<h3 id="tests" title="Tests whose resolved configuration changed, grouped by the change"><a href="#tests">Affected Tests</a></h3>
+{{ if .Tests }}
+{{ range $i, $group := .Tests }}
+<details>
+<summary>{{ len $group.Tests }} test(s): {{ range $j, $test := $group.Tests }}{{ if $j }}, {{ end }}<a href="/job?org={{ $test.Metadata.Org }}&repo={{ $test.Metadata.Repo }}&branch={{ $test.Metadata.Branch }}{{ if $test.Metadata.Variant }}&variant={{ $test.Metadata.Variant }}{{ end }}&test={{ $test.Test }}" style="font-family:monospace">{{ $test.Name }}</a>{{ end }}</summary>
+{{ syntaxedDiff $group.Diff }}
+</details>
+{{ end }}
+{{ else }}
+<p>No resolved test configurations changed between these generations.</p>
+{{ end }}
+{{ end }}
+`
+
+const componentHistoryPage = `
+<h2 id="title"><a href="#title">History of {{ .Type }}:</a> <a href="/{{ .Type }}/{{ .Name }}" style="font-family:monospace">{{ .Name }}</a></h2>
+{{ if .Changes }}
+<table class="table">
+  <thead><tr><th title="The generation of the registry in which the change was loaded" class="info">Generation</th><th title="When the generation was loaded" class="info">Loaded</th><th title="How the component changed" class="info">Change</th><th class="info"></th></tr></thead>
+  <tbody>
+  {{ range .Changes }}<tr><td>{{ .To }}</td><td>{{ formatTime .Loaded }}</td><td>{{ .Change }}</td><td><a href="/history?from={{ .From }}&to={{ .To }}#{{ $.Type }}-{{ $.Name }}">Show diff</a></td></tr>{{ end }}
+  </tbody>
+</table>
+{{ else }}
+<p>The {{ .Type }} did not change in the {{ .Generations }} generations of the registry loaded by this server.</p>
+{{ end }}
+`
+
+// generation identifies a retained snapshot of the registry
+type generation struct {
+	Generation int
+	Loaded     time.Time
+}
+
+// componentChange is a generation of the registry in which a component
+// changed, compared to the previous one
+type componentChange struct {
+	From, To int
+	Loaded   time.Time
+	Change   string
+}
+
+// componentDiff is a component that changed between two generations
+type componentDiff struct {
+	Type, Name, Change string
+	Diff               string
+}
+
+// affectedTest is a test whose resolved configuration changed
+type affectedTest struct {
+	Metadata api.Metadata
+	Test     string
+}
+
+func (t affectedTest) Name() string {
+	return fmt.Sprintf("%s:%s", t.Metadata.AsString(), t.Test)
+}
+
+// testDiffGroup holds the tests whose resolved configuration changed in the
+// same way, as a change in a shared component usually affects many tests
+// identically
+type testDiffGroup struct {
+	Diff  string
+	Tests []affectedTest
+}
+
+// registryDiff is the difference between two generations of the registry
+type registryDiff struct {
+	Components []componentDiff
+	Tests      []testDiffGroup
+}
+
+func componentIn(snapshot agents.RegistrySnapshot, componentType, name string) (interface{}, bool) {
+	var component interface{}
+	var ok bool
+	switch componentType {
+	case referenceType:
+		component, ok = snapshot.References[name]
+	case chainType:
+		component, ok = snapshot.Chains[name]
+	case workflowComponentType:
+		component, ok = snapshot.Workflows[name]
+	}
+	return component, ok
+}
+
+func changeBetween(from, to agents.RegistrySnapshot, componentType, name string) (string, interface{}, interface{}) {
+	before, inFrom := componentIn(from, componentType, name)
+	after, inTo := componentIn(to, componentType, name)
+	switch {
+	case !inFrom && inTo:
+		return changeAdded, nil, after
+	case inFrom && !inTo:
+		return changeRemoved, before, nil
+	case inFrom && inTo && !reflect.DeepEqual(before, after):
+		return changeModified, before, after
+	}
+	return "", nil, nil
+}
+
+// componentChanges lists the generations in which the component changed,
+// newest first
+func componentChanges(history []agents.RegistrySnapshot, componentType, name string) []componentChange {
+	var changes []componentChange
+	for i := len(history) - 1; i > 0; i-- {
+		if change, _, _ := changeBetween(history[i-1], history[i], componentType, name); change != "" {
+			changes = append(changes, componentChange{From: history[i-1].Generation, To: history[i].Generation, Loaded: history[i].Loaded, Change: change})
+		}
+	}
+	return changes
+}
+
+// yamlDiff renders a unified diff between the YAML serializations of two
+// objects, either of which may be nil
+func yamlDiff(before, after interface{}) (string, error) {
+	serialize := func(obj interface{}) (string, error) {
+		if obj == nil {
+			return "", nil
+		}
+		raw, err := yaml.Marshal(obj)
+		return string(raw), err
+	}
+	a, err := serialize(before)
+	if err != nil {
+		return "", err
+	}
+	b, err := serialize(after)
+	if err != nil {
+		return "", err
+	}
+	// unlike difflib.SplitLines, this does not add an empty line at the end
+	splitLines := func(s string) []string {
+		lines := strings.SplitAfter(s, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return lines
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(a),
+		B:        splitLines(b),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  3,
+	})
+}
+
+// diffRegistry determines the components that changed between two snapshots
+// and how the resolved configurations of the tests using them changed
+func diffRegistry(from, to agents.RegistrySnapshot, configs config.ByOrgRepo) (registryDiff, error) {
+	var diff registryDiff
+	changed := map[string]sets.Set[string]{}
+	for _, componentType := range []string{workflowComponentType, chainType, referenceType} {
+		names := sets.New[string]()
+		for _, snapshot := range []agents.RegistrySnapshot{from, to} {
+			switch componentType {
+			case referenceType:
+				names.Insert(sets.KeySet(snapshot.References).UnsortedList()...)
+			case chainType:
+				names.Insert(sets.KeySet(snapshot.Chains).UnsortedList()...)
+			case workflowComponentType:
+				names.Insert(sets.KeySet(snapshot.Workflows).UnsortedList()...)
+			}
+		}
+		changed[componentType] = sets.New[string]()
+		for _, name := range sets.List(names) {
+			change, before, after := changeBetween(from, to, componentType, name)
+			if change == "" {
+				continue
+			}
+			changed[componentType].Insert(name)
+			rendered, err := yamlDiff(before, after)
+			if err != nil {
+				return diff, fmt.Errorf("failed to render the diff of %s %s: %w", componentType, name, err)
+			}
+			diff.Components = append(diff.Components, componentDiff{Type: componentType, Name: name, Change: change, Diff: rendered})
+		}
+	}
+	if len(diff.Components) == 0 {
+		return diff, nil
+	}
+
+	groups := map[string][]affectedTest{}
+	for _, repos := range configs {
+		for _, repoConfigs := range repos {
+			for _, c := range repoConfigs {
+				for _, test := range c.Tests {
+					if test.MultiStageTestConfiguration == nil || !affected(*test.MultiStageTestConfiguration, from, to, changed) {
+						continue
+					}
+					before := resolvedOrError(from.Resolver, test.As, *test.MultiStageTestConfiguration)
+					after := resolvedOrError(to.Resolver, test.As, *test.MultiStageTestConfiguration)
+					if reflect.DeepEqual(before, after) {
+						continue
+					}
+					rendered, err := yamlDiff(before, after)
+					if err != nil {
+						return diff, fmt.Errorf("failed to render the diff of test %s in %s: %w", test.As, c.Metadata.AsString(), err)
+					}
+					groups[rendered] = append(groups[rendered], affectedTest{Metadata: c.Metadata, Test: test.As})
+				}
+			}
+		}
+	}
+	for rendered, tests := range groups {
+		sort.Slice(tests, func(i, j int) bool {
+			if tests[i].Metadata.AsString() != tests[j].Metadata.AsString() {
+				return tests[i].Metadata.AsString() < tests[j].Metadata.AsString()
+			}
+			return tests[i].Test < tests[j].Test
+		})
+		diff.Tests = append(diff.Tests, testDiffGroup{Diff: rendered, Tests: tests})
+	}
+	// the changes affecting the most tests come first
+	sort.Slice(diff.Tests, func(i, j int) bool {
+		if len(diff.Tests[i].Tests) != len(diff.Tests[j].Tests) {
+			return len(diff.Tests[i].Tests) > len(diff.Tests[j].Tests)
+		}
+		return diff.Tests[i].Diff < diff.Tests[j].Diff
+	})
+	return diff, nil
+}
+
+// affected determines if the test uses any of the changed components in
+// either snapshot
+func affected(test api.MultiStageTestConfiguration, from, to agents.RegistrySnapshot, changed map[string]sets.Set[string]) bool {
+	if test.Workflow != nil && changed[workflowComponentType].Has(*test.Workflow) {
+		return true
+	}
+	for _, snapshot := range []agents.RegistrySnapshot{from, to} {
+		used := registry.UsedBy(test, snapshot.Chains, snapshot.Workflows)
+		if used.Steps.HasAny(sets.List(changed[referenceType])...) || used.Chains.HasAny(sets.List(changed[chainType])...) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvedOrError resolves the test, representing a failure to resolve by
+// the error so that it shows up in the diff
+func resolvedOrError(resolver registry.Resolver, name string, test api.MultiStageTestConfiguration) interface{} {
+	resolved, err := resolver.Resolve(name, test)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return resolved
+}
+
+func syntaxDiff(source string) (string, error) {
+	var output bytes.Buffer
+	style := styles.Get("dracula")
+	formatter := htmlformatter.New(htmlformatter.Standalone(false), htmlformatter.WithClasses(true))
+	iterator, err := lexers.Get("diff").Tokenise(nil, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to tokenise diff: %w", err)
+	}
+	output.WriteString("<style>")
+	if err := formatter.WriteCSS(&output, style); err != nil {
+		return "", fmt.Errorf("failed to write css: %w", err)
+	}
+	output.WriteString("</style>")
+	if err := formatter.Format(&output, style, iterator); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+func historyFuncs() template.FuncMap {
+	return template.FuncMap{
+		"syntaxedDiff": func(source string) template.HTML {
+			formatted, err := syntaxDiff(source)
+			if err != nil {
+				logrus.Errorf("Failed to format diff: %v", err)
+				return template.HTML(template.HTMLEscapeString(source))
+			}
+			return template.HTML(formatted)
+		},
+		"formatTime": func(t time.Time) string {
+			return t.UTC().Format(time.RFC3339)
+		},
+	}
+}
+
+func snapshotFor(history []agents.RegistrySnapshot, raw string) (agents.RegistrySnapshot, error) {
+	number, err := strconv.Atoi(raw)
+	if err != nil {
+		return agents.RegistrySnapshot{}, fmt.Errorf("invalid generation %q", raw)
+	}
+	for _, snapshot := range history {
+		if snapshot.Generation == number {
+			return snapshot, nil
+		}
+	}
+	return agents.RegistrySnapshot{}, fmt.Errorf("generation %d is not retained", number)
+}
+
+func historyHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent, w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	defer func() { logrus.Infof("rendered in %s", time.Since(start)) }()
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	history := regAgent.GetHistory()
+	if len(history) == 0 {
+		writeErrorPage(w, errors.New("No registry has been loaded"), http.StatusInternalServerError)
+		return
+	}
+	page, err := baseTemplate.Clone()
+	if err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	if page, err = page.Funcs(historyFuncs()).Parse(historyPage); err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		Generations []generation
+		From, To    int
+		Diff        *registryDiff
+	}{}
+	for i := len(history) - 1; i >= 0; i-- {
+		data.Generations = append(data.Generations, generation{Generation: history[i].Generation, Loaded: history[i].Loaded})
+	}
+	data.To = history[len(history)-1].Generation
+	data.From = history[0].Generation
+	if len(history) > 1 {
+		data.From = history[len(history)-2].Generation
+	}
+
+	query := req.URL.Query()
+	if query.Get("from") != "" || query.Get("to") != "" {
+		from, err := snapshotFor(history, query.Get("from"))
+		if err != nil {
+			writeErrorPage(w, err, http.StatusNotFound)
+			return
+		}
+		to, err := snapshotFor(history, query.Get("to"))
+		if err != nil {
+			writeErrorPage(w, err, http.StatusNotFound)
+			return
+		}
+		diff, err := diffRegistry(from, to, confAgent.GetAll())
+		if err != nil {
+			writeErrorPage(w, fmt.Errorf("Failed to compare generations: %w", err), http.StatusInternalServerError)
+			return
+		}
+		data.From, data.To, data.Diff = from.Generation, to.Generation, &diff
+	}
+	writePage(w, "Registry History", page, data)
+}
+
+func componentHistoryHandler(regAgent agents.RegistryAgent, componentType string, w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	defer func() { logrus.Infof("rendered in %s", time.Since(start)) }()
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	switch componentType {
+	case referenceType, chainType, workflowComponentType:
+	default:
+		writeErrorPage(w, fmt.Errorf("Component type %s not found", componentType), http.StatusNotFound)
+		return
+	}
+	name := path.Base(req.URL.Path)
+	page, err := baseTemplate.Clone()
+	if err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	if page, err = page.Funcs(historyFuncs()).Parse(componentHistoryPage); err != nil {
+		writeErrorPage(w, fmt.Errorf("Failed to render page: %w", err), http.StatusInternalServerError)
+		return
+	}
+	history := regAgent.GetHistory()
+	data := struct {
+		Type, Name  string
+		Generations int
+		Changes     []componentChange
+	}{
+		Type:        componentType,
+		Name:        name,
+		Generations: len(history),
+		Changes:     componentChanges(history, componentType, name),
+	}
+	writePage(w, "Registry Component History", page, data)
+}
@@ -0,0 +1,131 @@
+package webreg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/load/agents"
+	"github.com/openshift/ci-tools/pkg/registry"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func snapshot(generation int, references registry.ReferenceByName, chains registry.ChainByName, workflows registry.WorkflowByName) agents.RegistrySnapshot {
+	return agents.RegistrySnapshot{
+		Generation: generation,
+		Loaded:     time.Date(2023, 1, 1, generation, 0, 0, 0, time.UTC),
+		References: references,
+		Chains:     chains,
+		Workflows:  workflows,
+		Resolver:   registry.NewResolver(references, chains, workflows, nil),
+	}
+}
+
+func historyFixture() []agents.RegistrySnapshot {
+	ref := func(name string) api.TestStep {
+		return api.TestStep{Reference: &name}
+	}
+	chain := func(name string) api.TestStep {
+		return api.TestStep{Chain: &name}
+	}
+	step := func(name, commands string) api.LiteralTestStep {
+		return api.LiteralTestStep{As: name, From: "src", Commands: commands, Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m"}}}
+	}
+	references := registry.ReferenceByName{
+		"install": step("install", "install"),
+		"test":    step("test", "test"),
+	}
+	chains := registry.ChainByName{"setup": {As: "setup", Steps: []api.TestStep{ref("install")}}}
+	workflows := registry.WorkflowByName{"e2e": {Pre: []api.TestStep{chain("setup")}, Test: []api.TestStep{ref("test")}}}
+
+	changedReferences := registry.ReferenceByName{
+		"install":   step("install", "install --verbose"),
+		"test":      step("test", "test"),
+		"configure": step("configure", "configure"),
+	}
+	changedChains := registry.ChainByName{"setup": {As: "setup", Steps: []api.TestStep{ref("install"), ref("configure")}}}
+
+	return []agents.RegistrySnapshot{
+		snapshot(1, references, chains, workflows),
+		snapshot(2, references, chains, workflows),
+		snapshot(3, changedReferences, changedChains, workflows),
+	}
+}
+
+func TestComponentChanges(t *testing.T) {
+	history := historyFixture()
+	for _, tc := range []struct {
+		name, componentType, component string
+		expected                       []componentChange
+	}{{
+		name:          "modified reference",
+		componentType: referenceType,
+		component:     "install",
+		expected:      []componentChange{{From: 2, To: 3, Loaded: history[2].Loaded, Change: changeModified}},
+	}, {
+		name:          "added reference",
+		componentType: referenceType,
+		component:     "configure",
+		expected:      []componentChange{{From: 2, To: 3, Loaded: history[2].Loaded, Change: changeAdded}},
+	}, {
+		name:          "modified chain",
+		componentType: chainType,
+		component:     "setup",
+		expected:      []componentChange{{From: 2, To: 3, Loaded: history[2].Loaded, Change: changeModified}},
+	}, {
+		name:          "unchanged workflow",
+		componentType: workflowComponentType,
+		component:     "e2e",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, componentChanges(history, tc.componentType, tc.component)); diff != "" {
+				t.Errorf("unexpected changes: %s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffRegistry(t *testing.T) {
+	history := historyFixture()
+	workflow := "e2e"
+	e2e := api.TestStepConfiguration{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Workflow: &workflow}}
+	configs := config.ByOrgRepo{
+		"org": {
+			"repo": {{
+				Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "master"},
+				Tests: []api.TestStepConfiguration{
+					e2e,
+					{
+						As: "overrides-pre",
+						MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+							Workflow: &workflow,
+							Pre:      []api.TestStep{},
+						},
+					},
+					{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+				},
+			}},
+			"other": {{
+				Metadata: api.Metadata{Org: "org", Repo: "other", Branch: "master"},
+				Tests:    []api.TestStepConfiguration{e2e},
+			}},
+		},
+	}
+
+	unchanged, err := diffRegistry(history[0], history[1], configs)
+	if err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	if diff := cmp.Diff(registryDiff{}, unchanged); diff != "" {
+		t.Errorf("expected no changes between identical generations: %s", diff)
+	}
+
+	changed, err := diffRegistry(history[1], history[2], configs)
+	if err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	testhelper.CompareWithFixture(t, changed)
+}
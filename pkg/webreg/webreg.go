@@ -70,6 +70,9 @@ const bodyStart = `
       <li class="nav-item">
         <a class="nav-link" href="/search">Jobs</a>
       </li>
+      <li class="nav-item">
+        <a class="nav-link" href="/history">History</a>
+      </li>
       <li class="nav-item">
         <a class="nav-link" href="http://docs.ci.openshift.org">Help</a>
       </li>
@@ -113,6 +116,7 @@ const referencePage = `
 {{ template "referenceProperties" .Reference }}
 <h3 id="github"><p><a href="#github">GitHub Link:</a></h3></p>{{ githubLink .Metadata.Path }}
 {{ ownersBlock .Metadata.Owners }}
+<h3 id="history"><a href="#history">History:</a></h3><a href="/history/reference/{{ .Reference.As }}">Changes to this step</a>
 `
 
 const chainPage = `
@@ -129,6 +133,7 @@ const chainPage = `
 {{ chainGraph .Chain.As }}
 <h3 id="github"><a href="#github">GitHub Link:</a></h3>{{ githubLink .Metadata.Path }}
 {{ ownersBlock .Metadata.Owners }}
+<h3 id="history"><a href="#history">History:</a></h3><a href="/history/chain/{{ .Chain.As }}">Changes to this chain</a>
 `
 
 // workflowJobPage defines the template for both jobs and workflows
@@ -157,6 +162,7 @@ const workflowJobPage = `
 {{ if eq $type "Workflow" }}
 <h3 id="github"><a href="#github">GitHub Link:</a></h3>{{ githubLink .Metadata.Path }}
 {{ ownersBlock .Metadata.Owners }}
+<h3 id="history"><a href="#history">History:</a></h3><a href="/history/workflow/{{ .Workflow.As }}">Changes to this workflow</a>
 {{ end }}
 `
 
@@ -1082,6 +1088,8 @@ func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent)
 				jobHandler(regAgent, confAgent, w, req)
 			case "ci-operator-reference":
 				ciOpConfigRefHandler(w)
+			case "history":
+				historyHandler(regAgent, confAgent, w, req)
 			default:
 				writeErrorPage(w, errors.New("Invalid path"), http.StatusNotImplemented)
 			}
@@ -1101,6 +1109,9 @@ func WebRegHandler(regAgent agents.RegistryAgent, confAgent agents.ConfigAgent)
 				writeErrorPage(w, fmt.Errorf("Component type %s not found", splitURI[0]), http.StatusNotFound)
 				return
 			}
+		} else if len(splitURI) == 3 && splitURI[0] == "history" {
+			componentHistoryHandler(regAgent, splitURI[1], w, req)
+			return
 		}
 		writeErrorPage(w, errors.New("Invalid path"), http.StatusNotImplemented)
 	}
@@ -752,6 +752,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
+	"                  # Retries defines how many times the step should be retried when it\n" +
+	"                  # fails. Overrides the policy set for the whole test, if any.\n" +
+	"                  retries:\n" +
+	"                    # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                    attempts: 0\n" +
+	"                    # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                    # doubled for every subsequent retry.\n" +
+	"                    backoff: 0s\n" +
 	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"                  # in the test container instead of being executed directly via bash\n" +
 	"                  run_as_script: false\n" +
@@ -842,11 +850,27 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
+	"                  # Retries defines how many times the step should be retried when it\n" +
+	"                  # fails. Overrides the policy set for the whole test, if any.\n" +
+	"                  retries:\n" +
+	"                    # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                    attempts: 0\n" +
+	"                    # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                    # doubled for every subsequent retry.\n" +
+	"                    backoff: 0s\n" +
 	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"                  # in the test container instead of being executed directly via bash\n" +
 	"                  run_as_script: false\n" +
 	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"                  timeout: 0s\n" +
+	"            # Retries defines how many times failed steps should be retried, unless\n" +
+	"            # the step defines its own policy.\n" +
+	"            retries:\n" +
+	"                # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                attempts: 0\n" +
+	"                # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                # doubled for every subsequent retry.\n" +
+	"                backoff: 0s\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
 	"                - # As is the name of the LiteralTestStep.\n" +
@@ -932,6 +956,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
+	"                  # Retries defines how many times the step should be retried when it\n" +
+	"                  # fails. Overrides the policy set for the whole test, if any.\n" +
+	"                  retries:\n" +
+	"                    # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                    attempts: 0\n" +
+	"                    # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                    # doubled for every subsequent retry.\n" +
+	"                    backoff: 0s\n" +
 	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"                  # in the test container instead of being executed directly via bash\n" +
 	"                  run_as_script: false\n" +
@@ -1110,6 +1142,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  retries:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    attempts: 0\n" +
+	"                    backoff: 0s\n" +
 	"                  run_as_script: false\n" +
 	"                  timeout: 0s\n" +
 	"            # Pre is the array of test steps run to set up the environment for the test.\n" +
@@ -1173,8 +1209,20 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  retries:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    attempts: 0\n" +
+	"                    backoff: 0s\n" +
 	"                  run_as_script: false\n" +
 	"                  timeout: 0s\n" +
+	"            # Retries defines how many times failed steps should be retried, unless\n" +
+	"            # the step defines its own policy.\n" +
+	"            retries:\n" +
+	"                # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                attempts: 0\n" +
+	"                # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                # doubled for every subsequent retry.\n" +
+	"                backoff: 0s\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
@@ -1236,6 +1284,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  retries:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    attempts: 0\n" +
+	"                    backoff: 0s\n" +
 	"                  run_as_script: false\n" +
 	"                  timeout: 0s\n" +
 	"            # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +
@@ -1565,6 +1617,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
+	"              # Retries defines how many times the step should be retried when it\n" +
+	"              # fails. Overrides the policy set for the whole test, if any.\n" +
+	"              retries:\n" +
+	"                # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                attempts: 0\n" +
+	"                # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                # doubled for every subsequent retry.\n" +
+	"                backoff: 0s\n" +
 	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"              # in the test container instead of being executed directly via bash\n" +
 	"              run_as_script: false\n" +
@@ -1655,11 +1715,27 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
+	"              # Retries defines how many times the step should be retried when it\n" +
+	"              # fails. Overrides the policy set for the whole test, if any.\n" +
+	"              retries:\n" +
+	"                # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                attempts: 0\n" +
+	"                # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                # doubled for every subsequent retry.\n" +
+	"                backoff: 0s\n" +
 	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"              # in the test container instead of being executed directly via bash\n" +
 	"              run_as_script: false\n" +
 	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"              timeout: 0s\n" +
+	"        # Retries defines how many times failed steps should be retried, unless\n" +
+	"        # the step defines its own policy.\n" +
+	"        retries:\n" +
+	"            # Attempts is the maximum number of times a failed step is retried.\n" +
+	"            attempts: 0\n" +
+	"            # Backoff is how long to wait before the first retry. The delay is\n" +
+	"            # doubled for every subsequent retry.\n" +
+	"            backoff: 0s\n" +
 	"        # Test is the array of test steps that define the actual test.\n" +
 	"        test:\n" +
 	"            - # As is the name of the LiteralTestStep.\n" +
@@ -1745,6 +1821,14 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
+	"              # Retries defines how many times the step should be retried when it\n" +
+	"              # fails. Overrides the policy set for the whole test, if any.\n" +
+	"              retries:\n" +
+	"                # Attempts is the maximum number of times a failed step is retried.\n" +
+	"                attempts: 0\n" +
+	"                # Backoff is how long to wait before the first retry. The delay is\n" +
+	"                # doubled for every subsequent retry.\n" +
+	"                backoff: 0s\n" +
 	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
 	"              # in the test container instead of being executed directly via bash\n" +
 	"              run_as_script: false\n" +
@@ -1923,6 +2007,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              retries:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                attempts: 0\n" +
+	"                backoff: 0s\n" +
 	"              run_as_script: false\n" +
 	"              timeout: 0s\n" +
 	"        # Pre is the array of test steps run to set up the environment for the test.\n" +
@@ -1986,8 +2074,20 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              retries:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                attempts: 0\n" +
+	"                backoff: 0s\n" +
 	"              run_as_script: false\n" +
 	"              timeout: 0s\n" +
+	"        # Retries defines how many times failed steps should be retried, unless\n" +
+	"        # the step defines its own policy.\n" +
+	"        retries:\n" +
+	"            # Attempts is the maximum number of times a failed step is retried.\n" +
+	"            attempts: 0\n" +
+	"            # Backoff is how long to wait before the first retry. The delay is\n" +
+	"            # doubled for every subsequent retry.\n" +
+	"            backoff: 0s\n" +
 	"        # Test is the array of test steps that define the actual test.\n" +
 	"        test:\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
@@ -2049,6 +2149,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              retries:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                attempts: 0\n" +
+	"                backoff: 0s\n" +
 	"              run_as_script: false\n" +
 	"              timeout: 0s\n" +
 	"        # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +